@@ -0,0 +1,120 @@
+package zarinpalgo
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPAllowlist restricts requests to configured CIDR ranges. Note that Zarinpal callbacks are
+// browser redirects, so the client address is the customer's unless the callback endpoint is
+// only reachable through known proxies.
+type IPAllowlist struct {
+	allowed []netip.Prefix
+	proxies []netip.Prefix
+	Bypass  bool         // allow every request, e.g. in sandbox mode
+	Denied  http.Handler // answers rejected requests, defaults to 403
+}
+
+// NewIPAllowlist creates a new IPAllowlist of CIDR ranges or single addresses
+func NewIPAllowlist(cidrs ...string) (*IPAllowlist, error) {
+	allowed, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	return &IPAllowlist{allowed: allowed}, nil
+}
+
+// TrustProxies sets the proxies whose X-Forwarded-For header is used to find the client address
+func (a *IPAllowlist) TrustProxies(cidrs ...string) (err error) {
+	a.proxies, err = parsePrefixes(cidrs)
+	return
+}
+
+// Allowed reports whether the request comes from an allowed address
+func (a *IPAllowlist) Allowed(r *http.Request) bool {
+	if a.Bypass {
+		return true
+	}
+
+	addr, ok := a.clientAddr(r)
+	return ok && containsAddr(a.allowed, addr)
+}
+
+// Middleware returns a handler passing allowed requests to next
+func (a *IPAllowlist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Allowed(r) {
+			if a.Denied != nil {
+				a.Denied.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientAddr returns the remote address, or the last X-Forwarded-For hop that isn't a trusted proxy
+func (a *IPAllowlist) clientAddr(r *http.Request) (addr netip.Addr, ok bool) {
+	addr, ok = parseAddr(r.RemoteAddr)
+	if !ok || !containsAddr(a.proxies, addr) {
+		return
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, valid := parseAddr(strings.TrimSpace(hops[i]))
+		if !valid {
+			return netip.Addr{}, false
+		}
+		addr = hop
+		if !containsAddr(a.proxies, hop) {
+			break
+		}
+	}
+	return addr, true
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
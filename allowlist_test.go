@@ -0,0 +1,63 @@
+package zarinpalgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	allowlist, err := NewIPAllowlist("185.231.112.0/24", "2001:db8::/32", "10.1.2.3")
+	if err != nil {
+		t.Fatalf("Failed to create allowlist: %v", err)
+	}
+	if err := allowlist.TrustProxies("127.0.0.1"); err != nil {
+		t.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+
+	handler := allowlist.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		expectedCode int
+	}{
+		{"185.231.112.10:4000", "", http.StatusNoContent},
+		{"[2001:db8::1]:4000", "", http.StatusNoContent},
+		{"10.1.2.3:4000", "", http.StatusNoContent},
+		{"8.8.8.8:4000", "", http.StatusForbidden},
+		{"8.8.8.8:4000", "185.231.112.10", http.StatusForbidden},
+		{"127.0.0.1:4000", "185.231.112.10", http.StatusNoContent},
+		{"127.0.0.1:4000", "185.231.112.10, 8.8.8.8", http.StatusForbidden},
+		{"127.0.0.1:4000", "not-an-ip", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/callback", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Expected status code %d for %s (%s), got %d", test.expectedCode, test.remoteAddr, test.forwardedFor, rec.Code)
+		}
+	}
+
+	allowlist.Bypass = true
+	req := httptest.NewRequest("GET", "/callback", nil)
+	req.RemoteAddr = "8.8.8.8:4000"
+	if !allowlist.Allowed(req) {
+		t.Error("Expected bypass mode to allow every request")
+	}
+}
+
+func TestNewIPAllowlistInvalidRange(t *testing.T) {
+	if _, err := NewIPAllowlist("300.0.0.0/8"); err == nil {
+		t.Error("Expected error for invalid range")
+	}
+}
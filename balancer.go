@@ -0,0 +1,205 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoTerminalAvailable is returned by TerminalBalancer when every terminal is suspended
+var ErrNoTerminalAvailable = errors.New("no terminal available")
+
+// DefaultSuspendFor is how long TerminalBalancer skips a terminal that reported a suspension
+const DefaultSuspendFor = 10 * time.Minute
+
+// suspensionCodes are the gateway errors that keep a terminal from creating payments for a while
+var suspensionCodes = map[int]bool{
+	-11: true, // terminal is not active
+	-12: true, // too many attempts
+	-15: true, // terminal user is suspended
+	-16: true, // terminal user level is not valid
+	-17: true, // terminal user level is not valid, Zarinpal answers it like -16 for some accounts
+}
+
+// IsTerminalSuspended reports whether the error is a gateway answer that the terminal can't
+// create payments at the moment
+func IsTerminalSuspended(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && suspensionCodes[apiErr.Code]
+}
+
+// BalancedTerminal is a terminal of a TerminalBalancer, weights below 1 count as 1
+type BalancedTerminal struct {
+	Name   string
+	Client *Zarinpal
+	Weight int
+}
+
+// TerminalStats are the counters of a terminal of a TerminalBalancer
+type TerminalStats struct {
+	Name           string    `json:"name"`
+	Weight         int       `json:"weight"`
+	Created        int       `json:"created"`
+	Failed         int       `json:"failed"`
+	Suspensions    int       `json:"suspensions"`
+	SuspendedUntil time.Time `json:"suspended_until,omitempty"` // zero when the terminal is in use
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// BalancedPayment is a payment created by a TerminalBalancer, verify it with the client of
+// its terminal
+type BalancedPayment struct {
+	Terminal   string
+	Payment    PaymentCreationResponse
+	PaymentURL string
+}
+
+// TerminalBalancer spreads payment creations across the terminals of a merchant by weight.
+// Terminals answering that they are suspended are skipped for SuspendFor and the payment is
+// created on the next terminal. It is safe for concurrent use.
+type TerminalBalancer struct {
+	SuspendFor time.Duration // DefaultSuspendFor when zero
+
+	// OnSuspend is called when a terminal is skipped after the error
+	OnSuspend func(ctx context.Context, terminal string, err error)
+
+	mu        sync.Mutex
+	terminals []*balancedTerminal
+}
+
+type balancedTerminal struct {
+	BalancedTerminal
+	current int // of the smooth weighted round robin
+	stats   TerminalStats
+}
+
+// NewTerminalBalancer creates a TerminalBalancer over the terminals
+func NewTerminalBalancer(terminals ...BalancedTerminal) *TerminalBalancer {
+	b := &TerminalBalancer{}
+	for _, terminal := range terminals {
+		if terminal.Weight < 1 {
+			terminal.Weight = 1
+		}
+		b.terminals = append(b.terminals, &balancedTerminal{
+			BalancedTerminal: terminal,
+			stats:            TerminalStats{Name: terminal.Name, Weight: terminal.Weight},
+		})
+	}
+	return b
+}
+
+// CreatePayment creates the payment on the next terminal by weight, moving on to the other
+// terminals while they answer they are suspended
+func (b *TerminalBalancer) CreatePayment(ctx context.Context, params PaymentParams) (payment BalancedPayment, err error) {
+	tried := make(map[*balancedTerminal]bool)
+	for {
+		terminal := b.next(tried)
+		if terminal == nil {
+			if err == nil {
+				err = ErrNoTerminalAvailable
+			} else {
+				err = fmt.Errorf("%w: %v", ErrNoTerminalAvailable, err)
+			}
+			return
+		}
+		tried[terminal] = true
+
+		created, createErr := terminal.Client.CreatePayment(ctx, params)
+		b.record(ctx, terminal, createErr)
+		if createErr == nil {
+			payment = BalancedPayment{Terminal: terminal.Name, Payment: created, PaymentURL: terminal.Client.GetPaymentURL(created.Authority)}
+			return payment, nil
+		}
+		if !IsTerminalSuspended(createErr) {
+			return payment, createErr
+		}
+		err = createErr
+	}
+}
+
+// Client returns the client of the terminal, to verify its payments
+func (b *TerminalBalancer) Client(terminal string) (*Zarinpal, bool) {
+	for _, t := range b.terminals {
+		if t.Name == terminal {
+			return t.Client, true
+		}
+	}
+	return nil, false
+}
+
+// Stats returns the counters of the terminals, in the order they were given
+func (b *TerminalBalancer) Stats() []TerminalStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := make([]TerminalStats, len(b.terminals))
+	now := time.Now()
+	for i, terminal := range b.terminals {
+		stats[i] = terminal.stats
+		if !now.Before(stats[i].SuspendedUntil) {
+			stats[i].SuspendedUntil = time.Time{}
+		}
+	}
+	return stats
+}
+
+// next picks the terminal by smooth weighted round robin among the ones that aren't suspended
+// or tried already. Failovers leave the weights alone, the round robin already counted the
+// payment for the terminal picked first and counting it again would skew the shares.
+func (b *TerminalBalancer) next(tried map[*balancedTerminal]bool) *balancedTerminal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	failover := len(tried) > 0
+	var best *balancedTerminal
+	total := 0
+	for _, terminal := range b.terminals {
+		if tried[terminal] || now.Before(terminal.stats.SuspendedUntil) {
+			continue
+		}
+		if failover {
+			if best == nil || terminal.current+terminal.Weight > best.current+best.Weight {
+				best = terminal
+			}
+			continue
+		}
+		terminal.current += terminal.Weight
+		total += terminal.Weight
+		if best == nil || terminal.current > best.current {
+			best = terminal
+		}
+	}
+	if best != nil && !failover {
+		best.current -= total
+	}
+	return best
+}
+
+// record counts the outcome of a creation on the terminal, suspending it on suspension errors
+func (b *TerminalBalancer) record(ctx context.Context, terminal *balancedTerminal, err error) {
+	b.mu.Lock()
+	if err == nil {
+		terminal.stats.Created++
+		b.mu.Unlock()
+		return
+	}
+	terminal.stats.Failed++
+	terminal.stats.LastError = err.Error()
+	suspended := IsTerminalSuspended(err)
+	if suspended {
+		suspendFor := b.SuspendFor
+		if suspendFor <= 0 {
+			suspendFor = DefaultSuspendFor
+		}
+		terminal.stats.Suspensions++
+		terminal.stats.SuspendedUntil = time.Now().Add(suspendFor)
+	}
+	b.mu.Unlock()
+
+	if suspended && b.OnSuspend != nil {
+		terminal.Client.runHook(ctx, "balancer suspend callback", func() { b.OnSuspend(ctx, terminal.Name, err) })
+	}
+}
@@ -0,0 +1,125 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestTerminalBalancerWeights(t *testing.T) {
+	a := newStubClient(t, map[string]string{"request.json": fixtures.RequestSuccess})
+	b := newStubClient(t, map[string]string{"request.json": fixtures.RequestSuccess})
+	balancer := NewTerminalBalancer(
+		BalancedTerminal{Name: "a", Client: a, Weight: 3},
+		BalancedTerminal{Name: "b", Client: b, Weight: 1},
+	)
+
+	params := PaymentParams{Amount: 10000, Description: "Order", CallbackURL: "https://example.com/callback"}
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		payment, err := balancer.CreatePayment(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Failed to create payment: %v", err)
+		}
+		counts[payment.Terminal]++
+		if payment.PaymentURL != a.GetPaymentURL(fixtures.Authority) {
+			t.Errorf("Expected the payment URL of the terminal, got %s", payment.PaymentURL)
+		}
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("Expected payments spread 6 to 2, got %v", counts)
+	}
+
+	stats := balancer.Stats()
+	if stats[0].Created != 6 || stats[1].Created != 2 || stats[0].Weight != 3 {
+		t.Errorf("Expected the counters of the terminals, got %+v", stats)
+	}
+	if client, ok := balancer.Client("b"); !ok || client != b {
+		t.Error("Expected the client of terminal b")
+	}
+}
+
+func TestTerminalBalancerSuspension(t *testing.T) {
+	suspended := newStubClient(t, map[string]string{"request.json": fixtures.Error(-15)})
+	healthy := newStubClient(t, map[string]string{"request.json": fixtures.RequestSuccess})
+	balancer := NewTerminalBalancer(
+		BalancedTerminal{Name: "suspended", Client: suspended, Weight: 10},
+		BalancedTerminal{Name: "healthy", Client: healthy, Weight: 1},
+	)
+	var skipped []string
+	balancer.OnSuspend = func(ctx context.Context, terminal string, err error) {
+		skipped = append(skipped, terminal)
+	}
+
+	params := PaymentParams{Amount: 10000, Description: "Order", CallbackURL: "https://example.com/callback"}
+	for i := 0; i < 3; i++ {
+		payment, err := balancer.CreatePayment(context.Background(), params)
+		if err != nil || payment.Terminal != "healthy" {
+			t.Fatalf("Expected the payment created on the healthy terminal, got %+v %v", payment, err)
+		}
+	}
+	if len(skipped) != 1 || skipped[0] != "suspended" {
+		t.Errorf("Expected the suspended terminal skipped once, got %v", skipped)
+	}
+
+	stats := balancer.Stats()
+	if stats[0].Suspensions != 1 || stats[0].Failed != 1 || stats[0].SuspendedUntil.IsZero() || stats[0].LastError == "" {
+		t.Errorf("Expected the suspension counted, got %+v", stats[0])
+	}
+	if stats[1].Created != 3 {
+		t.Errorf("Expected 3 payments on the healthy terminal, got %+v", stats[1])
+	}
+}
+
+func TestTerminalBalancerUnavailable(t *testing.T) {
+	suspended := newStubClient(t, map[string]string{"request.json": fixtures.Error(-11)})
+	balancer := NewTerminalBalancer(BalancedTerminal{Name: "a", Client: suspended})
+	balancer.SuspendFor = time.Hour
+
+	params := PaymentParams{Amount: 10000, Description: "Order", CallbackURL: "https://example.com/callback"}
+	_, err := balancer.CreatePayment(context.Background(), params)
+	if !errors.Is(err, ErrNoTerminalAvailable) {
+		t.Errorf("Expected ErrNoTerminalAvailable, got %v", err)
+	}
+	if _, err := balancer.CreatePayment(context.Background(), params); !errors.Is(err, ErrNoTerminalAvailable) {
+		t.Errorf("Expected the terminal still skipped, got %v", err)
+	}
+
+	failing := newStubClient(t, map[string]string{"request.json": fixtures.Error(-9)})
+	balancer = NewTerminalBalancer(BalancedTerminal{Name: "a", Client: failing})
+	var apiErr *APIError
+	if _, err := balancer.CreatePayment(context.Background(), params); !errors.As(err, &apiErr) || apiErr.Code != -9 {
+		t.Errorf("Expected other errors returned as is, got %v", err)
+	}
+	if stats := balancer.Stats(); !stats[0].SuspendedUntil.IsZero() {
+		t.Errorf("Expected the terminal kept in use, got %+v", stats[0])
+	}
+}
+
+func TestTerminalBalancerFailoverKeepsWeights(t *testing.T) {
+	balancer := NewTerminalBalancer(
+		BalancedTerminal{Name: "a", Weight: 2},
+		BalancedTerminal{Name: "b", Weight: 1},
+		BalancedTerminal{Name: "c", Weight: 1},
+	)
+	first := balancer.next(map[*balancedTerminal]bool{})
+	if first.Name != "a" {
+		t.Fatalf("Expected terminal a picked first, got %s", first.Name)
+	}
+	before := []int{balancer.terminals[0].current, balancer.terminals[1].current, balancer.terminals[2].current}
+
+	failover := balancer.next(map[*balancedTerminal]bool{first: true})
+	if failover == nil || failover == first {
+		t.Fatalf("Expected another terminal, got %+v", failover)
+	}
+	after := []int{balancer.terminals[0].current, balancer.terminals[1].current, balancer.terminals[2].current}
+	for i := range before {
+		if before[i] != after[i] {
+			t.Errorf("Expected the failover to leave the weights alone, got %v then %v", before, after)
+			break
+		}
+	}
+}
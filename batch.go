@@ -0,0 +1,63 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBatchConcurrency is how many payments CreatePayments creates at once by default
+const DefaultBatchConcurrency = 4
+
+// PaymentCreationResult is the outcome of creating one payment of a batch
+type PaymentCreationResult struct {
+	Params     PaymentParams
+	Payment    PaymentCreationResponse
+	PaymentURL string // empty when the creation failed
+	Err        error
+}
+
+// CreatePayments creates the payments with at most concurrency requests at once, or
+// DefaultBatchConcurrency when it isn't positive. Results are ordered like the params and a
+// failed payment doesn't stop the others, payments not started when the context is done fail
+// with its error.
+func (z *Zarinpal) CreatePayments(ctx context.Context, params []PaymentParams, concurrency int) []PaymentCreationResult {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make([]PaymentCreationResult, len(params))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range params {
+		results[i].Params = params[i]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *PaymentCreationResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result.Payment, result.Err = z.CreatePayment(ctx, result.Params)
+			if result.Err == nil {
+				result.PaymentURL = z.GetPaymentURL(result.Payment.Authority)
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// FailedPayments returns the results of the payments that couldn't be created, to retry them
+func FailedPayments(results []PaymentCreationResult) (failed []PaymentCreationResult) {
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return
+}
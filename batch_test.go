@@ -0,0 +1,81 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestCreatePayments(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Amount int `json:"amount"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if body.Amount == 0 {
+			w.Write([]byte(fixtures.Error(-9)))
+			return
+		}
+		w.Write([]byte(fmt.Sprintf(`{"data":{"code":100,"message":"Success","authority":"A%d","fee_type":"Merchant","fee":100},"errors":[]}`, body.Amount)))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	params := make([]PaymentParams, 10)
+	for i := range params {
+		params[i] = PaymentParams{Amount: (i + 1) * 1000, Description: "Invoice", CallbackURL: "https://example.com/callback"}
+	}
+	params[3].Amount = 0
+
+	results := zp.CreatePayments(context.Background(), params, 3)
+	if len(results) != len(params) {
+		t.Fatalf("Expected %d results, got %d", len(params), len(results))
+	}
+	for i, result := range results {
+		if i == 3 {
+			continue
+		}
+		authority := fmt.Sprintf("A%d", (i+1)*1000)
+		if result.Err != nil || result.Payment.Authority != authority || result.PaymentURL != zp.GetPaymentURL(authority) {
+			t.Errorf("Expected payment %s at %d, got %+v", authority, i, result)
+		}
+	}
+	if failed := FailedPayments(results); len(failed) != 1 || failed[0].Params.Amount != 0 || failed[0].PaymentURL != "" {
+		t.Errorf("Expected the payment without amount to fail, got %+v", failed)
+	}
+	if peak > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", peak)
+	}
+}
+
+func TestCreatePaymentsCanceled(t *testing.T) {
+	zp := New("merchant-1")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := zp.CreatePayments(ctx, make([]PaymentParams, 3), 1)
+	if len(FailedPayments(results)) != 3 {
+		t.Errorf("Expected every payment to fail, got %+v", results)
+	}
+}
@@ -0,0 +1,157 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Payment parameter validation errors, amounts are checked against ErrInvalidAmount
+var (
+	ErrMissingDescription = errors.New("payment description is required")
+	ErrInvalidCallbackURL = errors.New("callback URL must be an absolute http or https URL")
+	ErrInvalidWage        = errors.New("invalid wage")
+)
+
+// Validate checks the parameters before they are sent to the gateway
+func (p PaymentParams) Validate() error {
+	if p.Amount <= 0 {
+		return fmt.Errorf("%w: %d, it must be positive", ErrInvalidAmount, p.Amount)
+	}
+	if err := checkMinAmount(p.Amount, p.Currency, nil); err != nil {
+		return err
+	}
+	if SanitizeDescription(p.Description) == "" {
+		return ErrMissingDescription
+	}
+	if u, err := url.Parse(p.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidCallbackURL, p.CallbackURL)
+	}
+
+	wages := 0
+	for i, wage := range p.Wages {
+		if wage.Iban == "" || wage.Amount <= 0 {
+			return fmt.Errorf("%w: wage %d needs an IBAN and a positive amount", ErrInvalidWage, i+1)
+		}
+		wages += wage.Amount
+	}
+	if wages > p.Amount {
+		return fmt.Errorf("%w: wages total %d exceeds the amount %d", ErrInvalidWage, wages, p.Amount)
+	}
+	return nil
+}
+
+// PaymentBuilder assembles the parameters of a payment step by step, start one with
+// Zarinpal.Payment. The parameters are validated when the payment is created.
+type PaymentBuilder struct {
+	z      *Zarinpal
+	params PaymentParams
+	err    error // of a step, returned instead of validating
+}
+
+// Payment starts building a payment:
+//
+//	z.Payment().Amount(50000).Toman().Description("Order 1024").Callback(url).Wage(iban, 5000).Create(ctx)
+func (z *Zarinpal) Payment() *PaymentBuilder {
+	return &PaymentBuilder{z: z}
+}
+
+// Amount sets the amount, in Rials unless Toman is called
+func (b *PaymentBuilder) Amount(amount int) *PaymentBuilder {
+	b.params.Amount = amount
+	return b
+}
+
+// Money sets the amount and its currency
+func (b *PaymentBuilder) Money(amount Money) *PaymentBuilder {
+	b.params.Amount = amount.Value()
+	b.params.Currency = amount.Currency()
+	return b
+}
+
+// Rial sets the currency of the amount and wages to Rials, the default
+func (b *PaymentBuilder) Rial() *PaymentBuilder {
+	b.params.Currency = CurrencyRial
+	return b
+}
+
+// Toman sets the currency of the amount and wages to Tomans
+func (b *PaymentBuilder) Toman() *PaymentBuilder {
+	b.params.Currency = CurrencyToman
+	return b
+}
+
+// Description sets the description shown to the user
+func (b *PaymentBuilder) Description(description string) *PaymentBuilder {
+	b.params.Description = description
+	return b
+}
+
+// Callback sets the URL the user returns to after paying
+func (b *PaymentBuilder) Callback(callbackURL string) *PaymentBuilder {
+	b.params.CallbackURL = callbackURL
+	return b
+}
+
+// Mobile sets the mobile number of the payer
+func (b *PaymentBuilder) Mobile(mobile string) *PaymentBuilder {
+	b.metadata().Mobile = mobile
+	return b
+}
+
+// Email sets the email of the payer
+func (b *PaymentBuilder) Email(email string) *PaymentBuilder {
+	b.metadata().Email = email
+	return b
+}
+
+// OrderID sets the order ID of the metadata
+func (b *PaymentBuilder) OrderID(orderID string) *PaymentBuilder {
+	b.metadata().OrderID = orderID
+	return b
+}
+
+func (b *PaymentBuilder) metadata() *Metadata {
+	if b.params.Metadata == nil {
+		b.params.Metadata = &Metadata{}
+	}
+	return b.params.Metadata
+}
+
+// Wage pays amount of the payment to the IBAN, an optional description is shown in the
+// settlement report
+func (b *PaymentBuilder) Wage(iban string, amount int, description ...string) *PaymentBuilder {
+	wage := Wage{Iban: iban, Amount: amount}
+	if len(description) > 0 {
+		wage.Description = description[0]
+	}
+	b.params.Wages = append(b.params.Wages, wage)
+	return b
+}
+
+// Params validates and returns the parameters built so far
+func (b *PaymentBuilder) Params() (PaymentParams, error) {
+	if b.err != nil {
+		return b.params, b.err
+	}
+	return b.params, b.params.Validate()
+}
+
+// Create validates the parameters and creates the payment
+func (b *PaymentBuilder) Create(ctx context.Context) (payment PaymentCreationResponse, err error) {
+	params, err := b.Params()
+	if err != nil {
+		return
+	}
+	return b.z.CreatePayment(ctx, params)
+}
+
+// Session validates the parameters and creates the payment as a session, see NewSession
+func (b *PaymentBuilder) Session(ctx context.Context) (session PaymentSession, err error) {
+	params, err := b.Params()
+	if err != nil {
+		return
+	}
+	return b.z.NewSession(ctx, params)
+}
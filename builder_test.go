@@ -0,0 +1,94 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPaymentBuilder(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`,
+	})
+
+	b := zp.Payment().Amount(50000).Toman().Description("Order 1024").Callback("https://example.com/callback").
+		Mobile("09120000000").OrderID("1024").Wage("IR000000000000000000000001", 5000, "Seller")
+	params, err := b.Params()
+	if err != nil {
+		t.Fatalf("Expected valid parameters, got %v", err)
+	}
+	if params.Amount != 50000 || params.Currency != CurrencyToman || params.Metadata.Mobile != "09120000000" || params.Metadata.OrderID != "1024" {
+		t.Errorf("Unexpected parameters %+v", params)
+	}
+	if len(params.Wages) != 1 || params.Wages[0].Description != "Seller" {
+		t.Errorf("Expected one wage, got %+v", params.Wages)
+	}
+
+	payment, err := b.Create(context.Background())
+	if err != nil || payment.Authority != "A1" {
+		t.Errorf("Expected payment A1, got %+v %v", payment, err)
+	}
+
+	session, err := zp.Payment().Money(Rial(120000)).Description("Order 1025").Callback("https://example.com/callback").Session(context.Background())
+	if err != nil || session.Amount != 120000 || session.Currency != CurrencyRial {
+		t.Errorf("Expected a session of 120000 Rials, got %+v %v", session, err)
+	}
+}
+
+func TestPaymentParamsValidate(t *testing.T) {
+	valid := PaymentParams{Amount: 10000, Description: "Order 1", CallbackURL: "https://example.com/callback"}
+
+	tests := []struct {
+		name   string
+		modify func(p *PaymentParams)
+		err    error
+	}{
+		{"valid", func(p *PaymentParams) {}, nil},
+		{"zero amount", func(p *PaymentParams) { p.Amount = 0 }, ErrInvalidAmount},
+		{"below the Rial minimum", func(p *PaymentParams) { p.Amount = MinAmountRial - 1 }, ErrInvalidAmount},
+		{"below the Toman minimum", func(p *PaymentParams) { p.Amount, p.Currency = 99, CurrencyToman }, ErrInvalidAmount},
+		{"Toman minimum", func(p *PaymentParams) { p.Amount, p.Currency = 100, CurrencyToman }, nil},
+		{"no description", func(p *PaymentParams) { p.Description = "" }, ErrMissingDescription},
+		{"relative callback", func(p *PaymentParams) { p.CallbackURL = "/callback" }, ErrInvalidCallbackURL},
+		{"ftp callback", func(p *PaymentParams) { p.CallbackURL = "ftp://example.com/callback" }, ErrInvalidCallbackURL},
+		{"wage without IBAN", func(p *PaymentParams) { p.Wages = []Wage{{Amount: 1000}} }, ErrInvalidWage},
+		{"wages over amount", func(p *PaymentParams) {
+			p.Wages = []Wage{{Iban: "IR1", Amount: 6000}, {Iban: "IR2", Amount: 6000}}
+		}, ErrInvalidWage},
+	}
+
+	for _, test := range tests {
+		params := valid
+		test.modify(&params)
+		if err := params.Validate(); !errors.Is(err, test.err) || (test.err == nil) != (err == nil) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestPaymentBuilderValidatesOnCreate(t *testing.T) {
+	zp := newStubClient(t, nil)
+
+	if _, err := zp.Payment().Amount(10000).Callback("https://example.com/callback").Create(context.Background()); !errors.Is(err, ErrMissingDescription) {
+		t.Errorf("Expected ErrMissingDescription before any request, got %v", err)
+	}
+}
+
+func TestMinAmounts(t *testing.T) {
+	zp := newStubClient(t, nil)
+
+	_, err := zp.CreatePayment(context.Background(), PaymentParams{Amount: 500, Description: "Order 1", CallbackURL: "https://example.com/callback"})
+	if !errors.Is(err, ErrInvalidAmount) || !strings.Contains(err.Error(), "500 IRR is below the minimum of 1,000 IRR") {
+		t.Errorf("Expected the minimum in the error, got %v", err)
+	}
+
+	zp.MinAmounts = map[Currency]int{CurrencyRial: 20000}
+	_, err = zp.CreatePayment(context.Background(), PaymentParams{Amount: 10000, Description: "Order 1", CallbackURL: "https://example.com/callback"})
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected the overridden minimum to apply, got %v", err)
+	}
+	if min, _ := MinAmount(CurrencyRial); min != MinAmountRial {
+		t.Errorf("Expected the override kept to the client, got a minimum of %d", min)
+	}
+}
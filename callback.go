@@ -0,0 +1,66 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CallbackStatus is the payment outcome reported by Zarinpal on the callback URL
+type CallbackStatus string
+
+// CallbackStatus values
+const (
+	CallbackStatusOK  CallbackStatus = "OK"  // User completed the payment, it still has to be verified
+	CallbackStatusNOK CallbackStatus = "NOK" // User canceled or the payment failed
+)
+
+// Callback query parameter names
+const (
+	CallbackAuthorityParam = "Authority"
+	CallbackStatusParam    = "Status"
+)
+
+var (
+	ErrMissingAuthority      = errors.New("callback is missing the authority parameter")
+	ErrInvalidCallbackStatus = errors.New("callback has an invalid status parameter")
+)
+
+// CallbackData holds the parameters Zarinpal appends to the callback URL
+type CallbackData struct {
+	Authority string
+	Status    CallbackStatus
+	Values    url.Values // all query values, including the ones set by the merchant on the callback URL
+}
+
+// IsOK reports whether the user completed the payment
+func (c CallbackData) IsOK() bool {
+	return c.Status == CallbackStatusOK
+}
+
+// ParseCallback parses the callback parameters from an incoming request
+func ParseCallback(r *http.Request) (CallbackData, error) {
+	return ParseCallbackValues(r.URL.Query())
+}
+
+// ParseCallbackValues parses the callback parameters from query values
+func ParseCallbackValues(values url.Values) (callbackData CallbackData, err error) {
+	authority := strings.TrimSpace(values.Get(CallbackAuthorityParam))
+	if authority == "" {
+		err = ErrMissingAuthority
+		return
+	}
+
+	status := CallbackStatus(strings.ToUpper(strings.TrimSpace(values.Get(CallbackStatusParam))))
+	if status != CallbackStatusOK && status != CallbackStatusNOK {
+		err = ErrInvalidCallbackStatus
+		return
+	}
+
+	return CallbackData{
+		Authority: authority,
+		Status:    status,
+		Values:    values,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package zarinpalgo
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseCallback(t *testing.T) {
+	req := httptest.NewRequest("GET", "/callback?order=42&Authority=A000000000000000000000000000000&Status=OK", nil)
+
+	callback, err := ParseCallback(req)
+	if err != nil {
+		t.Fatalf("Failed to parse callback: %v", err)
+	}
+
+	if callback.Authority != "A000000000000000000000000000000" {
+		t.Errorf("Expected authority %s, got %s", "A000000000000000000000000000000", callback.Authority)
+	}
+	if !callback.IsOK() {
+		t.Errorf("Expected status OK, got %s", callback.Status)
+	}
+	if callback.Values.Get("order") != "42" {
+		t.Errorf("Expected merchant parameter to be kept, got %q", callback.Values.Get("order"))
+	}
+}
+
+func TestParseCallbackValuesErrors(t *testing.T) {
+	tests := []struct {
+		values url.Values
+		err    error
+	}{
+		{url.Values{"Status": {"OK"}}, ErrMissingAuthority},
+		{url.Values{"Authority": {"A1"}}, ErrInvalidCallbackStatus},
+		{url.Values{"Authority": {"A1"}, "Status": {"MAYBE"}}, ErrInvalidCallbackStatus},
+	}
+
+	for _, test := range tests {
+		_, err := ParseCallbackValues(test.values)
+		if err != test.err {
+			t.Errorf("Expected error %v for %v, got %v", test.err, test.values, err)
+		}
+	}
+}
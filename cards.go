@@ -0,0 +1,113 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CardPayment is a successful payment of a card, known by the hash Zarinpal reports for it
+type CardPayment struct {
+	CardHash  string    `json:"card_hash"`
+	Authority string    `json:"authority"`
+	RefID     int       `json:"ref_id"`
+	Amount    int       `json:"amount"`
+	PaidAt    time.Time `json:"paid_at"`
+}
+
+// CardStats sums up the payments of a card
+type CardStats struct {
+	CardHash    string    `json:"card_hash"`
+	Payments    int       `json:"payments"`
+	TotalAmount int       `json:"total_amount"`
+	FirstPaidAt time.Time `json:"first_paid_at,omitempty"`
+	LastPaidAt  time.Time `json:"last_paid_at,omitempty"`
+}
+
+// PaidBefore reports whether the card paid at least once
+func (s CardStats) PaidBefore() bool {
+	return s.Payments > 0
+}
+
+// CardHistory records the cards customers paid with, so returning customers can be recognized
+// without storing card numbers. Stats of unknown cards are empty, not an error.
+type CardHistory interface {
+	// RecordCard records the payment, recording an authority again has no effect
+	RecordCard(ctx context.Context, payment CardPayment) error
+	CardStats(ctx context.Context, cardHash string) (CardStats, error)
+}
+
+// WithCardHistory records the cards of the successful callbacks in the history
+func WithCardHistory(history CardHistory) CallbackOption {
+	return func(o *callbackOptions) {
+		o.cards = history
+	}
+}
+
+// RecordCardPayment records the card of a successful verification, other statuses and statuses
+// without a card hash are skipped. Repeated verifications are recorded too, the history records
+// an authority once, so a redelivered callback records a card the first delivery failed to.
+func RecordCardPayment(ctx context.Context, history CardHistory, status PaymentStatus) error {
+	if !status.IsSuccessful || status.CardHash == "" {
+		return nil
+	}
+	return history.RecordCard(ctx, CardPayment{
+		CardHash:  status.CardHash,
+		Authority: status.Authority,
+		RefID:     status.RefID,
+		Amount:    status.Amount,
+		PaidAt:    time.Now(),
+	})
+}
+
+// MemoryCardHistory is a CardHistory keeping the stats of cards in memory, it is safe for concurrent use
+type MemoryCardHistory struct {
+	mu          sync.Mutex
+	cards       map[string]*CardStats
+	authorities map[string]bool
+}
+
+// NewMemoryCardHistory creates an empty MemoryCardHistory
+func NewMemoryCardHistory() *MemoryCardHistory {
+	return &MemoryCardHistory{
+		cards:       make(map[string]*CardStats),
+		authorities: make(map[string]bool),
+	}
+}
+
+// RecordCard implements CardHistory
+func (h *MemoryCardHistory) RecordCard(ctx context.Context, payment CardPayment) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.authorities[payment.Authority] {
+		return nil
+	}
+	h.authorities[payment.Authority] = true
+
+	stats, ok := h.cards[payment.CardHash]
+	if !ok {
+		stats = &CardStats{CardHash: payment.CardHash, FirstPaidAt: payment.PaidAt}
+		h.cards[payment.CardHash] = stats
+	}
+	stats.Payments++
+	stats.TotalAmount += payment.Amount
+	if payment.PaidAt.Before(stats.FirstPaidAt) {
+		stats.FirstPaidAt = payment.PaidAt
+	}
+	if payment.PaidAt.After(stats.LastPaidAt) {
+		stats.LastPaidAt = payment.PaidAt
+	}
+	return nil
+}
+
+// CardStats implements CardHistory
+func (h *MemoryCardHistory) CardStats(ctx context.Context, cardHash string) (CardStats, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if stats, ok := h.cards[cardHash]; ok {
+		return *stats, nil
+	}
+	return CardStats{CardHash: cardHash}, nil
+}
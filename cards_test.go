@@ -0,0 +1,52 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMemoryCardHistory(t *testing.T) {
+	ctx := context.Background()
+	history := NewMemoryCardHistory()
+	first := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	history.RecordCard(ctx, CardPayment{CardHash: "H1", Authority: "A1", Amount: 10000, PaidAt: first.Add(time.Hour)})
+	history.RecordCard(ctx, CardPayment{CardHash: "H1", Authority: "A2", Amount: 5000, PaidAt: first})
+	history.RecordCard(ctx, CardPayment{CardHash: "H1", Authority: "A2", Amount: 5000, PaidAt: first})
+
+	stats, err := history.CardStats(ctx, "H1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.PaidBefore() || stats.Payments != 2 || stats.TotalAmount != 15000 || !stats.FirstPaidAt.Equal(first) || !stats.LastPaidAt.Equal(first.Add(time.Hour)) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats, _ := history.CardStats(ctx, "H2"); stats.PaidBefore() {
+		t.Errorf("Expected an unknown card not to have paid, got %+v", stats)
+	}
+}
+
+func TestProcessCallbackWithCardHistory(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201,"card_hash":"H1","card_pan":"502229******5995"},"errors":[]}`,
+	})
+	history := NewMemoryCardHistory()
+	lookup := func(ctx context.Context, callback CallbackData) (int, error) { return 10000, nil }
+
+	for _, authority := range []string{"A1", "A2"} {
+		values := url.Values{"Authority": {authority}, "Status": {"OK"}}
+		status, err := zp.ProcessCallback(context.Background(), values, lookup, WithCardHistory(history))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.CardHash != "H1" {
+			t.Errorf("Expected card hash H1, got %q", status.CardHash)
+		}
+	}
+
+	if stats, _ := history.CardStats(context.Background(), "H1"); stats.Payments != 2 {
+		t.Errorf("Expected the card to have paid twice, got %+v", stats)
+	}
+}
@@ -0,0 +1,17 @@
+package zarinpalgo
+
+import "context"
+
+// Client is the set of gateway operations implemented by *Zarinpal. Depend on it instead of
+// *Zarinpal to inject fakes in tests.
+type Client interface {
+	CreatePayment(ctx context.Context, params PaymentParams) (PaymentCreationResponse, error)
+	NewPayment(ctx context.Context, amount int, description string, metadata *Metadata, callbackURL string, wages []Wage) (PaymentCreationResponse, error)
+	VerifyPayment(ctx context.Context, amount int, authority string) (PaymentVerificationResponse, error)
+	CheckPaymentStatus(ctx context.Context, amount int, authority string) (PaymentStatus, error)
+	InquirePayment(ctx context.Context, authority string) (PaymentInquiryResponse, error)
+	UnverifiedPayments(ctx context.Context) (UnverifiedPaymentsResponse, error)
+	GetPaymentURL(authority string) string
+}
+
+var _ Client = (*Zarinpal)(nil)
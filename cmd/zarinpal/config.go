@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables selecting the config file and profile
+const (
+	ConfigEnv  = "ZARINPAL_CONFIG"
+	ProfileEnv = "ZARINPAL_PROFILE"
+)
+
+// Profile is a named set of credentials and endpoints in the config file
+type Profile struct {
+	MerchantID   string `yaml:"merchant_id"`
+	Sandbox      bool   `yaml:"sandbox"`
+	APIURL       string `yaml:"api_url"`
+	PaymentURL   string `yaml:"payment_url"`
+	AccessToken  string `yaml:"access_token"`
+	TerminalID   string `yaml:"terminal_id"`
+	ReportingURL string `yaml:"reporting_url"`
+}
+
+// Config is the config file, for example
+//
+//	default: prod
+//	profiles:
+//	  prod:
+//	    merchant_id: 00000000-0000-0000-0000-000000000000
+//	    access_token: ...
+//	    terminal_id: "123456"
+//	  sandbox:
+//	    merchant_id: 00000000-0000-0000-0000-000000000000
+//	    sandbox: true
+type Config struct {
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// configPath returns the path of the config file, $ZARINPAL_CONFIG or ~/.config/zarinpal/config.yaml
+func (c *cli) configPath() string {
+	if c.configFile != "" {
+		return c.configFile
+	}
+	if path := c.getenv(ConfigEnv); path != "" {
+		return path
+	}
+
+	dir := c.getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(c.getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "zarinpal", "config.yaml")
+}
+
+// loadConfig reads the config file, a missing file is an empty config unless it was asked for
+func (c *cli) loadConfig() (config Config, err error) {
+	path := c.configPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && c.configFile == "" && c.getenv(ConfigEnv) == "" {
+		return Config{}, nil
+	}
+	if err != nil {
+		return
+	}
+
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
+
+// profile returns the selected profile: -profile, $ZARINPAL_PROFILE or the default of the config.
+// Without any, an empty profile is returned.
+func (c *cli) profile() (Profile, error) {
+	config, err := c.loadConfig()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := c.profileName
+	if name == "" {
+		name = c.getenv(ProfileEnv)
+	}
+	if name == "" {
+		name = config.Default
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q in %s", name, c.configPath())
+	}
+	return profile, nil
+}
+
+func runProfiles(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("profiles")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	config, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summaries := make([]profileSummary, 0, len(names))
+	for _, name := range names {
+		profile := config.Profiles[name]
+		summaries = append(summaries, profileSummary{
+			Name:       name,
+			Default:    name == config.Default,
+			MerchantID: profile.MerchantID,
+			Sandbox:    profile.Sandbox,
+			TerminalID: profile.TerminalID,
+		})
+	}
+
+	return c.print(summaries, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROFILE\tMERCHANT\tSANDBOX\tTERMINAL")
+		for _, s := range summaries {
+			name := s.Name
+			if s.Default {
+				name += " (default)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", name, s.MerchantID, s.Sandbox, s.TerminalID)
+		}
+		return tw.Flush()
+	})
+}
+
+// profileSummary is the output of the profiles command, credentials are left out
+type profileSummary struct {
+	Name       string `json:"name"`
+	Default    bool   `json:"default"`
+	MerchantID string `json:"merchant_id"`
+	Sandbox    bool   `json:"sandbox"`
+	TerminalID string `json:"terminal_id,omitempty"`
+}
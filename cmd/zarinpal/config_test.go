@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zarinpal", "config.yaml")
+	os.MkdirAll(filepath.Dir(path), 0o700)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return dir
+}
+
+func TestProfiles(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+	sim.MerchantID = "merchant-sim"
+
+	dir := writeConfig(t, `default: prod
+profiles:
+  prod:
+    merchant_id: merchant-prod
+    api_url: `+sim.URL+`/pg/v4/payment/
+  sim:
+    merchant_id: merchant-sim
+    api_url: `+sim.URL+`/pg/v4/payment/
+    terminal_id: "42"
+`)
+	env := map[string]string{"XDG_CONFIG_HOME": dir}
+
+	code, _, stderr := runCLI(t, env, "unverified")
+	if code != 1 || !strings.Contains(stderr, "-10") {
+		t.Errorf("Expected the default profile to be rejected by the simulator, got %d %q", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, env, "unverified", "-profile", "sim")
+	if code != 0 {
+		t.Errorf("Expected the sim profile to be accepted, got %d %q", code, stderr)
+	}
+
+	env[ProfileEnv] = "sim"
+	code, _, stderr = runCLI(t, env, "unverified")
+	if code != 0 {
+		t.Errorf("Expected $%s to select the sim profile, got %d %q", ProfileEnv, code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, env, "unverified", "-merchant", "merchant-other")
+	if code != 1 {
+		t.Errorf("Expected -merchant to override the profile, got %d %q", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, env, "unverified", "-profile", "staging")
+	if code != 1 || !strings.Contains(stderr, `unknown profile "staging"`) {
+		t.Errorf("Expected unknown profile error, got %d %q", code, stderr)
+	}
+
+	code, stdout, _ := runCLI(t, env, "profiles")
+	if code != 0 || !strings.Contains(stdout, "prod (default)") || !strings.Contains(stdout, "merchant-sim") {
+		t.Errorf("Expected the profiles to be listed, got %d %q", code, stdout)
+	}
+}
+
+func TestMissingConfig(t *testing.T) {
+	code, _, stderr := runCLI(t, nil, "unverified", "-config", filepath.Join(t.TempDir(), "missing.yaml"))
+	if code != 1 || !strings.Contains(stderr, "missing.yaml") {
+		t.Errorf("Expected an explicit missing config to fail, got %d %q", code, stderr)
+	}
+}
@@ -0,0 +1,252 @@
+// Command zarinpal creates, verifies and inspects Zarinpal payments from the terminal.
+// Credentials are read from flags, the environment or a profile of ~/.config/zarinpal/config.yaml.
+//
+//	zarinpal request -merchant <id> -amount 10000 -description "Test" -callback https://example.com/callback
+//	zarinpal verify -merchant <id> -amount 10000 -authority A0000000000000000000000000000wwOGYpd
+//	zarinpal simulate -port 8099
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Environment variables read when the matching flags are not given
+const (
+	MerchantIDEnv  = "ZARINPAL_MERCHANT_ID"
+	AccessTokenEnv = "ZARINPAL_ACCESS_TOKEN"
+	TerminalIDEnv  = "ZARINPAL_TERMINAL_ID"
+)
+
+var (
+	errMissingMerchant = errors.New("missing merchant ID, use -merchant or $" + MerchantIDEnv)
+	errMissingToken    = errors.New("missing access token and terminal ID, use -token and -terminal or $" + AccessTokenEnv + " and $" + TerminalIDEnv)
+)
+
+// command is a subcommand of the tool
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []command{
+	{"request", "create a payment and print its StartPay URL", runRequest},
+	{"verify", "verify a payment", runVerify},
+	{"unverified", "list paid payments that were not verified", runUnverified},
+	{"reverse", "return a successful payment to the user's card", runReverse},
+	{"refund", "refund a verified transaction through the reporting API", runRefund},
+	{"transactions", "list and export the transactions of the terminal", runTransactions},
+	{"watch", "poll payments waiting for verification and optionally verify them", runWatch},
+	{"replay", "replay recorded gateway interactions and diff the responses", runReplay},
+	{"simulate", "serve a local fake gateway with its StartPay page", runSimulate},
+	{"tui", "interactive dashboard of recent payments", runTUI},
+	{"profiles", "list the profiles of the config file", runProfiles},
+}
+
+// cli holds what the commands write to and read from
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	// set by the -config, -profile and -output flags of every command
+	configFile  string
+	profileName string
+	output      outputFormat
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes the command line and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		return 2
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr, getenv: getenv}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(ctx, c, args[1:]); err != nil {
+			if errors.Is(err, errUsage) {
+				return 2
+			}
+			fmt.Fprintf(stderr, "zarinpal %s: %v\n", cmd.name, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "zarinpal: unknown command %q\n", args[0])
+	printUsage(stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: zarinpal <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "zarinpal <command> -h" for the flags of a command.`)
+}
+
+// flagSet returns a flag set reporting errors instead of exiting, with the -config, -profile and
+// -output flags
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("zarinpal "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configFile, "config", "", "config file, defaults to $"+ConfigEnv+" or ~/.config/zarinpal/config.yaml")
+	fs.StringVar(&c.profileName, "profile", "", "config profile, defaults to $"+ProfileEnv+" or the default profile of the config")
+	fs.Var(&c.output, "output", "output format: table, json or yaml")
+	return fs
+}
+
+// errUsage is returned for invalid flags, the flag set already reported them
+var errUsage = errors.New("usage")
+
+// parseFlags parses the flags of a command
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// firstOf returns the first non-empty value
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clientFlags are the flags selecting the merchant and the gateway, flags take precedence over
+// the environment and the environment over the profile
+type clientFlags struct {
+	cli        *cli
+	fs         *flag.FlagSet
+	merchantID string
+	sandbox    bool
+	apiURL     string
+	paymentURL string
+}
+
+func (c *cli) clientFlags(fs *flag.FlagSet) *clientFlags {
+	f := &clientFlags{cli: c, fs: fs}
+	fs.StringVar(&f.merchantID, "merchant", "", "merchant ID, defaults to $"+MerchantIDEnv+" or the profile")
+	fs.BoolVar(&f.sandbox, "sandbox", false, "use the sandbox gateway")
+	fs.StringVar(&f.apiURL, "api-url", "", "base URL of the payment API, overrides -sandbox")
+	fs.StringVar(&f.paymentURL, "payment-url", "", "base URL of the StartPay page, overrides -sandbox")
+	return f
+}
+
+// client returns the client selected by the flags
+func (f *clientFlags) client() (*zarinpalgo.Zarinpal, error) {
+	profile, err := f.cli.profile()
+	if err != nil {
+		return nil, err
+	}
+
+	merchantID := firstOf(f.merchantID, f.cli.getenv(MerchantIDEnv), profile.MerchantID)
+	if merchantID == "" {
+		return nil, errMissingMerchant
+	}
+	sandbox := profile.Sandbox
+	if isSet(f.fs, "sandbox") {
+		sandbox = f.sandbox
+	}
+
+	z := zarinpalgo.NewWithMode(merchantID, sandbox)
+	if apiURL := firstOf(f.apiURL, profile.APIURL); apiURL != "" {
+		z.APIBaseURL = withSlash(apiURL)
+	}
+	if paymentURL := firstOf(f.paymentURL, profile.PaymentURL); paymentURL != "" {
+		z.PaymentBaseURL = withSlash(paymentURL)
+	}
+	return z, nil
+}
+
+// reportingFlags are the flags of the reporting API client
+type reportingFlags struct {
+	cli        *cli
+	token      string
+	terminalID string
+	url        string
+}
+
+func (c *cli) reportingFlags(fs *flag.FlagSet) *reportingFlags {
+	f := &reportingFlags{cli: c}
+	fs.StringVar(&f.token, "token", "", "panel access token, defaults to $"+AccessTokenEnv+" or the profile")
+	fs.StringVar(&f.terminalID, "terminal", "", "terminal ID, defaults to $"+TerminalIDEnv+" or the profile")
+	fs.StringVar(&f.url, "reporting-url", "", "URL of the reporting API, defaults to the profile or "+zarinpalgo.DefaultReportingURL)
+	return f
+}
+
+// reporting returns the reporting client selected by the flags
+func (f *reportingFlags) reporting() (*zarinpalgo.Reporting, error) {
+	profile, err := f.cli.profile()
+	if err != nil {
+		return nil, err
+	}
+
+	token := firstOf(f.token, f.cli.getenv(AccessTokenEnv), profile.AccessToken)
+	terminalID := firstOf(f.terminalID, f.cli.getenv(TerminalIDEnv), profile.TerminalID)
+	if token == "" || terminalID == "" {
+		return nil, errMissingToken
+	}
+
+	r := zarinpalgo.NewReporting(token, terminalID)
+	r.URL = firstOf(f.url, profile.ReportingURL, zarinpalgo.DefaultReportingURL)
+	return r, nil
+}
+
+// isSet reports whether the flag was given on the command line
+func isSet(fs *flag.FlagSet, name string) (set bool) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return
+}
+
+func withSlash(url string) string {
+	if strings.HasSuffix(url, "/") {
+		return url
+	}
+	return url + "/"
+}
+
+// printFields writes aligned "name: value" lines, skipping empty values
+func printFields(w io.Writer, fields [][2]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+// runCLI runs the command line and returns its exit code and output
+func runCLI(t *testing.T, env map[string]string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, &out, &errOut, func(key string) string { return env[key] })
+	return code, out.String(), errOut.String()
+}
+
+// simulatorArgs returns the flags pointing the CLI to the simulator
+func simulatorArgs(sim *zarinpalgotest.Simulator) []string {
+	return []string{"-merchant", "merchant-1", "-api-url", sim.URL + "/pg/v4/payment/", "-payment-url", sim.URL + "/pg/StartPay/"}
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runCLI(t, nil)
+	if code != 2 || !strings.Contains(stderr, "request") || !strings.Contains(stderr, "verify") {
+		t.Errorf("Expected usage with exit code 2, got %d %q", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, nil, "pay")
+	if code != 2 || !strings.Contains(stderr, `unknown command "pay"`) {
+		t.Errorf("Expected unknown command with exit code 2, got %d %q", code, stderr)
+	}
+}
+
+func TestMissingMerchant(t *testing.T) {
+	code, _, stderr := runCLI(t, nil, "verify", "-amount", "10000", "-authority", "A1")
+	if code != 1 || !strings.Contains(stderr, errMissingMerchant.Error()) {
+		t.Errorf("Expected missing merchant error, got %d %q", code, stderr)
+	}
+}
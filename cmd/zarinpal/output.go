@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the -output flag
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFormat is the value of the -output flag
+type outputFormat string
+
+func (o *outputFormat) String() string {
+	if *o == "" {
+		return outputTable
+	}
+	return string(*o)
+}
+
+func (o *outputFormat) Set(s string) error {
+	switch s {
+	case outputTable, outputJSON, outputYAML:
+		*o = outputFormat(s)
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or yaml", s)
+}
+
+// print writes a command result in the selected format. JSON and YAML share the JSON field names
+// of the result, so scripts see the same schema in both.
+func (c *cli) print(v interface{}, table func(w io.Writer) error) error {
+	switch c.output {
+	case outputJSON:
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		return writeYAML(c.stdout, v)
+	}
+	return table(c.stdout)
+}
+
+// printEvent writes one event of a stream: a JSON line, a YAML document or a table line
+func (c *cli) printEvent(v interface{}, line func(w io.Writer) error) error {
+	switch c.output {
+	case outputJSON:
+		return json.NewEncoder(c.stdout).Encode(v)
+	case outputYAML:
+		if _, err := io.WriteString(c.stdout, "---\n"); err != nil {
+			return err
+		}
+		return writeYAML(c.stdout, v)
+	}
+	return line(c.stdout)
+}
+
+// writeYAML writes v as YAML using its JSON encoding, keeping the field order
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	// JSON style strings and flow collections are valid YAML, switch them to block style
+	resetStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
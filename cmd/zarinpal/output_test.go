@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOutputFormats(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	payment, _ := sim.Client("merchant-1").NewPayment(context.Background(), 10000, "Test payment", nil, "https://example.com/callback", nil)
+	sim.Pay(payment.Authority)
+	verify := append(append([]string{"verify"}, simulatorArgs(sim)...), "-amount", "10000", "-authority", payment.Authority)
+
+	code, stdout, stderr := runCLI(t, nil, append(verify, "-output", "json")...)
+	if code != 0 {
+		t.Fatalf("Expected verification to succeed, got %d %q", code, stderr)
+	}
+	var status zarinpalgo.PaymentStatus
+	if err := json.Unmarshal([]byte(stdout), &status); err != nil || !status.IsSuccessful || status.Authority != payment.Authority {
+		t.Errorf("Expected a JSON status, got %q %v", stdout, err)
+	}
+
+	code, stdout, _ = runCLI(t, nil, append(verify, "--output=yaml")...)
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(stdout), &fields); err != nil || code != 0 {
+		t.Fatalf("Expected YAML output, got %q %v", stdout, err)
+	}
+	if fields["is_repeated"] != true || fields["authority"] != payment.Authority {
+		t.Errorf("Expected YAML to use the JSON field names, got %v", fields)
+	}
+
+	code, stdout, _ = runCLI(t, nil, append(append([]string{"unverified"}, simulatorArgs(sim)...), "-output", "json")...)
+	if code != 0 || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("Expected an empty JSON list, got %d %q", code, stdout)
+	}
+
+	code, _, stderr = runCLI(t, nil, append(verify, "-output", "xml")...)
+	if code != 2 || !strings.Contains(stderr, `unknown output format "xml"`) {
+		t.Errorf("Expected an invalid output format to be rejected, got %d %q", code, stderr)
+	}
+}
+
+func TestWriteYAMLQuoting(t *testing.T) {
+	var b strings.Builder
+	writeYAML(&b, map[string]interface{}{"terminal_id": "42", "sandbox": true, "note": "true"})
+
+	var fields map[string]interface{}
+	yaml.Unmarshal([]byte(b.String()), &fields)
+	if fields["terminal_id"] != "42" || fields["note"] != "true" || fields["sandbox"] != true {
+		t.Errorf("Expected string values to stay strings, got %q", b.String())
+	}
+}
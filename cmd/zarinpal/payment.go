@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// errUnsuccessful makes verify exit with a failure after printing an unsuccessful status
+var errUnsuccessful = errors.New("payment was not successful")
+
+// requestResult is the output of the request command
+type requestResult struct {
+	Authority  string `json:"authority"`
+	PaymentURL string `json:"payment_url"`
+	Fee        int    `json:"fee"`
+	FeeType    string `json:"fee_type"`
+}
+
+func runRequest(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("request")
+	client := c.clientFlags(fs)
+	amount := fs.Int("amount", 0, "amount in Rials")
+	description := fs.String("description", "", "payment description")
+	callbackURL := fs.String("callback", "", "callback URL")
+	mobile := fs.String("mobile", "", "payer mobile number")
+	email := fs.String("email", "", "payer email")
+	orderID := fs.String("order", "", "order ID")
+	qr := fs.Bool("qr", false, "draw the payment URL as a QR code, to open it on a phone")
+	qrInvert := fs.Bool("qr-invert", false, "invert the QR code colors, for terminals with a light background")
+	qrPNG := fs.String("qr-png", "", "also write the QR code to this PNG file")
+	qrSize := fs.Int("qr-size", 256, "width in pixels of the PNG")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *amount <= 0 || *description == "" || *callbackURL == "" {
+		return errors.New("-amount, -description and -callback are required")
+	}
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	var metadata *zarinpalgo.Metadata
+	if *mobile != "" || *email != "" || *orderID != "" {
+		metadata = &zarinpalgo.Metadata{Mobile: *mobile, Email: *email, OrderID: *orderID}
+	}
+
+	payment, err := z.CreatePayment(ctx, zarinpalgo.PaymentParams{
+		Amount:      *amount,
+		Description: *description,
+		CallbackURL: *callbackURL,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := requestResult{
+		Authority:  payment.Authority,
+		PaymentURL: z.GetPaymentURL(payment.Authority),
+		Fee:        payment.Fee,
+		FeeType:    payment.FeeType,
+	}
+	err = c.print(result, func(w io.Writer) error {
+		return printFields(w, [][2]string{
+			{"Authority", result.Authority},
+			{"Payment URL", result.PaymentURL},
+			{"Fee", strconv.Itoa(result.Fee) + " (" + result.FeeType + ")"},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if *qrPNG != "" {
+		image, err := z.PaymentQRCode(payment.Authority, *qrSize)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*qrPNG, image, 0o644); err != nil {
+			return err
+		}
+	}
+	if *qr {
+		text, err := z.PaymentQRCodeText(payment.Authority, *qrInvert)
+		if err != nil {
+			return err
+		}
+		// keep the JSON and YAML output parseable
+		w := c.stdout
+		if c.output == outputJSON || c.output == outputYAML {
+			w = c.stderr
+		}
+		if _, err := io.WriteString(w, "\n"+text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runVerify(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("verify")
+	client := c.clientFlags(fs)
+	amount := fs.Int("amount", 0, "amount in Rials the payment was requested for")
+	authority := fs.String("authority", "", "payment authority")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *amount <= 0 || *authority == "" {
+		return errors.New("-amount and -authority are required")
+	}
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	status, err := z.CheckPaymentStatus(ctx, *amount, *authority)
+	var apiErr *zarinpalgo.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		return err
+	}
+
+	fields := [][2]string{
+		{"Authority", status.Authority},
+		{"Successful", strconv.FormatBool(status.IsSuccessful)},
+		{"Already verified", strconv.FormatBool(status.IsRepeated)},
+		{"Message", status.Message},
+	}
+	if status.IsSuccessful {
+		fields = append(fields, [2]string{"Ref ID", strconv.Itoa(status.RefID)}, [2]string{"Card", status.CardPan})
+	}
+	if err := c.print(status, func(w io.Writer) error { return printFields(w, fields) }); err != nil {
+		return err
+	}
+	if !status.IsSuccessful {
+		return errUnsuccessful
+	}
+	return nil
+}
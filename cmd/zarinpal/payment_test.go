@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+func TestRequestAndVerify(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	args := append([]string{"request"}, simulatorArgs(sim)...)
+	args = append(args, "-amount", "10000", "-description", "Test payment", "-callback", "https://example.com/callback")
+	code, stdout, stderr := runCLI(t, nil, args...)
+	if code != 0 {
+		t.Fatalf("Expected request to succeed, got %d %q", code, stderr)
+	}
+	if !strings.Contains(stdout, sim.URL+"/pg/StartPay/") {
+		t.Errorf("Expected the StartPay URL to be printed, got %q", stdout)
+	}
+
+	authority := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(stdout, "\n", 2)[0], "Authority:"))
+	verify := append([]string{"verify"}, simulatorArgs(sim)...)
+	verify = append(verify, "-amount", "10000", "-authority", authority)
+
+	code, stdout, _ = runCLI(t, nil, verify...)
+	if code != 1 || !strings.Contains(stdout, "Successful:") || !strings.Contains(stdout, "false") {
+		t.Errorf("Expected an unpaid payment to fail verification, got %d %q", code, stdout)
+	}
+
+	sim.Pay(authority)
+	code, stdout, stderr = runCLI(t, nil, verify...)
+	if code != 0 || !strings.Contains(stdout, "Ref ID:") {
+		t.Errorf("Expected verification to succeed, got %d %q %q", code, stdout, stderr)
+	}
+}
+
+func TestRequestMissingFlags(t *testing.T) {
+	code, _, stderr := runCLI(t, map[string]string{MerchantIDEnv: "merchant-1"}, "request", "-amount", "10000")
+	if code != 1 || !strings.Contains(stderr, "required") {
+		t.Errorf("Expected missing flags error, got %d %q", code, stderr)
+	}
+}
+
+func TestRequestQRCode(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	png := filepath.Join(t.TempDir(), "payment.png")
+	args := append([]string{"request"}, simulatorArgs(sim)...)
+	args = append(args, "-amount", "10000", "-description", "Test payment", "-callback", "https://example.com/callback", "-qr", "-qr-png", png)
+	code, stdout, stderr := runCLI(t, nil, args...)
+	if code != 0 {
+		t.Fatalf("Expected request to succeed, got %d %q", code, stderr)
+	}
+	if !strings.Contains(stdout, "Payment URL:") || !strings.ContainsAny(stdout, "█▀▄") {
+		t.Errorf("Expected the payment URL and a QR code, got %q", stdout)
+	}
+	if data, err := os.ReadFile(png); err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Errorf("Expected a PNG file, got %v", err)
+	}
+
+	code, stdout, stderr = runCLI(t, nil, append(args, "-output", "json")...)
+	var result requestResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &result) != nil || !strings.ContainsAny(stderr, "█▀▄") {
+		t.Errorf("Expected JSON on stdout and the QR code on stderr, got %d %q %q", code, stdout, stderr)
+	}
+}
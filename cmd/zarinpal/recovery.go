@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func runUnverified(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("unverified")
+	client := c.clientFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	unverified, err := z.UnverifiedPayments(ctx)
+	if err != nil {
+		return err
+	}
+
+	payments := unverified.Authorities
+	if payments == nil {
+		payments = []zarinpalgo.UnverifiedPayment{}
+	}
+	return c.print(payments, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "AUTHORITY\tAMOUNT\tDATE\tCALLBACK")
+		for _, payment := range payments {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", payment.Authority, payment.Amount, payment.Date, payment.CallbackURL)
+		}
+		return tw.Flush()
+	})
+}
+
+// reverseResult is the output of the reverse command
+type reverseResult struct {
+	Authority string `json:"authority"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+}
+
+func runReverse(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("reverse")
+	client := c.clientFlags(fs)
+	authority := fs.String("authority", "", "payment authority")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *authority == "" {
+		return errors.New("-authority is required")
+	}
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	response, err := z.ReversePayment(ctx, *authority)
+	if err != nil {
+		return err
+	}
+
+	result := reverseResult{Authority: *authority, Code: response.Code, Message: response.Message}
+	return c.print(result, func(w io.Writer) error {
+		return printFields(w, [][2]string{
+			{"Authority", result.Authority},
+			{"Code", strconv.Itoa(result.Code)},
+			{"Message", result.Message},
+		})
+	})
+}
+
+func runRefund(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("refund")
+	reporting := c.reportingFlags(fs)
+	var req zarinpalgo.RefundRequest
+	fs.StringVar(&req.SessionID, "session", "", "ID of the transaction to refund")
+	fs.IntVar(&req.Amount, "amount", 0, "amount in Rials to refund")
+	fs.StringVar(&req.Description, "description", "", "refund description")
+	fs.StringVar(&req.Method, "method", zarinpalgo.RefundMethodPaya, "PAYA or CARD")
+	fs.StringVar(&req.Reason, "reason", zarinpalgo.RefundReasonCustomerRequest, "CUSTOMER_REQUEST, DUPLICATE_TRANSACTION, SUSPICIOUS_TRANSACTION or OTHER")
+	wait := fs.Duration("wait", 0, "poll the refund every interval until it is completed, failed or canceled")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if req.SessionID == "" || req.Amount <= 0 {
+		return errors.New("-session and -amount are required")
+	}
+	r, err := reporting.reporting()
+	if err != nil {
+		return err
+	}
+
+	refund, err := r.Refund(ctx, req)
+	if err != nil {
+		return err
+	}
+	if *wait > 0 && !refund.Status.IsFinal() {
+		refund, err = r.WaitRefund(ctx, refund.ID, *wait, func(refund zarinpalgo.Refund) {
+			fmt.Fprintf(c.stderr, "refund %s is %s\n", refund.ID, refund.Status)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.print(refund, func(w io.Writer) error {
+		return printFields(w, [][2]string{
+			{"Refund ID", refund.ID},
+			{"Amount", strconv.Itoa(refund.Amount)},
+			{"Status", string(refund.Status)},
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+func TestUnverifiedAndReverse(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	payment, err := sim.Client("merchant-1").NewPayment(context.Background(), 10000, "Test payment", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	sim.Pay(payment.Authority)
+
+	code, stdout, stderr := runCLI(t, nil, append([]string{"unverified"}, simulatorArgs(sim)...)...)
+	if code != 0 || !strings.Contains(stdout, payment.Authority) || !strings.Contains(stdout, "10000") {
+		t.Errorf("Expected the paid payment to be listed, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, nil, append(append([]string{"reverse"}, simulatorArgs(sim)...), "-authority", payment.Authority)...)
+	if code != 0 || !strings.Contains(stdout, "Reversed") {
+		t.Errorf("Expected the payment to be reversed, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, _, stderr = runCLI(t, nil, append(append([]string{"reverse"}, simulatorArgs(sim)...), "-authority", payment.Authority)...)
+	if code != 1 || !strings.Contains(stderr, "-61") {
+		t.Errorf("Expected reversing twice to fail, got %d %q", code, stderr)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"resource":{"terminal_id":"terminal-1","id":"R1","amount":5000,"timeline":{"refund_status":"PENDING"}}}}`))
+	}))
+	defer server.Close()
+
+	env := map[string]string{AccessTokenEnv: "token", TerminalIDEnv: "terminal-1"}
+	code, stdout, stderr := runCLI(t, env, "refund", "-reporting-url", server.URL, "-session", "S1", "-amount", "5000")
+	if code != 0 || !strings.Contains(stdout, "R1") || !strings.Contains(stdout, "PENDING") {
+		t.Errorf("Expected the refund to be printed, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, _, stderr = runCLI(t, nil, "refund", "-session", "S1", "-amount", "5000")
+	if code != 1 || !strings.Contains(stderr, errMissingToken.Error()) {
+		t.Errorf("Expected missing token error, got %d %q", code, stderr)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+// defaultIgnoredFields are response fields that differ on every run
+const defaultIgnoredFields = "authority,ref_id,card_hash,card_pan,date"
+
+// replayResult is the outcome of replaying one interaction
+type replayResult struct {
+	Index          int      `json:"index"`
+	Method         string   `json:"method"`
+	Path           string   `json:"path"`
+	RecordedStatus int      `json:"recorded_status"`
+	Status         int      `json:"status"`
+	Differences    []string `json:"differences"`
+	Error          string   `json:"error,omitempty"`
+}
+
+func runReplay(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("replay")
+	cassettePath := fs.String("cassette", "", "cassette file recorded by zarinpalgotest.Recorder")
+	target := fs.String("target", "", "base URL to replay against, like https://sandbox.zarinpal.com")
+	sandbox := fs.Bool("sandbox", false, "replay against the sandbox gateway")
+	simulator := fs.Bool("simulator", false, "replay against an in-process simulator")
+	merchantID := fs.String("merchant", "", "merchant ID replacing the recorded one, for redacted cassettes")
+	ignore := fs.String("ignore", defaultIgnoredFields, "comma separated response fields left out of the diff")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *cassettePath == "" {
+		return errors.New("-cassette is required")
+	}
+	recorder, err := zarinpalgotest.NewRecorder(*cassettePath, zarinpalgotest.ModeReplay)
+	if err != nil {
+		return err
+	}
+
+	baseURL := *target
+	switch {
+	case *simulator:
+		sim := zarinpalgotest.NewSimulator()
+		defer sim.Close()
+		baseURL = sim.URL
+	case *sandbox:
+		baseURL = "https://sandbox.zarinpal.com"
+	case baseURL == "":
+		return errors.New("one of -target, -sandbox or -simulator is required")
+	}
+
+	ignored := make(map[string]bool)
+	for _, field := range splitList(*ignore) {
+		ignored[field] = true
+	}
+
+	r := &replayer{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		merchantID:  *merchantID,
+		ignored:     ignored,
+		authorities: make(map[string]string),
+	}
+
+	results := make([]replayResult, 0)
+	differing := 0
+	for i, interaction := range recorder.Interactions() {
+		result := r.replay(ctx, i+1, interaction)
+		if len(result.Differences) > 0 || result.Error != "" {
+			differing++
+		}
+		results = append(results, result)
+	}
+
+	err = c.print(results, func(w io.Writer) error {
+		for _, result := range results {
+			outcome := "ok"
+			if result.Error != "" {
+				outcome = "error: " + result.Error
+			} else if len(result.Differences) > 0 {
+				outcome = "differs"
+			}
+			fmt.Fprintf(w, "#%d %s %s  %s\n", result.Index, result.Method, result.Path, outcome)
+			for _, difference := range result.Differences {
+				fmt.Fprintf(w, "    %s\n", difference)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if differing > 0 {
+		return fmt.Errorf("%d of %d interactions differ", differing, len(results))
+	}
+	return nil
+}
+
+// replayer sends recorded requests to a gateway
+type replayer struct {
+	client     *http.Client
+	baseURL    string
+	merchantID string
+	ignored    map[string]bool
+	// authorities maps recorded authorities to the ones the target created for the same requests
+	authorities map[string]string
+}
+
+func (r *replayer) replay(ctx context.Context, index int, interaction zarinpalgotest.Interaction) replayResult {
+	result := replayResult{
+		Index:          index,
+		Method:         interaction.Method,
+		Path:           interaction.Path,
+		RecordedStatus: interaction.StatusCode,
+		Differences:    []string{},
+	}
+
+	body := r.rewriteRequest(interaction.RequestBody)
+	req, err := http.NewRequestWithContext(ctx, interaction.Method, r.baseURL+interaction.Path, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = resp.StatusCode
+	if resp.StatusCode != interaction.StatusCode {
+		result.Differences = append(result.Differences, fmt.Sprintf("status: %d != %d", interaction.StatusCode, resp.StatusCode))
+	}
+
+	var recorded, replayed interface{}
+	if json.Unmarshal(interaction.ResponseBody, &recorded) != nil || json.Unmarshal(responseBody, &replayed) != nil {
+		if !bytes.Equal(bytes.TrimSpace(interaction.ResponseBody), bytes.TrimSpace(responseBody)) {
+			result.Differences = append(result.Differences, "body: not JSON and not equal")
+		}
+		return result
+	}
+
+	r.mapAuthority(recorded, replayed)
+	result.Differences = append(result.Differences, diffJSON("", recorded, replayed, r.ignored)...)
+	return result
+}
+
+// rewriteRequest replaces the merchant ID and the authorities created during the replay
+func (r *replayer) rewriteRequest(body json.RawMessage) []byte {
+	var fields map[string]interface{}
+	if len(body) == 0 || json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+
+	if r.merchantID != "" {
+		if _, ok := fields["merchant_id"]; ok {
+			fields["merchant_id"] = r.merchantID
+		}
+	}
+	if authority, ok := fields["authority"].(string); ok {
+		if replaced, ok := r.authorities[authority]; ok {
+			fields["authority"] = replaced
+		}
+	}
+
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
+
+// mapAuthority remembers the authority the target returned in place of the recorded one
+func (r *replayer) mapAuthority(recorded, replayed interface{}) {
+	recordedAuthority := dataField(recorded, "authority")
+	replayedAuthority := dataField(replayed, "authority")
+	if recordedAuthority != "" && replayedAuthority != "" {
+		r.authorities[recordedAuthority] = replayedAuthority
+	}
+}
+
+func dataField(body interface{}, name string) string {
+	root, _ := body.(map[string]interface{})
+	data, _ := root["data"].(map[string]interface{})
+	value, _ := data[name].(string)
+	return value
+}
+
+// diffJSON lists the differences between two decoded JSON values, fields named in ignored are
+// only compared on presence
+func diffJSON(path string, a, b interface{}, ignored map[string]bool) (differences []string) {
+	name := path
+	if i := strings.LastIndexAny(path, ".]"); i >= 0 {
+		name = path[i+1:]
+	}
+	if ignored[name] && a != nil && b != nil {
+		return nil
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %s != %s", displayPath(path), compact(a), compact(b))}
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			av, inA := a[key]
+			bv, inB := b[key]
+			switch {
+			case !inA:
+				differences = append(differences, fmt.Sprintf("%s: added %s", child, compact(bv)))
+			case !inB:
+				differences = append(differences, fmt.Sprintf("%s: removed %s", child, compact(av)))
+			default:
+				differences = append(differences, diffJSON(child, av, bv, ignored)...)
+			}
+		}
+		return differences
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return []string{fmt.Sprintf("%s: %s != %s", displayPath(path), compact(a), compact(b))}
+		}
+		for i := range a {
+			differences = append(differences, diffJSON(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], ignored)...)
+		}
+		return differences
+	}
+
+	if compact(a) != compact(b) {
+		return []string{fmt.Sprintf("%s: %s != %s", displayPath(path), compact(a), compact(b))}
+	}
+	return nil
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
+
+func compact(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+// recordCassette records a payment request and its inquiry against a simulator
+func recordCassette(t *testing.T) string {
+	t.Helper()
+
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := zarinpalgotest.NewRecorder(path, zarinpalgotest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z := sim.Client("merchant-1")
+	z.HTTPClient = recorder.Client()
+	payment, err := z.NewPayment(context.Background(), 10000, "Test", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := z.InquirePayment(context.Background(), payment.Authority); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	path := recordCassette(t)
+
+	code, stdout, stderr := runCLI(t, nil, "replay", "-cassette", path, "-simulator")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d %q", code, stderr)
+	}
+	if strings.Count(stdout, " ok\n") != 2 {
+		t.Errorf("Expected 2 matching interactions, got %q", stdout)
+	}
+}
+
+func TestReplayDifferences(t *testing.T) {
+	path := recordCassette(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cassette zarinpalgotest.Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		t.Fatal(err)
+	}
+	cassette.Interactions[1].ResponseBody = json.RawMessage(`{"data":{"code":100,"message":"Success","status":"VERIFIED"},"errors":[]}`)
+	data, _ = json.Marshal(cassette)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, nil, "replay", "-cassette", path, "-simulator", "-output", "json")
+	if code != 1 || !strings.Contains(stderr, "1 of 2 interactions differ") {
+		t.Fatalf("Expected exit code 1 with differences, got %d %q", code, stderr)
+	}
+
+	var results []replayResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || len(results[0].Differences) != 0 {
+		t.Fatalf("Expected the request to match, got %+v", results)
+	}
+	if want := `data.status: "VERIFIED" != "IN_BANK"`; !contains(results[1].Differences, want) {
+		t.Errorf("Expected %q, got %v", want, results[1].Differences)
+	}
+}
+
+func TestReplayNeedsTarget(t *testing.T) {
+	path := recordCassette(t)
+
+	code, _, stderr := runCLI(t, nil, "replay", "-cassette", path)
+	if code != 1 || !strings.Contains(stderr, "-target") {
+		t.Errorf("Expected a missing target error, got %d %q", code, stderr)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	var a, b interface{}
+	json.Unmarshal([]byte(`{"data":{"code":100,"authority":"A1","fee":0},"errors":[]}`), &a)
+	json.Unmarshal([]byte(`{"data":{"code":101,"authority":"A2","ref_id":5},"errors":[]}`), &b)
+
+	got := diffJSON("", a, b, map[string]bool{"authority": true})
+	want := []string{"data.code: 100 != 101", "data.fee: removed 0", "data.ref_id: added 5"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
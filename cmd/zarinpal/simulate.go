@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+// simulatorInfo tells where the local gateway is served
+type simulatorInfo struct {
+	URL        string `json:"url"`
+	APIURL     string `json:"api_url"`
+	PaymentURL string `json:"payment_url"`
+	MerchantID string `json:"merchant_id,omitempty"`
+}
+
+func runSimulate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("simulate")
+	host := fs.String("host", "127.0.0.1", "address to listen on")
+	port := fs.Int("port", 8099, "port to listen on, 0 picks a free one")
+	merchantID := fs.String("merchant", "", "only accept this merchant ID, any is accepted when empty")
+	seed := fs.Int64("seed", 0, "seed for reproducible authorities, random when 0")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort(*host, strconv.Itoa(*port)))
+	if err != nil {
+		return err
+	}
+
+	opts := []zarinpalgotest.SimulatorOption{zarinpalgotest.WithListener(l)}
+	if *seed != 0 {
+		opts = append(opts, zarinpalgotest.WithRand(zarinpalgotest.SeededRand(*seed)))
+	}
+	sim := zarinpalgotest.NewSimulator(opts...)
+	defer sim.Close()
+	sim.MerchantID = *merchantID
+
+	info := simulatorInfo{
+		URL:        sim.URL,
+		APIURL:     sim.URL + "/pg/v4/payment/",
+		PaymentURL: sim.URL + "/pg/StartPay/",
+		MerchantID: *merchantID,
+	}
+	err = c.print(info, func(w io.Writer) error {
+		err := printFields(w, [][2]string{
+			{"Simulator", info.URL},
+			{"API URL", info.APIURL},
+			{"Payment URL", info.PaymentURL},
+			{"Merchant", info.MerchantID},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "\nPoint clients to it with\n  zarinpal request -api-url %s -payment-url %s ...\nThe StartPay page pays or cancels the payment and redirects to its callback URL. Press Ctrl+C to stop.\n",
+			info.APIURL, info.PaymentURL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return nil
+}
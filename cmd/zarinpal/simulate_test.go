@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestSimulate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, w := io.Pipe()
+	done := make(chan int)
+	go func() {
+		code := run(ctx, []string{"simulate", "-port", "0", "-merchant", "merchant-1", "-output", "json"}, w, io.Discard, func(string) string { return "" })
+		w.Close()
+		done <- code
+	}()
+
+	var info simulatorInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		t.Fatalf("Failed to read the simulator address: %v", err)
+	}
+	go io.Copy(io.Discard, r)
+
+	z := zarinpalgo.New("merchant-1")
+	z.APIBaseURL = info.APIURL
+	z.PaymentBaseURL = info.PaymentURL
+	payment, err := z.NewPayment(ctx, 10000, "Test", nil, "http://localhost:3000/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	resp, err := http.Get(info.PaymentURL + payment.Authority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the StartPay page, got %d", resp.StatusCode)
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirect.PostForm(info.PaymentURL+payment.Authority, url.Values{"action": {"pay"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if location := resp.Header.Get("Location"); !strings.HasPrefix(location, "http://localhost:3000/callback?") || !strings.Contains(location, "Status=OK") {
+		t.Errorf("Expected a redirect to the callback, got %q", location)
+	}
+
+	status, err := z.CheckPaymentStatus(ctx, 10000, payment.Authority)
+	if err != nil || !status.IsSuccessful {
+		t.Errorf("Expected the paid payment to verify, got %+v %v", status, err)
+	}
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+}
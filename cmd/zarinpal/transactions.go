@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/export"
+)
+
+func runTransactions(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("transactions")
+	reporting := c.reportingFlags(fs)
+	from := fs.String("from", "", "first day, as 2024-05-12 or Jalali 1403/02/23, defaults to 7 days ago")
+	to := fs.String("to", "", "last day included, defaults to today")
+	status := fs.String("status", "", "comma separated statuses to keep, e.g. VERIFIED,PAID")
+	minAmount := fs.Int("min-amount", 0, "smallest amount in Rials to keep")
+	maxAmount := fs.Int("max-amount", 0, "largest amount in Rials to keep")
+	card := fs.String("card", "", "last digits of the card to keep")
+	refID := fs.Int("ref-id", 0, "reference ID to keep")
+	exportFormat := fs.String("export", "", "write csv, xlsx or jsonl instead of the regular output")
+	columns := fs.String("columns", "", "comma separated export columns, all by default")
+	file := fs.String("file", "", "file to export to, defaults to stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	now := time.Now().In(zarinpalgo.IranLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zarinpalgo.IranLocation)
+	start, err := parseDay(*from, today.AddDate(0, 0, -7))
+	if err != nil {
+		return err
+	}
+	end, err := parseDay(*to, today)
+	if err != nil {
+		return err
+	}
+	end = end.AddDate(0, 0, 1)
+
+	switch *exportFormat {
+	case "", "csv", "xlsx", "jsonl":
+	default:
+		return fmt.Errorf("unknown export format %q, use csv, xlsx or jsonl", *exportFormat)
+	}
+	var exportColumns []export.Column
+	if *columns != "" {
+		if exportColumns, err = export.ColumnsByKey(splitList(*columns)...); err != nil {
+			return err
+		}
+	}
+
+	r, err := reporting.reporting()
+	if err != nil {
+		return err
+	}
+	filter := zarinpalgo.TransactionFilter{
+		From:          start,
+		To:            end,
+		Statuses:      splitList(strings.ToUpper(*status)),
+		MinAmount:     *minAmount,
+		MaxAmount:     *maxAmount,
+		CardPanSuffix: *card,
+		RefID:         *refID,
+	}
+	if *exportFormat == "jsonl" {
+		// streamed as the pages are fetched, for ranges too large to hold
+		return exportFile(c, *file, func(w io.Writer) error {
+			_, err := export.StreamTransactions(ctx, w, r, filter, exportColumns)
+			return err
+		})
+	}
+	transactions, err := r.FilterTransactions(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if *exportFormat != "" {
+		return exportTransactions(c, transactions, *exportFormat, exportColumns, *file)
+	}
+
+	if transactions == nil {
+		transactions = []zarinpalgo.Transaction{}
+	}
+	return c.print(transactions, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tAUTHORITY\tSTATUS\tAMOUNT\tFEE\tREF ID\tCREATED")
+		for _, t := range transactions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n", t.ID, t.Authority, t.Status, t.Amount, t.Fee, t.RefID,
+				zarinpalgo.FormatJalali(t.CreatedAt.In(zarinpalgo.IranLocation)))
+		}
+		return tw.Flush()
+	})
+}
+
+// parseDay parses a Gregorian or Jalali day in Iran time, empty values are the fallback.
+// Years before 1700 are taken as Jalali.
+func parseDay(s string, fallback time.Time) (time.Time, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", s, zarinpalgo.IranLocation); err == nil && day.Year() >= 1700 {
+		return day, nil
+	}
+	jalali, err := zarinpalgo.ParseJalali(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid day %q, use 2024-05-12 or 1403/02/23", s)
+	}
+	return jalali.Time(zarinpalgo.IranLocation), nil
+}
+
+func splitList(s string) (items []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+func exportTransactions(c *cli, transactions []zarinpalgo.Transaction, format string, columns []export.Column, file string) error {
+	if file == "" && format == "xlsx" {
+		return errors.New("-export xlsx needs -file")
+	}
+
+	rows := export.FromTransactions(transactions)
+	return exportFile(c, file, func(w io.Writer) error {
+		if format == "xlsx" {
+			return export.WriteXLSX(w, rows, columns, "Transactions")
+		}
+		return export.WriteCSV(w, rows, columns)
+	})
+}
+
+// exportFile calls write with the file, or stdout when it is empty
+func exportFile(c *cli, file string, write func(w io.Writer) error) (err error) {
+	if file == "" {
+		return write(c.stdout)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return write(f)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func newReportingServer(t *testing.T, transactions ...zarinpalgo.Transaction) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"Session": transactions},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTransactions(t *testing.T) {
+	day := time.Date(2024, 5, 12, 10, 0, 0, 0, zarinpalgo.IranLocation)
+	server := newReportingServer(t,
+		zarinpalgo.Transaction{ID: "3", Authority: "A3", Status: zarinpalgo.InquiryStatusVerified, Amount: 30000, CreatedAt: day.AddDate(0, 0, 1)},
+		zarinpalgo.Transaction{ID: "2", Authority: "A2", Status: zarinpalgo.InquiryStatusFailed, Amount: 20000, CreatedAt: day},
+		zarinpalgo.Transaction{ID: "1", Authority: "A1", Status: zarinpalgo.InquiryStatusVerified, Amount: 10000, Fee: 100, CreatedAt: day},
+		zarinpalgo.Transaction{ID: "0", Authority: "A0", Status: zarinpalgo.InquiryStatusVerified, Amount: 5000, CreatedAt: day.AddDate(0, 0, -1)},
+	)
+	base := []string{"transactions", "-token", "token", "-terminal", "42", "-reporting-url", server.URL}
+
+	code, stdout, stderr := runCLI(t, nil, append(base, "-from", "2024-05-12", "-to", "1403/02/23", "-status", "verified")...)
+	if code != 0 || !strings.Contains(stdout, "A1") || strings.Contains(stdout, "A2") || strings.Contains(stdout, "A3") || strings.Contains(stdout, "A0") {
+		t.Errorf("Expected only A1 to be listed, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, nil, append(base, "-from", "2024-05-12", "-to", "2024-05-13", "-export", "csv", "-columns", "authority,status,amount")...)
+	expected := "Authority,Status,Amount\nA3,VERIFIED,30000\nA2,FAILED,20000\nA1,VERIFIED,10000\n"
+	if code != 0 || stdout != expected {
+		t.Errorf("Expected CSV %q, got %d %q", expected, code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, nil, append(base, "-from", "2024-05-12", "-to", "2024-05-12", "-export", "jsonl", "-columns", "authority,amount")...)
+	expected = `{"authority":"A2","amount":20000}` + "\n" + `{"authority":"A1","amount":10000}` + "\n"
+	if code != 0 || stdout != expected {
+		t.Errorf("Expected JSON Lines %q, got %d %q", expected, code, stdout)
+	}
+
+	file := filepath.Join(t.TempDir(), "transactions.xlsx")
+	code, _, stderr = runCLI(t, nil, append(base, "-from", "2024-05-12", "-export", "xlsx", "-file", file)...)
+	if info, err := os.Stat(file); code != 0 || err != nil || info.Size() == 0 {
+		t.Errorf("Expected the XLSX file to be written, got %d %q %v", code, stderr, err)
+	}
+
+	for _, args := range [][]string{{"-from", "12/05/2024"}, {"-export", "pdf"}, {"-columns", "iban"}} {
+		if code, _, _ := runCLI(t, nil, append(base, args...)...); code != 1 {
+			t.Errorf("Expected %v to fail, got %d", args, code)
+		}
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	for s, expected := range map[string]string{"2024-05-12": "2024-05-12", "1403/02/23": "2024-05-12", "1403-02-23": "2024-05-12"} {
+		day, err := parseDay(s, time.Time{})
+		if err != nil || day.Format("2006-01-02") != expected {
+			t.Errorf("Expected %s to be %s, got %s %v", s, expected, day, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/redisstore"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences used by the dashboard
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiReverse = "\x1b[7m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiReset   = "\x1b[0m"
+)
+
+// dashboardHelp lists the keybindings of the dashboard
+const dashboardHelp = "↑/k ↓/j select  v verify  r reverse  R refresh  q quit"
+
+func runTUI(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("tui")
+	client := c.clientFlags(fs)
+	interval := fs.Duration("interval", 10*time.Second, "time between refreshes")
+	redisURL := fs.String("redis", "", "redis:// URL of a redisstore to show the sessions of the last day from")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	d := &dashboard{client: z}
+	if *redisURL != "" {
+		opts, err := redis.ParseURL(*redisURL)
+		if err != nil {
+			return err
+		}
+		rdb := redis.NewClient(opts)
+		defer rdb.Close()
+		d.store = redisstore.New(rdb)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("tui needs an interactive terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	d.refresh(ctx)
+	for {
+		d.render(c.stdout)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.refresh(ctx)
+		case key, ok := <-keys:
+			if !ok || d.handleKey(ctx, key) {
+				fmt.Fprint(c.stdout, ansiClear)
+				return nil
+			}
+		}
+	}
+}
+
+// readKeys sends the keys read from r, escape sequences of the arrow keys are sent whole
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		if n > 1 && buf[0] == '\x1b' {
+			keys <- string(buf[:n])
+			continue
+		}
+		for _, b := range buf[:n] {
+			keys <- string(b)
+		}
+	}
+}
+
+// dashboardRow is a payment shown on the dashboard
+type dashboardRow struct {
+	Authority string
+	Amount    int
+	State     string
+	Date      string
+}
+
+// dashboard is the state of the terminal UI, kept apart from the terminal so it can be tested
+type dashboard struct {
+	client *zarinpalgo.Zarinpal
+	store  zarinpalgo.PaymentStore
+
+	rows     []dashboardRow
+	selected int
+	message  string
+	confirm  string // authority waiting for the reverse to be confirmed
+
+	latency   time.Duration
+	healthErr error
+	checkedAt time.Time
+}
+
+// refresh reloads the payments and measures the gateway health with the unverified listing
+func (d *dashboard) refresh(ctx context.Context) {
+	start := time.Now()
+	unverified, err := d.client.UnverifiedPayments(ctx)
+	d.latency = time.Since(start)
+	d.checkedAt = time.Now()
+	d.healthErr = err
+
+	rows := make([]dashboardRow, 0, len(unverified.Authorities))
+	seen := make(map[string]bool)
+	for _, payment := range unverified.Authorities {
+		seen[payment.Authority] = true
+		rows = append(rows, dashboardRow{Authority: payment.Authority, Amount: payment.Amount, State: "unverified", Date: payment.Date})
+	}
+
+	if lister, ok := d.store.(zarinpalgo.PaymentLister); ok {
+		payments, err := lister.ListCreated(ctx, time.Now().Add(-24*time.Hour), time.Now())
+		if err != nil {
+			d.message = "store: " + err.Error()
+		}
+		// newest first, below the payments waiting for verification
+		for i := len(payments) - 1; i >= 0; i-- {
+			payment := payments[i]
+			if seen[payment.Authority] {
+				continue
+			}
+			rows = append(rows, dashboardRow{
+				Authority: payment.Authority,
+				Amount:    payment.Amount,
+				State:     string(payment.State),
+				Date:      payment.CreatedAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+	}
+
+	d.rows = rows
+	if d.selected >= len(d.rows) {
+		d.selected = len(d.rows) - 1
+	}
+	if d.selected < 0 {
+		d.selected = 0
+	}
+}
+
+// handleKey applies a key and reports whether the dashboard should quit
+func (d *dashboard) handleKey(ctx context.Context, key string) (quit bool) {
+	if d.confirm != "" {
+		authority := d.confirm
+		d.confirm = ""
+		if key == "y" || key == "Y" {
+			d.reverse(ctx, authority)
+		} else {
+			d.message = "reverse canceled"
+		}
+		return false
+	}
+
+	switch key {
+	case "q", "\x03":
+		return true
+	case "j", "\x1b[B":
+		if d.selected < len(d.rows)-1 {
+			d.selected++
+		}
+	case "k", "\x1b[A":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "R":
+		d.refresh(ctx)
+		d.message = "refreshed"
+	case "v":
+		if row, ok := d.current(); ok {
+			d.verify(ctx, row)
+		}
+	case "r":
+		if row, ok := d.current(); ok {
+			d.confirm = row.Authority
+			d.message = fmt.Sprintf("reverse %s? press y to confirm", row.Authority)
+		}
+	}
+	return false
+}
+
+func (d *dashboard) current() (dashboardRow, bool) {
+	if d.selected < 0 || d.selected >= len(d.rows) {
+		return dashboardRow{}, false
+	}
+	return d.rows[d.selected], true
+}
+
+func (d *dashboard) verify(ctx context.Context, row dashboardRow) {
+	status, err := d.client.CheckPaymentStatus(ctx, row.Amount, row.Authority)
+	switch {
+	case err != nil:
+		d.message = fmt.Sprintf("verify %s: %v", row.Authority, err)
+	case status.IsSuccessful:
+		d.message = fmt.Sprintf("verified %s, ref_id %d", row.Authority, status.RefID)
+	default:
+		d.message = fmt.Sprintf("verify %s: %s", row.Authority, status.Message)
+	}
+	d.refresh(ctx)
+}
+
+func (d *dashboard) reverse(ctx context.Context, authority string) {
+	response, err := d.client.ReversePayment(ctx, authority)
+	if err != nil {
+		d.message = fmt.Sprintf("reverse %s: %v", authority, err)
+	} else {
+		d.message = fmt.Sprintf("reversed %s: %s", authority, response.Message)
+	}
+	d.refresh(ctx)
+}
+
+// render draws the dashboard, lines end with \r\n as the terminal is in raw mode
+func (d *dashboard) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(ansiClear)
+
+	health := ansiGreen + "OK" + ansiReset
+	if d.healthErr != nil {
+		health = ansiRed + "DOWN: " + d.healthErr.Error() + ansiReset
+	}
+	fmt.Fprintf(&b, "%sZarinpal%s  merchant %s  gateway %s  latency %s  checked %s\r\n\r\n",
+		ansiBold, ansiReset, d.client.MerchantID, health, d.latency.Round(time.Millisecond), d.checkedAt.Format(time.TimeOnly))
+
+	fmt.Fprintf(&b, "%s%-40s %12s  %-11s %s%s\r\n", ansiBold, "AUTHORITY", "AMOUNT", "STATE", "DATE", ansiReset)
+	if len(d.rows) == 0 {
+		b.WriteString("no payments\r\n")
+	}
+	for i, row := range d.rows {
+		line := fmt.Sprintf("%-40s %12d  %-11s %s", row.Authority, row.Amount, row.State, row.Date)
+		if i == d.selected {
+			line = ansiReverse + line + ansiReset
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	fmt.Fprintf(&b, "\r\n%s\r\n%s\r\n", d.message, dashboardHelp)
+	io.WriteString(w, b.String())
+}
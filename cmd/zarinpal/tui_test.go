@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/redisstore"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDashboard(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	z := sim.Client("merchant-1")
+	first, _ := z.NewPayment(ctx, 10000, "First", nil, "https://example.com/callback", nil)
+	second, _ := z.NewPayment(ctx, 20000, "Second", nil, "https://example.com/callback", nil)
+	sim.Pay(first.Authority)
+	sim.Pay(second.Authority)
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+	store := redisstore.New(rdb)
+	stored, _ := z.NewPayment(ctx, 30000, "Stored", nil, "https://example.com/callback", nil)
+	store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: stored.Authority, Amount: 30000, CreatedAt: time.Now()})
+
+	d := &dashboard{client: z, store: store}
+	d.refresh(ctx)
+	if len(d.rows) != 3 || d.rows[2].Authority != stored.Authority || d.rows[2].State != "created" {
+		t.Fatalf("Expected two unverified payments and the stored one, got %+v", d.rows)
+	}
+
+	var screen strings.Builder
+	d.render(&screen)
+	if !strings.Contains(screen.String(), "OK") || !strings.Contains(screen.String(), first.Authority) {
+		t.Errorf("Expected a healthy gateway and the payments, got %q", screen.String())
+	}
+
+	// select the payment of the first row and verify it
+	verified := d.rows[0].Authority
+	if d.handleKey(ctx, "v") {
+		t.Fatal("Expected v not to quit")
+	}
+	if p, _ := sim.Payment(verified); p.Status != zarinpalgo.InquiryStatusVerified || !strings.Contains(d.message, "verified") {
+		t.Errorf("Expected %s to be verified, got %s %q", verified, p.Status, d.message)
+	}
+	if len(d.rows) != 2 {
+		t.Errorf("Expected the verified payment to leave the unverified list, got %+v", d.rows)
+	}
+
+	// reversing needs a confirmation
+	reversed := d.rows[0].Authority
+	d.handleKey(ctx, "r")
+	d.handleKey(ctx, "n")
+	if p, _ := sim.Payment(reversed); p.Status != zarinpalgo.InquiryStatusPaid || d.message != "reverse canceled" {
+		t.Errorf("Expected the reverse to be canceled, got %s %q", p.Status, d.message)
+	}
+	d.handleKey(ctx, "r")
+	d.handleKey(ctx, "y")
+	if p, _ := sim.Payment(reversed); p.Status != zarinpalgo.InquiryStatusReversed {
+		t.Errorf("Expected %s to be reversed, got %s", reversed, p.Status)
+	}
+
+	d.handleKey(ctx, "\x1b[B")
+	d.handleKey(ctx, "\x1b[B")
+	if d.selected != 0 {
+		t.Errorf("Expected the selection to stay on the only row, got %d", d.selected)
+	}
+	if !d.handleKey(ctx, "q") {
+		t.Error("Expected q to quit")
+	}
+}
+
+func TestDashboardHealth(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	z := sim.Client("merchant-1")
+	sim.Close()
+
+	d := &dashboard{client: z}
+	d.refresh(context.Background())
+
+	var screen strings.Builder
+	d.render(&screen)
+	if d.healthErr == nil || !strings.Contains(screen.String(), "DOWN") {
+		t.Errorf("Expected the gateway to be down, got %q", screen.String())
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/redisstore"
+	"github.com/redis/go-redis/v9"
+)
+
+func runWatch(ctx context.Context, c *cli, args []string) error {
+	fs := c.flagSet("watch")
+	client := c.clientFlags(fs)
+	interval := fs.Duration("interval", 30*time.Second, "time between polls")
+	verify := fs.Bool("verify", false, "verify the payments found, moving stored sessions to their outcome")
+	redisURL := fs.String("redis", "", "redis:// URL of a redisstore to watch the pending sessions of")
+	minAge := fs.Duration("min-age", zarinpalgo.DefaultReconcileMinAge, "age after which pending sessions are reported")
+	once := fs.Bool("once", false, "poll once and exit")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	z, err := client.client()
+	if err != nil {
+		return err
+	}
+
+	w := &watcher{cli: c, client: z, minAge: *minAge, verify: *verify, seen: make(map[string]bool)}
+	if *redisURL != "" {
+		opts, err := redis.ParseURL(*redisURL)
+		if err != nil {
+			return err
+		}
+		rdb := redis.NewClient(opts)
+		defer rdb.Close()
+		w.store = redisstore.New(rdb)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if *once {
+				return err
+			}
+			fmt.Fprintf(c.stderr, "%s poll failed: %v\n", time.Now().Format(time.TimeOnly), err)
+		}
+		if *once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watcher prints the payments waiting for verification the first time they are seen
+type watcher struct {
+	cli    *cli
+	client *zarinpalgo.Zarinpal
+	store  zarinpalgo.PaymentStore
+	minAge time.Duration
+	verify bool
+	seen   map[string]bool
+}
+
+func (w *watcher) poll(ctx context.Context) error {
+	if w.verify {
+		return w.reconcile(ctx)
+	}
+
+	unverified, err := w.client.UnverifiedPayments(ctx)
+	if err != nil {
+		return err
+	}
+	for _, payment := range unverified.Authorities {
+		if err := w.report("unverified", payment.Authority, payment.Amount, payment.Date); err != nil {
+			return err
+		}
+	}
+
+	if w.store == nil {
+		return nil
+	}
+	pending, err := w.store.ListPending(ctx, time.Now().Add(-w.minAge))
+	if err != nil {
+		return err
+	}
+	for _, payment := range pending {
+		if err := w.report("pending", payment.Authority, payment.Amount, payment.CreatedAt.Format("2006-01-02 15:04:05")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcile verifies the unverified payments and resolves the pending sessions of the store
+func (w *watcher) reconcile(ctx context.Context) error {
+	reconciler := zarinpalgo.NewReconciler(w.client, w.store)
+	reconciler.MinAge = w.minAge
+	reconciler.OnError = func(authority string, err error) {
+		fmt.Fprintf(w.cli.stderr, "%s %s: %v\n", time.Now().Format(time.TimeOnly), authority, err)
+	}
+
+	resolved, err := reconciler.ReconcileOnce(ctx)
+	for _, status := range resolved {
+		event := watchEvent{
+			Time:      time.Now(),
+			Kind:      "failed",
+			Authority: status.Authority,
+			Amount:    status.Amount,
+			RefID:     status.RefID,
+			Message:   status.Message,
+		}
+		if status.IsSuccessful {
+			event.Kind = "verified"
+		}
+		if printErr := w.print(event); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// watchEvent is a line of the watch output
+type watchEvent struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"` // unverified, pending, verified or failed
+	Authority string    `json:"authority"`
+	Amount    int       `json:"amount,omitempty"`
+	Date      string    `json:"date,omitempty"`
+	RefID     int       `json:"ref_id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// report prints a payment unless it was already printed
+func (w *watcher) report(kind, authority string, amount int, date string) error {
+	if w.seen[authority] {
+		return nil
+	}
+	w.seen[authority] = true
+	return w.print(watchEvent{Time: time.Now(), Kind: kind, Authority: authority, Amount: amount, Date: date})
+}
+
+func (w *watcher) print(event watchEvent) error {
+	return w.cli.printEvent(event, func(out io.Writer) error {
+		line := fmt.Sprintf("%s %-10s %s", event.Time.Format(time.TimeOnly), event.Kind, event.Authority)
+		if event.Amount != 0 {
+			line += fmt.Sprintf(" amount=%d", event.Amount)
+		}
+		if event.Date != "" {
+			line += " date=" + event.Date
+		}
+		if event.RefID != 0 {
+			line += fmt.Sprintf(" ref_id=%d", event.RefID)
+		}
+		if event.Message != "" {
+			line += " " + event.Message
+		}
+		_, err := fmt.Fprintln(out, line)
+		return err
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/redisstore"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWatch(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	paid, _ := sim.Client("merchant-1").NewPayment(ctx, 10000, "Paid", nil, "https://example.com/callback", nil)
+	sim.Pay(paid.Authority)
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+	store := redisstore.New(rdb)
+	stale, _ := sim.Client("merchant-1").NewPayment(ctx, 20000, "Stale", nil, "https://example.com/callback", nil)
+	store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: stale.Authority, Amount: 20000, CreatedAt: time.Now().Add(-time.Hour)})
+	store.UpdateStatus(ctx, stale.Authority, zarinpalgo.PaymentStatePending, 0)
+
+	args := append([]string{"watch", "-once", "-redis", "redis://" + server.Addr()}, simulatorArgs(sim)...)
+	code, stdout, stderr := runCLI(t, nil, args...)
+	if code != 0 || !strings.Contains(stdout, "unverified "+paid.Authority) || !strings.Contains(stdout, "pending    "+stale.Authority) {
+		t.Errorf("Expected the paid and the stale payments to be listed, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, nil, append(args, "-verify")...)
+	if code != 0 || !strings.Contains(stdout, "verified   "+paid.Authority) {
+		t.Errorf("Expected the paid payment to be verified, got %d %q %q", code, stdout, stderr)
+	}
+	if p, _ := sim.Payment(paid.Authority); p.Status != zarinpalgo.InquiryStatusVerified {
+		t.Errorf("Expected the payment to be verified on the gateway, got %s", p.Status)
+	}
+}
@@ -0,0 +1,248 @@
+// Package contract checks the responses of the real Zarinpal sandbox against the library's models,
+// reporting fields the models don't decode and fields the gateway stopped sending.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// EnvVar enables the sandbox contract tests when set to 1
+const EnvVar = "ZARINPAL_SANDBOX_CONTRACT"
+
+// Difference kinds
+const (
+	KindUnknown = "unknown" // gateway sent a field the model doesn't decode
+	KindMissing = "missing" // model field the gateway didn't send
+	KindType    = "type"    // field has a different JSON type than the model expects
+)
+
+// Difference is a mismatch between a gateway response and the library's model
+type Difference struct {
+	Operation string
+	Field     string // dotted path of the field, list elements are written as []
+	Kind      string
+	Detail    string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s field %s %s", d.Operation, d.Kind, d.Field, d.Detail)
+}
+
+// Report is the result of a contract run
+type Report struct {
+	Differences []Difference
+	Errors      []error // operations that failed to run
+}
+
+// OK reports whether the run found no differences and no errors
+func (r Report) OK() bool {
+	return len(r.Differences) == 0 && len(r.Errors) == 0
+}
+
+// Compare checks a raw gateway response body against the model the response data is decoded into.
+// Error responses are checked against zarinpalgo.ErrorResponse, in the object and list forms.
+func Compare(operation string, body []byte, model interface{}) (differences []Difference, err error) {
+	var base zarinpalgo.BaseResponse
+	err = json.Unmarshal(body, &base)
+	if err != nil {
+		return
+	}
+
+	errs := bytes.TrimSpace(base.Errors)
+	if len(errs) > 0 && string(errs) != "[]" && string(errs) != "{}" {
+		if errs[0] == '[' {
+			var list []json.RawMessage
+			err = json.Unmarshal(errs, &list)
+			if err != nil || len(list) == 0 {
+				return
+			}
+			errs = list[0]
+		}
+		return compareValue(operation, "errors", errs, reflect.TypeOf(zarinpalgo.ErrorResponse{})), nil
+	}
+
+	return compareValue(operation, "data", base.Data, reflect.TypeOf(model)), nil
+}
+
+func compareValue(operation, path string, raw json.RawMessage, typ reflect.Type) (differences []Difference) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var value interface{}
+	if json.Unmarshal(raw, &value) != nil || value == nil {
+		return
+	}
+
+	mismatch := func(expected string) []Difference {
+		return []Difference{{Operation: operation, Field: path, Kind: KindType, Detail: fmt.Sprintf("is %s, expected %s", jsonType(value), expected)}}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		var fields map[string]json.RawMessage
+		json.Unmarshal(raw, &fields)
+
+		known := make(map[string]bool)
+		for i := 0; i < typ.NumField(); i++ {
+			name, omitempty := jsonName(typ.Field(i))
+			if name == "" {
+				continue
+			}
+			known[name] = true
+			if _, ok := object[name]; !ok {
+				if !omitempty {
+					differences = append(differences, Difference{Operation: operation, Field: path + "." + name, Kind: KindMissing})
+				}
+				continue
+			}
+			differences = append(differences, compareValue(operation, path+"."+name, fields[name], typ.Field(i).Type)...)
+		}
+		for name := range object {
+			if !known[name] {
+				differences = append(differences, Difference{Operation: operation, Field: path + "." + name, Kind: KindUnknown, Detail: "of type " + jsonType(object[name])})
+			}
+		}
+	case reflect.Slice:
+		if _, ok := value.([]interface{}); !ok {
+			return mismatch("array")
+		}
+		var items []json.RawMessage
+		json.Unmarshal(raw, &items)
+		if len(items) > 0 {
+			differences = compareValue(operation, path+".[]", items[0], typ.Elem())
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			return mismatch("number")
+		}
+	}
+	return
+}
+
+func jsonName(field reflect.StructField) (name string, omitempty bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" || !field.IsExported() {
+		return
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty")
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return "null"
+}
+
+// Run creates a payment on the sandbox, verifies and inquires it and lists unverified payments,
+// comparing every response with its model
+func Run(ctx context.Context, merchantID string) (report Report) {
+	zp := zarinpalgo.NewWithMode(merchantID, true)
+	recorder := &bodyRecorder{base: zp.HTTPClient.Transport}
+	zp.HTTPClient.Transport = recorder
+
+	check := func(operation string, model interface{}, err error) {
+		var apiErr *zarinpalgo.APIError
+		if err != nil && !errors.As(err, &apiErr) {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", operation, err))
+			return
+		}
+		differences, err := Compare(operation, recorder.last(), model)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", operation, err))
+			return
+		}
+		report.Differences = append(report.Differences, differences...)
+	}
+
+	metadata := &zarinpalgo.Metadata{Email: "contract@example.com", Mobile: "09123456789", OrderID: "CONTRACT-1"}
+	payment, err := zp.NewPayment(ctx, 10000, "Contract test", metadata, "http://localhost:8080/callback", nil)
+	check("request", payment, err)
+	if err != nil {
+		return
+	}
+
+	verification, err := zp.VerifyPayment(ctx, 10000, payment.Authority)
+	check("verify", verification, err)
+
+	inquiry, err := zp.InquirePayment(ctx, payment.Authority)
+	check("inquiry", inquiry, err)
+
+	unverified, err := zp.UnverifiedPayments(ctx)
+	check("unverified", unverified, err)
+
+	return
+}
+
+// bodyRecorder keeps the body of the last response
+type bodyRecorder struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	body []byte
+}
+
+func (b *bodyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := b.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	b.mu.Lock()
+	b.body = body
+	b.mu.Unlock()
+	return resp, nil
+}
+
+func (b *bodyRecorder) last() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.body
+}
@@ -0,0 +1,65 @@
+package contract
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+	"github.com/google/uuid"
+)
+
+func TestCompare(t *testing.T) {
+	differences, err := Compare("verify", []byte(fixtures.VerifySuccess), zarinpalgo.PaymentVerificationResponse{})
+	if err != nil || len(differences) != 0 {
+		t.Errorf("Expected fixture to match the model, got %v %v", differences, err)
+	}
+
+	body := `{"data":{"code":"100","message":"Paid","card_pan":"502229******5995","ref_id":201,"fee_type":"Merchant","fee":100,"shaparak_fee":120},"errors":[]}`
+	differences, err = Compare("verify", []byte(body), zarinpalgo.PaymentVerificationResponse{})
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+
+	kinds := make(map[string]string)
+	for _, difference := range differences {
+		kinds[difference.Field] = difference.Kind
+	}
+	expected := map[string]string{
+		"data.code":         KindType,
+		"data.card_hash":    KindMissing,
+		"data.shaparak_fee": KindUnknown,
+	}
+	if len(kinds) != len(expected) {
+		t.Errorf("Expected %d differences, got %v", len(expected), differences)
+	}
+	for field, kind := range expected {
+		if kinds[field] != kind {
+			t.Errorf("Expected %s to be %s, got %q", field, kind, kinds[field])
+		}
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	for _, body := range []string{fixtures.ValidationError, fixtures.ArrayErrors, fixtures.Error(-51)} {
+		differences, err := Compare("verify", []byte(body), zarinpalgo.PaymentVerificationResponse{})
+		if err != nil || len(differences) != 0 {
+			t.Errorf("Expected error fixture to match the model, got %v %v", differences, err)
+		}
+	}
+}
+
+func TestSandboxContract(t *testing.T) {
+	if os.Getenv(EnvVar) != "1" {
+		t.Skipf("set %s=1 to run the contract tests against the sandbox", EnvVar)
+	}
+
+	report := Run(context.Background(), uuid.New().String())
+	for _, err := range report.Errors {
+		t.Error(err)
+	}
+	for _, difference := range report.Differences {
+		t.Error(difference)
+	}
+}
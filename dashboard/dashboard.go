@@ -0,0 +1,151 @@
+// Package dashboard provides a minimal internal dashboard for payment operations: recent
+// payments, the gateway's unverified payments and a button to verify them right away.
+//
+// Mount it behind http.StripPrefix, e.g.
+//
+//	http.Handle("/admin/payments/", http.StripPrefix("/admin/payments", dashboard.New(zp, opts)))
+package dashboard
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Record is a payment shown in the recent payments table
+type Record struct {
+	Authority string
+	OrderID   string
+	Amount    int
+	Status    string
+	CreatedAt time.Time
+}
+
+// Options configures the dashboard
+type Options struct {
+	// Auth authorizes each request, requests it rejects are answered with 403. It is required.
+	Auth func(r *http.Request) bool
+	// Recent returns the recent payments to list, the table is hidden when it's nil
+	Recent func(ctx context.Context) ([]Record, error)
+	Title  string
+}
+
+type dashboard struct {
+	z    zarinpalgo.Client
+	opts Options
+}
+
+type pageData struct {
+	Title      string
+	Recent     []Record
+	Unverified []zarinpalgo.UnverifiedPayment
+	Result     *zarinpalgo.PaymentStatus
+	Errors     []string
+}
+
+var pageTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>body{font-family:sans-serif;margin:2em;color:#222}table{border-collapse:collapse;margin-bottom:2em}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left}.error{color:#b00}.ok{color:#070}</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Errors}}<p class="error">{{.}}</p>{{end}}
+{{with .Result}}<p class="{{if .IsSuccessful}}ok{{else}}error{{end}}">{{.Authority}}: {{.Message}}{{if .IsSuccessful}} (RefID {{.RefID}}){{end}}</p>{{end}}
+<h2>Unverified payments</h2>
+<table>
+<tr><th>Authority</th><th>Amount</th><th>Date</th><th></th></tr>
+{{range .Unverified}}<tr><td>{{.Authority}}</td><td>{{.Amount}}</td><td>{{.Date}}</td><td><form method="post" action="verify"><input type="hidden" name="authority" value="{{.Authority}}"><input type="hidden" name="amount" value="{{.Amount}}"><button>Verify now</button></form></td></tr>
+{{else}}<tr><td colspan="4">No unverified payments</td></tr>{{end}}
+</table>
+{{if .Recent}}<h2>Recent payments</h2>
+<table>
+<tr><th>Created</th><th>Order</th><th>Authority</th><th>Amount</th><th>Status</th></tr>
+{{range .Recent}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.OrderID}}</td><td>{{.Authority}}</td><td>{{.Amount}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// New returns the dashboard handler
+func New(z zarinpalgo.Client, opts Options) http.Handler {
+	if opts.Title == "" {
+		opts.Title = "Payments"
+	}
+	d := &dashboard{z: z, opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.index)
+	mux.HandleFunc("POST /verify", d.verify)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.opts.Auth == nil || !d.opts.Auth(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if r.Method == "POST" && !sameOrigin(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (d *dashboard) index(w http.ResponseWriter, r *http.Request) {
+	d.render(w, r, nil)
+}
+
+func (d *dashboard) verify(w http.ResponseWriter, r *http.Request) {
+	amount, err := strconv.Atoi(r.PostFormValue("amount"))
+	if err != nil {
+		http.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	status, _ := d.z.CheckPaymentStatus(r.Context(), amount, r.PostFormValue("authority"))
+	d.render(w, r, &status)
+}
+
+func (d *dashboard) render(w http.ResponseWriter, r *http.Request, result *zarinpalgo.PaymentStatus) {
+	data := pageData{
+		Title:  d.opts.Title,
+		Result: result,
+	}
+
+	unverified, err := d.z.UnverifiedPayments(r.Context())
+	if err != nil {
+		data.Errors = append(data.Errors, "unverified payments: "+err.Error())
+	}
+	data.Unverified = unverified.Authorities
+
+	if d.opts.Recent != nil {
+		data.Recent, err = d.opts.Recent(r.Context())
+		if err != nil {
+			data.Errors = append(data.Errors, "recent payments: "+err.Error())
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	pageTemplate.Execute(w, data)
+}
+
+// sameOrigin rejects cross-site form posts
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == r.Host
+	}
+	return true
+}
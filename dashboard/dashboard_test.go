@@ -0,0 +1,83 @@
+package dashboard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func newTestDashboard(t *testing.T) http.Handler {
+	t.Helper()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "unVerified.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","authorities":[{"authority":"A1","amount":50500,"date":"2024-05-12 12:24:11"}]},"errors":[]}`))
+		case strings.HasSuffix(r.URL.Path, "verify.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+		}
+	}))
+	t.Cleanup(gateway.Close)
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = gateway.URL + "/"
+
+	return New(zp, Options{
+		Auth: func(r *http.Request) bool {
+			return r.Header.Get("X-Admin") == "yes"
+		},
+		Recent: func(ctx context.Context) ([]Record, error) {
+			return []Record{{Authority: "A0", OrderID: "ORDER-7", Amount: 1000, Status: "verified", CreatedAt: time.Now()}}, nil
+		},
+	})
+}
+
+func TestDashboard(t *testing.T) {
+	handler := newTestDashboard(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Admin", "yes")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "A1") || !strings.Contains(body, "ORDER-7") {
+		t.Errorf("Unexpected dashboard page %d: %s", rec.Code, body)
+	}
+
+	form := url.Values{"authority": {"A1"}, "amount": {"50500"}}
+	req = httptest.NewRequest("POST", "/verify", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Admin", "yes")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "RefID 201") {
+		t.Errorf("Expected verification result, got %s", rec.Body.String())
+	}
+}
+
+func TestDashboardRejectsRequests(t *testing.T) {
+	handler := newTestDashboard(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d without auth, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader("authority=A1&amount=1"))
+	req.Header.Set("X-Admin", "yes")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d for cross-site post, got %d", http.StatusForbidden, rec.Code)
+	}
+}
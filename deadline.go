@@ -0,0 +1,92 @@
+package zarinpalgo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// latencyWeight is the weight of the latest request in the typical latency of an operation
+const latencyWeight = 0.2
+
+// DeadlineWarning describes a gateway request whose context deadline is likely to cut it short,
+// a common cause of verifications failing at random
+type DeadlineWarning struct {
+	Operation      string        // like "request" or "verify"
+	Tenant         string        // see WithTenant
+	Remaining      time.Duration // until the deadline of the context, negative once passed
+	Timeout        time.Duration // of the HTTP client, zero when unset
+	TypicalLatency time.Duration // of the operation so far, zero before it succeeded once
+}
+
+func (w DeadlineWarning) String() string {
+	return fmt.Sprintf("zarinpal %s has %s left before its context deadline, the client timeout is %s and the typical latency %s",
+		w.Operation, w.Remaining.Round(time.Millisecond), w.Timeout, w.TypicalLatency.Round(time.Millisecond))
+}
+
+// DeadlineHook is called before gateway requests whose context deadline is shorter than the
+// timeout of the HTTP client or the typical latency of the operation. The request is sent
+// anyway.
+type DeadlineHook func(ctx context.Context, warning DeadlineWarning)
+
+// LogDeadlines returns a DeadlineHook logging warnings to the logger
+func LogDeadlines(logger *slog.Logger) DeadlineHook {
+	return func(ctx context.Context, warning DeadlineWarning) {
+		logger.WarnContext(ctx, "context deadline shorter than the gateway request may take",
+			"operation", warning.Operation,
+			"remaining", warning.Remaining,
+			"timeout", warning.Timeout,
+			"typical_latency", warning.TypicalLatency)
+	}
+}
+
+// latencies keeps the typical latency of every operation, as a moving average
+type latencies struct {
+	mu        sync.Mutex
+	operation map[string]time.Duration
+}
+
+func (l *latencies) get(operation string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.operation[operation]
+}
+
+func (l *latencies) observe(operation string, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.operation == nil {
+		l.operation = make(map[string]time.Duration)
+	}
+	typical, ok := l.operation[operation]
+	if !ok {
+		l.operation[operation] = latency
+		return
+	}
+	l.operation[operation] = typical + time.Duration(latencyWeight*float64(latency-typical))
+}
+
+// checkDeadline calls the deadline hook when the deadline of the context is too close for the
+// operation
+func (z *Zarinpal) checkDeadline(ctx context.Context, operation string) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	warning := DeadlineWarning{
+		Operation:      operation,
+		Tenant:         Tenant(ctx),
+		Remaining:      time.Until(deadline),
+		TypicalLatency: z.latencies.get(operation),
+	}
+	if z.HTTPClient != nil {
+		warning.Timeout = z.HTTPClient.Timeout
+	}
+	if warning.Remaining < warning.Timeout || warning.Remaining < warning.TypicalLatency || warning.Remaining <= 0 {
+		z.runHook(ctx, "deadline hook", func() { z.DeadlineHook(ctx, warning) })
+	}
+}
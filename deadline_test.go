@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadlineHook(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"inquiry.json": `{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`,
+	})
+	var warnings []DeadlineWarning
+	zp.DeadlineHook = func(ctx context.Context, warning DeadlineWarning) {
+		warnings = append(warnings, warning)
+	}
+
+	zp.InquirePayment(context.Background(), "A1")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	zp.InquirePayment(ctx, "A1")
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := zp.InquirePayment(ctx, "A1"); err != nil {
+		t.Fatalf("Expected the request to be sent anyway, got %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Operation != "inquiry" || warnings[0].Timeout != 30*time.Second || warnings[0].Remaining > 5*time.Second || warnings[0].TypicalLatency == 0 {
+		t.Errorf("Expected a warning for the inquiry, got %+v", warnings)
+	}
+}
+
+func TestDeadlineHookTypicalLatency(t *testing.T) {
+	zp := New("merchant-1")
+	zp.HTTPClient.Timeout = 0
+	zp.latencies.observe("verify", 2*time.Second)
+	zp.latencies.observe("verify", time.Second)
+	if typical := zp.latencies.get("verify"); typical != 1800*time.Millisecond {
+		t.Errorf("Expected a typical latency of 1.8s, got %s", typical)
+	}
+
+	var buf bytes.Buffer
+	zp.DeadlineHook = LogDeadlines(slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	zp.checkDeadline(ctx, "verify")
+	zp.checkDeadline(ctx, "inquiry")
+	if log := buf.String(); strings.Count(log, "level=WARN") != 1 || !strings.Contains(log, "operation=verify") {
+		t.Errorf("Expected a warning for verify only, got %q", log)
+	}
+}
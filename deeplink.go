@@ -0,0 +1,92 @@
+package zarinpalgo
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AppLink describes the deep link a native app registers to receive the payment result
+type AppLink struct {
+	Scheme         string // custom URL scheme of the app, e.g. "myshop"
+	Host           string // e.g. "payment"
+	Path           string // e.g. "/result"
+	AndroidPackage string // optional, enables intent:// links that fall back to the store page
+}
+
+// URL returns the deep link carrying the callback parameters back to the app
+func (l AppLink) URL(callback CallbackData) string {
+	u := url.URL{
+		Scheme:   l.Scheme,
+		Host:     l.Host,
+		Path:     l.Path,
+		RawQuery: l.query(callback),
+	}
+	return u.String()
+}
+
+// AndroidIntentURL returns an intent:// link opening the app on Android, or fallbackURL
+// when the app isn't installed
+func (l AppLink) AndroidIntentURL(callback CallbackData, fallbackURL string) string {
+	var b strings.Builder
+	b.WriteString("intent://")
+	b.WriteString(l.Host)
+	b.WriteString(l.Path)
+	if query := l.query(callback); query != "" {
+		b.WriteString("?" + query)
+	}
+	b.WriteString("#Intent;scheme=" + l.Scheme)
+	if l.AndroidPackage != "" {
+		b.WriteString(";package=" + l.AndroidPackage)
+	}
+	if fallbackURL != "" {
+		b.WriteString(";S.browser_fallback_url=" + url.QueryEscape(fallbackURL))
+	}
+	b.WriteString(";end")
+	return b.String()
+}
+
+func (l AppLink) query(callback CallbackData) string {
+	values := url.Values{}
+	values.Set(CallbackAuthorityParam, callback.Authority)
+	values.Set(CallbackStatusParam, string(callback.Status))
+	return values.Encode()
+}
+
+var appReturnTemplate = template.Must(template.New("app").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Return to app</title>
+<style>body{font-family:sans-serif;text-align:center;margin-top:15vh}a{display:inline-block;padding:12px 24px;background:#0a58ca;color:#fff;border-radius:6px;text-decoration:none}</style>
+</head>
+<body>
+<p><a href="{{.Link}}">Return to app</a></p>
+<script>window.location.href = {{.Link}};</script>
+</body>
+</html>
+`))
+
+// AppReturnHandler returns an http.Handler to use as the payment callback URL of app payments.
+// It hands the callback parameters over to the app through its deep link, using an intent://
+// link on Android when a package is configured.
+func AppReturnHandler(link AppLink) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callback, err := ParseCallback(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		target := link.URL(callback)
+		if link.AndroidPackage != "" && strings.Contains(r.UserAgent(), "Android") {
+			target = link.AndroidIntentURL(callback, "")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		appReturnTemplate.Execute(w, struct{ Link template.URL }{template.URL(target)})
+	})
+}
@@ -0,0 +1,46 @@
+package zarinpalgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAppLink(t *testing.T) {
+	link := AppLink{Scheme: "myshop", Host: "payment", Path: "/result", AndroidPackage: "com.example.shop"}
+	callback := CallbackData{Authority: "A1", Status: CallbackStatusOK}
+
+	if got := link.URL(callback); got != "myshop://payment/result?Authority=A1&Status=OK" {
+		t.Errorf("Unexpected deep link %s", got)
+	}
+
+	expected := "intent://payment/result?Authority=A1&Status=OK#Intent;scheme=myshop;package=com.example.shop;S.browser_fallback_url=https%3A%2F%2Fexample.com%2Fapp;end"
+	if got := link.AndroidIntentURL(callback, "https://example.com/app"); got != expected {
+		t.Errorf("Expected intent link %s, got %s", expected, got)
+	}
+}
+
+func TestAppReturnHandler(t *testing.T) {
+	handler := AppReturnHandler(AppLink{Scheme: "myshop", Host: "payment", AndroidPackage: "com.example.shop"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/app-callback?Authority=A1&Status=NOK", nil))
+	if !strings.Contains(rec.Body.String(), `href="myshop://payment?Authority=A1&amp;Status=NOK"`) {
+		t.Errorf("Expected deep link in page, got %s", rec.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/app-callback?Authority=A1&Status=OK", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14)")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "intent://payment?Authority=A1") {
+		t.Error("Expected intent link for Android browsers")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/app-callback", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxDescriptionLength is the longest payment description sent to the gateway, in characters
+const MaxDescriptionLength = 500
+
+// ErrDescriptionTooLong is returned for descriptions longer than MaxDescriptionLength, unless
+// the client truncates them
+var ErrDescriptionTooLong = errors.New("payment description is too long")
+
+// SanitizeDescription prepares a description for the gateway: control characters like newlines
+// become spaces, explicit direction embeddings, overrides and isolates are dropped since an
+// unbalanced one garbles the mixed Persian and Latin text around it, and runs of spaces are
+// collapsed. Marks Persian text needs, like the zero width non-joiner, are kept.
+func SanitizeDescription(description string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range description {
+		switch {
+		case r == utf8.RuneError, isBidiControl(r):
+			continue
+		case unicode.IsControl(r), unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// TruncateDescription cuts the description to max characters, ending it with an ellipsis when
+// something was cut
+func TruncateDescription(description string, max int) string {
+	if utf8.RuneCountInString(description) <= max {
+		return description
+	}
+	if max <= 0 {
+		return ""
+	}
+	runes := []rune(description)[:max-1]
+	return strings.TrimRightFunc(string(runes), unicode.IsSpace) + "…"
+}
+
+// isBidiControl reports whether r is an explicit embedding, override or isolate, the left to
+// right and right to left marks are kept
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// checkDescription sanitizes the description and checks its length, truncating it instead when
+// truncate is set
+func checkDescription(description string, truncate bool) (string, error) {
+	description = SanitizeDescription(description)
+	if length := utf8.RuneCountInString(description); length > MaxDescriptionLength {
+		if !truncate {
+			return description, fmt.Errorf("%w: %d characters, at most %d are accepted", ErrDescriptionTooLong, length, MaxDescriptionLength)
+		}
+		description = TruncateDescription(description, MaxDescriptionLength)
+	}
+	return description, nil
+}
@@ -0,0 +1,79 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeDescription(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Order 1024", "Order 1024"},
+		{"  Order\n1024\t\tfor Ali  ", "Order 1024 for Ali"},
+		{"سفارش\u202e 1024\u202c", "سفارش 1024"},
+		{"\u2067خرید\u2069 Order", "خرید Order"},
+		{"می\u200cخواهم", "می\u200cخواهم"},
+		{"Order\x00 1\x7f", "Order 1"},
+		{"\n\t", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizeDescription(tt.in); got != tt.want {
+			t.Errorf("Expected %q for %q, got %q", tt.want, tt.in, got)
+		}
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	if got := TruncateDescription("Order 1024", 20); got != "Order 1024" {
+		t.Errorf("Expected short descriptions kept, got %q", got)
+	}
+	if got := TruncateDescription("Order 1024 for Ali", 11); got != "Order 1024…" {
+		t.Errorf("Expected Order 1024…, got %q", got)
+	}
+	if got := TruncateDescription("سفارش شماره ۱۰۲۴", 6); got != "سفارش…" {
+		t.Errorf("Expected characters rather than bytes counted, got %q", got)
+	}
+}
+
+func TestCreatePaymentDescription(t *testing.T) {
+	var sent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body PaymentRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		sent = body.Description
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+	params := PaymentParams{Amount: 10000, Description: "Order\n1024\u202e", CallbackURL: "https://example.com/callback"}
+
+	if _, err := zp.CreatePayment(context.Background(), params); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if sent != "Order 1024" {
+		t.Errorf("Expected the sanitized description sent, got %q", sent)
+	}
+
+	params.Description = strings.Repeat("a", MaxDescriptionLength+1)
+	sent = ""
+	if _, err := zp.CreatePayment(context.Background(), params); !errors.Is(err, ErrDescriptionTooLong) || sent != "" {
+		t.Errorf("Expected ErrDescriptionTooLong before any request, got %v", err)
+	}
+
+	zp.TruncateDescriptions = true
+	if _, err := zp.CreatePayment(context.Background(), params); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if utf8.RuneCountInString(sent) != MaxDescriptionLength || !strings.HasSuffix(sent, "…") {
+		t.Errorf("Expected the description truncated to %d characters, got %d", MaxDescriptionLength, utf8.RuneCountInString(sent))
+	}
+}
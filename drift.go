@@ -0,0 +1,141 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldDrift lists response fields of a gateway operation the client doesn't decode, a sign
+// Zarinpal added fields the library should model
+type FieldDrift struct {
+	Operation string   // like "request" or "verify"
+	Tenant    string   // of the request that received the fields, see WithTenant
+	Fields    []string // sorted paths, like "card_type" or "authorities[].terminal"
+}
+
+// DriftHook is called with the fields of gateway responses the client doesn't decode, once per
+// operation and field. Finding them decodes every response a second time, so set it in debug
+// and staging builds rather than in production.
+type DriftHook func(ctx context.Context, drift FieldDrift)
+
+// LogDrift returns a DriftHook logging the new fields to the logger
+func LogDrift(logger *slog.Logger) DriftHook {
+	return func(ctx context.Context, drift FieldDrift) {
+		logger.InfoContext(ctx, "zarinpal response has fields the client doesn't decode",
+			"operation", drift.Operation,
+			"fields", drift.Fields)
+	}
+}
+
+// driftSeen keeps the unknown fields already reported per operation
+type driftSeen struct {
+	mu     sync.Mutex
+	fields map[string]bool
+}
+
+// unseen returns the fields not reported for the operation before and marks them reported
+func (d *driftSeen) unseen(operation string, fields []string) (fresh []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.fields == nil {
+		d.fields = make(map[string]bool)
+	}
+	for _, field := range fields {
+		if key := operation + " " + field; !d.fields[key] {
+			d.fields[key] = true
+			fresh = append(fresh, field)
+		}
+	}
+	return
+}
+
+// checkDrift decodes the response data a second time and reports the fields out has no room for
+func (z *Zarinpal) checkDrift(ctx context.Context, operation string, data json.RawMessage, out interface{}) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	fields := make(map[string]bool)
+	unknownFields(raw, reflect.TypeOf(out), "", fields)
+
+	sorted := make([]string, 0, len(fields))
+	for field := range fields {
+		sorted = append(sorted, field)
+	}
+	sort.Strings(sorted)
+	fresh := z.drift.unseen(operation, sorted)
+	if len(fresh) == 0 {
+		return
+	}
+	z.runHook(ctx, "drift hook", func() { z.DriftHook(ctx, FieldDrift{Operation: operation, Tenant: Tenant(ctx), Fields: fresh}) })
+}
+
+// unknownFields adds the paths of the object keys of value that t has no field for
+func unknownFields(value interface{}, t reflect.Type, prefix string, fields map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			// maps and interfaces take any key
+			return
+		}
+		for key, v := range value {
+			field, ok := jsonField(t, key)
+			if !ok {
+				fields[prefix+key] = true
+				continue
+			}
+			unknownFields(v, field.Type, prefix+key+".", fields)
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for _, v := range value {
+			unknownFields(v, t.Elem(), strings.TrimSuffix(prefix, ".")+"[].", fields)
+		}
+	}
+}
+
+// jsonField returns the field of the struct encoding/json decodes key into, matching names
+// without regard to case like it does
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if inner, ok := jsonField(embedded, key); ok {
+					return inner, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
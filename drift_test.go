@@ -0,0 +1,54 @@
+package zarinpalgo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDriftHook(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json":     `{"data":{"code":100,"message":"Verified","ref_id":201,"card_type":"debit","Card_Pan":"6037****1234"},"errors":[]}`,
+		"unVerified.json": `{"data":{"code":100,"message":"Success","authorities":[{"authority":"A1","amount":1000,"terminal":"T1"},{"authority":"A2","mobile":"0912"}]},"errors":[]}`,
+	})
+	var drifts []FieldDrift
+	zp.DriftHook = func(ctx context.Context, drift FieldDrift) {
+		drifts = append(drifts, drift)
+	}
+
+	verification, err := zp.VerifyPayment(context.Background(), 1000, "A1")
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if verification.CardPan != "6037****1234" {
+		t.Errorf("Expected the card pan decoded regardless of case, got %q", verification.CardPan)
+	}
+	if _, err := zp.VerifyPayment(context.Background(), 1000, "A1"); err != nil {
+		t.Fatalf("Failed to verify again: %v", err)
+	}
+	if _, err := zp.UnverifiedPayments(context.Background()); err != nil {
+		t.Fatalf("Failed to list unverified payments: %v", err)
+	}
+
+	want := []FieldDrift{
+		{Operation: "verify", Fields: []string{"card_type"}},
+		{Operation: "unverified", Fields: []string{"authorities[].mobile", "authorities[].terminal"}},
+	}
+	if !reflect.DeepEqual(drifts, want) {
+		t.Errorf("Expected %+v reported once, got %+v", want, drifts)
+	}
+}
+
+func TestDriftHookPanic(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"inquiry.json": `{"data":{"code":100,"message":"Success","status":"PAID","new":true},"errors":[]}`,
+	})
+	zp.DriftHook = func(ctx context.Context, drift FieldDrift) {
+		panic("boom")
+	}
+
+	inquiry, err := zp.InquirePayment(context.Background(), "A1")
+	if err != nil || inquiry.Status != InquiryStatusPaid {
+		t.Errorf("Expected the inquiry to succeed despite the hook, got %+v %v", inquiry, err)
+	}
+}
@@ -0,0 +1,207 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventPaymentDuplicate is the type of the events added when an order is paid more than once
+const EventPaymentDuplicate = "payment.duplicate"
+
+// ErrListingUnsupported is returned when a store doesn't implement PaymentLister
+var ErrListingUnsupported = errors.New("store doesn't support listing payments")
+
+// PaymentLister is implemented by stores able to list payments beyond the PaymentStore lookups
+type PaymentLister interface {
+	// ListByOrderID returns every payment of the order, oldest first
+	ListByOrderID(ctx context.Context, orderID string) ([]StoredPayment, error)
+	// ListVerified returns the verified payments updated since the given time, oldest first
+	ListVerified(ctx context.Context, since time.Time) ([]StoredPayment, error)
+	// ListCreated returns the payments created in [from, to), oldest first
+	ListCreated(ctx context.Context, from, to time.Time) ([]StoredPayment, error)
+}
+
+// DuplicatePayment is an order paid with more than one verified payment, every payment
+// after the first one should be refunded
+type DuplicatePayment struct {
+	OrderID  string          `json:"order_id"`
+	Payments []StoredPayment `json:"payments"` // oldest first
+}
+
+// Duplicates returns the payments to refund
+func (d DuplicatePayment) Duplicates() []StoredPayment {
+	if len(d.Payments) < 2 {
+		return nil
+	}
+	return d.Payments[1:]
+}
+
+// DuplicateDetector finds orders the customer paid more than once
+type DuplicateDetector struct {
+	Store PaymentStore // must implement PaymentLister
+
+	// OnDuplicate is called with every duplicate found by Check
+	OnDuplicate func(ctx context.Context, duplicate DuplicatePayment)
+	// Outbox is optional, an EventPaymentDuplicate event is added to it for every duplicate found by Check
+	Outbox Outbox
+	// OnError is called when OnResult fails to check a payment
+	OnError func(authority string, err error)
+}
+
+// Check reports whether the order of the payment has other verified payments
+func (d *DuplicateDetector) Check(ctx context.Context, authority string) (duplicate DuplicatePayment, found bool, err error) {
+	lister, ok := d.Store.(PaymentLister)
+	if !ok {
+		err = ErrListingUnsupported
+		return
+	}
+
+	payment, err := d.Store.GetByAuthority(ctx, authority)
+	if err != nil || payment.OrderID == "" {
+		return
+	}
+
+	payments, err := lister.ListByOrderID(ctx, payment.OrderID)
+	if err != nil {
+		return
+	}
+
+	duplicate = DuplicatePayment{OrderID: payment.OrderID, Payments: verifiedPayments(payments)}
+	if len(duplicate.Payments) < 2 {
+		return DuplicatePayment{}, false, nil
+	}
+
+	if d.Outbox != nil {
+		event := WebhookEvent{
+			ID:        uuid.NewString(),
+			Type:      EventPaymentDuplicate,
+			CreatedAt: time.Now().UTC(),
+			Payment: PaymentStatus{
+				Authority:    payment.Authority,
+				IsSuccessful: true,
+				RefID:        payment.RefID,
+				Amount:       payment.Amount,
+				Message:      "order " + payment.OrderID + " was already paid",
+			},
+		}
+		if err = d.Outbox.AddEvent(ctx, event); err != nil {
+			return
+		}
+	}
+	if d.OnDuplicate != nil {
+		if panicErr := RunHook("duplicate detector OnDuplicate", func() { d.OnDuplicate(ctx, duplicate) }); panicErr != nil {
+			d.fail(authority, panicErr)
+		}
+	}
+	return duplicate, true, nil
+}
+
+// OnResult checks successful payments, it matches the result callbacks of CallbackHandler and Reconciler
+func (d *DuplicateDetector) OnResult(ctx context.Context, status PaymentStatus) {
+	if !status.IsSuccessful || status.IsRepeated {
+		return
+	}
+	if _, _, err := d.Check(ctx, status.Authority); err != nil {
+		d.fail(status.Authority, err)
+	}
+}
+
+func (d *DuplicateDetector) fail(authority string, err error) {
+	if d.OnError != nil {
+		RunHook("duplicate detector OnError", func() { d.OnError(authority, err) })
+	}
+}
+
+// Report returns the orders with more than one payment verified since the given time
+func (d *DuplicateDetector) Report(ctx context.Context, since time.Time) (duplicates []DuplicatePayment, err error) {
+	lister, ok := d.Store.(PaymentLister)
+	if !ok {
+		err = ErrListingUnsupported
+		return
+	}
+
+	verified, err := lister.ListVerified(ctx, since)
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, payment := range verified {
+		if payment.OrderID == "" || seen[payment.OrderID] {
+			continue
+		}
+		seen[payment.OrderID] = true
+
+		// earlier payments of the order may have been verified before the report period
+		payments, listErr := lister.ListByOrderID(ctx, payment.OrderID)
+		if listErr != nil {
+			return nil, listErr
+		}
+		if payments = verifiedPayments(payments); len(payments) > 1 {
+			duplicates = append(duplicates, DuplicatePayment{OrderID: payment.OrderID, Payments: payments})
+		}
+	}
+	return
+}
+
+// verifiedPayments keeps the payments the customer was charged for, including refunded ones
+func verifiedPayments(payments []StoredPayment) (verified []StoredPayment) {
+	for _, payment := range payments {
+		if payment.State == PaymentStateVerified || payment.State == PaymentStateRefunded {
+			verified = append(verified, payment)
+		}
+	}
+	return
+}
+
+// ListByOrderID implements PaymentLister
+func (s *MemoryPaymentStore) ListByOrderID(ctx context.Context, orderID string) (payments []StoredPayment, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, payment := range s.payments {
+		if payment.OrderID == orderID {
+			payments = append(payments, *payment)
+		}
+	}
+	sortByCreation(payments)
+	return
+}
+
+// ListVerified implements PaymentLister
+func (s *MemoryPaymentStore) ListVerified(ctx context.Context, since time.Time) (payments []StoredPayment, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, payment := range s.payments {
+		if payment.State == PaymentStateVerified && !payment.UpdatedAt.Before(since) {
+			payments = append(payments, *payment)
+		}
+	}
+	sortByCreation(payments)
+	return
+}
+
+// ListCreated implements PaymentLister
+func (s *MemoryPaymentStore) ListCreated(ctx context.Context, from, to time.Time) (payments []StoredPayment, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, payment := range s.payments {
+		if !payment.CreatedAt.Before(from) && payment.CreatedAt.Before(to) {
+			payments = append(payments, *payment)
+		}
+	}
+	sortByCreation(payments)
+	return
+}
+
+func sortByCreation(payments []StoredPayment) {
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+	})
+}
@@ -0,0 +1,46 @@
+package zarinpalgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuplicateDetector(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	outbox := NewMemoryOutbox()
+	now := time.Now()
+
+	for i, authority := range []string{"A1", "A2", "A3"} {
+		store.SaveSession(ctx, PaymentSession{Authority: authority, Amount: 10000, OrderID: "42", CreatedAt: now.Add(time.Duration(i) * time.Second)})
+	}
+	store.SaveSession(ctx, PaymentSession{Authority: "B1", Amount: 10000, OrderID: "43", CreatedAt: now})
+	for _, authority := range []string{"A1", "A3", "B1"} {
+		store.UpdateStatus(ctx, authority, PaymentStatePending, 0)
+		store.UpdateStatus(ctx, authority, PaymentStateVerified, 0)
+	}
+
+	var found []DuplicatePayment
+	detector := &DuplicateDetector{
+		Store:  store,
+		Outbox: outbox,
+		OnDuplicate: func(ctx context.Context, duplicate DuplicatePayment) {
+			found = append(found, duplicate)
+		},
+	}
+
+	detector.OnResult(ctx, PaymentStatus{Authority: "B1", IsSuccessful: true})
+	detector.OnResult(ctx, PaymentStatus{Authority: "A3", IsSuccessful: true})
+	if len(found) != 1 || found[0].OrderID != "42" || len(found[0].Duplicates()) != 1 || found[0].Duplicates()[0].Authority != "A3" {
+		t.Errorf("Expected A3 to duplicate A1, got %+v", found)
+	}
+	if events, _ := outbox.PendingEvents(ctx, 10); len(events) != 1 || events[0].Type != EventPaymentDuplicate || events[0].Payment.Authority != "A3" {
+		t.Errorf("Expected a duplicate event, got %+v", events)
+	}
+
+	report, err := detector.Report(ctx, now.Add(-time.Hour))
+	if err != nil || len(report) != 1 || report[0].OrderID != "42" || len(report[0].Payments) != 2 {
+		t.Errorf("Expected one duplicate in the report, got %+v %v", report, err)
+	}
+}
@@ -0,0 +1,38 @@
+package zarinpalgo
+
+import "net/url"
+
+// Operations whose endpoint can be overridden
+const (
+	EndpointRequest    = "request"
+	EndpointVerify     = "verify"
+	EndpointInquiry    = "inquiry"
+	EndpointUnverified = "unverified"
+	EndpointReverse    = "reverse"
+	EndpointRefund     = "refund" // Reporting.Refund, the others are gateway calls
+)
+
+// EndpointOverrides routes operations to other endpoints, like the paths an API gateway in
+// front of Zarinpal rewrites them to. Overrides are resolved against the base URL of the
+// client the way links are, so "verify-v2" replaces the endpoint, "/psp/verify" the whole
+// path and "https://psp.example.com/verify" the whole URL. Operations without an override
+// use their regular endpoint.
+type EndpointOverrides map[string]string
+
+// url returns the URL of the operation, base+path unless it is overridden
+func (e EndpointOverrides) url(operation, base, path string) string {
+	override := e[operation]
+	if override == "" {
+		return base + path
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		// the request fails on the base URL anyway
+		return base + override
+	}
+	ref, err := url.Parse(override)
+	if err != nil {
+		return base + override
+	}
+	return baseURL.ResolveReference(ref).String()
+}
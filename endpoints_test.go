@@ -0,0 +1,81 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpointOverridesURL(t *testing.T) {
+	overrides := EndpointOverrides{
+		EndpointVerify:  "verify-v2",
+		EndpointReverse: "/psp/reverse",
+		EndpointRefund:  "https://psp.example.com/refunds",
+	}
+	base := "https://payment.zarinpal.com/pg/v4/payment/"
+	tests := []struct {
+		operation, path, want string
+	}{
+		{EndpointRequest, "request.json", base + "request.json"},
+		{EndpointVerify, "verify.json", base + "verify-v2"},
+		{EndpointReverse, "reverse.json", "https://payment.zarinpal.com/psp/reverse"},
+		{EndpointRefund, "", "https://psp.example.com/refunds"},
+	}
+	for _, tt := range tests {
+		if got := overrides.url(tt.operation, base, tt.path); got != tt.want {
+			t.Errorf("Expected %s for %s, got %s", tt.want, tt.operation, got)
+		}
+	}
+
+	var none EndpointOverrides
+	if got := none.url(EndpointVerify, base, "verify.json"); got != base+"verify.json" {
+		t.Errorf("Expected the regular endpoint without overrides, got %s", got)
+	}
+}
+
+func TestEndpointOverrides(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/gateway/zp/verify":
+			w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+		case "/pg/v4/payment/inquiry.json":
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`))
+		case "/gateway/refunds":
+			w.Write([]byte(`{"data":{"resource":{"id":"R1","amount":1000,"timeline":{"refund_status":"PENDING"}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	zp.Endpoints = EndpointOverrides{EndpointVerify: "/gateway/zp/verify"}
+
+	if _, err := zp.VerifyPayment(context.Background(), 1000, "A1"); err != nil {
+		t.Fatalf("Failed to verify through the override: %v", err)
+	}
+	if _, err := zp.InquirePayment(context.Background(), "A1"); err != nil {
+		t.Fatalf("Failed to inquire without an override: %v", err)
+	}
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL + "/api/v4/graphql/"
+	reporting.Endpoints = EndpointOverrides{EndpointRefund: "/gateway/refunds"}
+	if _, err := reporting.Refund(context.Background(), RefundRequest{SessionID: "S1", Amount: 1000}); err != nil {
+		t.Fatalf("Failed to refund through the override: %v", err)
+	}
+
+	want := []string{"/gateway/zp/verify", "/pg/v4/payment/inquiry.json", "/gateway/refunds"}
+	if len(paths) != len(want) {
+		t.Fatalf("Expected requests to %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("Expected request %d to %s, got %s", i, want[i], paths[i])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Expiry tracker defaults
+const (
+	DefaultExpiryTick  = time.Second
+	DefaultExpirySlots = 512
+)
+
+// ExpiryTracker fires an expiration for every tracked session whose authority expires unpaid,
+// so reserved stock can be released. Sessions are kept in a hashed timer wheel, which makes
+// tracking and untracking constant time regardless of how many carts are open.
+type ExpiryTracker struct {
+	// OnExpire is called with every expired session
+	OnExpire func(ctx context.Context, session PaymentSession)
+	// Store is optional, expired sessions are moved to the expired state in it. Only sessions
+	// still created or redirected there expire: the user of a pending session came back from
+	// the gateway and maybe paid, so it is left to the Reconciler, which inquires it first.
+	Store PaymentStore
+	// Outbox is optional, an EventPaymentExpired event is added to it for every expired session
+	Outbox Outbox
+	// OnError is called when updating the store or the outbox fails
+	OnError func(authority string, err error)
+
+	tick    time.Duration
+	mu      sync.Mutex
+	slots   []map[string]*wheelEntry
+	index   map[string]int // slot of each tracked authority
+	current int
+	last    time.Time
+}
+
+type wheelEntry struct {
+	session PaymentSession
+	rounds  int // full turns of the wheel left before the entry fires
+}
+
+// NewExpiryTracker creates an ExpiryTracker with the given resolution, DefaultExpiryTick if zero
+func NewExpiryTracker(tick time.Duration) *ExpiryTracker {
+	if tick <= 0 {
+		tick = DefaultExpiryTick
+	}
+	t := &ExpiryTracker{
+		tick:  tick,
+		slots: make([]map[string]*wheelEntry, DefaultExpirySlots),
+		index: make(map[string]int),
+		last:  time.Now(),
+	}
+	for i := range t.slots {
+		t.slots[i] = make(map[string]*wheelEntry)
+	}
+	return t
+}
+
+// Track schedules the expiration of a session, replacing an earlier schedule of its authority
+func (t *ExpiryTracker) Track(session PaymentSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remove(session.Authority)
+
+	ticks := int(time.Until(session.ExpiresAt) / t.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	slot := (t.current + ticks) % len(t.slots)
+	t.slots[slot][session.Authority] = &wheelEntry{session: session, rounds: (ticks - 1) / len(t.slots)}
+	t.index[session.Authority] = slot
+}
+
+// Untrack cancels the expiration of a paid or canceled session
+func (t *ExpiryTracker) Untrack(authority string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(authority)
+}
+
+// Len returns the number of tracked sessions
+func (t *ExpiryTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.index)
+}
+
+func (t *ExpiryTracker) remove(authority string) {
+	if slot, ok := t.index[authority]; ok {
+		delete(t.slots[slot], authority)
+		delete(t.index, authority)
+	}
+}
+
+// Load tracks the pending sessions of the store, call it on start up to resume tracking
+func (t *ExpiryTracker) Load(ctx context.Context) error {
+	pending, err := t.Store.ListPending(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, payment := range pending {
+		t.Track(payment.PaymentSession)
+	}
+	return nil
+}
+
+// Run advances the wheel until the context is done
+func (t *ExpiryTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			t.advance(ctx, now)
+		}
+	}
+}
+
+// advance moves the wheel by the ticks elapsed since the last advance and expires the due sessions
+func (t *ExpiryTracker) advance(ctx context.Context, now time.Time) {
+	t.mu.Lock()
+	var expired []PaymentSession
+	for !now.Before(t.last.Add(t.tick)) {
+		t.last = t.last.Add(t.tick)
+		t.current = (t.current + 1) % len(t.slots)
+		for authority, entry := range t.slots[t.current] {
+			if entry.rounds > 0 {
+				entry.rounds--
+				continue
+			}
+			expired = append(expired, entry.session)
+			delete(t.slots[t.current], authority)
+			delete(t.index, authority)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, session := range expired {
+		t.expire(ctx, session)
+	}
+}
+
+func (t *ExpiryTracker) expire(ctx context.Context, session PaymentSession) {
+	if t.Store != nil {
+		payment, err := t.Store.GetByAuthority(ctx, session.Authority)
+		if err != nil {
+			t.fail(session.Authority, err)
+			return
+		}
+		if payment.State != PaymentStateCreated && payment.State != PaymentStateRedirected {
+			// the user came back or the session was resolved in the meantime
+			return
+		}
+		err = t.Store.UpdateStatus(ctx, session.Authority, PaymentStateExpired, 0)
+		if errors.Is(err, ErrInvalidTransition) {
+			return
+		}
+		if err != nil {
+			t.fail(session.Authority, err)
+			return
+		}
+	}
+
+	if t.Outbox != nil {
+		event := WebhookEvent{
+			ID:        uuid.NewString(),
+			Type:      EventPaymentExpired,
+			CreatedAt: time.Now().UTC(),
+			Payment: PaymentStatus{
+				Authority: session.Authority,
+				Amount:    session.Amount,
+				Message:   "payment expired",
+			},
+		}
+		if err := t.Outbox.AddEvent(ctx, event); err != nil {
+			t.fail(session.Authority, err)
+		}
+	}
+
+	if t.OnExpire != nil {
+		if panicErr := RunHook("expiry tracker OnExpire", func() { t.OnExpire(ctx, session) }); panicErr != nil {
+			t.fail(session.Authority, panicErr)
+		}
+	}
+}
+
+func (t *ExpiryTracker) fail(authority string, err error) {
+	if t.OnError != nil {
+		RunHook("expiry tracker OnError", func() { t.OnError(authority, err) })
+	}
+}
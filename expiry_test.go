@@ -0,0 +1,67 @@
+package zarinpalgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExpiryTracker(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	outbox := NewMemoryOutbox()
+
+	tracker := NewExpiryTracker(10 * time.Millisecond)
+	tracker.Store = store
+	tracker.Outbox = outbox
+	var expired []string
+	tracker.OnExpire = func(ctx context.Context, session PaymentSession) {
+		expired = append(expired, session.Authority)
+	}
+
+	now := time.Now()
+	sessions := []PaymentSession{
+		{Authority: "A1", Amount: 10000, ExpiresAt: now.Add(30 * time.Millisecond)},
+		{Authority: "A2", Amount: 10000, ExpiresAt: now.Add(30 * time.Millisecond)},
+		{Authority: "A3", Amount: 10000, ExpiresAt: now.Add(30 * time.Millisecond)},
+		{Authority: "A5", Amount: 10000, ExpiresAt: now.Add(30 * time.Millisecond)},
+		// beyond a full turn of the wheel
+		{Authority: "A4", Amount: 10000, ExpiresAt: now.Add(DefaultExpirySlots*10*time.Millisecond + 50*time.Millisecond)},
+	}
+	for _, session := range sessions {
+		store.SaveSession(ctx, session)
+		tracker.Track(session)
+	}
+
+	tracker.Untrack("A2")
+	store.UpdateStatus(ctx, "A3", PaymentStatePending, 0)
+	store.UpdateStatus(ctx, "A3", PaymentStateVerified, 201)
+	// the user came back at the last minute and the callback awaits verification
+	store.UpdateStatus(ctx, "A5", PaymentStatePending, 0)
+
+	tracker.advance(ctx, now.Add(50*time.Millisecond))
+	if len(expired) != 1 || expired[0] != "A1" {
+		t.Errorf("Expected only A1 to expire, got %v", expired)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateExpired {
+		t.Errorf("Expected A1 to be expired, got %s", payment.State)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A5"); payment.State != PaymentStatePending {
+		t.Errorf("Expected A5 left pending for the reconciler, got %s", payment.State)
+	}
+	if events, _ := outbox.PendingEvents(ctx, 10); len(events) != 1 || events[0].Type != EventPaymentExpired {
+		t.Errorf("Expected an expiration event, got %+v", events)
+	}
+	if tracker.Len() != 1 {
+		t.Errorf("Expected one tracked session, got %d", tracker.Len())
+	}
+
+	tracker.advance(ctx, now.Add(DefaultExpirySlots*10*time.Millisecond))
+	if len(expired) != 1 {
+		t.Errorf("Expected A4 not to expire before its time, got %v", expired)
+	}
+	tracker.advance(ctx, now.Add(DefaultExpirySlots*10*time.Millisecond+60*time.Millisecond))
+	if len(expired) != 2 || expired[1] != "A4" {
+		t.Errorf("Expected A4 to expire, got %v", expired)
+	}
+}
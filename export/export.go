@@ -0,0 +1,185 @@
+// Package export writes zarinpalgo payments and transactions to CSV and Excel spreadsheets, and
+// streams them as JSON Lines
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// ErrUnknownColumn is returned by ColumnsByKey for keys that are not in AllColumns
+var ErrUnknownColumn = errors.New("unknown column")
+
+// TimeLayout is the layout times are written in
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Row is a payment or transaction flattened for export
+type Row struct {
+	Authority   string
+	OrderID     string
+	Status      string
+	Amount      int
+	Fee         int
+	RefID       int
+	CardPan     string
+	Description string
+	CreatedAt   time.Time
+}
+
+// FromPayments converts stored payments to rows, their state is used as status
+func FromPayments(payments []zarinpalgo.StoredPayment) []Row {
+	rows := make([]Row, 0, len(payments))
+	for _, payment := range payments {
+		rows = append(rows, PaymentRow(payment))
+	}
+	return rows
+}
+
+// PaymentRow converts a stored payment to a row, its state is used as status
+func PaymentRow(payment zarinpalgo.StoredPayment) Row {
+	return Row{
+		Authority: payment.Authority,
+		OrderID:   payment.OrderID,
+		Status:    string(payment.State),
+		Amount:    payment.Amount,
+		RefID:     payment.RefID,
+		CreatedAt: payment.CreatedAt,
+	}
+}
+
+// FromTransactions converts transactions of the reporting API to rows
+func FromTransactions(transactions []zarinpalgo.Transaction) []Row {
+	rows := make([]Row, 0, len(transactions))
+	for _, transaction := range transactions {
+		rows = append(rows, TransactionRow(transaction))
+	}
+	return rows
+}
+
+// TransactionRow converts a transaction of the reporting API to a row
+func TransactionRow(transaction zarinpalgo.Transaction) Row {
+	return Row{
+		Authority:   transaction.Authority,
+		Status:      transaction.Status,
+		Amount:      transaction.Amount,
+		Fee:         transaction.Fee,
+		RefID:       transaction.RefID,
+		CardPan:     transaction.CardPan,
+		Description: transaction.Description,
+		CreatedAt:   transaction.CreatedAt,
+	}
+}
+
+// Column is a column of the exported sheet. Value returns a string, an int or a time.Time.
+type Column struct {
+	Key    string
+	Header string
+	Value  func(Row) interface{}
+}
+
+// AllColumns are the columns known to ColumnsByKey, in their default order
+var AllColumns = []Column{
+	{Key: "authority", Header: "Authority", Value: func(r Row) interface{} { return r.Authority }},
+	{Key: "order_id", Header: "Order ID", Value: func(r Row) interface{} { return r.OrderID }},
+	{Key: "status", Header: "Status", Value: func(r Row) interface{} { return r.Status }},
+	{Key: "amount", Header: "Amount", Value: func(r Row) interface{} { return r.Amount }},
+	{Key: "fee", Header: "Fee", Value: func(r Row) interface{} { return r.Fee }},
+	{Key: "ref_id", Header: "Reference ID", Value: func(r Row) interface{} { return r.RefID }},
+	{Key: "card_pan", Header: "Card", Value: func(r Row) interface{} { return r.CardPan }},
+	{Key: "description", Header: "Description", Value: func(r Row) interface{} { return r.Description }},
+	{Key: "created_at", Header: "Created At", Value: func(r Row) interface{} { return r.CreatedAt }},
+	{Key: "created_at_jalali", Header: "Created At (Jalali)", Value: func(r Row) interface{} {
+		return zarinpalgo.FormatJalali(r.CreatedAt.In(zarinpalgo.IranLocation))
+	}},
+}
+
+// ColumnsByKey returns the columns of AllColumns with the given keys, in the given order
+func ColumnsByKey(keys ...string) ([]Column, error) {
+	columns := make([]Column, 0, len(keys))
+	for _, key := range keys {
+		column, ok := columnByKey(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, key)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func columnByKey(key string) (Column, bool) {
+	for _, column := range AllColumns {
+		if column.Key == key {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// WriteCSV writes a header line and the rows, AllColumns are used when columns is empty.
+// Text cells starting like a spreadsheet formula are prefixed with a quote, see escapeFormula.
+func WriteCSV(w io.Writer, rows []Row, columns []Column) error {
+	if len(columns) == 0 {
+		columns = AllColumns
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(headers(columns)); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = formatValue(column.Value(row))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func headers(columns []Column) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Header
+	}
+	return names
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return escapeFormula(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(TimeLayout)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula prefixes text starting with =, +, -, @, a tab or a carriage return with a quote
+// so spreadsheets opening the CSV show it instead of running it as a formula, descriptions and
+// names come from customers and sellers
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
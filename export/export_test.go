@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/xuri/excelize/v2"
+)
+
+var testRows = FromTransactions([]zarinpalgo.Transaction{
+	{Authority: "A1", Status: zarinpalgo.InquiryStatusVerified, Amount: 10000, Fee: 100, RefID: 201, Description: "Order, one", CreatedAt: time.Date(2024, 5, 12, 10, 30, 0, 0, time.UTC)},
+	{Authority: "A2", Status: zarinpalgo.InquiryStatusFailed, Amount: 20000},
+})
+
+func TestWriteCSV(t *testing.T) {
+	columns, err := ColumnsByKey("authority", "amount", "description", "created_at", "created_at_jalali")
+	if err != nil {
+		t.Fatalf("Failed to select columns: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testRows, columns); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	expected := "Authority,Amount,Description,Created At,Created At (Jalali)\n" +
+		"A1,10000,\"Order, one\",2024-05-12 10:30:00,1403/02/23 14:00:00\n" +
+		"A2,20000,,,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteCSVFormulas(t *testing.T) {
+	rows := FromTransactions([]zarinpalgo.Transaction{
+		{Authority: "A1", Amount: -5000, Description: "=HYPERLINK(\"http://example.com\")"},
+		{Authority: "A2", Description: "@SUM(A1)"},
+		{Authority: "A3", Description: "Order 7 - blue"},
+	})
+	columns, _ := ColumnsByKey("authority", "amount", "description")
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, rows, columns); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	expected := "Authority,Amount,Description\n" +
+		"A1,-5000,\"'=HYPERLINK(\"\"http://example.com\"\")\"\n" +
+		"A2,0,'@SUM(A1)\n" +
+		"A3,0,Order 7 - blue\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestColumnsByKeyUnknown(t *testing.T) {
+	if _, err := ColumnsByKey("authority", "iban"); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn, got %v", err)
+	}
+}
+
+func TestFromPayments(t *testing.T) {
+	rows := FromPayments([]zarinpalgo.StoredPayment{{
+		PaymentSession: zarinpalgo.PaymentSession{Authority: "A1", OrderID: "order-1", Amount: 10000},
+		State:          zarinpalgo.PaymentStateVerified,
+		RefID:          201,
+	}})
+
+	var buf bytes.Buffer
+	WriteCSV(&buf, rows, nil)
+	if !strings.Contains(buf.String(), "A1,order-1,verified,10000,0,201") {
+		t.Errorf("Unexpected CSV %q", buf.String())
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, testRows, nil, ""); err != nil {
+		t.Fatalf("Failed to write XLSX: %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("Failed to open XLSX: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(DefaultSheet)
+	if err != nil {
+		t.Fatalf("Failed to read rows: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	if rows[0][0] != "Authority" || rows[1][0] != "A1" || rows[1][3] != "10000" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	amountType, err := f.GetCellType(DefaultSheet, "D2")
+	if err != nil || amountType == excelize.CellTypeSharedString || amountType == excelize.CellTypeInlineString {
+		t.Errorf("Expected amounts to be numbers, got cell type %v %v", amountType, err)
+	}
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// PaymentChunk is the range of creation times StreamPayments lists from the store at once
+const PaymentChunk = 24 * time.Hour
+
+// JSONLWriter writes rows as JSON Lines, one object per row keyed by the column keys in the
+// order of the columns. Every row is written as soon as it is given, nothing is buffered.
+type JSONLWriter struct {
+	w       io.Writer
+	columns []Column
+	line    []byte
+	count   int
+}
+
+// NewJSONLWriter creates a JSONLWriter, AllColumns are used when columns is empty
+func NewJSONLWriter(w io.Writer, columns []Column) *JSONLWriter {
+	if len(columns) == 0 {
+		columns = AllColumns
+	}
+	return &JSONLWriter{w: w, columns: columns}
+}
+
+// Write writes the row as a line. Times are written in RFC 3339 and zero times as null.
+func (j *JSONLWriter) Write(row Row) error {
+	j.line = append(j.line[:0], '{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.line = append(j.line, ',')
+		}
+		key, err := json.Marshal(column.Key)
+		if err != nil {
+			return err
+		}
+		value := column.Value(row)
+		if t, ok := value.(time.Time); ok && t.IsZero() {
+			value = nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		j.line = append(append(append(j.line, key...), ':'), encoded...)
+	}
+	j.line = append(j.line, '}', '\n')
+
+	if _, err := j.w.Write(j.line); err != nil {
+		return err
+	}
+	j.count++
+	return nil
+}
+
+// Count returns the number of rows written
+func (j *JSONLWriter) Count() int {
+	return j.count
+}
+
+// StreamTransactions writes the transactions passing the filter as JSON Lines while their pages
+// are fetched, newest first, and returns how many were written
+func StreamTransactions(ctx context.Context, w io.Writer, reporting *zarinpalgo.Reporting, filter zarinpalgo.TransactionFilter, columns []Column) (count int, err error) {
+	writer := NewJSONLWriter(w, columns)
+	var writeErr error
+	err = reporting.EachTransaction(ctx, filter, func(transaction zarinpalgo.Transaction) bool {
+		writeErr = writer.Write(TransactionRow(transaction))
+		return writeErr == nil
+	})
+	if err == nil {
+		err = writeErr
+	}
+	return writer.Count(), err
+}
+
+// StreamPayments writes the payments created in [from, to) as JSON Lines, oldest first, listing
+// PaymentChunk of them from the store at a time, and returns how many were written. To
+// defaults to now.
+func StreamPayments(ctx context.Context, w io.Writer, lister zarinpalgo.PaymentLister, from, to time.Time, columns []Column) (count int, err error) {
+	if from.IsZero() {
+		return 0, errors.New("export: streaming payments needs a start time")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	writer := NewJSONLWriter(w, columns)
+	for start := from; start.Before(to); start = start.Add(PaymentChunk) {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		end := start.Add(PaymentChunk)
+		if end.After(to) {
+			end = to
+		}
+		var payments []zarinpalgo.StoredPayment
+		if payments, err = lister.ListCreated(ctx, start, end); err != nil {
+			break
+		}
+		for _, payment := range payments {
+			if err = writer.Write(PaymentRow(payment)); err != nil {
+				return writer.Count(), err
+			}
+		}
+	}
+	return writer.Count(), err
+}
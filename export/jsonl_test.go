@@ -0,0 +1,98 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestJSONLWriter(t *testing.T) {
+	columns, err := ColumnsByKey("authority", "amount", "description", "created_at")
+	if err != nil {
+		t.Fatalf("Failed to select columns: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := NewJSONLWriter(&buf, columns)
+	for _, row := range testRows {
+		if err := writer.Write(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+
+	expected := `{"authority":"A1","amount":10000,"description":"Order, one","created_at":"2024-05-12T10:30:00Z"}` + "\n" +
+		`{"authority":"A2","amount":20000,"description":"","created_at":null}` + "\n"
+	if buf.String() != expected || writer.Count() != 2 {
+		t.Errorf("Expected %q, got %d %q", expected, writer.Count(), buf.String())
+	}
+}
+
+// failingWriter fails after the given number of writes
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.writes--
+	return len(p), nil
+}
+
+func TestStreamTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"Session":[
+			{"id":"2","authority":"A2","status":"VERIFIED","amount":20000,"created_at":"2024-05-12T11:00:00Z"},
+			{"id":"1","authority":"A1","status":"FAILED","amount":10000,"created_at":"2024-05-12T10:00:00Z"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	reporting := zarinpalgo.NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	columns, _ := ColumnsByKey("authority", "status")
+	filter := zarinpalgo.TransactionFilter{From: time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	count, err := StreamTransactions(context.Background(), &buf, reporting, filter, columns)
+	expected := `{"authority":"A2","status":"VERIFIED"}` + "\n" + `{"authority":"A1","status":"FAILED"}` + "\n"
+	if err != nil || count != 2 || buf.String() != expected {
+		t.Errorf("Expected %q, got %d %q %v", expected, count, buf.String(), err)
+	}
+
+	if count, err := StreamTransactions(context.Background(), &failingWriter{writes: 1}, reporting, filter, columns); err == nil || count != 1 {
+		t.Errorf("Expected the write error after 1 transaction, got %d %v", count, err)
+	}
+}
+
+func TestStreamPayments(t *testing.T) {
+	ctx := context.Background()
+	store := zarinpalgo.NewMemoryPaymentStore()
+	start := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	for i, authority := range []string{"A1", "A2", "A3", "A4"} {
+		created := start.Add(time.Duration(i) * 30 * time.Hour)
+		store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: authority, Amount: 10000, CreatedAt: created, ExpiresAt: created.Add(time.Hour)})
+	}
+	columns, _ := ColumnsByKey("authority")
+
+	var buf bytes.Buffer
+	count, err := StreamPayments(ctx, &buf, store, start, start.Add(90*time.Hour), columns)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 payments, got %d %v", count, err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); lines[0] != `{"authority":"A1"}` || lines[2] != `{"authority":"A3"}` {
+		t.Errorf("Expected the payments oldest first, got %q", buf.String())
+	}
+
+	if _, err := StreamPayments(ctx, &buf, store, time.Time{}, start, columns); err == nil {
+		t.Error("Expected an error without a start time")
+	}
+}
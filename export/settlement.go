@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// settlementHeaders are the columns of WriteSettlementCSV
+var settlementHeaders = []string{"IBAN", "Seller", "Authority", "Reference ID", "Verified At", "Payment Amount", "Wage", "Fee Share", "Net", "Description"}
+
+// WriteSettlementCSV writes a line per wage of the report, grouped by seller, followed by a
+// total line for each seller whose authority column reads "TOTAL". The seller column is
+// empty unless the report was passed to SellerRegistry.NameSellers.
+func WriteSettlementCSV(w io.Writer, report zarinpalgo.SettlementReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(settlementHeaders); err != nil {
+		return err
+	}
+
+	for _, seller := range report.Sellers {
+		for _, line := range seller.Lines {
+			err := writer.Write([]string{
+				seller.Iban,
+				escapeFormula(seller.Name),
+				line.Authority,
+				formatValue(line.RefID),
+				formatValue(line.VerifiedAt),
+				formatValue(line.PaymentAmount),
+				formatValue(line.Wage),
+				formatValue(line.FeeShare),
+				formatValue(line.Wage - line.FeeShare),
+				escapeFormula(line.Description),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		err := writer.Write([]string{
+			seller.Iban, escapeFormula(seller.Name), "TOTAL", "", "", "",
+			formatValue(seller.Gross),
+			formatValue(seller.Fees),
+			formatValue(seller.Net()),
+			formatValue(seller.Payments) + " payments",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestWriteSettlementCSV(t *testing.T) {
+	verifiedAt := time.Date(2024, 5, 12, 10, 30, 0, 0, time.UTC)
+	report := zarinpalgo.Settle([]zarinpalgo.WagePayment{
+		{Authority: "A1", RefID: 201, Amount: 100000, Fee: 1000, VerifiedAt: verifiedAt, Wages: []zarinpalgo.Wage{{Iban: "IR01", Amount: 60000, Description: "Desk"}}},
+	}, verifiedAt, verifiedAt.Add(time.Hour), zarinpalgo.FeeProportional)
+
+	var buf bytes.Buffer
+	if err := WriteSettlementCSV(&buf, report); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	expected := "IBAN,Seller,Authority,Reference ID,Verified At,Payment Amount,Wage,Fee Share,Net,Description\n" +
+		"IR01,,A1,201,2024-05-12 10:30:00,100000,60000,600,59400,Desk\n" +
+		"IR01,,TOTAL,,,,60000,600,59400,1 payments\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// DefaultSheet is the name of the sheet WriteXLSX writes to when none is given
+const DefaultSheet = "Payments"
+
+// WriteXLSX writes the rows to a single sheet workbook, AllColumns are used when columns is empty.
+// Amounts are written as numbers and times as dates, so the sheet can be summed and sorted.
+func WriteXLSX(w io.Writer, rows []Row, columns []Column, sheet string) (err error) {
+	if len(columns) == 0 {
+		columns = AllColumns
+	}
+	if sheet == "" {
+		sheet = DefaultSheet
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err = f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return
+	}
+
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: stringPtr("yyyy-mm-dd hh:mm:ss")})
+	if err != nil {
+		return
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	if err = f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return
+	}
+
+	for r, row := range rows {
+		for c, column := range columns {
+			cell, cellErr := excelize.CoordinatesToCellName(c+1, r+2)
+			if cellErr != nil {
+				return cellErr
+			}
+
+			value := column.Value(row)
+			if t, ok := value.(time.Time); ok {
+				if t.IsZero() {
+					continue
+				}
+				if err = f.SetCellValue(sheet, cell, t); err != nil {
+					return
+				}
+				if err = f.SetCellStyle(sheet, cell, cell, dateStyle); err != nil {
+					return
+				}
+				continue
+			}
+			if err = f.SetCellValue(sheet, cell, value); err != nil {
+				return
+			}
+		}
+	}
+
+	_, err = f.WriteTo(w)
+	return
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
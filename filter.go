@@ -0,0 +1,131 @@
+package zarinpalgo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransactionFilter selects transactions of the reporting API. Zero fields don't filter.
+// Amounts, the card and the reference ID are sent as query arguments, every field is also
+// checked on the fetched transactions so the results hold whatever the API applied. The
+// authority is only checked on the fetched transactions.
+type TransactionFilter struct {
+	From time.Time // created at or after
+	To   time.Time // created before
+
+	Statuses  []string // any of the InquiryStatus constants
+	MinAmount int      // in Rials, inclusive
+	MaxAmount int      // in Rials, inclusive
+
+	CardPanSuffix string // last digits of the card, like "5995"
+	RefID         int
+	Authority     string
+	ZarinLinkID   string // paid through the link, matched by the API only
+}
+
+// queryArgument is an optional argument of the sessions query
+type queryArgument struct {
+	name      string
+	graphType string
+	value     interface{}
+}
+
+// arguments returns the query arguments of the set fields. The range is left to the pages
+// being listed newest first, a single status is sent as is and sets are only matched locally.
+func (f TransactionFilter) arguments() (args []queryArgument) {
+	if len(f.Statuses) == 1 {
+		args = append(args, queryArgument{"status", "String", f.Statuses[0]})
+	}
+	if f.MinAmount > 0 {
+		args = append(args, queryArgument{"min_amount", "BigInteger", f.MinAmount})
+	}
+	if f.MaxAmount > 0 {
+		args = append(args, queryArgument{"max_amount", "BigInteger", f.MaxAmount})
+	}
+	if f.CardPanSuffix != "" {
+		args = append(args, queryArgument{"card_pan", "String", f.CardPanSuffix})
+	}
+	if f.RefID != 0 {
+		args = append(args, queryArgument{"reference_id", "String", strconv.Itoa(f.RefID)})
+	}
+	if f.ZarinLinkID != "" {
+		args = append(args, queryArgument{"zarin_link_id", "ID", f.ZarinLinkID})
+	}
+	return
+}
+
+// Matches reports whether the transaction passes the filter
+func (f TransactionFilter) Matches(t Transaction) bool {
+	if !f.From.IsZero() && t.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.CreatedAt.Before(f.To) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, t.Status) {
+		return false
+	}
+	if (f.MinAmount > 0 && t.Amount < f.MinAmount) || (f.MaxAmount > 0 && t.Amount > f.MaxAmount) {
+		return false
+	}
+	if f.CardPanSuffix != "" && !strings.HasSuffix(t.CardPan, f.CardPanSuffix) {
+		return false
+	}
+	if f.Authority != "" && t.Authority != f.Authority {
+		return false
+	}
+	return f.RefID == 0 || t.RefID == f.RefID
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionsQuery builds the sessions query with the arguments of the filter, and its variables
+func sessionsQuery(terminalID string, limit, offset int, filter TransactionFilter) (string, map[string]interface{}) {
+	declarations := []string{"$terminal_id: ID!", "$limit: Int", "$offset: Int"}
+	arguments := []string{"terminal_id: $terminal_id", "limit: $limit", "offset: $offset"}
+	variables := map[string]interface{}{
+		"terminal_id": terminalID,
+		"limit":       limit,
+		"offset":      offset,
+	}
+	for _, arg := range filter.arguments() {
+		declarations = append(declarations, fmt.Sprintf("$%s: %s", arg.name, arg.graphType))
+		arguments = append(arguments, fmt.Sprintf("%s: $%s", arg.name, arg.name))
+		variables[arg.name] = arg.value
+	}
+
+	query := `query Sessions(` + strings.Join(declarations, ", ") + `) {
+  Session(` + strings.Join(arguments, ", ") + `) {
+    id
+    authority
+    status
+    amount
+    fee
+    reference_id
+    card_pan
+    description
+    created_at
+  }
+}`
+	return query, variables
+}
+
+// FilterTransactions returns the transactions passing the filter, newest first. Without a From
+// time every transaction of the terminal is fetched.
+func (r *Reporting) FilterTransactions(ctx context.Context, filter TransactionFilter) (transactions []Transaction, err error) {
+	err = r.eachTransaction(ctx, filter, func(transaction Transaction) bool {
+		transactions = append(transactions, transaction)
+		return true
+	})
+	return
+}
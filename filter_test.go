@@ -0,0 +1,104 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionFilterMatches(t *testing.T) {
+	created := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	transaction := Transaction{Authority: "A1", Status: InquiryStatusVerified, Amount: 50000, CardPan: "502229******5995", RefID: 201, CreatedAt: created}
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		match  bool
+	}{
+		{"empty", TransactionFilter{}, true},
+		{"range", TransactionFilter{From: created, To: created.Add(time.Hour)}, true},
+		{"to is exclusive", TransactionFilter{To: created}, false},
+		{"statuses", TransactionFilter{Statuses: []string{InquiryStatusPaid, InquiryStatusVerified}}, true},
+		{"other status", TransactionFilter{Statuses: []string{InquiryStatusPaid}}, false},
+		{"amount range", TransactionFilter{MinAmount: 50000, MaxAmount: 50000}, true},
+		{"below minimum", TransactionFilter{MinAmount: 60000}, false},
+		{"card suffix", TransactionFilter{CardPanSuffix: "5995"}, true},
+		{"other card", TransactionFilter{CardPanSuffix: "1234"}, false},
+		{"ref ID", TransactionFilter{RefID: 201}, true},
+		{"other ref ID", TransactionFilter{RefID: 202}, false},
+		{"authority", TransactionFilter{Authority: "A1"}, true},
+		{"other authority", TransactionFilter{Authority: "A2"}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.filter.Matches(transaction); got != test.match {
+			t.Errorf("%s: expected %v, got %v", test.name, test.match, got)
+		}
+	}
+}
+
+func TestFilterTransactions(t *testing.T) {
+	var query string
+	var variables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		query, variables = body.Query, body.Variables
+
+		// the API ignores the card filter here, the client still applies it
+		w.Write([]byte(`{"data":{"Session":[
+			{"id":"1","authority":"A1","status":"VERIFIED","amount":50000,"card_pan":"502229******5995","created_at":"2024-01-10T12:00:00Z"},
+			{"id":"2","authority":"A2","status":"VERIFIED","amount":50000,"card_pan":"603799******1234","created_at":"2024-01-10T11:00:00Z"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+
+	transactions, err := reporting.FilterTransactions(context.Background(), TransactionFilter{
+		Statuses:      []string{InquiryStatusVerified},
+		MinAmount:     10000,
+		CardPanSuffix: "5995",
+		RefID:         201,
+	})
+	if err != nil {
+		t.Fatalf("Failed to filter transactions: %v", err)
+	}
+
+	for _, want := range []string{"$status: String", "$min_amount: BigInteger", "$card_pan: String", "$reference_id: String", "card_pan: $card_pan"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected the query to contain %q, got %s", want, query)
+		}
+	}
+	if strings.Contains(query, "max_amount") {
+		t.Errorf("Expected unset fields to be left out, got %s", query)
+	}
+	if variables["card_pan"] != "5995" || variables["reference_id"] != "201" || variables["min_amount"] != float64(10000) {
+		t.Errorf("Unexpected variables %v", variables)
+	}
+
+	// A1 has no reference ID in the response, so the local check drops it as well
+	if len(transactions) != 0 {
+		t.Errorf("Expected no transactions, got %+v", transactions)
+	}
+
+	transactions, _ = reporting.FilterTransactions(context.Background(), TransactionFilter{CardPanSuffix: "5995"})
+	if len(transactions) != 1 || transactions[0].Authority != "A1" {
+		t.Errorf("Expected A1, got %+v", transactions)
+	}
+}
+
+func TestSessionsQueryWithoutFilter(t *testing.T) {
+	query, variables := sessionsQuery("terminal-1", 100, 0, TransactionFilter{From: time.Now()})
+	if !strings.HasPrefix(query, "query Sessions($terminal_id: ID!, $limit: Int, $offset: Int) {") || len(variables) != 3 {
+		t.Errorf("Expected the plain sessions query, got %s %v", query, variables)
+	}
+}
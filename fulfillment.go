@@ -0,0 +1,137 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrFulfillmentInProgress is returned when the payment is being fulfilled by another caller
+var ErrFulfillmentInProgress = errors.New("payment is being fulfilled")
+
+// DefaultFulfillmentLockTTL is the default Fulfillment.LockTTL
+const DefaultFulfillmentLockTTL = 5 * time.Minute
+
+// FulfillmentLog durably records the payments whose fulfillment completed
+type FulfillmentLog interface {
+	IsFulfilled(ctx context.Context, authority string) (bool, error)
+	MarkFulfilled(ctx context.Context, authority string) error
+}
+
+// Fulfillment runs the fulfillment of each payment once, even with callbacks, reconciliation
+// and retries verifying the same payment concurrently
+type Fulfillment struct {
+	Client Client
+	Store  PaymentStore // provides the amounts, verified payments are recorded in it
+	Log    FulfillmentLog
+
+	// Locker serializes the callers fulfilling the same payment, share it between
+	// instances, e.g. a redisstore.Store. It defaults to a lock local to the process.
+	Locker IdempotencyLocker
+	// LockTTL bounds how long a crashed instance can hold the lock of a payment, and so how
+	// long fn may run, it defaults to DefaultFulfillmentLockTTL
+	LockTTL time.Duration
+}
+
+// NewFulfillment creates a Fulfillment with a lock local to the process
+func NewFulfillment(client Client, store PaymentStore, log FulfillmentLog) *Fulfillment {
+	return &Fulfillment{
+		Client:  client,
+		Store:   store,
+		Log:     log,
+		Locker:  &memoryLocker{keys: make(map[string]time.Time)},
+		LockTTL: DefaultFulfillmentLockTTL,
+	}
+}
+
+// OnceVerified verifies the payment and runs fn if it is successful and wasn't fulfilled yet.
+// A gateway answer of 100 or 101 doesn't decide on its own, since 101 is also returned when an
+// earlier fn failed or the process stopped while running it: fn runs until it returns nil once.
+// The status of payments fulfilled before reports IsRepeated without asking the gateway.
+// The lock of the payment isn't renewed: fn runs with a context canceled once LockTTL passes
+// since the lock was taken, after which another caller can fulfill the payment too, so fn must
+// finish within LockTTL.
+func (f *Fulfillment) OnceVerified(ctx context.Context, authority string, fn func(ctx context.Context, status PaymentStatus) error) (status PaymentStatus, ran bool, err error) {
+	ttl := f.LockTTL
+	if ttl <= 0 {
+		ttl = DefaultFulfillmentLockTTL
+	}
+	lockCtx, cancel := context.WithTimeout(ctx, ttl)
+	defer cancel()
+
+	reserved, err := f.Locker.Reserve(ctx, "fulfill:"+authority, ttl)
+	if err != nil {
+		return
+	}
+	if !reserved {
+		err = ErrFulfillmentInProgress
+		return
+	}
+	defer f.Locker.Release(context.WithoutCancel(ctx), "fulfill:"+authority)
+
+	payment, err := f.Store.GetByAuthority(ctx, authority)
+	if err != nil {
+		return
+	}
+
+	fulfilled, err := f.Log.IsFulfilled(ctx, authority)
+	if err != nil {
+		return
+	}
+	if fulfilled {
+		return PaymentStatus{
+			Authority:    authority,
+			IsSuccessful: true,
+			IsRepeated:   true,
+			RefID:        payment.RefID,
+			Amount:       payment.Amount,
+			Message:      "payment was already fulfilled",
+		}, false, nil
+	}
+
+	status, err = f.Client.CheckPaymentStatus(ctx, payment.Amount, authority)
+	var apiErr *APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		return
+	}
+	if err = recordCallback(ctx, f.Store, status, IsPaymentRejected(err)); err != nil {
+		return
+	}
+	if !status.IsSuccessful {
+		return
+	}
+
+	if err = fn(lockCtx, status); err != nil {
+		return
+	}
+	ran = true
+	err = f.Log.MarkFulfilled(ctx, authority)
+	return
+}
+
+// MemoryFulfillmentLog is a FulfillmentLog kept in memory, it doesn't survive restarts
+type MemoryFulfillmentLog struct {
+	mu        sync.Mutex
+	fulfilled map[string]bool
+}
+
+// NewMemoryFulfillmentLog creates an empty MemoryFulfillmentLog
+func NewMemoryFulfillmentLog() *MemoryFulfillmentLog {
+	return &MemoryFulfillmentLog{fulfilled: make(map[string]bool)}
+}
+
+// IsFulfilled implements FulfillmentLog
+func (l *MemoryFulfillmentLog) IsFulfilled(ctx context.Context, authority string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fulfilled[authority], nil
+}
+
+// MarkFulfilled implements FulfillmentLog
+func (l *MemoryFulfillmentLog) MarkFulfilled(ctx context.Context, authority string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fulfilled[authority] = true
+	return nil
+}
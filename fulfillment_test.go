@@ -0,0 +1,93 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFulfillmentOnceVerified(t *testing.T) {
+	var verifications int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := "101"
+		if atomic.AddInt32(&verifications, 1) == 1 {
+			code = "100"
+		}
+		w.Write([]byte(`{"data":{"code":` + code + `,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+	fulfillment := NewFulfillment(zp, store, NewMemoryFulfillmentLog())
+
+	// the first run fails after the gateway answered 100, so the 101 of the retry must still fulfill
+	_, ran, err := fulfillment.OnceVerified(ctx, "A1", func(ctx context.Context, status PaymentStatus) error {
+		return errors.New("warehouse unavailable")
+	})
+	if ran || err == nil {
+		t.Errorf("Expected failed fulfillment, got %v %v", ran, err)
+	}
+
+	var runs int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, _, err := fulfillment.OnceVerified(ctx, "A1", func(ctx context.Context, status PaymentStatus) error {
+					atomic.AddInt32(&runs, 1)
+					return nil
+				})
+				if !errors.Is(err, ErrFulfillmentInProgress) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if runs != 1 {
+		t.Errorf("Expected fulfillment to run once, got %d", runs)
+	}
+
+	status, ran, err := fulfillment.OnceVerified(ctx, "A1", func(ctx context.Context, status PaymentStatus) error {
+		t.Error("Fulfillment must not run again")
+		return nil
+	})
+	if ran || err != nil || !status.IsRepeated || status.RefID != 201 {
+		t.Errorf("Expected repeated status, got %+v %v %v", status, ran, err)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateVerified {
+		t.Errorf("Expected payment to be verified, got %s", payment.State)
+	}
+}
+
+func TestFulfillmentLockTTL(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+	fulfillment := NewFulfillment(zp, store, NewMemoryFulfillmentLog())
+	fulfillment.LockTTL = 20 * time.Millisecond
+
+	_, ran, err := fulfillment.OnceVerified(ctx, "A1", func(ctx context.Context, status PaymentStatus) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if ran || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected fn canceled when the lock expires, got %v %v", ran, err)
+	}
+}
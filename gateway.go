@@ -0,0 +1,166 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ZarinpalGateway is the name Zarinpal.Gateway is registered under
+const ZarinpalGateway = "zarinpal"
+
+var (
+	// ErrUnknownGateway is returned by OpenGateway for names no gateway is registered under
+	ErrUnknownGateway = errors.New("unknown payment gateway")
+	// ErrPartialRefund is returned by gateways that can only refund whole payments
+	ErrPartialRefund = errors.New("gateway can only refund whole payments")
+)
+
+// PaymentGateway is a payment service provider behind a provider neutral API. Zarinpal.Gateway
+// implements it, adapters of other Iranian PSPs like IDPay or Zibal register themselves with
+// RegisterGateway so merchants can fail over between providers or A/B test them.
+type PaymentGateway interface {
+	// Create creates a payment and returns where to send the user
+	Create(ctx context.Context, params PaymentParams) (GatewayPayment, error)
+	// Verify verifies the payment of id after the user returned to the callback URL
+	Verify(ctx context.Context, id string, amount int) (GatewayVerification, error)
+	// URL returns the page the user pays the payment of id on
+	URL(id string) string
+	// Refund returns amount of a verified payment to the user, 0 refunds the whole payment
+	Refund(ctx context.Context, id string, amount int) (GatewayRefund, error)
+}
+
+// GatewayPayment is a payment created on a gateway
+type GatewayPayment struct {
+	ID  string `json:"id"` // authority on Zarinpal, track ID or token on other gateways
+	URL string `json:"url"`
+	Fee int    `json:"fee,omitempty"`
+}
+
+// GatewayVerification is the outcome of verifying a payment on a gateway
+type GatewayVerification struct {
+	ID         string `json:"id"`
+	Successful bool   `json:"successful"`
+	Repeated   bool   `json:"repeated"` // the payment was verified before
+	RefID      string `json:"ref_id,omitempty"`
+	CardPan    string `json:"card_pan,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// GatewayRefund is a refund registered on a gateway
+type GatewayRefund struct {
+	ID      string `json:"id"`
+	Amount  int    `json:"amount,omitempty"` // 0 when the gateway doesn't report it
+	Message string `json:"message,omitempty"`
+}
+
+// GatewayFactory creates a gateway for the merchant ID, in sandbox mode when sandbox is set
+type GatewayFactory func(merchantID string, sandbox bool) (PaymentGateway, error)
+
+var (
+	gatewaysMu sync.RWMutex
+	gateways   = map[string]GatewayFactory{
+		ZarinpalGateway: func(merchantID string, sandbox bool) (PaymentGateway, error) {
+			return NewWithMode(merchantID, sandbox).Gateway(), nil
+		},
+	}
+)
+
+// RegisterGateway makes a gateway available under name, adapters call it from their init
+// function. It panics when the name is taken or factory is nil.
+func RegisterGateway(name string, factory GatewayFactory) {
+	gatewaysMu.Lock()
+	defer gatewaysMu.Unlock()
+
+	if factory == nil {
+		panic("zarinpalgo: RegisterGateway factory is nil")
+	}
+	if _, dup := gateways[name]; dup {
+		panic("zarinpalgo: RegisterGateway called twice for gateway " + name)
+	}
+	gateways[name] = factory
+}
+
+// OpenGateway creates the gateway registered under name
+func OpenGateway(name, merchantID string, sandbox bool) (PaymentGateway, error) {
+	gatewaysMu.RLock()
+	factory, ok := gateways[name]
+	gatewaysMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGateway, name)
+	}
+	return factory(merchantID, sandbox)
+}
+
+// Gateways returns the sorted names of the registered gateways
+func Gateways() []string {
+	gatewaysMu.RLock()
+	defer gatewaysMu.RUnlock()
+
+	names := make([]string, 0, len(gateways))
+	for name := range gateways {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// zarinpalGateway adapts the client to PaymentGateway, whose method names the client already uses
+type zarinpalGateway struct {
+	z *Zarinpal
+}
+
+// Gateway returns the client as a PaymentGateway
+func (z *Zarinpal) Gateway() PaymentGateway {
+	return zarinpalGateway{z: z}
+}
+
+// Create implements PaymentGateway
+func (g zarinpalGateway) Create(ctx context.Context, params PaymentParams) (payment GatewayPayment, err error) {
+	created, err := g.z.CreatePayment(ctx, params)
+	if err != nil {
+		return
+	}
+	payment = GatewayPayment{ID: created.Authority, URL: g.z.GetPaymentURL(created.Authority), Fee: created.Fee}
+	return
+}
+
+// Verify implements PaymentGateway
+func (g zarinpalGateway) Verify(ctx context.Context, authority string, amount int) (verification GatewayVerification, err error) {
+	status, err := g.z.CheckPaymentStatus(ctx, amount, authority)
+	verification = GatewayVerification{
+		ID:         authority,
+		Successful: status.IsSuccessful,
+		Repeated:   status.IsRepeated,
+		CardPan:    status.CardPan,
+		Message:    status.Message,
+	}
+	if status.RefID != 0 {
+		verification.RefID = strconv.Itoa(status.RefID)
+	}
+	return
+}
+
+// URL implements PaymentGateway
+func (g zarinpalGateway) URL(authority string) string {
+	return g.z.GetPaymentURL(authority)
+}
+
+// Refund implements PaymentGateway by reversing the payment, which Zarinpal allows for whole
+// payments shortly after their verification. Partial and later refunds need Reporting.Refund.
+func (g zarinpalGateway) Refund(ctx context.Context, authority string, amount int) (refund GatewayRefund, err error) {
+	if amount != 0 {
+		err = ErrPartialRefund
+		return
+	}
+
+	reversed, err := g.z.ReversePayment(ctx, authority)
+	if err != nil {
+		return
+	}
+	refund = GatewayRefund{ID: authority, Message: reversed.Message}
+	return
+}
@@ -0,0 +1,100 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubGateway is a PaymentGateway of another provider
+type stubGateway struct {
+	merchantID string
+}
+
+func (g stubGateway) Create(ctx context.Context, params PaymentParams) (GatewayPayment, error) {
+	return GatewayPayment{ID: "T1", URL: g.URL("T1")}, nil
+}
+
+func (g stubGateway) Verify(ctx context.Context, id string, amount int) (GatewayVerification, error) {
+	return GatewayVerification{ID: id, Successful: true, RefID: "R1"}, nil
+}
+
+func (g stubGateway) URL(id string) string {
+	return "https://psp.example.com/pay/" + id
+}
+
+func (g stubGateway) Refund(ctx context.Context, id string, amount int) (GatewayRefund, error) {
+	return GatewayRefund{ID: id, Amount: amount}, nil
+}
+
+func TestRegisterGateway(t *testing.T) {
+	RegisterGateway("stub", func(merchantID string, sandbox bool) (PaymentGateway, error) {
+		return stubGateway{merchantID: merchantID}, nil
+	})
+
+	gateway, err := OpenGateway("stub", "merchant-1", false)
+	if err != nil {
+		t.Fatalf("Failed to open gateway: %v", err)
+	}
+	payment, err := gateway.Create(context.Background(), PaymentParams{Amount: 10000})
+	if err != nil || payment.URL != "https://psp.example.com/pay/T1" {
+		t.Errorf("Expected the stub payment, got %+v %v", payment, err)
+	}
+
+	names := Gateways()
+	if len(names) != 2 || names[0] != "stub" || names[1] != ZarinpalGateway {
+		t.Errorf("Expected [stub zarinpal], got %v", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a gateway twice to panic")
+		}
+	}()
+	RegisterGateway("stub", func(string, bool) (PaymentGateway, error) { return stubGateway{}, nil })
+}
+
+func TestOpenGateway(t *testing.T) {
+	gateway, err := OpenGateway(ZarinpalGateway, "merchant-1", true)
+	if err != nil {
+		t.Fatalf("Failed to open gateway: %v", err)
+	}
+	if url := gateway.URL("A1"); url != "https://sandbox.zarinpal.com/pg/StartPay/A1" {
+		t.Errorf("Expected the sandbox StartPay URL, got %s", url)
+	}
+
+	if _, err := OpenGateway("idpay", "merchant-1", false); !errors.Is(err, ErrUnknownGateway) {
+		t.Errorf("Expected ErrUnknownGateway, got %v", err)
+	}
+}
+
+func TestZarinpalGateway(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`,
+		"verify.json":  `{"data":{"code":100,"message":"Verified","card_pan":"502229******5995","ref_id":201},"errors":[]}`,
+		"reverse.json": `{"data":{"code":100,"message":"Reversed"},"errors":[]}`,
+	})
+	ctx := context.Background()
+	gateway := zp.Gateway()
+
+	payment, err := gateway.Create(ctx, PaymentParams{Amount: 10000, Description: "Test", CallbackURL: "https://example.com/callback"})
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if payment.ID != "A1" || payment.URL != zp.GetPaymentURL("A1") || payment.Fee != 100 {
+		t.Errorf("Expected payment A1, got %+v", payment)
+	}
+
+	verification, err := gateway.Verify(ctx, "A1", 10000)
+	if err != nil || !verification.Successful || verification.RefID != "201" || verification.CardPan != "502229******5995" {
+		t.Errorf("Expected a successful verification, got %+v %v", verification, err)
+	}
+
+	if _, err := gateway.Refund(ctx, "A1", 5000); !errors.Is(err, ErrPartialRefund) {
+		t.Errorf("Expected ErrPartialRefund, got %v", err)
+	}
+	refund, err := gateway.Refund(ctx, "A1", 0)
+	if err != nil || refund.ID != "A1" || refund.Message != "Reversed" {
+		t.Errorf("Expected the payment to be reversed, got %+v %v", refund, err)
+	}
+}
@@ -0,0 +1,211 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrPaymentNotFound should be returned by lookup functions when the authority is unknown
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// AmountLookupFunc returns the amount that was requested for the payment of a callback
+type AmountLookupFunc func(ctx context.Context, callback CallbackData) (amount int, err error)
+
+// HandlerError is returned by the server-side helpers along with the HTTP status code
+// that should be sent to the user
+type HandlerError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *HandlerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// CallbackOption configures the callback helpers
+type CallbackOption func(*callbackOptions)
+
+type callbackOptions struct {
+	replayGuard ReplayGuard
+	store       PaymentStore
+	retryQueue  RetryQueue
+	outbox      Outbox
+	cards       CardHistory
+}
+
+// WithReplayGuard marks callbacks already recorded by the guard as Replayed,
+// CallbackHandler doesn't pass replayed callbacks to its result callback. Only
+// verified and reversed payments are recorded, so a forged NOK callback can't mark the
+// authority before the real one arrives, and only once the other writes of the callback
+// succeeded.
+func WithReplayGuard(guard ReplayGuard) CallbackOption {
+	return func(o *callbackOptions) {
+		o.replayGuard = guard
+	}
+}
+
+// WithPaymentStore records the outcome of callbacks in the store, moving their payment to the
+// verified state, the reversed state when the risk hook declined it, or the failed state once
+// the gateway rejected the verification. Canceled
+// callbacks and transient gateway errors leave the payment pending for the reconciler. Use it
+// along with StoreAmountLookup.
+func WithPaymentStore(store PaymentStore) CallbackOption {
+	return func(o *callbackOptions) {
+		o.store = store
+	}
+}
+
+func newCallbackOptions(opts []CallbackOption) callbackOptions {
+	var o callbackOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ProcessCallback parses the callback values, looks up the expected amount and verifies the payment.
+// Payments canceled by the user and payments rejected by Zarinpal are reported as an unsuccessful status,
+// any other failure is returned as a *HandlerError.
+func (z *Zarinpal) ProcessCallback(ctx context.Context, values url.Values, lookup AmountLookupFunc, opts ...CallbackOption) (status PaymentStatus, err error) {
+	o := newCallbackOptions(opts)
+	status, rejected, err := z.processCallback(ctx, values, lookup, o)
+	if err != nil {
+		return
+	}
+
+	if o.store != nil {
+		if storeErr := recordCallback(ctx, o.store, status, rejected); storeErr != nil {
+			err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: storeErr}
+			return
+		}
+	}
+
+	// card history and outbox writes are idempotent per authority, they run for replayed
+	// callbacks too so a redelivery completes the writes a failed delivery left undone
+	if o.cards != nil {
+		if cardErr := RecordCardPayment(ctx, o.cards, status); cardErr != nil {
+			err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: cardErr}
+			return
+		}
+	}
+
+	if o.outbox != nil {
+		if outboxErr := o.outbox.AddEvent(ctx, outboxEvent(status)); outboxErr != nil {
+			err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: outboxErr}
+			return
+		}
+	}
+
+	// the guard is marked last, a callback whose writes failed isn't replayed when redelivered
+	if o.replayGuard != nil && (status.IsSuccessful || status.Reversed) {
+		first, guardErr := o.replayGuard.MarkProcessed(ctx, status.Authority)
+		if guardErr != nil {
+			err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: guardErr}
+			return
+		}
+		status.Replayed = !first
+	}
+
+	return
+}
+
+// recordCallback moves the payment to pending and then to its outcome, transitions already made
+// by an earlier delivery of the callback are skipped. Unsuccessful payments only fail when the
+// gateway rejected the verification, a canceled callback can be forged and transient errors
+// say nothing about the payment, so they stay pending until the real outcome is known.
+func recordCallback(ctx context.Context, store PaymentStore, status PaymentStatus, rejected bool) error {
+	states := []PaymentState{PaymentStatePending}
+	switch {
+	case status.IsSuccessful:
+		states = append(states, PaymentStateVerified)
+	case status.Reversed:
+		states = append(states, PaymentStateReversed)
+	case rejected:
+		states = append(states, PaymentStateFailed)
+	}
+	return advancePayment(ctx, store, status.Authority, status.RefID, states...)
+}
+
+// processCallback verifies the payment of the callback, rejected reports whether the gateway
+// answered the payment won't verify
+func (z *Zarinpal) processCallback(ctx context.Context, values url.Values, lookup AmountLookupFunc, o callbackOptions) (status PaymentStatus, rejected bool, err error) {
+	callback, err := ParseCallbackValues(values)
+	if err != nil {
+		err = &HandlerError{StatusCode: http.StatusBadRequest, Err: err}
+		return
+	}
+
+	if !callback.IsOK() {
+		return PaymentStatus{
+			Authority: callback.Authority,
+			Message:   "payment was canceled or failed",
+		}, false, nil
+	}
+
+	amount, err := lookup(ctx, callback)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrPaymentNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		err = &HandlerError{StatusCode: statusCode, Err: err}
+		return
+	}
+
+	status, err = z.CheckPaymentStatus(ctx, amount, callback.Authority)
+	if err != nil {
+		rejected = IsPaymentRejected(err)
+		var apiErr *APIError
+		if rejected || (errors.As(err, &apiErr) && o.retryQueue == nil) {
+			// Zarinpal answered, so the payment is unsuccessful for now
+			return status, rejected, nil
+		}
+		if o.retryQueue != nil {
+			if queueErr := enqueueVerification(ctx, o.retryQueue, callback.Authority, amount, err); queueErr != nil {
+				err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: queueErr}
+				return
+			}
+			err = &HandlerError{StatusCode: http.StatusAccepted, Err: fmt.Errorf("%w: %v", ErrVerificationQueued, err)}
+			return
+		}
+		err = &HandlerError{StatusCode: http.StatusBadGateway, Err: err}
+		return
+	}
+
+	return status, false, nil
+}
+
+// CallbackHandler returns an http.Handler serving the callback URL. It verifies the payment
+// and passes the result to onResult before answering the user with the status message.
+func (z *Zarinpal) CallbackHandler(lookup AmountLookupFunc, onResult func(ctx context.Context, status PaymentStatus), opts ...CallbackOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := z.ProcessCallback(withRemoteIP(r.Context(), r.RemoteAddr), r.URL.Query(), lookup, opts...)
+		if err != nil {
+			writeHandlerError(w, err)
+			return
+		}
+
+		if onResult != nil && !status.Replayed {
+			z.runHook(r.Context(), "callback result", func() { onResult(r.Context(), status) })
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(status.Message))
+	})
+}
+
+func writeHandlerError(w http.ResponseWriter, err error) {
+	statusCode := http.StatusInternalServerError
+	var handlerErr *HandlerError
+	if errors.As(err, &handlerErr) {
+		statusCode = handlerErr.StatusCode
+	}
+	http.Error(w, http.StatusText(statusCode), statusCode)
+}
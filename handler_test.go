@@ -0,0 +1,101 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newStubClient returns a client talking to a server that answers each endpoint with a canned body
+func newStubClient(t *testing.T, responses map[string]string) *Zarinpal {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		body, ok := responses[endpoint]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	return zp
+}
+
+func TestCallbackHandler(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	var result PaymentStatus
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		result = status
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !result.IsSuccessful || result.RefID != 201 || result.Authority != "A1" {
+		t.Errorf("Unexpected payment status: %+v", result)
+	}
+}
+
+func TestCallbackHandlerRejectedPayment(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":[],"errors":{"code":-51,"message":"Session is not active, paid try","validations":[]}}`,
+	})
+
+	called := false
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		called = true
+		if status.IsSuccessful {
+			t.Error("Expected unsuccessful payment status")
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+
+	if !called {
+		t.Error("Expected result callback to be invoked")
+	}
+}
+
+func TestCallbackHandlerErrors(t *testing.T) {
+	zp := newStubClient(t, nil)
+
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 0, ErrPaymentNotFound
+	}, func(ctx context.Context, status PaymentStatus) {
+		t.Error("Result callback must not be invoked")
+	})
+
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/callback?Status=OK", http.StatusBadRequest},
+		{"/callback?Authority=A1&Status=OK", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.target, nil))
+		if rec.Code != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, rec.Code)
+		}
+	}
+}
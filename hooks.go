@@ -0,0 +1,49 @@
+package zarinpalgo
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic of a user supplied hook, callback or subscriber, recovered so the
+// payment flow goes on. Clients report it to OnHookPanic, background workers to their OnError.
+type PanicError struct {
+	Hook  string      // like "risk hook" or "reconciler OnResult"
+	Value interface{} // passed to panic
+	Stack []byte      // of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Hook, e.Value)
+}
+
+// Unwrap returns the value passed to panic when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RunHook calls fn and returns its panic, nil when it returned, for packages calling hooks on
+// the payment path
+func RunHook(hook string, fn func()) (panicErr *PanicError) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr = &PanicError{Hook: hook, Value: value, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// runHook runs a hook of the client and reports its panic to OnHookPanic
+func (z *Zarinpal) runHook(ctx context.Context, hook string, fn func()) error {
+	panicErr := RunHook(hook, fn)
+	if panicErr == nil {
+		return nil
+	}
+	if z.OnHookPanic != nil {
+		z.OnHookPanic(ctx, panicErr)
+	}
+	return panicErr
+}
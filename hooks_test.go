@@ -0,0 +1,95 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunHook(t *testing.T) {
+	if panicErr := RunHook("hook", func() {}); panicErr != nil {
+		t.Errorf("Expected no error, got %v", panicErr)
+	}
+
+	cause := errors.New("boom")
+	panicErr := RunHook("hook", func() { panic(cause) })
+	if panicErr == nil || panicErr.Hook != "hook" || !errors.Is(panicErr, cause) || len(panicErr.Stack) == 0 {
+		t.Errorf("Expected the panic of the hook, got %+v", panicErr)
+	}
+	if panicErr.Error() != "hook panicked: boom" {
+		t.Errorf("Unexpected message %q", panicErr.Error())
+	}
+}
+
+func TestHookPanicsOnThePaymentPath(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`,
+		"verify.json":  `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+	var reported []string
+	zp.OnHookPanic = func(ctx context.Context, err *PanicError) {
+		reported = append(reported, err.Hook)
+	}
+	zp.RiskHook = func(ctx context.Context, check RiskCheck) (RiskResult, error) {
+		panic("risk service is down")
+	}
+
+	var panicErr *PanicError
+	if _, err := zp.CreatePayment(context.Background(), PaymentParams{Amount: 10000}); !errors.As(err, &panicErr) {
+		t.Errorf("Expected the creation to fail with the panic, got %v", err)
+	}
+
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		panic("logging is broken")
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "Verified" {
+		t.Errorf("Expected the verified payment to be answered, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// the risk hook runs again after the verification, leaving it unassessed
+	expected := []string{"risk hook", "risk hook", "callback result"}
+	if len(reported) != len(expected) || reported[0] != expected[0] || reported[1] != expected[1] || reported[2] != expected[2] {
+		t.Errorf("Expected panics %v to be reported, got %v", expected, reported)
+	}
+}
+
+func TestHookPanicsInWorkers(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	outbox.AddEvent(context.Background(), NewWebhookEvent(PaymentStatus{Authority: "A1", IsSuccessful: true}))
+
+	var reported error
+	relay := NewOutboxRelay(outbox, PublisherFunc(func(ctx context.Context, event WebhookEvent) error {
+		panic("broker client is broken")
+	}))
+	relay.OnError = func(event WebhookEvent, err error) {
+		reported = err
+		panic("and so is the error reporter")
+	}
+
+	if n, err := relay.PublishPending(context.Background()); n != 0 || err != nil {
+		t.Errorf("Expected nothing to be published, got %d %v", n, err)
+	}
+	var panicErr *PanicError
+	if !errors.As(reported, &panicErr) || panicErr.Hook != "outbox publisher" {
+		t.Errorf("Expected the panic to be reported, got %v", reported)
+	}
+	if events, _ := outbox.PendingEvents(context.Background(), 10); len(events) != 1 {
+		t.Errorf("Expected the event to stay pending, got %+v", events)
+	}
+
+	var failures []error
+	reconciler := &Reconciler{Interval: time.Minute}
+	reconciler.OnResult = func(ctx context.Context, status PaymentStatus) { panic("boom") }
+	reconciler.OnError = func(authority string, err error) { failures = append(failures, err) }
+	reconciler.result(context.Background(), PaymentStatus{Authority: "A2"})
+	if len(failures) != 1 || !errors.As(failures[0], &panicErr) {
+		t.Errorf("Expected the panic of OnResult to be reported, got %v", failures)
+	}
+}
@@ -0,0 +1,122 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOrderInProgress is returned when another payment of the order is being created
+var ErrOrderInProgress = errors.New("payment of the order is being created")
+
+// idempotencyLockTTL bounds how long a crashed instance can hold the lock of an order
+const idempotencyLockTTL = time.Minute
+
+// IdempotencyLocker claims keys across instances, redisstore.Store implements it
+type IdempotencyLocker interface {
+	// Reserve claims the key for ttl and reports whether it was free
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotentSessions creates payment sessions at most once per order: while the latest session of
+// an order is unpaid, unexpired and for the same amount, it is returned instead of a new one.
+// Sessions without an order ID in their metadata are always created. Generate order IDs with
+// NewOrderID rather than from timestamps.
+type IdempotentSessions struct {
+	client *Zarinpal
+	store  PaymentStore
+
+	// Locker keeps concurrent calls for the same order from creating two sessions,
+	// it defaults to a lock local to the process
+	Locker IdempotencyLocker
+	// ValidateOrderIDs fails the sessions of order IDs ValidateOrderID rejects with
+	// ErrInvalidOrderID, set it when order IDs come from users or other systems
+	ValidateOrderIDs bool
+}
+
+// NewIdempotentSessions creates an IdempotentSessions saving sessions in the store
+func NewIdempotentSessions(client *Zarinpal, store PaymentStore) *IdempotentSessions {
+	return &IdempotentSessions{
+		client: client,
+		store:  store,
+		Locker: &memoryLocker{keys: make(map[string]time.Time)},
+	}
+}
+
+// NewSession returns the reusable session of the order or creates and saves a new one
+func (s *IdempotentSessions) NewSession(ctx context.Context, params PaymentParams) (session PaymentSession, err error) {
+	if params.Metadata == nil || params.Metadata.OrderID == "" {
+		return s.create(ctx, params)
+	}
+	orderID := params.Metadata.OrderID
+	if s.ValidateOrderIDs {
+		if err = ValidateOrderID(orderID); err != nil {
+			return
+		}
+	}
+
+	reserved, err := s.Locker.Reserve(ctx, "order:"+orderID, idempotencyLockTTL)
+	if err != nil {
+		return
+	}
+	if !reserved {
+		err = ErrOrderInProgress
+		return
+	}
+	defer s.Locker.Release(context.WithoutCancel(ctx), "order:"+orderID)
+
+	existing, err := s.store.GetByOrderID(ctx, orderID)
+	if err == nil && reusable(existing, params) {
+		return existing.PaymentSession, nil
+	}
+	if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+		return
+	}
+
+	return s.create(ctx, params)
+}
+
+func (s *IdempotentSessions) create(ctx context.Context, params PaymentParams) (session PaymentSession, err error) {
+	session, err = s.client.NewSession(ctx, params)
+	if err != nil {
+		return
+	}
+	err = s.store.SaveSession(ctx, session)
+	return
+}
+
+// reusable reports whether the user can still pay the stored payment for the requested one
+func reusable(payment StoredPayment, params PaymentParams) bool {
+	return (payment.State == PaymentStateCreated || payment.State == PaymentStateRedirected) &&
+		!payment.IsExpired() &&
+		payment.Amount == params.Amount &&
+		payment.Currency == params.Currency
+}
+
+// memoryLocker is an IdempotencyLocker local to the process
+type memoryLocker struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func (l *memoryLocker) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if expiresAt, ok := l.keys[key]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	l.keys[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (l *memoryLocker) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIdempotentSessions(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A` + string(rune('0'+n)) + `"},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	store := NewMemoryPaymentStore()
+	sessions := NewIdempotentSessions(zp, store)
+	ctx := context.Background()
+	params := PaymentParams{Amount: 10000, Description: "Order 42", CallbackURL: "https://example.com/callback", Metadata: &Metadata{OrderID: "42"}}
+
+	first, err := sessions.NewSession(ctx, params)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	second, err := sessions.NewSession(ctx, params)
+	if err != nil || second.Authority != first.Authority {
+		t.Errorf("Expected session %s to be reused, got %+v %v", first.Authority, second, err)
+	}
+
+	params.Amount = 20000
+	third, err := sessions.NewSession(ctx, params)
+	if err != nil || third.Authority == first.Authority {
+		t.Errorf("Expected a new session for a new amount, got %+v %v", third, err)
+	}
+
+	store.UpdateStatus(ctx, third.Authority, PaymentStateFailed, 0)
+	fourth, err := sessions.NewSession(ctx, params)
+	if err != nil || fourth.Authority == third.Authority {
+		t.Errorf("Expected a new session after a failed payment, got %+v %v", fourth, err)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 gateway requests, got %d", requests)
+	}
+
+	sessions.Locker.Reserve(ctx, "order:42", idempotencyLockTTL)
+	if _, err := sessions.NewSession(ctx, params); !errors.Is(err, ErrOrderInProgress) {
+		t.Errorf("Expected ErrOrderInProgress, got %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Installment errors
+var (
+	ErrInstallmentPlanNotFound = errors.New("installment plan not found")
+	ErrInvalidInstallments     = errors.New("invalid installments")
+)
+
+// Installment scheduler defaults
+const (
+	DefaultInstallmentInterval = time.Hour
+	DefaultInstallmentGrace    = 3 * 24 * time.Hour
+)
+
+// InstallmentState is the state of a single installment
+type InstallmentState string
+
+// InstallmentState constants
+const (
+	InstallmentScheduled InstallmentState = "SCHEDULED" // not due yet
+	InstallmentOpen      InstallmentState = "OPEN"      // due, its payment was created
+	InstallmentPaid      InstallmentState = "PAID"      // its payment was verified
+	InstallmentMissed    InstallmentState = "MISSED"    // not paid within the grace period, reissue it to collect it
+)
+
+// Installment is one of the scheduled payments of a plan
+type Installment struct {
+	Number     int              `json:"number"` // from 1
+	Amount     int              `json:"amount"`
+	DueAt      time.Time        `json:"due_at"`
+	State      InstallmentState `json:"state"`
+	Authority  string           `json:"authority,omitempty"` // of the latest payment created for it
+	PaymentURL string           `json:"payment_url,omitempty"`
+	RefID      int              `json:"ref_id,omitempty"`
+	// Previous are the authorities Reissue replaced, a late verification of one of them still
+	// pays the installment
+	Previous []string `json:"previous_authorities,omitempty"`
+}
+
+// InstallmentPlanStatus is the overall state of a plan
+type InstallmentPlanStatus string
+
+// InstallmentPlanStatus constants
+const (
+	InstallmentPlanActive    InstallmentPlanStatus = "ACTIVE"
+	InstallmentPlanOverdue   InstallmentPlanStatus = "OVERDUE" // an installment was missed
+	InstallmentPlanCompleted InstallmentPlanStatus = "COMPLETED"
+)
+
+// InstallmentPlan splits the payment of an order into scheduled installments
+type InstallmentPlan struct {
+	ID           string        `json:"id"`
+	OrderID      string        `json:"order_id,omitempty"` // the installments are paid as "<OrderID>-<Number>"
+	Description  string        `json:"description"`
+	CallbackURL  string        `json:"callback_url"`
+	Total        int           `json:"total"` // in Rials
+	Installments []Installment `json:"installments"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// SplitAmount splits total into count amounts, the remainder goes to the first ones so no
+// two amounts differ by more than one Rial
+func SplitAmount(total, count int) ([]int, error) {
+	if count <= 0 || total < count {
+		return nil, fmt.Errorf("%w: %d can't be split into %d positive amounts", ErrInvalidInstallments, total, count)
+	}
+	amounts := make([]int, count)
+	for i := range amounts {
+		amounts[i] = total / count
+		if i < total%count {
+			amounts[i]++
+		}
+	}
+	return amounts, nil
+}
+
+// NewInstallmentPlan splits total into count installments, the first one due at first and the
+// next ones every intervalMonths months
+func NewInstallmentPlan(id string, total, count int, first time.Time, intervalMonths int) (plan InstallmentPlan, err error) {
+	if intervalMonths <= 0 {
+		err = fmt.Errorf("%w: interval of %d months", ErrInvalidInstallments, intervalMonths)
+		return
+	}
+	amounts, err := SplitAmount(total, count)
+	if err != nil {
+		return
+	}
+
+	plan = InstallmentPlan{ID: id, Total: total, CreatedAt: time.Now()}
+	for i, amount := range amounts {
+		plan.Installments = append(plan.Installments, Installment{
+			Number: i + 1,
+			Amount: amount,
+			DueAt:  first.AddDate(0, i*intervalMonths, 0),
+			State:  InstallmentScheduled,
+		})
+	}
+	return
+}
+
+// Status returns the overall state of the plan
+func (p InstallmentPlan) Status() InstallmentPlanStatus {
+	status := InstallmentPlanCompleted
+	for _, installment := range p.Installments {
+		switch installment.State {
+		case InstallmentMissed:
+			return InstallmentPlanOverdue
+		case InstallmentPaid:
+		default:
+			status = InstallmentPlanActive
+		}
+	}
+	return status
+}
+
+// InstallmentOrderID returns the order ID of the payments of an installment, the payments
+// reissued for an installment share it
+func (p InstallmentPlan) InstallmentOrderID(number int) string {
+	return fmt.Sprintf("%s-%d", p.OrderID, number)
+}
+
+// Paid returns the amount paid so far
+func (p InstallmentPlan) Paid() (paid int) {
+	for _, installment := range p.Installments {
+		if installment.State == InstallmentPaid {
+			paid += installment.Amount
+		}
+	}
+	return
+}
+
+// InstallmentStore keeps installment plans, lookups of unknown ones return ErrInstallmentPlanNotFound
+type InstallmentStore interface {
+	// SaveInstallmentPlan creates or replaces the plan
+	SaveInstallmentPlan(ctx context.Context, plan InstallmentPlan) error
+	GetInstallmentPlan(ctx context.Context, id string) (InstallmentPlan, error)
+	// ListUnfinished returns the plans that aren't completed
+	ListUnfinished(ctx context.Context) ([]InstallmentPlan, error)
+}
+
+// InstallmentEventType is what happened to an installment
+type InstallmentEventType string
+
+// InstallmentEventType constants
+const (
+	InstallmentEventDue       InstallmentEventType = "DUE"       // its payment was created, send the payment URL to the customer
+	InstallmentEventPaid      InstallmentEventType = "PAID"      // its payment was verified
+	InstallmentEventMissed    InstallmentEventType = "MISSED"    // the grace period passed without a payment
+	InstallmentEventCompleted InstallmentEventType = "COMPLETED" // the last installment of the plan was paid
+)
+
+// InstallmentEvent reports a change of an installment plan
+type InstallmentEvent struct {
+	Type        InstallmentEventType
+	Plan        InstallmentPlan // after the change
+	Installment Installment     // zero for InstallmentEventCompleted
+}
+
+// InstallmentScheduler creates the payments of the installments when they are due and follows
+// them through the payment store, which the callback handler updates with WithPaymentStore.
+// Installments not paid within the grace period after their due time are missed.
+type InstallmentScheduler struct {
+	Zarinpal *Zarinpal
+	Plans    InstallmentStore
+	Payments PaymentStore
+
+	Interval time.Duration // time between runs, defaults to DefaultInstallmentInterval
+	Grace    time.Duration // defaults to DefaultInstallmentGrace
+
+	// OnEvent is called with every change of the plans
+	OnEvent func(ctx context.Context, event InstallmentEvent)
+	// OnError is called with the failures of single plans, they don't stop a run
+	OnError func(planID string, err error)
+}
+
+// NewInstallmentScheduler creates an InstallmentScheduler with the default interval and grace period
+func NewInstallmentScheduler(z *Zarinpal, plans InstallmentStore, payments PaymentStore) *InstallmentScheduler {
+	return &InstallmentScheduler{
+		Zarinpal: z,
+		Plans:    plans,
+		Payments: payments,
+		Interval: DefaultInstallmentInterval,
+		Grace:    DefaultInstallmentGrace,
+	}
+}
+
+// Run schedules installments every interval until the context is done
+func (s *InstallmentScheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInstallmentInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ScheduleOnce(ctx); err != nil {
+			s.fail("", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScheduleOnce opens the due installments, follows the open ones and returns the changes.
+// Only failing to list plans is returned as an error.
+func (s *InstallmentScheduler) ScheduleOnce(ctx context.Context) (events []InstallmentEvent, err error) {
+	plans, err := s.Plans.ListUnfinished(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, plan := range plans {
+		planEvents, advanceErr := s.advance(ctx, &plan)
+		if len(planEvents) > 0 {
+			if saveErr := s.Plans.SaveInstallmentPlan(ctx, plan); saveErr != nil {
+				s.fail(plan.ID, saveErr)
+				continue
+			}
+		}
+		if advanceErr != nil {
+			s.fail(plan.ID, advanceErr)
+		}
+		for _, event := range planEvents {
+			event.Plan = plan
+			events = append(events, event)
+			if s.OnEvent != nil {
+				if panicErr := RunHook("installment scheduler OnEvent", func() { s.OnEvent(ctx, event) }); panicErr != nil {
+					s.fail(plan.ID, panicErr)
+				}
+			}
+		}
+	}
+	return
+}
+
+// advance moves the installments of the plan, stopping at the first failure
+func (s *InstallmentScheduler) advance(ctx context.Context, plan *InstallmentPlan) (events []InstallmentEvent, err error) {
+	grace := s.Grace
+	if grace <= 0 {
+		grace = DefaultInstallmentGrace
+	}
+	now := time.Now()
+
+	for i := range plan.Installments {
+		installment := &plan.Installments[i]
+		switch {
+		case installment.State == InstallmentScheduled && !now.Before(installment.DueAt):
+			if err = s.open(ctx, plan, installment); err != nil {
+				return
+			}
+			events = append(events, InstallmentEvent{Type: InstallmentEventDue, Installment: *installment})
+
+		case installment.State == InstallmentOpen || installment.State == InstallmentMissed:
+			payment, getErr := s.verifiedPayment(ctx, installment)
+			if getErr != nil {
+				err = getErr
+				return
+			}
+			if payment.State == PaymentStateVerified {
+				installment.State = InstallmentPaid
+				installment.RefID = payment.RefID
+				events = append(events, InstallmentEvent{Type: InstallmentEventPaid, Installment: *installment})
+			} else if installment.State == InstallmentOpen && !now.Before(installment.DueAt.Add(grace)) {
+				installment.State = InstallmentMissed
+				events = append(events, InstallmentEvent{Type: InstallmentEventMissed, Installment: *installment})
+			}
+		}
+	}
+
+	if len(events) > 0 && plan.Status() == InstallmentPlanCompleted {
+		events = append(events, InstallmentEvent{Type: InstallmentEventCompleted})
+	}
+	return
+}
+
+// verifiedPayment returns the verified payment among the ones created for the installment,
+// or its latest payment when none is verified
+func (s *InstallmentScheduler) verifiedPayment(ctx context.Context, installment *Installment) (latest StoredPayment, err error) {
+	latest, err = s.Payments.GetByAuthority(ctx, installment.Authority)
+	if err != nil || latest.State == PaymentStateVerified {
+		return
+	}
+	for _, authority := range installment.Previous {
+		payment, getErr := s.Payments.GetByAuthority(ctx, authority)
+		if getErr != nil {
+			return latest, getErr
+		}
+		if payment.State == PaymentStateVerified {
+			return payment, nil
+		}
+	}
+	return
+}
+
+// open creates the payment of the installment and stores its session
+func (s *InstallmentScheduler) open(ctx context.Context, plan *InstallmentPlan, installment *Installment) error {
+	params := PaymentParams{
+		Amount:      installment.Amount,
+		Description: fmt.Sprintf("%s, installment %d of %d", plan.Description, installment.Number, len(plan.Installments)),
+		CallbackURL: plan.CallbackURL,
+	}
+	if plan.OrderID != "" {
+		// every installment is an order of its own, DuplicateDetector would report the others
+		// as paying the order again
+		params.Metadata = &Metadata{OrderID: plan.InstallmentOrderID(installment.Number)}
+	}
+
+	session, err := s.Zarinpal.NewSession(ctx, params)
+	if err != nil {
+		return err
+	}
+	if err = s.Payments.SaveSession(ctx, session); err != nil {
+		return err
+	}
+
+	if installment.Authority != "" {
+		installment.Previous = append(installment.Previous, installment.Authority)
+	}
+	installment.State = InstallmentOpen
+	installment.Authority = session.Authority
+	installment.PaymentURL = session.PaymentURL
+	return nil
+}
+
+// Reissue creates a new payment for an open or missed installment, like when the customer asks
+// to pay after the authority of its payment expired. A missed installment is open again.
+// Installments whose latest payment can still be paid, or was paid and awaits verification,
+// aren't reissued so the customer can't pay twice.
+func (s *InstallmentScheduler) Reissue(ctx context.Context, planID string, number int) (installment Installment, err error) {
+	plan, err := s.Plans.GetInstallmentPlan(ctx, planID)
+	if err != nil {
+		return
+	}
+	if number < 1 || number > len(plan.Installments) {
+		err = fmt.Errorf("%w: plan %s has no installment %d", ErrInvalidInstallments, planID, number)
+		return
+	}
+
+	target := &plan.Installments[number-1]
+	if target.State != InstallmentOpen && target.State != InstallmentMissed {
+		err = fmt.Errorf("%w: installment %d of plan %s is %s", ErrInvalidInstallments, number, planID, target.State)
+		return
+	}
+	for _, authority := range append([]string{target.Authority}, target.Previous...) {
+		payment, getErr := s.Payments.GetByAuthority(ctx, authority)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+		if payment.State == PaymentStatePending || payment.State == PaymentStateVerified || (payment.State.Pending() && !payment.IsExpired()) {
+			err = fmt.Errorf("%w: installment %d of plan %s is %s through %s", ErrInvalidInstallments, number, planID, payment.State, authority)
+			return
+		}
+	}
+	if err = s.open(ctx, &plan, target); err != nil {
+		return
+	}
+	if err = s.Plans.SaveInstallmentPlan(ctx, plan); err != nil {
+		return
+	}
+	return *target, nil
+}
+
+func (s *InstallmentScheduler) fail(planID string, err error) {
+	if s.OnError != nil {
+		RunHook("installment scheduler OnError", func() { s.OnError(planID, err) })
+	}
+}
+
+// MemoryInstallmentStore is an InstallmentStore keeping plans in memory, it is safe for concurrent use
+type MemoryInstallmentStore struct {
+	mu    sync.Mutex
+	plans map[string]InstallmentPlan
+}
+
+// NewMemoryInstallmentStore creates an empty MemoryInstallmentStore
+func NewMemoryInstallmentStore() *MemoryInstallmentStore {
+	return &MemoryInstallmentStore{plans: make(map[string]InstallmentPlan)}
+}
+
+// SaveInstallmentPlan implements InstallmentStore
+func (s *MemoryInstallmentStore) SaveInstallmentPlan(ctx context.Context, plan InstallmentPlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan.Installments = append([]Installment(nil), plan.Installments...)
+	s.plans[plan.ID] = plan
+	return nil
+}
+
+// GetInstallmentPlan implements InstallmentStore
+func (s *MemoryInstallmentStore) GetInstallmentPlan(ctx context.Context, id string) (InstallmentPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, ok := s.plans[id]
+	if !ok {
+		return InstallmentPlan{}, ErrInstallmentPlanNotFound
+	}
+	plan.Installments = append([]Installment(nil), plan.Installments...)
+	return plan, nil
+}
+
+// ListUnfinished implements InstallmentStore
+func (s *MemoryInstallmentStore) ListUnfinished(ctx context.Context) (plans []InstallmentPlan, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, plan := range s.plans {
+		if plan.Status() != InstallmentPlanCompleted {
+			plan.Installments = append([]Installment(nil), plan.Installments...)
+			plans = append(plans, plan)
+		}
+	}
+	return
+}
@@ -0,0 +1,189 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitAmount(t *testing.T) {
+	amounts, err := SplitAmount(100001, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(amounts) != "[33334 33334 33333]" {
+		t.Errorf("Expected [33334 33334 33333], got %v", amounts)
+	}
+	if _, err := SplitAmount(2, 3); !errors.Is(err, ErrInvalidInstallments) {
+		t.Errorf("Expected ErrInvalidInstallments, got %v", err)
+	}
+}
+
+func TestInstallmentScheduler(t *testing.T) {
+	var created int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&created, 1)
+		fmt.Fprintf(w, `{"data":{"code":100,"message":"Success","authority":"A%d"},"errors":[]}`, n)
+	}))
+	defer server.Close()
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	ctx := context.Background()
+	plans := NewMemoryInstallmentStore()
+	payments := NewMemoryPaymentStore()
+	scheduler := NewInstallmentScheduler(zp, plans, payments)
+	scheduler.Grace = time.Hour
+	scheduler.OnError = func(planID string, err error) {
+		t.Errorf("Unexpected error for %s: %v", planID, err)
+	}
+
+	plan, err := NewInstallmentPlan("P1", 90000, 3, time.Now().Add(-2*time.Hour), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Description = "Order 7"
+	plan.CallbackURL = "https://example.com/callback"
+	plans.SaveInstallmentPlan(ctx, plan)
+
+	events, err := scheduler.ScheduleOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != InstallmentEventDue || events[0].Installment.Authority != "A1" {
+		t.Fatalf("Expected the first installment to be due, got %+v", events)
+	}
+	if stored, _ := payments.GetByAuthority(ctx, "A1"); stored.Amount != 30000 {
+		t.Errorf("Expected a stored payment of 30000, got %+v", stored)
+	}
+
+	// the grace period passed without a payment
+	events, _ = scheduler.ScheduleOnce(ctx)
+	if len(events) != 1 || events[0].Type != InstallmentEventMissed || events[0].Plan.Status() != InstallmentPlanOverdue {
+		t.Fatalf("Expected the first installment to be missed, got %+v", events)
+	}
+
+	// A1 can still be paid, reissuing it would let the customer pay twice
+	if _, err := scheduler.Reissue(ctx, "P1", 1); !errors.Is(err, ErrInvalidInstallments) {
+		t.Fatalf("Expected ErrInvalidInstallments while A1 can be paid, got %v", err)
+	}
+	advancePayment(ctx, payments, "A1", 0, PaymentStateExpired)
+	reissued, err := scheduler.Reissue(ctx, "P1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued.State != InstallmentOpen || reissued.Authority != "A2" || len(reissued.Previous) != 1 || reissued.Previous[0] != "A1" {
+		t.Errorf("Expected an open installment paid through A2 replacing A1, got %+v", reissued)
+	}
+	if _, err := scheduler.Reissue(ctx, "P1", 2); !errors.Is(err, ErrInvalidInstallments) {
+		t.Errorf("Expected ErrInvalidInstallments for a scheduled installment, got %v", err)
+	}
+
+	advancePayment(ctx, payments, "A2", 501, PaymentStatePending, PaymentStateVerified)
+	events, _ = scheduler.ScheduleOnce(ctx)
+	if len(events) != 1 || events[0].Type != InstallmentEventPaid || events[0].Installment.RefID != 501 {
+		t.Fatalf("Expected the first installment to be paid, got %+v", events)
+	}
+	if paid := events[0].Plan.Paid(); paid != 30000 {
+		t.Errorf("Expected 30000 paid, got %d", paid)
+	}
+}
+
+func TestInstallmentSchedulerCompletesPlan(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+	ctx := context.Background()
+	plans := NewMemoryInstallmentStore()
+	payments := NewMemoryPaymentStore()
+	scheduler := NewInstallmentScheduler(zp, plans, payments)
+
+	plan, _ := NewInstallmentPlan("P1", 50000, 1, time.Now(), 1)
+	plans.SaveInstallmentPlan(ctx, plan)
+	scheduler.ScheduleOnce(ctx)
+	advancePayment(ctx, payments, "A1", 9, PaymentStatePending, PaymentStateVerified)
+
+	events, _ := scheduler.ScheduleOnce(ctx)
+	if len(events) != 2 || events[1].Type != InstallmentEventCompleted || events[1].Plan.Status() != InstallmentPlanCompleted {
+		t.Fatalf("Expected the plan to be completed, got %+v", events)
+	}
+	if unfinished, _ := plans.ListUnfinished(ctx); len(unfinished) != 0 {
+		t.Errorf("Expected no unfinished plans, got %+v", unfinished)
+	}
+}
+
+func TestInstallmentSchedulerPreviousAuthorities(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A3"},"errors":[]}`,
+	})
+	ctx := context.Background()
+	plans := NewMemoryInstallmentStore()
+	payments := NewMemoryPaymentStore()
+	scheduler := NewInstallmentScheduler(zp, plans, payments)
+
+	plan, _ := NewInstallmentPlan("P1", 50000, 1, time.Now().Add(-time.Hour), 1)
+	plan.Installments[0].State = InstallmentMissed
+	plan.Installments[0].Authority = "A1"
+	plans.SaveInstallmentPlan(ctx, plan)
+	// the session of A1 expired without the expiry tracker marking it
+	payments.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 50000, ExpiresAt: time.Now().Add(-time.Minute)})
+
+	reissued, err := scheduler.Reissue(ctx, "P1", 1)
+	if err != nil || reissued.Authority != "A3" {
+		t.Fatalf("Expected the installment reissued through A3, got %+v %v", reissued, err)
+	}
+
+	// the customer paid A1 at the last moment
+	advancePayment(ctx, payments, "A3", 0, PaymentStateExpired)
+	advancePayment(ctx, payments, "A1", 77, PaymentStatePending)
+	if _, err := scheduler.Reissue(ctx, "P1", 1); !errors.Is(err, ErrInvalidInstallments) {
+		t.Errorf("Expected ErrInvalidInstallments while A1 awaits verification, got %v", err)
+	}
+	advancePayment(ctx, payments, "A1", 77, PaymentStateVerified)
+	events, _ := scheduler.ScheduleOnce(ctx)
+	if len(events) != 2 || events[0].Type != InstallmentEventPaid || events[0].Installment.RefID != 77 {
+		t.Fatalf("Expected the installment paid through A1, got %+v", events)
+	}
+}
+
+func TestInstallmentPlanNotDuplicate(t *testing.T) {
+	var created int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&created, 1)
+		fmt.Fprintf(w, `{"data":{"code":100,"message":"Success","authority":"A%d"},"errors":[]}`, n)
+	}))
+	defer server.Close()
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	ctx := context.Background()
+	plans := NewMemoryInstallmentStore()
+	payments := NewMemoryPaymentStore()
+	scheduler := NewInstallmentScheduler(zp, plans, payments)
+
+	plan, _ := NewInstallmentPlan("P1", 90000, 3, time.Now().AddDate(0, -3, 0), 1)
+	plan.OrderID = "42"
+	plans.SaveInstallmentPlan(ctx, plan)
+	scheduler.ScheduleOnce(ctx)
+	for _, authority := range []string{"A1", "A2", "A3"} {
+		advancePayment(ctx, payments, authority, 0, PaymentStatePending, PaymentStateVerified)
+	}
+	if stored, _ := payments.GetByAuthority(ctx, "A2"); stored.OrderID != "42-2" {
+		t.Errorf("Expected the second installment paid as order 42-2, got %q", stored.OrderID)
+	}
+
+	detector := &DuplicateDetector{Store: payments}
+	for _, authority := range []string{"A1", "A2", "A3"} {
+		if duplicate, found, err := detector.Check(ctx, authority); found || err != nil {
+			t.Errorf("Expected %s not to be a duplicate, got %+v %v", authority, duplicate, err)
+		}
+	}
+	if report, err := detector.Report(ctx, time.Now().Add(-time.Hour)); len(report) != 0 || err != nil {
+		t.Errorf("Expected no duplicates in the report, got %+v %v", report, err)
+	}
+}
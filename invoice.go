@@ -0,0 +1,131 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Invoice validation errors
+var (
+	ErrEmptyInvoice    = errors.New("invoice has no items")
+	ErrInvalidLineItem = errors.New("invalid invoice line item")
+	ErrInvalidDiscount = errors.New("discount exceeds the invoice subtotal")
+)
+
+// invoiceDescriptionLimit is the length invoice descriptions are cut to, in runes
+const invoiceDescriptionLimit = 255
+
+// LineItem is a line of an invoice, amounts are in the currency of the invoice
+type LineItem struct {
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Discount  int    `json:"discount,omitempty"` // off the whole line
+}
+
+// Total returns the price of the line after its discount
+func (l LineItem) Total() int {
+	return l.Quantity*l.UnitPrice - l.Discount
+}
+
+// Invoice is an order priced from its line items, pay it with PayInvoice
+type Invoice struct {
+	Number   string     `json:"number"` // used as order ID
+	Currency Currency   `json:"currency,omitempty"`
+	Items    []LineItem `json:"items"`
+	Discount int        `json:"discount,omitempty"` // off the invoice, before VAT
+	VATRate  int        `json:"vat_rate,omitempty"` // percent, applied after discounts
+}
+
+// Subtotal returns the sum of the line totals
+func (inv Invoice) Subtotal() (subtotal int) {
+	for _, item := range inv.Items {
+		subtotal += item.Total()
+	}
+	return
+}
+
+// VAT returns the value added tax of the discounted subtotal, rounded half up
+func (inv Invoice) VAT() int {
+	taxable := inv.Subtotal() - inv.Discount
+	return (taxable*inv.VATRate + 50) / 100
+}
+
+// Total returns the payable amount
+func (inv Invoice) Total() int {
+	return inv.Subtotal() - inv.Discount + inv.VAT()
+}
+
+// Validate checks the items and discounts of the invoice
+func (inv Invoice) Validate() error {
+	if len(inv.Items) == 0 {
+		return ErrEmptyInvoice
+	}
+	for i, item := range inv.Items {
+		if item.Quantity <= 0 || item.UnitPrice < 0 || item.Discount < 0 || item.Total() < 0 {
+			return fmt.Errorf("%w: line %d (%s)", ErrInvalidLineItem, i+1, item.Name)
+		}
+	}
+	if inv.Discount < 0 || inv.Discount > inv.Subtotal() || inv.VATRate < 0 {
+		return ErrInvalidDiscount
+	}
+	return nil
+}
+
+// Description summarizes the invoice for the payment description, like
+// "Invoice 1024: 2 x Book, 1 x Pen", cut to 255 characters
+func (inv Invoice) Description() string {
+	lines := make([]string, len(inv.Items))
+	for i, item := range inv.Items {
+		lines[i] = fmt.Sprintf("%d x %s", item.Quantity, item.Name)
+	}
+
+	description := strings.Join(lines, ", ")
+	if inv.Number != "" {
+		description = "Invoice " + inv.Number + ": " + description
+	}
+
+	if runes := []rune(description); len(runes) > invoiceDescriptionLimit {
+		description = string(runes[:invoiceDescriptionLimit-3]) + "..."
+	}
+	return description
+}
+
+// PaymentParams returns the parameters of the payment of the invoice, the invoice number is set
+// as order ID of the metadata when it has none
+func (inv Invoice) PaymentParams(callbackURL string, metadata *Metadata) (params PaymentParams, err error) {
+	if err = inv.Validate(); err != nil {
+		return
+	}
+
+	if inv.Number != "" {
+		m := Metadata{}
+		if metadata != nil {
+			m = *metadata
+		}
+		if m.OrderID == "" {
+			m.OrderID = inv.Number
+		}
+		metadata = &m
+	}
+
+	params = PaymentParams{
+		Amount:      inv.Total(),
+		Currency:    inv.Currency,
+		Description: inv.Description(),
+		CallbackURL: callbackURL,
+		Metadata:    metadata,
+	}
+	return
+}
+
+// PayInvoice creates the payment of an invoice and returns its session
+func (z *Zarinpal) PayInvoice(ctx context.Context, invoice Invoice, callbackURL string, metadata *Metadata) (session PaymentSession, err error) {
+	params, err := invoice.PaymentParams(callbackURL, metadata)
+	if err != nil {
+		return
+	}
+	return z.NewSession(ctx, params)
+}
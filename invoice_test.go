@@ -0,0 +1,78 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInvoiceTotals(t *testing.T) {
+	invoice := Invoice{
+		Number: "1024",
+		Items: []LineItem{
+			{Name: "Book", Quantity: 2, UnitPrice: 150000, Discount: 20000},
+			{Name: "Pen", Quantity: 3, UnitPrice: 15000},
+		},
+		Discount: 5000,
+		VATRate:  10,
+	}
+
+	if invoice.Subtotal() != 325000 {
+		t.Errorf("Expected subtotal 325000, got %d", invoice.Subtotal())
+	}
+	if invoice.VAT() != 32000 {
+		t.Errorf("Expected VAT 32000, got %d", invoice.VAT())
+	}
+	if invoice.Total() != 352000 {
+		t.Errorf("Expected total 352000, got %d", invoice.Total())
+	}
+	if invoice.Description() != "Invoice 1024: 2 x Book, 3 x Pen" {
+		t.Errorf("Unexpected description %q", invoice.Description())
+	}
+}
+
+func TestInvoiceValidate(t *testing.T) {
+	tests := []struct {
+		invoice Invoice
+		err     error
+	}{
+		{Invoice{}, ErrEmptyInvoice},
+		{Invoice{Items: []LineItem{{Name: "Book", Quantity: 0, UnitPrice: 1000}}}, ErrInvalidLineItem},
+		{Invoice{Items: []LineItem{{Name: "Book", Quantity: 1, UnitPrice: 1000, Discount: 2000}}}, ErrInvalidLineItem},
+		{Invoice{Items: []LineItem{{Name: "Book", Quantity: 1, UnitPrice: 1000}}, Discount: 2000}, ErrInvalidDiscount},
+		{Invoice{Items: []LineItem{{Name: "Book", Quantity: 1, UnitPrice: 1000}}}, nil},
+	}
+
+	for i, test := range tests {
+		if err := test.invoice.Validate(); !errors.Is(err, test.err) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestInvoiceDescriptionLimit(t *testing.T) {
+	invoice := Invoice{Items: []LineItem{{Name: strings.Repeat("کتاب", 100), Quantity: 1, UnitPrice: 1000}}}
+	if n := len([]rune(invoice.Description())); n != invoiceDescriptionLimit {
+		t.Errorf("Expected the description to be cut to %d characters, got %d", invoiceDescriptionLimit, n)
+	}
+}
+
+func TestPayInvoice(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+
+	invoice := Invoice{Number: "1024", Items: []LineItem{{Name: "Book", Quantity: 2, UnitPrice: 50000}}, VATRate: 10}
+	session, err := zp.PayInvoice(context.Background(), invoice, "https://example.com/callback", &Metadata{Mobile: "09123456789"})
+	if err != nil {
+		t.Fatalf("Failed to pay invoice: %v", err)
+	}
+	if session.Amount != 110000 || session.OrderID != "1024" {
+		t.Errorf("Expected amount 110000 for order 1024, got %d for %q", session.Amount, session.OrderID)
+	}
+
+	if _, err := zp.PayInvoice(context.Background(), Invoice{}, "https://example.com/callback", nil); !errors.Is(err, ErrEmptyInvoice) {
+		t.Errorf("Expected ErrEmptyInvoice, got %v", err)
+	}
+}
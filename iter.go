@@ -0,0 +1,54 @@
+//go:build go1.23
+
+package zarinpalgo
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// TransactionsSeq iterates over the transactions created in the range, newest first. Pages are
+// fetched as the loop needs them and breaking out of the loop stops fetching. A failed request
+// is yielded as the last error:
+//
+//	for transaction, err := range reporting.TransactionsSeq(ctx, from, to) {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+func (r *Reporting) TransactionsSeq(ctx context.Context, from, to time.Time) iter.Seq2[Transaction, error] {
+	return r.FilterTransactionsSeq(ctx, TransactionFilter{From: from, To: to})
+}
+
+// FilterTransactionsSeq iterates over the transactions passing the filter like TransactionsSeq
+func (r *Reporting) FilterTransactionsSeq(ctx context.Context, filter TransactionFilter) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		stopped := false
+		err := r.eachTransaction(ctx, filter, func(transaction Transaction) bool {
+			stopped = !yield(transaction, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(Transaction{}, err)
+		}
+	}
+}
+
+// UnverifiedPaymentsSeq iterates over the paid payments that were not verified, a failed request
+// is yielded as the only error
+func (z *Zarinpal) UnverifiedPaymentsSeq(ctx context.Context) iter.Seq2[UnverifiedPayment, error] {
+	return func(yield func(UnverifiedPayment, error) bool) {
+		unverified, err := z.UnverifiedPayments(ctx)
+		if err != nil {
+			yield(UnverifiedPayment{}, err)
+			return
+		}
+		for _, payment := range unverified.Authorities {
+			if !yield(payment, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//go:build go1.23
+
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionsSeq(t *testing.T) {
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.Write([]byte(`{"data":null,"errors":[{"message":"Unauthenticated."}]}`))
+			return
+		}
+
+		var body struct {
+			Variables struct {
+				Offset int `json:"offset"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		// 250 sessions, one per hour, newest first
+		var sessions []string
+		for i := body.Variables.Offset; i < body.Variables.Offset+reportingPageSize && i < 250; i++ {
+			createdAt := start.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)
+			sessions = append(sessions, fmt.Sprintf(`{"id":"%d","authority":"A%d","status":"VERIFIED","amount":10000,"created_at":"%s"}`, i, i, createdAt))
+		}
+		w.Write([]byte(`{"data":{"Session":[` + strings.Join(sessions, ",") + `]}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	ctx := context.Background()
+
+	count := 0
+	for transaction, err := range reporting.TransactionsSeq(ctx, start.Add(-300*time.Hour), start.Add(time.Hour)) {
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if transaction.Authority != fmt.Sprintf("A%d", count) {
+			t.Fatalf("Expected A%d, got %s", count, transaction.Authority)
+		}
+		count++
+	}
+	if count != 250 || requests != 3 {
+		t.Errorf("Expected 250 transactions in 3 pages, got %d in %d", count, requests)
+	}
+
+	requests = 0
+	for transaction := range reporting.TransactionsSeq(ctx, start.Add(-300*time.Hour), start.Add(time.Hour)) {
+		if transaction.Authority == "A10" {
+			break
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected breaking out of the loop to stop fetching, got %d requests", requests)
+	}
+
+	reporting.AccessToken = "expired"
+	var errs int
+	for _, err := range reporting.TransactionsSeq(ctx, start.Add(-time.Hour), start) {
+		if err != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("Expected one error, got %d", errs)
+	}
+}
+
+func TestUnverifiedPaymentsSeq(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"unVerified.json": `{"data":{"code":100,"message":"Success","authorities":[{"authority":"A1","amount":10000},{"authority":"A2","amount":20000}]},"errors":[]}`,
+	})
+
+	var authorities []string
+	for payment, err := range zp.UnverifiedPaymentsSeq(context.Background()) {
+		if err != nil {
+			t.Fatalf("Failed to list unverified payments: %v", err)
+		}
+		authorities = append(authorities, payment.Authority)
+	}
+	if strings.Join(authorities, ",") != "A1,A2" {
+		t.Errorf("Expected A1,A2, got %v", authorities)
+	}
+}
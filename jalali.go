@@ -0,0 +1,171 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidJalaliDate is returned by ParseJalali for malformed or out of range dates
+var ErrInvalidJalaliDate = errors.New("invalid jalali date")
+
+// IranLocation is the Iran Standard Time zone, Iran has not observed daylight saving since 2022
+var IranLocation = time.FixedZone("IRST", 3*3600+30*60)
+
+// JalaliMonths are the Persian names of the Jalali months, the first month being Farvardin
+var JalaliMonths = [12]string{
+	"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور",
+	"مهر", "آبان", "آذر", "دی", "بهمن", "اسفند",
+}
+
+// JalaliDate is a date of the Jalali (Shamsi) calendar
+type JalaliDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"` // 1 to 12
+	Day   int `json:"day"`
+}
+
+// ToJalali returns the Jalali date of t in its own location, convert t with In(IranLocation)
+// first for the date as seen in Iran
+func ToJalali(t time.Time) JalaliDate {
+	year, month, day := t.Date()
+	jy, jm, jd := gregorianToJalali(year, int(month), day)
+	return JalaliDate{Year: jy, Month: jm, Day: jd}
+}
+
+// ParseJalali parses dates written as 1403/01/15 or 1403-01-15
+func ParseJalali(s string) (d JalaliDate, err error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "-", "/")
+	if _, err = fmt.Sscanf(s, "%d/%d/%d", &d.Year, &d.Month, &d.Day); err != nil {
+		err = fmt.Errorf("%w: %q", ErrInvalidJalaliDate, s)
+		return
+	}
+	if !d.IsValid() {
+		err = fmt.Errorf("%w: %q", ErrInvalidJalaliDate, s)
+	}
+	return
+}
+
+// IsValid reports whether the date exists in the Jalali calendar
+func (d JalaliDate) IsValid() bool {
+	return d.Year > 0 && d.Month >= 1 && d.Month <= 12 && d.Day >= 1 && d.Day <= jalaliMonthDays(d.Year, d.Month)
+}
+
+// IsLeap reports whether the year of the date has 366 days
+func (d JalaliDate) IsLeap() bool {
+	return jalaliMonthDays(d.Year, 12) == 30
+}
+
+// Time returns the start of the date in loc, nil meaning UTC
+func (d JalaliDate) Time(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	gy, gm, gd := jalaliToGregorian(d.Year, d.Month, d.Day)
+	return time.Date(gy, time.Month(gm), gd, 0, 0, 0, 0, loc)
+}
+
+// MonthName returns the Persian name of the month
+func (d JalaliDate) MonthName() string {
+	if d.Month < 1 || d.Month > 12 {
+		return ""
+	}
+	return JalaliMonths[d.Month-1]
+}
+
+// String formats the date as 1403/01/15
+func (d JalaliDate) String() string {
+	return fmt.Sprintf("%04d/%02d/%02d", d.Year, d.Month, d.Day)
+}
+
+// FormatJalali formats t in its own location as 1403/01/15 14:05:00, zero times are formatted as an
+// empty string
+func FormatJalali(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return ToJalali(t).String() + t.Format(" 15:04:05")
+}
+
+// jalaliMonthDays returns the number of days of a Jalali month
+func jalaliMonthDays(year, month int) int {
+	switch {
+	case month <= 6:
+		return 31
+	case month <= 11:
+		return 30
+	}
+	// Esfand has 30 days in leap years, so the next Farvardin starts a day later
+	start := JalaliDate{Year: year, Month: 12, Day: 1}.Time(nil)
+	next := JalaliDate{Year: year + 1, Month: 1, Day: 1}.Time(nil)
+	return int(next.Sub(start).Hours() / 24)
+}
+
+// gregorianToJalali converts a Gregorian date using the 33 year cycle arithmetic of jdf.scr.ir,
+// which matches the official calendar between 1800 and 2200
+func gregorianToJalali(gy, gm, gd int) (jy, jm, jd int) {
+	monthStart := [12]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+
+	gy2 := gy
+	if gm > 2 {
+		gy2 = gy + 1
+	}
+	days := 355666 + 365*gy + (gy2+3)/4 - (gy2+99)/100 + (gy2+399)/400 + gd + monthStart[gm-1]
+
+	jy = -1595 + 33*(days/12053)
+	days %= 12053
+	jy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		jy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+
+	if days < 186 {
+		jm = 1 + days/31
+		jd = 1 + days%31
+	} else {
+		jm = 7 + (days-186)/30
+		jd = 1 + (days-186)%30
+	}
+	return
+}
+
+// jalaliToGregorian is the inverse of gregorianToJalali
+func jalaliToGregorian(jy, jm, jd int) (gy, gm, gd int) {
+	jy += 1595
+	days := -355668 + 365*jy + (jy/33)*8 + ((jy%33)+3)/4 + jd
+	if jm < 7 {
+		days += (jm - 1) * 31
+	} else {
+		days += (jm-7)*30 + 186
+	}
+
+	gy = 400 * (days / 146097)
+	days %= 146097
+	if days > 36524 {
+		days--
+		gy += 100 * (days / 36524)
+		days %= 36524
+		if days >= 365 {
+			days++
+		}
+	}
+	gy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		gy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+
+	gd = days + 1
+	monthDays := [13]int{0, 31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+	if (gy%4 == 0 && gy%100 != 0) || gy%400 == 0 {
+		monthDays[2] = 29
+	}
+	for gm = 1; gm <= 12 && gd > monthDays[gm]; gm++ {
+		gd -= monthDays[gm]
+	}
+	return
+}
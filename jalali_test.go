@@ -0,0 +1,76 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToJalali(t *testing.T) {
+	tests := []struct {
+		gregorian time.Time
+		jalali    string
+	}{
+		{time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), "1403/01/01"},
+		{time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), "1403/12/30"},
+		{time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC), "1404/01/01"},
+		{time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC), "1403/02/23"},
+		{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), "1378/10/11"},
+		{time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), "1402/10/10"},
+	}
+
+	for _, test := range tests {
+		if got := ToJalali(test.gregorian).String(); got != test.jalali {
+			t.Errorf("Expected %s to be %s, got %s", test.gregorian.Format("2006-01-02"), test.jalali, got)
+		}
+	}
+}
+
+func TestJalaliRoundTrip(t *testing.T) {
+	day := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 365*60; i++ {
+		d := ToJalali(day)
+		if !d.IsValid() {
+			t.Fatalf("Expected %s to convert to a valid date, got %s", day.Format("2006-01-02"), d)
+		}
+		if back := d.Time(nil); !back.Equal(day) {
+			t.Fatalf("Expected %s to convert back to %s, got %s", d, day.Format("2006-01-02"), back.Format("2006-01-02"))
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+func TestJalaliLeapYears(t *testing.T) {
+	for year, leap := range map[int]bool{1399: true, 1400: false, 1402: false, 1403: true, 1404: false} {
+		if got := (JalaliDate{Year: year}).IsLeap(); got != leap {
+			t.Errorf("Expected %d leap to be %v, got %v", year, leap, got)
+		}
+	}
+}
+
+func TestParseJalali(t *testing.T) {
+	d, err := ParseJalali("1403-02-23")
+	if err != nil {
+		t.Fatalf("Failed to parse date: %v", err)
+	}
+	if d != (JalaliDate{Year: 1403, Month: 2, Day: 23}) || d.MonthName() != "اردیبهشت" {
+		t.Errorf("Unexpected date %+v %s", d, d.MonthName())
+	}
+
+	for _, s := range []string{"1403/13/01", "1404/12/30", "yesterday"} {
+		if _, err := ParseJalali(s); !errors.Is(err, ErrInvalidJalaliDate) {
+			t.Errorf("Expected ErrInvalidJalaliDate for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestSessionJalaliDates(t *testing.T) {
+	// 22:00 UTC is already the next day in Iran
+	session := PaymentSession{CreatedAt: time.Date(2024, 5, 12, 22, 0, 0, 0, time.UTC)}
+	if got := session.CreatedAtJalali().String(); got != "1403/02/24" {
+		t.Errorf("Expected 1403/02/24, got %s", got)
+	}
+	if got := FormatJalali(session.CreatedAt.In(IranLocation)); got != "1403/02/24 01:30:00" {
+		t.Errorf("Expected 1403/02/24 01:30:00, got %s", got)
+	}
+}
@@ -0,0 +1,180 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAmount is returned when parsing an amount fails
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Smallest payment amounts Zarinpal accepts per currency
+const (
+	MinAmountRial  = 1000
+	MinAmountToman = 100
+)
+
+// minAmounts are the smallest payment amounts per currency, checked before payments are sent
+var minAmounts = map[Currency]int{
+	CurrencyRial:  MinAmountRial,
+	CurrencyToman: MinAmountToman,
+}
+
+// MinAmount returns the smallest payment amount Zarinpal accepts in the currency, amounts
+// without a currency are Rials. ok is false for currencies without a minimum.
+func MinAmount(currency Currency) (min int, ok bool) {
+	if currency == "" {
+		currency = CurrencyRial
+	}
+	min, ok = minAmounts[currency]
+	return
+}
+
+// checkMinAmount returns an ErrInvalidAmount for amounts below the minimum of their currency,
+// amounts without a currency are Rials. The overrides replace the minimums of MinAmount when
+// they aren't nil.
+func checkMinAmount(amount int, currency Currency, overrides map[Currency]int) error {
+	if currency == "" {
+		currency = CurrencyRial
+	}
+	min, ok := MinAmount(currency)
+	if overrides != nil {
+		min, ok = overrides[currency]
+	}
+	if ok && amount < min {
+		return fmt.Errorf("%w: %s is below the minimum of %s", ErrInvalidAmount, MoneyOf(amount, currency), MoneyOf(min, currency))
+	}
+	return nil
+}
+
+// Money is an amount in a currency Zarinpal accepts, Rial and Toman implement it
+type Money interface {
+	Currency() Currency
+	Value() int // amount in its own currency
+	Rials() Rial
+}
+
+// Rial is an amount in Rials
+type Rial int
+
+// Toman is an amount in Tomans, ten Rials
+type Toman int
+
+var (
+	_ Money = Rial(0)
+	_ Money = Toman(0)
+)
+
+// Currency implements Money
+func (r Rial) Currency() Currency { return CurrencyRial }
+
+// Value implements Money
+func (r Rial) Value() int { return int(r) }
+
+// Rials implements Money
+func (r Rial) Rials() Rial { return r }
+
+// Tomans converts the amount to Tomans, dropping Rials that don't make up a Toman.
+// Check IsWholeTomans first when the remainder matters.
+func (r Rial) Tomans() Toman { return Toman(r / 10) }
+
+// IsWholeTomans reports whether the amount converts to Tomans without a remainder
+func (r Rial) IsWholeTomans() bool { return r%10 == 0 }
+
+// Add returns the sum of the amounts
+func (r Rial) Add(o Rial) Rial { return r + o }
+
+// Sub returns the difference of the amounts
+func (r Rial) Sub(o Rial) Rial { return r - o }
+
+// Mul returns the amount multiplied by n, like a unit price by a quantity
+func (r Rial) Mul(n int) Rial { return r * Rial(n) }
+
+func (r Rial) String() string { return FormatNumber(int(r)) + " IRR" }
+
+// Currency implements Money
+func (t Toman) Currency() Currency { return CurrencyToman }
+
+// Value implements Money
+func (t Toman) Value() int { return int(t) }
+
+// Rials implements Money
+func (t Toman) Rials() Rial { return Rial(t) * 10 }
+
+// Add returns the sum of the amounts
+func (t Toman) Add(o Toman) Toman { return t + o }
+
+// Sub returns the difference of the amounts
+func (t Toman) Sub(o Toman) Toman { return t - o }
+
+// Mul returns the amount multiplied by n, like a unit price by a quantity
+func (t Toman) Mul(n int) Toman { return t * Toman(n) }
+
+func (t Toman) String() string { return FormatNumber(int(t)) + " IRT" }
+
+// MoneyOf returns the amount as Money, raw amounts without a currency are Rials
+func MoneyOf(amount int, currency Currency) Money {
+	if currency == CurrencyToman {
+		return Toman(amount)
+	}
+	return Rial(amount)
+}
+
+// ParseRial parses an amount in Rials like "120000", "120,000", "120,000 IRR" or "۱۲۰٬۰۰۰ ریال"
+func ParseRial(s string) (Rial, error) {
+	amount, err := parseAmount(s, string(CurrencyRial), RialName)
+	return Rial(amount), err
+}
+
+// ParseToman parses an amount in Tomans like "12000", "12,000", "12,000 IRT" or "۱۲٬۰۰۰ تومان"
+func ParseToman(s string) (Toman, error) {
+	amount, err := parseAmount(s, string(CurrencyToman), TomanName)
+	return Toman(amount), err
+}
+
+// parseAmount parses an integer amount in ASCII or Persian digits, ignoring thousands separators
+// and one of the unit names
+func parseAmount(s string, units ...string) (int, error) {
+	cleaned := strings.TrimSpace(NormalizeDigits(s))
+	for _, unit := range units {
+		if unit != "" && strings.HasSuffix(cleaned, unit) {
+			cleaned = strings.TrimSuffix(cleaned, unit)
+			break
+		}
+	}
+	cleaned = strings.NewReplacer(",", "", "_", "", " ", "", string(persianSeparator), "").Replace(cleaned)
+
+	amount, err := strconv.Atoi(cleaned)
+	if err != nil || cleaned == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	return amount, nil
+}
+
+// NewPaymentParams returns the parameters of a payment of the amount, setting the currency
+// to the one of the amount
+func NewPaymentParams(amount Money, description, callbackURL string) PaymentParams {
+	return PaymentParams{
+		Amount:      amount.Value(),
+		Currency:    amount.Currency(),
+		Description: description,
+		CallbackURL: callbackURL,
+	}
+}
+
+// Money returns the amount of the parameters in their currency
+func (p PaymentParams) Money() Money {
+	return MoneyOf(p.Amount, p.Currency)
+}
+
+// Money returns the amount of the session in its currency
+func (s PaymentSession) Money() Money {
+	return MoneyOf(s.Amount, s.Currency)
+}
+
+// Money returns the payable amount of the invoice in its currency
+func (inv Invoice) Money() Money {
+	return MoneyOf(inv.Total(), inv.Currency)
+}
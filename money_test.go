@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMoneyConversions(t *testing.T) {
+	price := Toman(12000)
+	if price.Rials() != 120000 {
+		t.Errorf("Expected 120000 Rials, got %d", price.Rials())
+	}
+	if Rial(120005).Tomans() != 12000 || Rial(120005).IsWholeTomans() {
+		t.Error("Expected 120005 Rials to be 12000 whole Tomans and a remainder")
+	}
+	if total := price.Mul(3).Sub(Toman(1000)); total != 35000 {
+		t.Errorf("Expected 35000 Tomans, got %d", total)
+	}
+	if price.String() != "12,000 IRT" || Rial(1500000).String() != "1,500,000 IRR" {
+		t.Errorf("Unexpected formatting %s %s", price, Rial(1500000))
+	}
+	if m := MoneyOf(1000, ""); m != Rial(1000) {
+		t.Errorf("Expected raw amounts to be Rials, got %#v", m)
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	for s, expected := range map[string]Rial{"120000": 120000, "120,000": 120000, " 120,000 IRR ": 120000, "-5_000": -5000} {
+		got, err := ParseRial(s)
+		if err != nil || got != expected {
+			t.Errorf("Expected %q to parse to %d, got %d %v", s, expected, got, err)
+		}
+	}
+	if got, err := ParseToman("12,000 IRT"); err != nil || got != 12000 {
+		t.Errorf("Expected 12000 Tomans, got %d %v", got, err)
+	}
+	for _, s := range []string{"", "IRR", "12.5", "12,000 IRT"} {
+		if _, err := ParseRial(s); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Expected ErrInvalidAmount for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestNewPaymentParams(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+
+	session, err := zp.NewSession(context.Background(), NewPaymentParams(Toman(12000), "Test payment", "https://example.com/callback"))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if session.Money() != Toman(12000) || session.Money().Rials() != 120000 {
+		t.Errorf("Expected 12000 Tomans, got %#v", session.Money())
+	}
+}
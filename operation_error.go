@@ -0,0 +1,79 @@
+package zarinpalgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// OperationError is returned by the gateway calls, it wraps the failure with the operation it
+// happened in so logs read like "zarinpal: verify A000f3a9c2e1: amount mismatch (-51)". Use
+// errors.As to reach the *APIError or network error underneath.
+type OperationError struct {
+	Operation     string // like "request" or "verify"
+	Endpoint      string // like "verify.json"
+	Authority     string // hashed with AuthorityHash, empty for calls without an authority
+	CorrelationID string // empty unless the request carried one
+	Tenant        string // see WithTenant, left out of the message
+	Err           error
+}
+
+func (e *OperationError) Error() string {
+	msg := "zarinpal: " + e.Operation
+	if e.Authority != "" {
+		msg += " " + e.Authority
+	}
+	if e.CorrelationID != "" {
+		msg += " [" + e.CorrelationID + "]"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// AuthorityHash returns the short form authorities take in errors: their first 4 characters
+// followed by a hash of the rest, enough to match a log line to a payment without spreading
+// authorities around
+func AuthorityHash(authority string) string {
+	if authority == "" {
+		return ""
+	}
+	prefix := authority
+	if len(prefix) > 4 {
+		prefix = prefix[:4]
+	}
+	sum := sha256.Sum256([]byte(authority))
+	return prefix + hex.EncodeToString(sum[:4])
+}
+
+// wrapOperation wraps err in an *OperationError with the correlation ID and tenant of the
+// context, errors that already are one are kept
+func wrapOperation(ctx context.Context, err error, operation, endpoint, authority string) error {
+	if err == nil {
+		return nil
+	}
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return err
+	}
+	return &OperationError{
+		Operation:     operation,
+		Endpoint:      endpoint,
+		Authority:     AuthorityHash(authority),
+		CorrelationID: CorrelationID(ctx),
+		Tenant:        Tenant(ctx),
+		Err:           err,
+	}
+}
+
+// operationCause returns the error an *OperationError wraps, for messages shown to users
+func operationCause(err error) error {
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return opErr.Err
+	}
+	return err
+}
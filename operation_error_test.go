@@ -0,0 +1,69 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestOperationError(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": fixtures.Error(-51),
+	})
+
+	ctx := WithCorrelationID(context.Background(), "req-1")
+	_, err := zp.VerifyPayment(ctx, 1000, "A0000000000000000000000000000123")
+
+	var opErr *OperationError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("Expected an OperationError, got %v", err)
+	}
+	if opErr.Operation != "verify" || opErr.Endpoint != "verify.json" || opErr.CorrelationID != "req-1" {
+		t.Errorf("Expected verify on verify.json with req-1, got %+v", opErr)
+	}
+	hash := AuthorityHash("A0000000000000000000000000000123")
+	if opErr.Authority != hash || strings.Contains(err.Error(), "A0000000000000000000000000000123") {
+		t.Errorf("Expected the authority hashed to %s, got %s", hash, err)
+	}
+	want := "zarinpal: verify " + hash + " [req-1]: " + fixtures.ErrorMessages[-51] + " (-51)"
+	if err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != -51 {
+		t.Errorf("Expected the API error to be reachable, got %v", err)
+	}
+
+	status, err := zp.CheckPaymentStatus(context.Background(), 1000, "A0000000000000000000000000000123")
+	if err == nil || status.Message != apiErr.Error() {
+		t.Errorf("Expected the status message %q, got %q", apiErr.Error(), status.Message)
+	}
+}
+
+func TestOperationErrorWithoutAuthority(t *testing.T) {
+	zp := newStubClient(t, map[string]string{})
+
+	_, err := zp.UnverifiedPayments(context.Background())
+	var opErr *OperationError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("Expected an OperationError, got %v", err)
+	}
+	if opErr.Authority != "" || !strings.HasPrefix(err.Error(), "zarinpal: unverified: ") {
+		t.Errorf("Expected an error without authority, got %q", err)
+	}
+}
+
+func TestAuthorityHash(t *testing.T) {
+	a := AuthorityHash("A0000000000000000000000000000123")
+	b := AuthorityHash("A0000000000000000000000000000124")
+	if a == b || !strings.HasPrefix(a, "A000") || len(a) != 12 {
+		t.Errorf("Expected distinct 12 character hashes, got %s and %s", a, b)
+	}
+	if AuthorityHash("") != "" {
+		t.Errorf("Expected no hash for an empty authority, got %s", AuthorityHash(""))
+	}
+}
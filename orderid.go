@@ -0,0 +1,69 @@
+package zarinpalgo
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxOrderIDLength is the longest order ID accepted in the metadata of a payment
+const MaxOrderIDLength = 64
+
+// ErrInvalidOrderID is returned for order IDs that are empty, too long or have characters other
+// than ASCII letters, digits, '-' and '_'
+var ErrInvalidOrderID = errors.New("invalid order ID")
+
+// crockford is the base32 alphabet of ULIDs, without the letters easily mistaken for digits
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewOrderID returns a ULID order ID after the prefix, like "ord_01HXKZ3V2QF6W8T4R9M5N7B1CD".
+// IDs sort by creation time and carry 80 random bits, so unlike timestamps they don't collide
+// under load.
+func NewOrderID(prefix string) string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic("zarinpalgo: reading random bytes failed: " + err.Error())
+	}
+	return prefix + encodeCrockford(id)
+}
+
+// NewShortOrderID returns a random UUID order ID after the prefix, encoded in 26 characters
+// instead of 36. Prefer NewOrderID when IDs should sort by time.
+func NewShortOrderID(prefix string) string {
+	return prefix + encodeCrockford(uuid.New())
+}
+
+// encodeCrockford encodes the 128 bits in 26 characters, the first one holding 3 bits
+func encodeCrockford(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ValidateOrderID checks an order ID supplied by users or other systems before it is sent in
+// the metadata of a payment
+func ValidateOrderID(orderID string) error {
+	if orderID == "" {
+		return fmt.Errorf("%w: it is empty", ErrInvalidOrderID)
+	}
+	if len(orderID) > MaxOrderIDLength {
+		return fmt.Errorf("%w: %d characters, at most %d are accepted", ErrInvalidOrderID, len(orderID), MaxOrderIDLength)
+	}
+	for _, r := range orderID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("%w: %q has the character %q", ErrInvalidOrderID, orderID, r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewOrderID(t *testing.T) {
+	seen := make(map[string]bool)
+	previous := ""
+	for i := 0; i < 1000; i++ {
+		id := NewOrderID("ord_")
+		if !strings.HasPrefix(id, "ord_") || len(id) != len("ord_")+26 {
+			t.Fatalf("Expected a prefixed 26 character ID, got %s", id)
+		}
+		if err := ValidateOrderID(id); err != nil {
+			t.Fatalf("Expected generated IDs to be valid, got %v", err)
+		}
+		if seen[id] {
+			t.Fatalf("Expected unique IDs, got %s twice", id)
+		}
+		seen[id] = true
+		// the first 10 characters after the prefix encode the time
+		if previous != "" && id[4:14] < previous[4:14] {
+			t.Errorf("Expected IDs to sort by time, got %s after %s", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestNewShortOrderID(t *testing.T) {
+	a, b := NewShortOrderID(""), NewShortOrderID("")
+	if len(a) != 26 || a == b {
+		t.Errorf("Expected distinct 26 character IDs, got %s and %s", a, b)
+	}
+	if err := ValidateOrderID(a); err != nil {
+		t.Errorf("Expected generated IDs to be valid, got %v", err)
+	}
+}
+
+func TestValidateOrderID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"1024", true},
+		{"order-1024_b", true},
+		{"", false},
+		{"order 1024", false},
+		{"سفارش-۱", false},
+		{"order/1024", false},
+		{strings.Repeat("a", MaxOrderIDLength), true},
+		{strings.Repeat("a", MaxOrderIDLength+1), false},
+	}
+	for _, tt := range tests {
+		err := ValidateOrderID(tt.id)
+		if tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrInvalidOrderID)) {
+			t.Errorf("Expected %q valid %v, got %v", tt.id, tt.valid, err)
+		}
+	}
+}
+
+func TestIdempotentSessionsInvalidOrderID(t *testing.T) {
+	sessions := NewIdempotentSessions(newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	}), NewMemoryPaymentStore())
+
+	params := PaymentParams{Amount: 10000, Description: "Order", CallbackURL: "https://example.com/callback", Metadata: &Metadata{OrderID: "order 1"}}
+	if _, err := sessions.NewSession(context.Background(), params); err != nil {
+		t.Errorf("Expected order IDs left unchecked by default, got %v", err)
+	}
+	if err := params.Validate(); err != nil {
+		t.Errorf("Expected Validate to leave the order ID unchecked, got %v", err)
+	}
+
+	sessions.ValidateOrderIDs = true
+	if _, err := sessions.NewSession(context.Background(), params); !errors.Is(err, ErrInvalidOrderID) {
+		t.Errorf("Expected ErrInvalidOrderID, got %v", err)
+	}
+}
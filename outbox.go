@@ -0,0 +1,181 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Outbox relay defaults
+const (
+	DefaultOutboxInterval  = time.Second
+	DefaultOutboxBatchSize = 100
+)
+
+// Outbox durably keeps payment events until they are published, so each event reaches
+// downstream systems at least once even if the process stops right after verifying a payment
+type Outbox interface {
+	// AddEvent stores the event, an event whose ID is pending already is kept once
+	AddEvent(ctx context.Context, event WebhookEvent) error
+	// PendingEvents returns up to limit unpublished events, oldest first
+	PendingEvents(ctx context.Context, limit int) ([]WebhookEvent, error)
+	MarkPublished(ctx context.Context, id string) error
+}
+
+// outboxEvent returns the event of the payment status, its ID is derived from the authority and
+// the event type so the callback, the reconciler and the retrier recording the same outcome add
+// a single event
+func outboxEvent(status PaymentStatus) WebhookEvent {
+	event := NewWebhookEvent(status)
+	event.ID = status.Authority + ":" + event.Type
+	return event
+}
+
+// Publisher publishes payment events to a downstream system
+type Publisher interface {
+	Publish(ctx context.Context, event WebhookEvent) error
+}
+
+// PublisherFunc adapts a function to a Publisher, e.g. PublisherFunc(relay.Send) for a WebhookRelay
+type PublisherFunc func(ctx context.Context, event WebhookEvent) error
+
+// Publish implements Publisher
+func (f PublisherFunc) Publish(ctx context.Context, event WebhookEvent) error {
+	return f(ctx, event)
+}
+
+// WithOutbox adds an event to the outbox for every callback, one per authority and outcome so
+// redelivered and replayed callbacks don't add it twice
+func WithOutbox(outbox Outbox) CallbackOption {
+	return func(o *callbackOptions) {
+		o.outbox = outbox
+	}
+}
+
+// OutboxRelay publishes the events of an outbox. Events are published in order, a failed
+// event stops the batch and is retried on the next run.
+type OutboxRelay struct {
+	Outbox    Outbox
+	Publisher Publisher
+
+	Interval  time.Duration // time between runs, defaults to DefaultOutboxInterval
+	BatchSize int           // events published per run, defaults to DefaultOutboxBatchSize
+
+	// OnError is called when publishing an event fails
+	OnError func(event WebhookEvent, err error)
+}
+
+// NewOutboxRelay creates an OutboxRelay with the default interval and batch size
+func NewOutboxRelay(outbox Outbox, publisher Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		Outbox:    outbox,
+		Publisher: publisher,
+		Interval:  DefaultOutboxInterval,
+		BatchSize: DefaultOutboxBatchSize,
+	}
+}
+
+// Run publishes pending events every interval until the context is done
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultOutboxInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.PublishPending(ctx); err != nil {
+			r.fail(WebhookEvent{}, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes a batch of pending events and returns how many were published.
+// Only failing to access the outbox is returned as an error.
+func (r *OutboxRelay) PublishPending(ctx context.Context) (published int, err error) {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultOutboxBatchSize
+	}
+
+	events, err := r.Outbox.PendingEvents(ctx, batchSize)
+	if err != nil {
+		return
+	}
+
+	for _, event := range events {
+		var publishErr error
+		if panicErr := RunHook("outbox publisher", func() { publishErr = r.Publisher.Publish(ctx, event) }); panicErr != nil {
+			publishErr = panicErr
+		}
+		if publishErr != nil {
+			r.fail(event, publishErr)
+			return
+		}
+		if err = r.Outbox.MarkPublished(ctx, event.ID); err != nil {
+			return
+		}
+		published++
+	}
+	return
+}
+
+// MemoryOutbox is an Outbox kept in memory, it doesn't survive restarts
+type MemoryOutbox struct {
+	mu     sync.Mutex
+	events map[string]WebhookEvent
+}
+
+// NewMemoryOutbox creates an empty MemoryOutbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{events: make(map[string]WebhookEvent)}
+}
+
+// AddEvent implements Outbox
+func (o *MemoryOutbox) AddEvent(ctx context.Context, event WebhookEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events[event.ID] = event
+	return nil
+}
+
+// PendingEvents implements Outbox
+func (o *MemoryOutbox) PendingEvents(ctx context.Context, limit int) (events []WebhookEvent, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, event := range o.events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return
+}
+
+// MarkPublished implements Outbox
+func (o *MemoryOutbox) MarkPublished(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.events, id)
+	return nil
+}
+
+func (r *OutboxRelay) fail(event WebhookEvent, err error) {
+	if r.OnError != nil {
+		RunHook("outbox relay OnError", func() { r.OnError(event, err) })
+	}
+}
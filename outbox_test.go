@@ -0,0 +1,55 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	outbox := NewMemoryOutbox()
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, nil, WithOutbox(outbox), WithReplayGuard(NewMemoryReplayGuard(time.Minute)))
+
+	for _, target := range []string{
+		"/callback?Authority=A1&Status=OK",
+		"/callback?Authority=A1&Status=OK",
+		"/callback?Authority=A2&Status=NOK",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+
+	ctx := context.Background()
+	var published []WebhookEvent
+	fail := true
+	relay := NewOutboxRelay(outbox, PublisherFunc(func(ctx context.Context, event WebhookEvent) error {
+		if fail {
+			return errors.New("broker unavailable")
+		}
+		published = append(published, event)
+		return nil
+	}))
+
+	if n, err := relay.PublishPending(ctx); n != 0 || err != nil {
+		t.Errorf("Expected nothing to be published, got %d %v", n, err)
+	}
+
+	fail = false
+	if n, err := relay.PublishPending(ctx); n != 2 || err != nil {
+		t.Errorf("Expected 2 events to be published, got %d %v", n, err)
+	}
+	if len(published) != 2 || published[0].Type != EventPaymentVerified || published[0].Payment.Authority != "A1" || published[1].Type != EventPaymentFailed {
+		t.Errorf("Unexpected events: %+v", published)
+	}
+
+	if pending, _ := outbox.PendingEvents(ctx, 10); len(pending) != 0 {
+		t.Errorf("Expected outbox to be empty, got %+v", pending)
+	}
+}
@@ -0,0 +1,96 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxPages is the number of pages after which listings of the reporting API are stopped
+const DefaultMaxPages = 1000
+
+// Pagination errors
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrPaginationLoop   = errors.New("reporting API returned a page seen before")
+	ErrTooManyPages     = errors.New("reporting API returned too many pages")
+)
+
+// pageTokenPrefix versions the page tokens, so their format can change without breaking
+// tokens handed out before
+const pageTokenPrefix = "o1:"
+
+// PageRequest selects a page of a listing
+type PageRequest struct {
+	Limit     int    // transactions per page, 100 when zero
+	PageToken string // NextPageToken of the previous page, empty for the first page
+	// Filter is sent with the request, the page holds what the API returned for it. Check
+	// Filter.Matches on the transactions for the fields the API doesn't filter on.
+	Filter TransactionFilter
+}
+
+// TransactionPage is a page of transactions, newest first
+type TransactionPage struct {
+	Transactions  []Transaction `json:"transactions"`
+	NextPageToken string        `json:"next_page_token,omitempty"` // empty on the last page
+	// Total is the number of transactions the API listed, it is only known on the last page
+	Total int `json:"total,omitempty"`
+}
+
+// TransactionsPage fetches one page of the transactions of the terminal, pass its NextPageToken
+// to the next call to continue the listing
+func (r *Reporting) TransactionsPage(ctx context.Context, req PageRequest) (page TransactionPage, err error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = reportingPageSize
+	}
+	offset, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return
+	}
+
+	var data struct {
+		Session []Transaction `json:"Session"`
+	}
+	query, variables := sessionsQuery(r.TerminalID, limit, offset, req.Filter)
+	err = r.Query(ctx, query, variables, &data)
+	if err != nil {
+		return
+	}
+
+	page.Transactions = data.Session
+	if len(data.Session) < limit {
+		page.Total = offset + len(data.Session)
+	} else {
+		page.NextPageToken = encodePageToken(offset + len(data.Session))
+	}
+	return
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageTokenPrefix + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	invalid := fmt.Errorf("%w: %q", ErrInvalidPageToken, token)
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, invalid
+	}
+	value, found := strings.CutPrefix(string(decoded), pageTokenPrefix)
+	if !found {
+		return 0, invalid
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
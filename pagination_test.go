@@ -0,0 +1,99 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSessionsServer serves count sessions one hour apart, newest first. With ignoreOffset it
+// answers every request with the first page, like a misbehaving API.
+func newSessionsServer(t *testing.T, count int, ignoreOffset bool) *Reporting {
+	t.Helper()
+
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables struct {
+				Limit  int `json:"limit"`
+				Offset int `json:"offset"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if ignoreOffset {
+			body.Variables.Offset = 0
+		}
+
+		var sessions []string
+		for i := body.Variables.Offset; i < body.Variables.Offset+body.Variables.Limit && i < count; i++ {
+			createdAt := start.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)
+			sessions = append(sessions, fmt.Sprintf(`{"id":"%d","authority":"A%d","created_at":"%s"}`, i, i, createdAt))
+		}
+		w.Write([]byte(`{"data":{"Session":[` + strings.Join(sessions, ",") + `]}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	return reporting
+}
+
+func TestTransactionsPage(t *testing.T) {
+	reporting := newSessionsServer(t, 25, false)
+	ctx := context.Background()
+
+	page, err := reporting.TransactionsPage(ctx, PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to fetch page: %v", err)
+	}
+	if len(page.Transactions) != 10 || page.NextPageToken == "" || page.Total != 0 {
+		t.Errorf("Expected a full first page with a next page token, got %d %q %d", len(page.Transactions), page.NextPageToken, page.Total)
+	}
+
+	var pages int
+	for request := (PageRequest{Limit: 10}); ; request.PageToken = page.NextPageToken {
+		page, err = reporting.TransactionsPage(ctx, request)
+		if err != nil {
+			t.Fatalf("Failed to fetch page: %v", err)
+		}
+		pages++
+		if page.NextPageToken == "" {
+			break
+		}
+	}
+	if pages != 3 || page.Total != 25 || page.Transactions[0].Authority != "A20" {
+		t.Errorf("Expected 3 pages and a total of 25, got %d pages %+v", pages, page)
+	}
+
+	if _, err := reporting.TransactionsPage(ctx, PageRequest{PageToken: "garbage"}); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("Expected ErrInvalidPageToken, got %v", err)
+	}
+}
+
+func TestTransactionsPaginationSafeguards(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	reporting := newSessionsServer(t, 500, true)
+	if _, err := reporting.Transactions(context.Background(), from, to); !errors.Is(err, ErrPaginationLoop) {
+		t.Errorf("Expected ErrPaginationLoop, got %v", err)
+	}
+
+	reporting = newSessionsServer(t, 500, false)
+	reporting.MaxPages = 2
+	if _, err := reporting.Transactions(context.Background(), from, to); !errors.Is(err, ErrTooManyPages) {
+		t.Errorf("Expected ErrTooManyPages, got %v", err)
+	}
+
+	reporting.MaxPages = 0
+	transactions, err := reporting.Transactions(context.Background(), from, to)
+	if err != nil || len(transactions) != 500 {
+		t.Errorf("Expected 500 transactions, got %d %v", len(transactions), err)
+	}
+}
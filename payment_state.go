@@ -0,0 +1,177 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PaymentState is the lifecycle state of a payment
+type PaymentState string
+
+// PaymentState values
+const (
+	PaymentStateCreated    PaymentState = "created"    // Authority was issued
+	PaymentStateRedirected PaymentState = "redirected" // User was sent to the payment page
+	PaymentStatePending    PaymentState = "pending"    // User came back and the payment awaits verification
+	PaymentStateVerified   PaymentState = "verified"   // Payment was verified, the order can be fulfilled
+	PaymentStateFailed     PaymentState = "failed"     // Payment was canceled or rejected
+	PaymentStateExpired    PaymentState = "expired"    // Authority expired before it was paid
+	PaymentStateReversed   PaymentState = "reversed"   // Payment was reversed to the user's card
+	PaymentStateRefunded   PaymentState = "refunded"   // Verified payment was refunded
+)
+
+// PaymentEvent moves a payment from one state to another
+type PaymentEvent string
+
+// PaymentEvent values
+const (
+	PaymentEventRedirect PaymentEvent = "redirect"
+	PaymentEventCallback PaymentEvent = "callback"
+	PaymentEventVerify   PaymentEvent = "verify"
+	PaymentEventFail     PaymentEvent = "fail"
+	PaymentEventExpire   PaymentEvent = "expire"
+	PaymentEventReverse  PaymentEvent = "reverse"
+	PaymentEventRefund   PaymentEvent = "refund"
+)
+
+var (
+	ErrInvalidTransition   = errors.New("invalid payment state transition")
+	ErrUnknownPaymentState = errors.New("unknown payment state")
+)
+
+// paymentTransitions lists the allowed transitions of each state, states without transitions are final
+var paymentTransitions = map[PaymentState]map[PaymentEvent]PaymentState{
+	PaymentStateCreated: {
+		PaymentEventRedirect: PaymentStateRedirected,
+		PaymentEventCallback: PaymentStatePending,
+		PaymentEventFail:     PaymentStateFailed,
+		PaymentEventExpire:   PaymentStateExpired,
+	},
+	PaymentStateRedirected: {
+		PaymentEventCallback: PaymentStatePending,
+		PaymentEventVerify:   PaymentStateVerified,
+		PaymentEventFail:     PaymentStateFailed,
+		PaymentEventExpire:   PaymentStateExpired,
+	},
+	PaymentStatePending: {
+		PaymentEventVerify:  PaymentStateVerified,
+		PaymentEventFail:    PaymentStateFailed,
+		PaymentEventExpire:  PaymentStateExpired,
+		PaymentEventReverse: PaymentStateReversed,
+	},
+	PaymentStateVerified: {
+		PaymentEventReverse: PaymentStateReversed,
+		PaymentEventRefund:  PaymentStateRefunded,
+	},
+	PaymentStateFailed:   {},
+	PaymentStateExpired:  {},
+	PaymentStateReversed: {},
+	PaymentStateRefunded: {},
+}
+
+// ParsePaymentState parses a state stored as a string
+func ParsePaymentState(s string) (state PaymentState, err error) {
+	state = PaymentState(s)
+	if _, ok := paymentTransitions[state]; !ok {
+		err = fmt.Errorf("%w: %q", ErrUnknownPaymentState, s)
+	}
+	return
+}
+
+// Next returns the state the event moves the payment to, or ErrInvalidTransition
+func (s PaymentState) Next(event PaymentEvent) (PaymentState, error) {
+	next, ok := paymentTransitions[s][event]
+	if !ok {
+		return s, fmt.Errorf("%w: %s on %s payment", ErrInvalidTransition, event, s)
+	}
+	return next, nil
+}
+
+// Can reports whether the event is allowed in the state
+func (s PaymentState) Can(event PaymentEvent) bool {
+	_, ok := paymentTransitions[s][event]
+	return ok
+}
+
+// Final reports whether the payment can't change state anymore
+func (s PaymentState) Final() bool {
+	return len(paymentTransitions[s]) == 0
+}
+
+// CanMoveTo reports whether some event moves the payment from the state to the given one
+func (s PaymentState) CanMoveTo(state PaymentState) bool {
+	for _, next := range paymentTransitions[s] {
+		if next == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Pending reports whether the payment's outcome is still unknown
+func (s PaymentState) Pending() bool {
+	return s == PaymentStateCreated || s == PaymentStateRedirected || s == PaymentStatePending
+}
+
+// PaymentTransition is a state change of a payment
+type PaymentTransition struct {
+	From  PaymentState `json:"from"`
+	To    PaymentState `json:"to"`
+	Event PaymentEvent `json:"event"`
+	At    time.Time    `json:"at"`
+}
+
+// PaymentStateMachine tracks the state of a payment, it is safe for concurrent use
+type PaymentStateMachine struct {
+	mu      sync.Mutex
+	state   PaymentState
+	history []PaymentTransition
+
+	// OnTransition is called after every transition, while the machine is locked
+	OnTransition func(transition PaymentTransition)
+	// OnHookPanic is called with the panics of OnTransition, they are recovered so the
+	// transition stands
+	OnHookPanic func(err *PanicError)
+}
+
+// NewPaymentStateMachine returns a machine in the given state
+func NewPaymentStateMachine(state PaymentState) *PaymentStateMachine {
+	return &PaymentStateMachine{state: state}
+}
+
+// State returns the current state
+func (m *PaymentStateMachine) State() PaymentState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// History returns the transitions made so far
+func (m *PaymentStateMachine) History() []PaymentTransition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]PaymentTransition(nil), m.history...)
+}
+
+// Fire applies the event, the state is left unchanged when the transition isn't allowed
+func (m *PaymentStateMachine) Fire(event PaymentEvent) (transition PaymentTransition, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next, err := m.state.Next(event)
+	if err != nil {
+		return
+	}
+
+	transition = PaymentTransition{From: m.state, To: next, Event: event, At: time.Now()}
+	m.state = next
+	m.history = append(m.history, transition)
+	if m.OnTransition != nil {
+		if panicErr := RunHook("state machine OnTransition", func() { m.OnTransition(transition) }); panicErr != nil && m.OnHookPanic != nil {
+			m.OnHookPanic(panicErr)
+		}
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPaymentStateMachine(t *testing.T) {
+	var events []PaymentEvent
+	machine := NewPaymentStateMachine(PaymentStateCreated)
+	machine.OnTransition = func(transition PaymentTransition) {
+		events = append(events, transition.Event)
+	}
+
+	for _, event := range []PaymentEvent{PaymentEventRedirect, PaymentEventCallback, PaymentEventVerify, PaymentEventRefund} {
+		if _, err := machine.Fire(event); err != nil {
+			t.Fatalf("Failed to fire %s: %v", event, err)
+		}
+	}
+
+	if machine.State() != PaymentStateRefunded || !machine.State().Final() {
+		t.Errorf("Expected final state %s, got %s", PaymentStateRefunded, machine.State())
+	}
+	if len(events) != 4 || len(machine.History()) != 4 {
+		t.Errorf("Expected 4 transitions, got %v", machine.History())
+	}
+
+	if _, err := machine.Fire(PaymentEventVerify); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+	if machine.State() != PaymentStateRefunded {
+		t.Errorf("Expected state to be unchanged, got %s", machine.State())
+	}
+
+	var panics []*PanicError
+	machine = NewPaymentStateMachine(PaymentStateCreated)
+	machine.OnTransition = func(transition PaymentTransition) { panic("boom") }
+	machine.OnHookPanic = func(err *PanicError) { panics = append(panics, err) }
+	if _, err := machine.Fire(PaymentEventRedirect); err != nil || machine.State() != PaymentStateRedirected {
+		t.Errorf("Expected the transition to stand despite the panic, got %s %v", machine.State(), err)
+	}
+	if len(panics) != 1 || panics[0].Hook != "state machine OnTransition" {
+		t.Errorf("Expected the panic reported, got %v", panics)
+	}
+	// the machine isn't left locked
+	if _, err := machine.Fire(PaymentEventCallback); err != nil {
+		t.Errorf("Expected the machine usable after the panic, got %v", err)
+	}
+}
+
+func TestPaymentStateTransitions(t *testing.T) {
+	tests := []struct {
+		state PaymentState
+		event PaymentEvent
+		next  PaymentState
+		ok    bool
+	}{
+		{PaymentStateCreated, PaymentEventRedirect, PaymentStateRedirected, true},
+		{PaymentStatePending, PaymentEventExpire, PaymentStateExpired, true},
+		{PaymentStateVerified, PaymentEventReverse, PaymentStateReversed, true},
+		{PaymentStateCreated, PaymentEventRefund, PaymentStateCreated, false},
+		{PaymentStateFailed, PaymentEventVerify, PaymentStateFailed, false},
+		{PaymentStateVerified, PaymentEventFail, PaymentStateVerified, false},
+	}
+
+	for _, test := range tests {
+		next, err := test.state.Next(test.event)
+		if next != test.next || (err == nil) != test.ok || test.state.Can(test.event) != test.ok {
+			t.Errorf("Expected %s on %s to give %s (%v), got %s %v", test.event, test.state, test.next, test.ok, next, err)
+		}
+	}
+}
+
+func TestParsePaymentState(t *testing.T) {
+	state, err := ParsePaymentState("verified")
+	if err != nil || state != PaymentStateVerified {
+		t.Errorf("Expected %s, got %s %v", PaymentStateVerified, state, err)
+	}
+	if _, err := ParsePaymentState("paid"); !errors.Is(err, ErrUnknownPaymentState) {
+		t.Errorf("Expected ErrUnknownPaymentState, got %v", err)
+	}
+}
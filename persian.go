@@ -0,0 +1,96 @@
+package zarinpalgo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Persian unit names
+const (
+	RialName  = "ریال"
+	TomanName = "تومان"
+)
+
+// persianSeparator is the Arabic thousands separator used in Persian text
+const persianSeparator = '٬'
+
+var (
+	toPersianDigits = strings.NewReplacer(
+		"0", "۰", "1", "۱", "2", "۲", "3", "۳", "4", "۴",
+		"5", "۵", "6", "۶", "7", "۷", "8", "۸", "9", "۹",
+	)
+	// toASCIIDigits also handles the Arabic-Indic digits some keyboards produce
+	toASCIIDigits = strings.NewReplacer(
+		"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4",
+		"۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+		"٠", "0", "١", "1", "٢", "2", "٣", "3", "٤", "4",
+		"٥", "5", "٦", "6", "٧", "7", "٨", "8", "٩", "9",
+	)
+)
+
+// PersianDigits replaces the ASCII digits of s with Persian digits
+func PersianDigits(s string) string {
+	return toPersianDigits.Replace(s)
+}
+
+// NormalizeDigits replaces the Persian and Arabic digits of s with ASCII digits, use it on user input
+func NormalizeDigits(s string) string {
+	return toASCIIDigits.Replace(s)
+}
+
+// FormatNumber formats n with ASCII digits and thousands separators, like "120,000"
+func FormatNumber(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// FormatPersianNumber formats n with Persian digits and separators, like "۱۲۰٬۰۰۰"
+func FormatPersianNumber(n int) string {
+	return PersianDigits(strings.ReplaceAll(FormatNumber(n), ",", string(persianSeparator)))
+}
+
+// FormatMoney formats an amount with its Persian unit name, like "12,000 تومان", or like
+// "۱۲۰٬۰۰۰ ریال" with persianDigits
+func FormatMoney(m Money, persianDigits bool) string {
+	unit := RialName
+	if m.Currency() == CurrencyToman {
+		unit = TomanName
+	}
+
+	number := FormatNumber(m.Value())
+	if persianDigits {
+		number = FormatPersianNumber(m.Value())
+	}
+	return number + " " + unit
+}
+
+// ParseMoney parses an amount written in Persian or ASCII digits with an optional unit, like
+// "۱۲۰٬۰۰۰ ریال", "12,000 تومان" or "12000 IRT". Amounts without a unit are Rials.
+func ParseMoney(s string) (Money, error) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasSuffix(trimmed, TomanName) || strings.HasSuffix(trimmed, string(CurrencyToman)) {
+		amount, err := ParseToman(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return amount, nil
+	}
+
+	amount, err := ParseRial(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return amount, nil
+}
@@ -0,0 +1,65 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		money         Money
+		persianDigits bool
+		expected      string
+	}{
+		{Rial(120000), true, "۱۲۰٬۰۰۰ ریال"},
+		{Toman(12000), false, "12,000 تومان"},
+		{Toman(999), true, "۹۹۹ تومان"},
+		{Rial(-5000), true, "-۵٬۰۰۰ ریال"},
+	}
+
+	for _, test := range tests {
+		if got := FormatMoney(test.money, test.persianDigits); got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567", -50000: "-50,000"}
+	for amount, expected := range tests {
+		if got := FormatNumber(amount); got != expected {
+			t.Errorf("Expected %s for %d, got %s", expected, amount, got)
+		}
+	}
+}
+
+func TestPersianDigits(t *testing.T) {
+	if got := PersianDigits("کد پیگیری 201"); got != "کد پیگیری ۲۰۱" {
+		t.Errorf("Expected Persian digits, got %q", got)
+	}
+	if got := NormalizeDigits("۰۹۱۲۳٤٥٦۷۸۹"); got != "09123456789" {
+		t.Errorf("Expected ASCII digits, got %q", got)
+	}
+}
+
+func TestParseMoneyPersian(t *testing.T) {
+	tests := map[string]Money{
+		"۱۲۰٬۰۰۰ ریال": Rial(120000),
+		"12,000 تومان": Toman(12000),
+		"۱۲۰۰۰ IRT":    Toman(12000),
+		"120000":       Rial(120000),
+	}
+	for s, expected := range tests {
+		got, err := ParseMoney(s)
+		if err != nil || got != expected {
+			t.Errorf("Expected %q to parse to %#v, got %#v %v", s, expected, got, err)
+		}
+	}
+
+	if got, err := ParseRial("۱۲۰٬۰۰۰ ریال"); err != nil || got != 120000 {
+		t.Errorf("Expected 120000 Rials, got %d %v", got, err)
+	}
+	if _, err := ParseMoney("دوازده تومان"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+}
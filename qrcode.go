@@ -0,0 +1,46 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// PaymentQRCode returns a PNG image of the payment URL as a QR code, size is the image width in pixels
+func (z *Zarinpal) PaymentQRCode(authority string, size int) ([]byte, error) {
+	return qrcode.Encode(z.GetPaymentURL(authority), qrcode.Medium, size)
+}
+
+// PaymentQRCodeSVG returns an SVG image of the payment URL as a QR code, size is the image width in pixels
+func (z *Zarinpal) PaymentQRCodeSVG(authority string, size int) ([]byte, error) {
+	code, err := qrcode.New(z.GetPaymentURL(authority), qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := code.Bitmap()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, len(bitmap), len(bitmap))
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+	for y, row := range bitmap {
+		for x, black := range row {
+			if black {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// PaymentQRCodeText returns the payment URL as a QR code drawn with Unicode half blocks, for
+// terminals. It suits light text on a dark background, inverse swaps the colors.
+func (z *Zarinpal) PaymentQRCodeText(authority string, inverse bool) (string, error) {
+	code, err := qrcode.New(z.GetPaymentURL(authority), qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	return code.ToSmallString(inverse), nil
+}
@@ -0,0 +1,54 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPaymentQRCode(t *testing.T) {
+	zp := NewWithMode("merchant-1", true)
+
+	pngBytes, err := zp.PaymentQRCode("A0000000000000000000000000000wwOGYpd", 256)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(pngBytes))
+	if err != nil {
+		t.Fatalf("Expected a valid PNG image: %v", err)
+	}
+	if img.Bounds().Dx() != 256 {
+		t.Errorf("Expected image width 256, got %d", img.Bounds().Dx())
+	}
+
+	svg, err := zp.PaymentQRCodeSVG("A0000000000000000000000000000wwOGYpd", 256)
+	if err != nil {
+		t.Fatalf("Failed to generate SVG QR code: %v", err)
+	}
+	if !strings.HasPrefix(string(svg), "<svg") || !strings.HasSuffix(string(svg), "</svg>") {
+		t.Error("Expected an SVG document")
+	}
+}
+
+func TestPaymentQRCodeText(t *testing.T) {
+	zp := NewWithMode("merchant-1", true)
+
+	text, err := zp.PaymentQRCodeText("A0000000000000000000000000000wwOGYpd", false)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := len([]rune(lines[0]))
+	if width < 21 || len(lines) != (width+1)/2 {
+		t.Errorf("Expected a square code of half height, got %d lines of %d", len(lines), width)
+	}
+	if !strings.ContainsAny(text, "█▀▄") {
+		t.Error("Expected half block characters")
+	}
+
+	inverse, _ := zp.PaymentQRCodeText("A0000000000000000000000000000wwOGYpd", true)
+	if inverse == text {
+		t.Error("Expected inverse to swap the colors")
+	}
+}
@@ -0,0 +1,143 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits requests per key with token buckets
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens added per second
+	burst     float64
+	buckets   map[string]*bucket
+	nextSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter allowing rate requests per second per key,
+// with bursts of up to burst requests
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of key and reports whether one was available
+func (l *RateLimiter) Allow(key string) bool {
+	_, ok := l.take(key)
+	return ok
+}
+
+// Wait takes a token from the bucket of key, waiting for one to be added when it is empty.
+// It fails with the error of the context when the context is done first.
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	for {
+		wait, ok := l.take(key)
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take takes a token from the bucket of key, or returns how long until one is added
+func (l *RateLimiter) take(key string) (wait time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.After(l.nextSweep) {
+		// buckets that have refilled completely are equivalent to new ones
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.nextSweep = now.Add(time.Minute)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		if l.rate <= 0 {
+			// the bucket never refills, waits end with their context
+			return time.Hour, false
+		}
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// CallbackRateLimit limits callback requests per client IP and per authority
+type CallbackRateLimit struct {
+	PerIP        *RateLimiter // optional
+	PerAuthority *RateLimiter // optional
+	// ClientIP returns the key used for PerIP, defaults to the remote address
+	ClientIP func(r *http.Request) string
+	// Limited answers rejected requests, defaults to 429
+	Limited http.Handler
+}
+
+// Middleware returns a handler passing requests within the limits to next
+func (c *CallbackRateLimit) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.allow(r) {
+			if c.Limited != nil {
+				c.Limited.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CallbackRateLimit) allow(r *http.Request) bool {
+	if c.PerIP != nil {
+		ip := ""
+		if c.ClientIP != nil {
+			ip = c.ClientIP(r)
+		} else if addr, ok := parseAddr(r.RemoteAddr); ok {
+			ip = addr.String()
+		}
+		if !c.PerIP.Allow(ip) {
+			return false
+		}
+	}
+
+	if c.PerAuthority != nil {
+		if authority := r.URL.Query().Get(CallbackAuthorityParam); authority != "" && !c.PerAuthority.Allow(authority) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,109 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(20, 2)
+
+	if !limiter.Allow("a") || !limiter.Allow("a") {
+		t.Error("Expected burst to be allowed")
+	}
+	if limiter.Allow("a") {
+		t.Error("Expected request over the burst to be rejected")
+	}
+	if !limiter.Allow("b") {
+		t.Error("Expected other keys to have their own bucket")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !limiter.Allow("a") {
+		t.Error("Expected bucket to refill")
+	}
+}
+
+func TestCallbackRateLimit(t *testing.T) {
+	limit := &CallbackRateLimit{
+		PerIP:        NewRateLimiter(0.001, 3),
+		PerAuthority: NewRateLimiter(0.001, 1),
+	}
+	handler := limit.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/callback?Authority=A1&Status=OK", http.StatusNoContent},
+		{"/callback?Authority=A1&Status=OK", http.StatusTooManyRequests},
+		{"/callback?Authority=A2&Status=OK", http.StatusNoContent},
+		{"/callback?Authority=A3&Status=OK", http.StatusTooManyRequests},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.target, nil)
+		req.RemoteAddr = "192.0.2.1:4000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter(100, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, "merchant-1"); err != nil {
+			t.Fatalf("Failed to wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Expected the third token after about 20ms, got it after %s", elapsed)
+	}
+
+	limiter = NewRateLimiter(0.001, 1)
+	limiter.Allow("merchant-1")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "merchant-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context deadline, got %v", err)
+	}
+}
+
+func TestClientRateLimiterPerMerchant(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 2)
+	busy := newStubClient(t, map[string]string{"inquiry.json": fixtures.InquiryPaid})
+	busy.MerchantID = "busy"
+	busy.RateLimiter = limiter
+	other := newStubClient(t, map[string]string{"inquiry.json": fixtures.InquiryPaid})
+	other.MerchantID = "other"
+	other.RateLimiter = limiter
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if _, err := busy.InquirePayment(ctx, "A1"); err != nil {
+			t.Fatalf("Failed to inquire within the burst: %v", err)
+		}
+	}
+	if _, err := busy.InquirePayment(ctx, "A1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the busy merchant to wait out its context, got %v", err)
+	}
+
+	if _, err := other.InquirePayment(context.Background(), "A1"); err != nil {
+		t.Errorf("Expected the other merchant unaffected, got %v", err)
+	}
+}
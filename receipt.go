@@ -0,0 +1,148 @@
+package zarinpalgo
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// Receipt is the record of a verified payment, assembled from its session and verification
+type Receipt struct {
+	Authority   string    `json:"authority"`
+	OrderID     string    `json:"order_id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Amount      int       `json:"amount"`
+	Currency    Currency  `json:"currency,omitempty"`
+	Fee         int       `json:"fee"`
+	FeeType     string    `json:"fee_type,omitempty"`
+	RefID       int       `json:"ref_id"`
+	CardPan     string    `json:"card_pan"` // masked card number
+	CreatedAt   time.Time `json:"created_at"`
+	VerifiedAt  time.Time `json:"verified_at"`
+
+	CreatedAtJalali  string `json:"created_at_jalali"`  // CreatedAt in Iran as 1403/02/23 14:00:00
+	VerifiedAtJalali string `json:"verified_at_jalali"` // VerifiedAt in Iran as 1403/02/23 14:00:00
+}
+
+// NewReceipt returns the receipt of a session verified now with the given response
+func NewReceipt(session PaymentSession, verification PaymentVerificationResponse) Receipt {
+	r := newReceipt(session, time.Now())
+	r.Fee = verification.Fee
+	r.FeeType = verification.FeeType
+	r.RefID = verification.RefID
+	r.CardPan = maskCardPan(verification.CardPan)
+	return r
+}
+
+// ReceiptFromStatus returns the receipt of a session verified now with Verify, the fee is not
+// known to PaymentStatus and left at zero
+func ReceiptFromStatus(session PaymentSession, status PaymentStatus) Receipt {
+	r := newReceipt(session, time.Now())
+	r.RefID = status.RefID
+	r.CardPan = maskCardPan(status.CardPan)
+	return r
+}
+
+func newReceipt(session PaymentSession, verifiedAt time.Time) (r Receipt) {
+	r = Receipt{
+		Authority:        session.Authority,
+		OrderID:          session.OrderID,
+		Amount:           session.Amount,
+		Currency:         session.Currency,
+		CreatedAt:        session.CreatedAt,
+		VerifiedAt:       verifiedAt,
+		CreatedAtJalali:  FormatJalali(session.CreatedAt.In(IranLocation)),
+		VerifiedAtJalali: FormatJalali(verifiedAt.In(IranLocation)),
+	}
+	if session.Params != nil {
+		r.Description = session.Params.Description
+	}
+	return
+}
+
+// CurrencyLabel returns the name of the receipt currency in the language, Rials by default
+func (r Receipt) CurrencyLabel(lang string) string {
+	labels := receiptLabels[lang]
+	if labels == nil {
+		labels = receiptLabels["en"]
+	}
+	if r.Currency == CurrencyToman {
+		return labels["toman"]
+	}
+	return labels["currency"]
+}
+
+// ReceiptOptions customizes the receipt rendered by RenderReceiptHTML
+type ReceiptOptions struct {
+	Lang     string // "fa" (default) for a right-to-left Persian receipt with Jalali dates, or "en"
+	ShopName string
+	Template *template.Template // replaces the built-in template, executed with a ReceiptData
+}
+
+// ReceiptData is the data receipt templates are executed with
+type ReceiptData struct {
+	Receipt    Receipt
+	Lang       string
+	Dir        string
+	ShopName   string
+	Amount     string // formatted amount
+	Fee        string // formatted fee
+	Currency   string
+	CreatedAt  string // Jalali in Persian receipts, Gregorian otherwise
+	VerifiedAt string
+	Labels     map[string]string
+}
+
+var receiptTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}" dir="{{.Dir}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Labels.receipt}} {{.Receipt.RefID}}</title>
+<style>body{font-family:Tahoma,sans-serif;color:#222}.receipt{max-width:560px;margin:24px auto;border:1px solid #ccc;padding:24px}h1{font-size:1.2em;border-bottom:2px solid #222;padding-bottom:8px}td{padding:6px 0;border-bottom:1px dotted #ccc}td+td{text-align:end;direction:ltr}table{width:100%;border-collapse:collapse}@media print{.receipt{border:0}}</style>
+</head>
+<body>
+<div class="receipt">
+<h1>{{if .ShopName}}{{.ShopName}} - {{end}}{{.Labels.receipt}}</h1>
+<table>
+<tr><td>{{.Labels.ref_id}}</td><td>{{.Receipt.RefID}}</td></tr>
+{{if .Receipt.OrderID}}<tr><td>{{.Labels.order_id}}</td><td>{{.Receipt.OrderID}}</td></tr>{{end}}
+<tr><td>{{.Labels.amount}}</td><td>{{.Amount}} {{.Currency}}</td></tr>
+{{if .Receipt.Fee}}<tr><td>{{.Labels.fee}}</td><td>{{.Fee}} {{.Currency}}</td></tr>{{end}}
+{{if .Receipt.CardPan}}<tr><td>{{.Labels.card}}</td><td>{{.Receipt.CardPan}}</td></tr>{{end}}
+{{if .Receipt.Description}}<tr><td>{{.Labels.description}}</td><td>{{.Receipt.Description}}</td></tr>{{end}}
+<tr><td>{{.Labels.authority}}</td><td>{{.Receipt.Authority}}</td></tr>
+<tr><td>{{.Labels.created_at}}</td><td>{{.CreatedAt}}</td></tr>
+<tr><td>{{.Labels.verified_at}}</td><td>{{.VerifiedAt}}</td></tr>
+</table>
+</div>
+</body>
+</html>
+`))
+
+// RenderReceiptHTML writes a printable HTML receipt
+func RenderReceiptHTML(w io.Writer, receipt Receipt, opts ReceiptOptions) error {
+	data := ReceiptData{
+		Receipt:    receipt,
+		Lang:       "fa",
+		Dir:        "rtl",
+		ShopName:   opts.ShopName,
+		Amount:     FormatNumber(receipt.Amount),
+		Fee:        FormatNumber(receipt.Fee),
+		CreatedAt:  receipt.CreatedAtJalali,
+		VerifiedAt: receipt.VerifiedAtJalali,
+	}
+	if opts.Lang == "en" {
+		data.Lang = "en"
+		data.Dir = "ltr"
+		data.CreatedAt = receipt.CreatedAt.Format("2006-01-02 15:04:05")
+		data.VerifiedAt = receipt.VerifiedAt.Format("2006-01-02 15:04:05")
+	}
+	data.Labels = receiptLabels[data.Lang]
+	data.Currency = receipt.CurrencyLabel(data.Lang)
+
+	tmpl := receiptTemplate
+	if opts.Template != nil {
+		tmpl = opts.Template
+	}
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,134 @@
+package zarinpalgo
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ReceiptPageOptions customizes the page rendered by RenderReceiptPage
+type ReceiptPageOptions struct {
+	Lang      string // "en" (default) or "fa" for a right-to-left Persian page
+	ShopName  string
+	ReturnURL string             // link back to the shop
+	Currency  Currency           // of the amount of the status, Rials when empty
+	Template  *template.Template // replaces the built-in template, executed with a ReceiptPageData
+}
+
+// ReceiptPageData is the data receipt templates are executed with
+type ReceiptPageData struct {
+	Status    PaymentStatus
+	Lang      string
+	Dir       string
+	ShopName  string
+	ReturnURL string
+	Amount    string // formatted amount
+	Currency  string // label of the currency of the amount
+	CardPan   string // masked card number
+	Labels    map[string]string
+}
+
+var receiptLabels = map[string]map[string]string{
+	"en": {
+		"success":     "Payment successful",
+		"failure":     "Payment failed",
+		"ref_id":      "Reference ID",
+		"amount":      "Amount",
+		"currency":    "Rials",
+		"toman":       "Tomans",
+		"card":        "Card",
+		"message":     "Message",
+		"return":      "Return to shop",
+		"receipt":     "Payment receipt",
+		"order_id":    "Order",
+		"fee":         "Fee",
+		"description": "Description",
+		"authority":   "Authority",
+		"created_at":  "Created at",
+		"verified_at": "Paid at",
+	},
+	"fa": {
+		"success":     "پرداخت موفق",
+		"failure":     "پرداخت ناموفق",
+		"ref_id":      "کد پیگیری",
+		"amount":      "مبلغ",
+		"currency":    "ریال",
+		"toman":       "تومان",
+		"card":        "کارت",
+		"message":     "پیام",
+		"return":      "بازگشت به فروشگاه",
+		"receipt":     "رسید پرداخت",
+		"order_id":    "شماره سفارش",
+		"fee":         "کارمزد",
+		"description": "توضیحات",
+		"authority":   "شناسه پرداخت",
+		"created_at":  "تاریخ ایجاد",
+		"verified_at": "تاریخ پرداخت",
+	},
+}
+
+var receiptPageTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}" dir="{{.Dir}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Status.IsSuccessful}}{{.Labels.success}}{{else}}{{.Labels.failure}}{{end}}</title>
+<style>body{font-family:Tahoma,sans-serif;background:#f5f5f5;color:#333}.receipt{max-width:420px;margin:10vh auto;background:#fff;border-radius:8px;padding:24px;box-shadow:0 1px 4px rgba(0,0,0,.1)}h1{font-size:1.3em}.ok{color:#198754}.nok{color:#dc3545}td{padding:6px 0}td+td{text-align:end;direction:ltr}table{width:100%}</style>
+</head>
+<body>
+<div class="receipt">
+{{if .ShopName}}<h2>{{.ShopName}}</h2>{{end}}
+{{if .Status.IsSuccessful}}<h1 class="ok">{{.Labels.success}}</h1>{{else}}<h1 class="nok">{{.Labels.failure}}</h1>{{end}}
+<table>
+{{if .Status.IsSuccessful}}<tr><td>{{.Labels.ref_id}}</td><td>{{.Status.RefID}}</td></tr>{{end}}
+{{if .Status.Amount}}<tr><td>{{.Labels.amount}}</td><td>{{.Amount}} {{.Currency}}</td></tr>{{end}}
+{{if .CardPan}}<tr><td>{{.Labels.card}}</td><td>{{.CardPan}}</td></tr>{{end}}
+{{if not .Status.IsSuccessful}}<tr><td>{{.Labels.message}}</td><td>{{.Status.Message}}</td></tr>{{end}}
+</table>
+{{if .ReturnURL}}<p><a href="{{.ReturnURL}}">{{.Labels.return}}</a></p>{{end}}
+</div>
+</body>
+</html>
+`))
+
+// RenderReceiptPage writes an HTML receipt page for the payment status
+func RenderReceiptPage(w io.Writer, status PaymentStatus, opts ReceiptPageOptions) error {
+	data := ReceiptPageData{
+		Status:    status,
+		Lang:      "en",
+		Dir:       "ltr",
+		ShopName:  opts.ShopName,
+		ReturnURL: opts.ReturnURL,
+		Amount:    FormatNumber(status.Amount),
+		CardPan:   maskCardPan(status.CardPan),
+	}
+	if opts.Lang == "fa" {
+		data.Lang = "fa"
+		data.Dir = "rtl"
+	}
+	data.Labels = receiptLabels[data.Lang]
+	data.Currency = Receipt{Currency: opts.Currency}.CurrencyLabel(data.Lang)
+
+	tmpl := receiptPageTemplate
+	if opts.Template != nil {
+		tmpl = opts.Template
+	}
+	return tmpl.Execute(w, data)
+}
+
+// WriteReceiptPage responds with the receipt page of the payment status
+func WriteReceiptPage(w http.ResponseWriter, status PaymentStatus, opts ReceiptPageOptions) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	return RenderReceiptPage(w, status, opts)
+}
+
+// maskCardPan masks all but the first six and last four digits of a card number,
+// numbers already masked by Zarinpal are returned unchanged
+func maskCardPan(pan string) string {
+	if strings.Contains(pan, "*") || len(pan) < 12 {
+		return pan
+	}
+	return pan[:6] + strings.Repeat("*", len(pan)-10) + pan[len(pan)-4:]
+}
@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestRenderReceiptPage(t *testing.T) {
+	status := PaymentStatus{
+		Authority:    "A1",
+		IsSuccessful: true,
+		RefID:        201,
+		Amount:       1250000,
+		CardPan:      "6037991234565995",
+		Message:      "Verified",
+	}
+
+	var buf bytes.Buffer
+	if err := RenderReceiptPage(&buf, status, ReceiptPageOptions{Lang: "fa", ShopName: "فروشگاه"}); err != nil {
+		t.Fatalf("Failed to render receipt page: %v", err)
+	}
+
+	body := buf.String()
+	for _, expected := range []string{`dir="rtl"`, "پرداخت موفق", "1,250,000", "603799******5995", "201"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected receipt page to contain %q", expected)
+		}
+	}
+	if strings.Contains(body, "6037991234565995") {
+		t.Error("Expected card number to be masked")
+	}
+	if !strings.Contains(body, "1,250,000 ریال") {
+		t.Error("Expected the amount in Rials by default")
+	}
+
+	buf.Reset()
+	if err := RenderReceiptPage(&buf, status, ReceiptPageOptions{Currency: CurrencyToman}); err != nil {
+		t.Fatalf("Failed to render receipt page: %v", err)
+	}
+	if body := buf.String(); !strings.Contains(body, "1,250,000 Tomans") {
+		t.Errorf("Expected the amount in Tomans, got %s", body)
+	}
+}
+
+func TestRenderReceiptPageCustomTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("custom").Parse(`{{.Status.Authority}} {{.Labels.failure}}`))
+
+	var buf bytes.Buffer
+	if err := RenderReceiptPage(&buf, PaymentStatus{Authority: "A1"}, ReceiptPageOptions{Template: tmpl}); err != nil {
+		t.Fatalf("Failed to render receipt page: %v", err)
+	}
+	if buf.String() != "A1 Payment failed" {
+		t.Errorf("Unexpected custom template output %q", buf.String())
+	}
+}
@@ -0,0 +1,53 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderReceiptHTML(t *testing.T) {
+	session := PaymentSession{
+		Authority: "A1",
+		Amount:    1500000,
+		Currency:  CurrencyToman,
+		OrderID:   "order-1",
+		CreatedAt: time.Date(2024, 5, 12, 10, 30, 0, 0, time.UTC),
+		Params:    &PaymentParams{Description: "خرید اشتراک"},
+	}
+	receipt := NewReceipt(session, PaymentVerificationResponse{Code: 100, RefID: 201, Fee: 15000, CardPan: "5022291234565995"})
+
+	if receipt.CardPan != "502229******5995" {
+		t.Errorf("Expected the card number to be masked, got %s", receipt.CardPan)
+	}
+	if receipt.Description != "خرید اشتراک" {
+		t.Errorf("Expected the description of the payment, got %q", receipt.Description)
+	}
+	if receipt.CreatedAtJalali != "1403/02/23 14:00:00" {
+		t.Errorf("Expected the Jalali creation date 1403/02/23 14:00:00, got %s", receipt.CreatedAtJalali)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderReceiptHTML(&buf, receipt, ReceiptOptions{ShopName: "فروشگاه"}); err != nil {
+		t.Fatalf("Failed to render receipt: %v", err)
+	}
+	page := buf.String()
+	for _, expected := range []string{`dir="rtl"`, "رسید پرداخت", "1,500,000 تومان", "15,000 تومان", "1403/02/23 14:00:00", "order-1", "خرید اشتراک"} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the receipt to contain %q", expected)
+		}
+	}
+
+	buf.Reset()
+	RenderReceiptHTML(&buf, ReceiptFromStatus(session, PaymentStatus{RefID: 201}), ReceiptOptions{Lang: "en"})
+	page = buf.String()
+	for _, expected := range []string{`dir="ltr"`, "2024-05-12 10:30:00", "1,500,000 Tomans"} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the receipt to contain %q", expected)
+		}
+	}
+	if strings.Contains(page, "Fee") {
+		t.Error("Expected no fee row without a fee")
+	}
+}
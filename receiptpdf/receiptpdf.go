@@ -0,0 +1,72 @@
+// Package receiptpdf renders zarinpalgo receipts as PDF documents
+package receiptpdf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/go-pdf/fpdf"
+)
+
+// Options customizes the rendered receipt
+type Options struct {
+	ShopName string
+	// Font is a UTF-8 TrueType font used instead of the built-in Helvetica, needed when the
+	// shop name or description are not Latin. fpdf doesn't shape Arabic script, so labels stay English.
+	Font []byte
+}
+
+// Render writes an A5 PDF receipt with both Gregorian and Jalali dates
+func Render(w io.Writer, receipt zarinpalgo.Receipt, opts Options) error {
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle(fmt.Sprintf("Payment receipt %d", receipt.RefID), true)
+	pdf.SetCreator("zarinpalgo", false)
+
+	family := "Helvetica"
+	if len(opts.Font) > 0 {
+		family = "receipt"
+		pdf.AddUTF8FontFromBytes(family, "", opts.Font)
+		pdf.AddUTF8FontFromBytes(family, "B", opts.Font)
+	}
+	pdf.AddPage()
+
+	title := "Payment receipt"
+	if opts.ShopName != "" {
+		title = opts.ShopName + " - " + title
+	}
+	pdf.SetFont(family, "B", 14)
+	pdf.CellFormat(0, 10, title, "B", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	currency := receipt.CurrencyLabel("en")
+	rows := [][2]string{
+		{"Reference ID", strconv.Itoa(receipt.RefID)},
+		{"Order", receipt.OrderID},
+		{"Amount", zarinpalgo.FormatNumber(receipt.Amount) + " " + currency},
+		{"Fee", zarinpalgo.FormatNumber(receipt.Fee) + " " + currency},
+		{"Card", receipt.CardPan},
+		{"Description", receipt.Description},
+		{"Authority", receipt.Authority},
+		{"Created at", receipt.CreatedAt.Format("2006-01-02 15:04:05")},
+		{"Created at (Jalali)", receipt.CreatedAtJalali},
+		{"Paid at", receipt.VerifiedAt.Format("2006-01-02 15:04:05")},
+		{"Paid at (Jalali)", receipt.VerifiedAtJalali},
+	}
+
+	for _, row := range rows {
+		if row[1] == "" || (row[0] == "Fee" && receipt.Fee == 0) {
+			continue
+		}
+		pdf.SetFont(family, "B", 10)
+		pdf.CellFormat(45, 8, row[0], "B", 0, "L", false, 0, "")
+		pdf.SetFont(family, "", 10)
+		pdf.CellFormat(0, 8, row[1], "B", 1, "R", false, 0, "")
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
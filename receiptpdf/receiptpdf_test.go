@@ -0,0 +1,26 @@
+package receiptpdf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestRender(t *testing.T) {
+	receipt := zarinpalgo.NewReceipt(zarinpalgo.PaymentSession{
+		Authority: "A0000000000000000000000000000wwOGYpd",
+		Amount:    1500000,
+		OrderID:   "order-1",
+		CreatedAt: time.Now(),
+	}, zarinpalgo.PaymentVerificationResponse{Code: 100, RefID: 201, Fee: 15000, CardPan: "502229******5995"})
+
+	var buf bytes.Buffer
+	if err := Render(&buf, receipt, Options{ShopName: "Shop"}); err != nil {
+		t.Fatalf("Failed to render receipt: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("Expected a PDF document, got %q", buf.Bytes()[:16])
+	}
+}
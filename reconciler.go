@@ -0,0 +1,220 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Reconciler defaults
+const (
+	DefaultReconcileInterval = 5 * time.Minute
+	DefaultReconcileMinAge   = 10 * time.Minute
+)
+
+// Reconciler verifies payments whose callback never arrived, users who paid but didn't return
+// to the callback URL would otherwise have their payment reversed by the gateway.
+// It verifies the payments the gateway lists as unverified and, when a store is set,
+// inquires the pending sessions of the store and moves them to their outcome.
+type Reconciler struct {
+	Client Client
+	Store  PaymentStore // optional
+	// Outbox is optional, the events of the payments the reconciler resolves are added to it
+	Outbox Outbox
+
+	Interval time.Duration // time between runs, defaults to DefaultReconcileInterval
+	MinAge   time.Duration // pending sessions younger than this are left to their callback, defaults to DefaultReconcileMinAge
+
+	// OnResult is called with every payment the reconciler resolved
+	OnResult func(ctx context.Context, status PaymentStatus)
+	// OnError is called with the failures of single payments, they don't stop a run
+	OnError func(authority string, err error)
+}
+
+// NewReconciler creates a Reconciler with the default interval and minimum age
+func NewReconciler(client Client, store PaymentStore) *Reconciler {
+	return &Reconciler{
+		Client:   client,
+		Store:    store,
+		Interval: DefaultReconcileInterval,
+		MinAge:   DefaultReconcileMinAge,
+	}
+}
+
+// Run reconciles payments every interval until the context is done
+func (r *Reconciler) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.ReconcileOnce(ctx); err != nil {
+			r.fail("", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileOnce runs a single reconciliation and returns the payments it resolved.
+// Only failing to list payments is returned as an error.
+func (r *Reconciler) ReconcileOnce(ctx context.Context) (resolved []PaymentStatus, err error) {
+	unverified, err := r.Client.UnverifiedPayments(ctx)
+	if err != nil {
+		return
+	}
+
+	done := make(map[string]bool)
+	for _, payment := range unverified.Authorities {
+		amount := payment.Amount
+		if r.Store != nil {
+			// prefer the amount the session was created for over the one reported by the gateway
+			if stored, getErr := r.Store.GetByAuthority(ctx, payment.Authority); getErr == nil {
+				amount = stored.Amount
+			}
+		}
+
+		done[payment.Authority] = true
+		if status, ok := r.verify(ctx, payment.Authority, amount); ok {
+			resolved = append(resolved, status)
+		}
+	}
+
+	if r.Store == nil {
+		return
+	}
+
+	minAge := r.MinAge
+	if minAge <= 0 {
+		minAge = DefaultReconcileMinAge
+	}
+	pending, err := r.Store.ListPending(ctx, time.Now().Add(-minAge))
+	if err != nil {
+		return
+	}
+
+	for _, payment := range pending {
+		if done[payment.Authority] {
+			continue
+		}
+		if status, ok := r.reconcileSession(ctx, payment); ok {
+			resolved = append(resolved, status)
+		}
+	}
+	return
+}
+
+// reconcileSession inquires a pending session and moves it to its outcome
+func (r *Reconciler) reconcileSession(ctx context.Context, payment StoredPayment) (status PaymentStatus, ok bool) {
+	inquiry, err := r.Client.InquirePayment(ctx, payment.Authority)
+	if err != nil {
+		r.fail(payment.Authority, err)
+		return
+	}
+
+	var state PaymentState
+	switch inquiry.Status {
+	case InquiryStatusPaid, InquiryStatusVerified:
+		return r.verify(ctx, payment.Authority, payment.Amount)
+	case InquiryStatusFailed:
+		state = PaymentStateFailed
+	case InquiryStatusReversed:
+		state = PaymentStateReversed
+	case InquiryStatusInBank:
+		if !payment.IsExpired() {
+			return
+		}
+		state = PaymentStateExpired
+	default:
+		return
+	}
+
+	status = PaymentStatus{
+		Authority: payment.Authority,
+		Amount:    payment.Amount,
+		Reversed:  state == PaymentStateReversed,
+		Message:   "payment " + string(state),
+	}
+	if !r.addEvent(ctx, status) {
+		return
+	}
+	if err := advancePayment(ctx, r.Store, payment.Authority, 0, PaymentStatePending, state); err != nil {
+		r.fail(payment.Authority, err)
+		return
+	}
+
+	r.result(ctx, status)
+	return status, true
+}
+
+// verify verifies a paid payment and records the outcome in the store. Only payments the
+// gateway rejected fail, transient errors like too many attempts leave the payment pending for
+// the next run.
+func (r *Reconciler) verify(ctx context.Context, authority string, amount int) (status PaymentStatus, ok bool) {
+	status, err := r.Client.CheckPaymentStatus(ctx, amount, authority)
+	rejected := IsPaymentRejected(err)
+	if err != nil && !rejected {
+		r.fail(authority, err)
+		return
+	}
+
+	if !r.addEvent(ctx, status) {
+		return
+	}
+	if r.Store != nil {
+		err = recordCallback(ctx, r.Store, status, rejected)
+		if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+			r.fail(authority, err)
+			return
+		}
+	}
+
+	r.result(ctx, status)
+	return status, true
+}
+
+// addEvent adds the event of the resolved payment to the outbox before the payment is recorded,
+// a payment recorded as resolved isn't listed again, so a failed write must leave it pending
+func (r *Reconciler) addEvent(ctx context.Context, status PaymentStatus) bool {
+	if r.Outbox == nil {
+		return true
+	}
+	if err := r.Outbox.AddEvent(ctx, outboxEvent(status)); err != nil {
+		r.fail(status.Authority, err)
+		return false
+	}
+	return true
+}
+
+func (r *Reconciler) result(ctx context.Context, status PaymentStatus) {
+	if r.OnResult != nil {
+		if panicErr := RunHook("reconciler OnResult", func() { r.OnResult(ctx, status) }); panicErr != nil {
+			r.fail(status.Authority, panicErr)
+		}
+	}
+}
+
+func (r *Reconciler) fail(authority string, err error) {
+	if r.OnError != nil {
+		RunHook("reconciler OnError", func() { r.OnError(authority, err) })
+	}
+}
+
+// advancePayment moves a stored payment through the given states, skipping the transitions
+// that were already made or don't apply to its current state
+func advancePayment(ctx context.Context, store PaymentStore, authority string, refID int, states ...PaymentState) error {
+	for _, state := range states {
+		err := store.UpdateStatus(ctx, authority, state, refID)
+		if err != nil && !errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+	}
+	return nil
+}
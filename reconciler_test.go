@@ -0,0 +1,134 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestReconciler(t *testing.T) {
+	inquiries := map[string]string{
+		"A1": InquiryStatusPaid,
+		"A2": InquiryStatusInBank,
+		"A3": InquiryStatusInBank,
+		"A4": InquiryStatusFailed,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Authority string `json:"authority"`
+			Amount    int    `json:"amount"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "unVerified.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","authorities":[{"authority":"A5","amount":5000}]},"errors":[]}`))
+		case strings.HasSuffix(r.URL.Path, "inquiry.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","status":"` + inquiries[body.Authority] + `"},"errors":[]}`))
+		case strings.HasSuffix(r.URL.Path, "verify.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":` + map[int]string{10000: "201", 5000: "205"}[body.Amount] + `},"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	old := time.Now().Add(-time.Hour)
+	for _, session := range []PaymentSession{
+		{Authority: "A1", Amount: 10000, CreatedAt: old, ExpiresAt: time.Now()},
+		{Authority: "A2", Amount: 10000, CreatedAt: old, ExpiresAt: time.Now()},
+		{Authority: "A3", Amount: 10000, CreatedAt: old, ExpiresAt: time.Now().Add(time.Minute)},
+		{Authority: "A4", Amount: 10000, CreatedAt: old, ExpiresAt: time.Now()},
+		{Authority: "A6", Amount: 10000, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute)},
+	} {
+		store.SaveSession(ctx, session)
+	}
+
+	var results []PaymentStatus
+	reconciler := NewReconciler(zp, store)
+	outbox := NewMemoryOutbox()
+	reconciler.Outbox = outbox
+	reconciler.OnResult = func(ctx context.Context, status PaymentStatus) {
+		results = append(results, status)
+	}
+	reconciler.OnError = func(authority string, err error) {
+		t.Errorf("Unexpected error for %s: %v", authority, err)
+	}
+
+	resolved, err := reconciler.ReconcileOnce(ctx)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if len(resolved) != 4 || len(results) != 4 {
+		t.Errorf("Expected 4 resolved payments, got %+v", resolved)
+	}
+	if resolved[0].Authority != "A5" || resolved[0].RefID != 205 {
+		t.Errorf("Expected unverified payment to be verified, got %+v", resolved[0])
+	}
+	if events, _ := outbox.PendingEvents(ctx, 10); len(events) != 4 {
+		t.Errorf("Expected an event for each resolved payment, got %+v", events)
+	}
+
+	expected := map[string]PaymentState{
+		"A1": PaymentStateVerified,
+		"A2": PaymentStateExpired,
+		"A3": PaymentStateCreated,
+		"A4": PaymentStateFailed,
+		"A6": PaymentStateCreated,
+	}
+	for authority, state := range expected {
+		if payment, _ := store.GetByAuthority(ctx, authority); payment.State != state {
+			t.Errorf("Expected %s to be %s, got %s", authority, state, payment.State)
+		}
+	}
+}
+
+func TestReconcilerTransientErrors(t *testing.T) {
+	code := -12
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "unVerified.json"):
+			w.Write([]byte(fixtures.UnverifiedPaymentsEmpty))
+		case strings.HasSuffix(r.URL.Path, "inquiry.json"):
+			w.Write([]byte(fixtures.InquiryPaid))
+		case strings.HasSuffix(r.URL.Path, "verify.json"):
+			w.Write([]byte(fixtures.Error(code)))
+		}
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000, CreatedAt: time.Now().Add(-time.Hour)})
+
+	var errs []error
+	reconciler := NewReconciler(zp, store)
+	reconciler.OnError = func(authority string, err error) { errs = append(errs, err) }
+
+	resolved, _ := reconciler.ReconcileOnce(ctx)
+	if len(resolved) != 0 || len(errs) != 1 {
+		t.Errorf("Expected the transient error reported, got %+v %v", resolved, errs)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); !payment.State.Pending() {
+		t.Errorf("Expected the payment left pending, got %s", payment.State)
+	}
+
+	code = -51
+	if resolved, _ := reconciler.ReconcileOnce(ctx); len(resolved) != 1 {
+		t.Errorf("Expected the rejected payment resolved, got %+v", resolved)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateFailed {
+		t.Errorf("Expected the rejected payment failed, got %s", payment.State)
+	}
+}
@@ -0,0 +1,106 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Discrepancy kinds
+const (
+	DiscrepancyMissingLocally  = "missing_locally"  // paid on Zarinpal but not verified in the store
+	DiscrepancyMissingRemotely = "missing_remotely" // verified in the store but not paid on Zarinpal
+	DiscrepancyAmountMismatch  = "amount_mismatch"  // amounts of the two sides differ
+)
+
+// Discrepancy is a payment on which the store and Zarinpal disagree
+type Discrepancy struct {
+	Kind      string         `json:"kind"`
+	Authority string         `json:"authority"`
+	Local     *StoredPayment `json:"local,omitempty"`
+	Remote    *Transaction   `json:"remote,omitempty"`
+}
+
+// ReconciliationReport compares the payments of a time range in the store and on Zarinpal
+type ReconciliationReport struct {
+	From          time.Time     `json:"from"`
+	To            time.Time     `json:"to"`
+	Matched       int           `json:"matched"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// DiffTransactions compares the payments created in [from, to) that were verified in the store
+// with the ones paid on Zarinpal. Payments refunded since are still paid on Zarinpal, reversed
+// ones are expected reversed there. The store must implement PaymentLister.
+func DiffTransactions(ctx context.Context, store PaymentStore, source TransactionSource, from, to time.Time) (report ReconciliationReport, err error) {
+	lister, ok := store.(PaymentLister)
+	if !ok {
+		err = ErrListingUnsupported
+		return
+	}
+
+	created, err := lister.ListCreated(ctx, from, to)
+	if err != nil {
+		return
+	}
+	transactions, err := source.Transactions(ctx, from, to)
+	if err != nil {
+		return
+	}
+
+	local := make(map[string]StoredPayment)
+	for _, payment := range created {
+		switch payment.State {
+		case PaymentStateVerified, PaymentStateRefunded, PaymentStateReversed:
+			local[payment.Authority] = payment
+		}
+	}
+
+	report = ReconciliationReport{From: from, To: to}
+	for i := range transactions {
+		transaction := transactions[i]
+		payment, ok := local[transaction.Authority]
+		reversed := ok && payment.State == PaymentStateReversed
+		switch {
+		case transaction.Status == InquiryStatusReversed && !reversed:
+			// the customer wasn't charged, a payment verified in the store is missing remotely
+			continue
+		case transaction.Status != InquiryStatusReversed && !transaction.IsPaid():
+			continue
+		case !ok || (reversed && transaction.IsPaid()):
+			discrepancy := Discrepancy{Kind: DiscrepancyMissingLocally, Authority: transaction.Authority, Remote: &transaction}
+			if ok {
+				discrepancy.Local = &payment
+				delete(local, transaction.Authority)
+			}
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+			continue
+		}
+		delete(local, transaction.Authority)
+
+		if payment.Amount != transaction.Amount {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:      DiscrepancyAmountMismatch,
+				Authority: transaction.Authority,
+				Local:     &payment,
+				Remote:    &transaction,
+			})
+			continue
+		}
+		report.Matched++
+	}
+
+	for authority := range local {
+		payment := local[authority]
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:      DiscrepancyMissingRemotely,
+			Authority: authority,
+			Local:     &payment,
+		})
+	}
+
+	sort.Slice(report.Discrepancies, func(i, j int) bool {
+		return report.Discrepancies[i].Authority < report.Discrepancies[j].Authority
+	})
+	return
+}
@@ -0,0 +1,68 @@
+package zarinpalgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type staticTransactions []Transaction
+
+func (s staticTransactions) Transactions(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	return s, nil
+}
+
+func TestDiffTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	now := time.Now()
+
+	for authority, amount := range map[string]int{"A1": 10000, "A2": 20000, "A3": 30000, "A5": 50000} {
+		store.SaveSession(ctx, PaymentSession{Authority: authority, Amount: amount, CreatedAt: now})
+		store.UpdateStatus(ctx, authority, PaymentStatePending, 0)
+		store.UpdateStatus(ctx, authority, PaymentStateVerified, 0)
+	}
+
+	for authority, states := range map[string][]PaymentState{
+		"A6": {PaymentStatePending, PaymentStateVerified, PaymentStateRefunded},
+		"A7": {PaymentStatePending, PaymentStateReversed},
+		"A8": {PaymentStatePending, PaymentStateVerified, PaymentStateReversed},
+	} {
+		store.SaveSession(ctx, PaymentSession{Authority: authority, Amount: 60000, CreatedAt: now})
+		advancePayment(ctx, store, authority, 0, states...)
+	}
+
+	source := staticTransactions{
+		{Authority: "A1", Status: InquiryStatusVerified, Amount: 10000, CreatedAt: now},
+		{Authority: "A2", Status: InquiryStatusVerified, Amount: 25000, CreatedAt: now},
+		{Authority: "A4", Status: InquiryStatusPaid, Amount: 40000, CreatedAt: now},
+		{Authority: "A5", Status: InquiryStatusFailed, Amount: 50000, CreatedAt: now},
+		{Authority: "A6", Status: InquiryStatusVerified, Amount: 60000, CreatedAt: now},
+		{Authority: "A7", Status: InquiryStatusReversed, Amount: 60000, CreatedAt: now},
+		{Authority: "A8", Status: InquiryStatusVerified, Amount: 60000, CreatedAt: now},
+	}
+
+	report, err := DiffTransactions(ctx, store, source, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to diff transactions: %v", err)
+	}
+	if report.Matched != 3 {
+		t.Errorf("Expected the verified, refunded and reversed payments matched, got %d", report.Matched)
+	}
+
+	expected := map[string]string{
+		"A2": DiscrepancyAmountMismatch,
+		"A3": DiscrepancyMissingRemotely,
+		"A4": DiscrepancyMissingLocally,
+		"A5": DiscrepancyMissingRemotely,
+		"A8": DiscrepancyMissingLocally,
+	}
+	if len(report.Discrepancies) != len(expected) {
+		t.Errorf("Expected %d discrepancies, got %+v", len(expected), report.Discrepancies)
+	}
+	for _, discrepancy := range report.Discrepancies {
+		if expected[discrepancy.Authority] != discrepancy.Kind {
+			t.Errorf("Expected %s to be %s, got %s", discrepancy.Authority, expected[discrepancy.Authority], discrepancy.Kind)
+		}
+	}
+}
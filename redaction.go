@@ -0,0 +1,132 @@
+package zarinpalgo
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// Redaction is how a field is masked
+type Redaction int
+
+// Redaction constants
+const (
+	RedactKeepLast4 Redaction = iota // mask all but the last 4 characters, short values entirely
+	RedactMask                       // mask every character
+	RedactDrop                       // leave the field out
+)
+
+// RedactionPolicy is which fields are masked, and how, wherever payment data leaves the
+// library: traffic records, webhook events and logs. Fields are JSON field names, or slog
+// attribute keys, matched at any depth. Strings are masked with asterisks and other values,
+// like amounts, are replaced with null.
+type RedactionPolicy struct {
+	Fields map[string]Redaction
+}
+
+// DefaultRedactionPolicy masks credentials and personal data, it is used by traffic records
+// when the client has no policy
+var DefaultRedactionPolicy = RedactionPolicy{Fields: map[string]Redaction{
+	"merchant_id": RedactKeepLast4,
+	"mobile":      RedactKeepLast4,
+	"email":       RedactMask,
+	"card_hash":   RedactKeepLast4,
+	"iban":        RedactKeepLast4,
+}}
+
+// StrictRedactionPolicy returns the default policy also masking amounts, order IDs and
+// descriptions, for merchants treating them as sensitive
+func StrictRedactionPolicy() RedactionPolicy {
+	return DefaultRedactionPolicy.With(map[string]Redaction{
+		"amount":      RedactMask,
+		"fee":         RedactMask,
+		"order_id":    RedactKeepLast4,
+		"description": RedactMask,
+	})
+}
+
+// With returns a copy of the policy with the fields added or replaced
+func (p RedactionPolicy) With(fields map[string]Redaction) RedactionPolicy {
+	merged := make(map[string]Redaction, len(p.Fields)+len(fields))
+	for field, redaction := range p.Fields {
+		merged[field] = redaction
+	}
+	for field, redaction := range fields {
+		merged[field] = redaction
+	}
+	return RedactionPolicy{Fields: merged}
+}
+
+// RedactJSON returns the JSON document with the fields of the policy masked, other bodies are
+// returned as a JSON string
+func (p RedactionPolicy) RedactJSON(body []byte) json.RawMessage {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		quoted, _ := json.Marshal(string(body))
+		return quoted
+	}
+	redacted, err := json.Marshal(p.redactValue(document))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// Redact marshals the value to JSON with the fields of the policy masked
+func (p RedactionPolicy) Redact(value interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return p.RedactJSON(body), nil
+}
+
+func (p RedactionPolicy) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			redaction, ok := p.Fields[key]
+			switch {
+			case !ok:
+				v[key] = p.redactValue(field)
+			case redaction == RedactDrop:
+				delete(v, key)
+			default:
+				v[key] = redactField(field, redaction)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = p.redactValue(v[i])
+		}
+	}
+	return value
+}
+
+func redactField(value interface{}, redaction Redaction) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	if redaction == RedactMask || len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
+
+// ReplaceAttr masks the attributes of the policy, set it as the ReplaceAttr of
+// slog.HandlerOptions to redact logs the same way. Dropped attributes are left out.
+func (p RedactionPolicy) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	redaction, ok := p.Fields[a.Key]
+	if !ok {
+		return a
+	}
+	if redaction == RedactDrop {
+		return slog.Attr{}
+	}
+	value := a.Value.Resolve()
+	if value.Kind() != slog.KindString {
+		return slog.String(a.Key, "***")
+	}
+	return slog.Any(a.Key, redactField(value.String(), redaction))
+}
@@ -0,0 +1,48 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactionPolicy(t *testing.T) {
+	body := []byte(`{"amount":10000,"metadata":{"order_id":"order-000042","email":"a@b.c"},"wages":[{"iban":"IR062960000000100324200001","amount":10000}]}`)
+
+	redacted := string(DefaultRedactionPolicy.RedactJSON(body))
+	expected := `{"amount":10000,"metadata":{"email":"*****","order_id":"order-000042"},"wages":[{"amount":10000,"iban":"**********************0001"}]}`
+	if redacted != expected {
+		t.Errorf("Expected %s, got %s", expected, redacted)
+	}
+
+	redacted = string(StrictRedactionPolicy().RedactJSON(body))
+	expected = `{"amount":null,"metadata":{"email":"*****","order_id":"********0042"},"wages":[{"amount":null,"iban":"**********************0001"}]}`
+	if redacted != expected {
+		t.Errorf("Expected %s, got %s", expected, redacted)
+	}
+
+	policy := DefaultRedactionPolicy.With(map[string]Redaction{"wages": RedactDrop})
+	if redacted := string(policy.RedactJSON(body)); strings.Contains(redacted, "wages") {
+		t.Errorf("Expected the wages to be dropped, got %s", redacted)
+	}
+	if _, ok := DefaultRedactionPolicy.Fields["wages"]; ok {
+		t.Error("Expected With to leave the default policy unchanged")
+	}
+
+	if plain := string(DefaultRedactionPolicy.RedactJSON([]byte("bad gateway"))); plain != `"bad gateway"` {
+		t.Errorf("Expected a non JSON body as a string, got %s", plain)
+	}
+}
+
+func TestRedactionPolicyReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	policy := StrictRedactionPolicy()
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: policy.ReplaceAttr}))
+
+	logger.Info("verified", "authority", "A1", "mobile", "09121234567", "amount", 10000)
+	line := buf.String()
+	if !strings.Contains(line, "authority=A1") || !strings.Contains(line, "mobile=*******4567") || !strings.Contains(line, "amount=***") {
+		t.Errorf("Expected the mobile and amount to be masked, got %q", line)
+	}
+}
@@ -0,0 +1,71 @@
+package zarinpalgo
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// RedirectPageOptions customizes the page rendered by RenderRedirectPage
+type RedirectPageOptions struct {
+	Title     string // defaults to "Redirecting to payment"
+	Message   string // defaults to "You are being redirected to the payment page."
+	LinkText  string // defaults to "Continue to payment"
+	BrandName string
+	LogoURL   string
+	Lang      string // defaults to "en", use "fa" for a right-to-left page
+}
+
+var redirectPageTemplate = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}" dir="{{.Dir}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="0;url={{.URL}}">
+<title>{{.Title}}</title>
+<style>body{font-family:sans-serif;text-align:center;margin-top:15vh;color:#333}img{max-height:64px}a{color:#0a58ca}</style>
+</head>
+<body>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="{{.BrandName}}">{{end}}
+{{if .BrandName}}<h2>{{.BrandName}}</h2>{{end}}
+<p>{{.Message}}</p>
+<p><a href="{{.URL}}">{{.LinkText}}</a></p>
+<script>window.location.replace({{.URL}});</script>
+</body>
+</html>
+`))
+
+// RenderRedirectPage writes an HTML page that sends the browser to paymentURL right away,
+// with a fallback link for browsers that block automatic redirects
+func RenderRedirectPage(w io.Writer, paymentURL string, opts RedirectPageOptions) error {
+	data := struct {
+		RedirectPageOptions
+		URL string
+		Dir string
+	}{opts, paymentURL, "ltr"}
+
+	if data.Lang == "" {
+		data.Lang = "en"
+	}
+	if data.Lang == "fa" {
+		data.Dir = "rtl"
+	}
+	if data.Title == "" {
+		data.Title = "Redirecting to payment"
+	}
+	if data.Message == "" {
+		data.Message = "You are being redirected to the payment page."
+	}
+	if data.LinkText == "" {
+		data.LinkText = "Continue to payment"
+	}
+
+	return redirectPageTemplate.Execute(w, data)
+}
+
+// WriteRedirectPage responds with a page redirecting the user to the payment page of the authority
+func (z *Zarinpal) WriteRedirectPage(w http.ResponseWriter, authority string, opts RedirectPageOptions) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	return RenderRedirectPage(w, z.GetPaymentURL(authority), opts)
+}
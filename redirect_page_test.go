@@ -0,0 +1,37 @@
+package zarinpalgo
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteRedirectPage(t *testing.T) {
+	zp := NewWithMode("merchant-1", true)
+
+	rec := httptest.NewRecorder()
+	err := zp.WriteRedirectPage(rec, "A1", RedirectPageOptions{
+		BrandName: "My <Shop>",
+		Lang:      "fa",
+	})
+	if err != nil {
+		t.Fatalf("Failed to render redirect page: %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `content="0;url=https://sandbox.zarinpal.com/pg/StartPay/A1"`) {
+		t.Error("Expected meta refresh to the payment URL")
+	}
+	if !strings.Contains(body, `href="https://sandbox.zarinpal.com/pg/StartPay/A1"`) {
+		t.Error("Expected fallback link to the payment URL")
+	}
+	if !strings.Contains(body, `dir="rtl"`) {
+		t.Error("Expected right-to-left page for Persian")
+	}
+	if strings.Contains(body, "<Shop>") {
+		t.Error("Expected brand name to be escaped")
+	}
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type %s", rec.Header().Get("Content-Type"))
+	}
+}
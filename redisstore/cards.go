@@ -0,0 +1,46 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+var _ zarinpalgo.CardHistory = (*Store)(nil)
+
+// cardKey is the hash of the payments of the card, by authority
+func (s *Store) cardKey(cardHash string) string { return s.prefix + "card:" + cardHash }
+
+// RecordCard implements zarinpalgo.CardHistory
+func (s *Store) RecordCard(ctx context.Context, payment zarinpalgo.CardPayment) error {
+	data, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+	return s.client.HSetNX(ctx, s.cardKey(payment.CardHash), payment.Authority, data).Err()
+}
+
+// CardStats implements zarinpalgo.CardHistory
+func (s *Store) CardStats(ctx context.Context, cardHash string) (stats zarinpalgo.CardStats, err error) {
+	stats.CardHash = cardHash
+	payments, err := s.client.HGetAll(ctx, s.cardKey(cardHash)).Result()
+	if err != nil {
+		return
+	}
+	for _, data := range payments {
+		var payment zarinpalgo.CardPayment
+		if err = json.Unmarshal([]byte(data), &payment); err != nil {
+			return
+		}
+		stats.Payments++
+		stats.TotalAmount += payment.Amount
+		if stats.FirstPaidAt.IsZero() || payment.PaidAt.Before(stats.FirstPaidAt) {
+			stats.FirstPaidAt = payment.PaidAt
+		}
+		if payment.PaidAt.After(stats.LastPaidAt) {
+			stats.LastPaidAt = payment.PaidAt
+		}
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestCardHistory(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+	first := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, payment := range []zarinpalgo.CardPayment{
+		{CardHash: "H1", Authority: "A1", Amount: 10000, PaidAt: first.Add(time.Hour)},
+		{CardHash: "H1", Authority: "A2", Amount: 5000, PaidAt: first},
+		{CardHash: "H1", Authority: "A2", Amount: 5000, PaidAt: first},
+	} {
+		if err := store.RecordCard(ctx, payment); err != nil {
+			t.Fatalf("Failed to record card: %v", err)
+		}
+	}
+
+	stats, err := store.CardStats(ctx, "H1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.PaidBefore() || stats.Payments != 2 || stats.TotalAmount != 15000 || !stats.FirstPaidAt.Equal(first) || !stats.LastPaidAt.Equal(first.Add(time.Hour)) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats, err := store.CardStats(ctx, "H2"); err != nil || stats.PaidBefore() || stats.CardHash != "H2" {
+		t.Errorf("Expected an unknown card not to have paid, got %+v %v", stats, err)
+	}
+}
@@ -0,0 +1,24 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+var (
+	_ zarinpalgo.FulfillmentLog    = (*Store)(nil)
+	_ zarinpalgo.IdempotencyLocker = (*Store)(nil)
+)
+
+func (s *Store) fulfilledKey() string { return s.prefix + "fulfilled" }
+
+// IsFulfilled implements zarinpalgo.FulfillmentLog
+func (s *Store) IsFulfilled(ctx context.Context, authority string) (bool, error) {
+	return s.client.SIsMember(ctx, s.fulfilledKey(), authority).Result()
+}
+
+// MarkFulfilled implements zarinpalgo.FulfillmentLog, fulfilled authorities are kept in a set without expiry
+func (s *Store) MarkFulfilled(ctx context.Context, authority string) error {
+	return s.client.SAdd(ctx, s.fulfilledKey(), authority).Err()
+}
@@ -0,0 +1,19 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFulfillmentLog(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+
+	if fulfilled, err := store.IsFulfilled(ctx, "A1"); fulfilled || err != nil {
+		t.Errorf("Expected payment not to be fulfilled, got %v %v", fulfilled, err)
+	}
+	store.MarkFulfilled(ctx, "A1")
+	if fulfilled, err := store.IsFulfilled(ctx, "A1"); !fulfilled || err != nil {
+		t.Errorf("Expected payment to be fulfilled, got %v %v", fulfilled, err)
+	}
+}
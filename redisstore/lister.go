@@ -0,0 +1,81 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.PaymentLister = (*Store)(nil)
+
+// ListByOrderID implements zarinpalgo.PaymentLister
+func (s *Store) ListByOrderID(ctx context.Context, orderID string) (payments []zarinpalgo.StoredPayment, err error) {
+	authorities, err := s.client.SMembers(ctx, s.orderPaymentsKey(orderID)).Result()
+	if err != nil {
+		return
+	}
+
+	payments, err = s.getAll(ctx, authorities, "")
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+	})
+	return
+}
+
+// ListVerified implements zarinpalgo.PaymentLister, payments that expired are dropped from the index as they are found
+func (s *Store) ListVerified(ctx context.Context, since time.Time) (verified []zarinpalgo.StoredPayment, err error) {
+	authorities, err := s.client.ZRangeByScore(ctx, s.verifiedKey(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixNano(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return
+	}
+
+	payments, err := s.getAll(ctx, authorities, s.verifiedKey())
+	for _, payment := range payments {
+		if payment.State == zarinpalgo.PaymentStateVerified {
+			verified = append(verified, payment)
+		}
+	}
+	sort.Slice(verified, func(i, j int) bool {
+		return verified[i].CreatedAt.Before(verified[j].CreatedAt)
+	})
+	return
+}
+
+// ListCreated implements zarinpalgo.PaymentLister, payments that expired are dropped from the index as they are found
+func (s *Store) ListCreated(ctx context.Context, from, to time.Time) ([]zarinpalgo.StoredPayment, error) {
+	authorities, err := s.client.ZRangeByScore(ctx, s.createdKey(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixNano(), 10),
+		Max: "(" + strconv.FormatInt(to.UnixNano(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	// the index is ordered by creation already
+	return s.getAll(ctx, authorities, s.createdKey())
+}
+
+// getAll returns the payments that still exist, the missing ones are removed from the index when given
+func (s *Store) getAll(ctx context.Context, authorities []string, index string) (payments []zarinpalgo.StoredPayment, err error) {
+	for _, authority := range authorities {
+		payment, getErr := s.get(ctx, s.client, authority)
+		if errors.Is(getErr, zarinpalgo.ErrPaymentNotFound) {
+			if index != "" {
+				s.client.ZRem(ctx, index, authority)
+			}
+			continue
+		}
+		if getErr != nil {
+			return nil, getErr
+		}
+		payments = append(payments, payment)
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.Outbox = (*Store)(nil)
+
+func (s *Store) outboxKey() string     { return s.prefix + "outbox" }
+func (s *Store) outboxDataKey() string { return s.prefix + "outbox:data" }
+
+// AddEvent implements zarinpalgo.Outbox
+func (s *Store) AddEvent(ctx context.Context, event zarinpalgo.WebhookEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.outboxDataKey(), event.ID, data)
+		pipe.ZAdd(ctx, s.outboxKey(), redis.Z{Score: float64(event.CreatedAt.UnixNano()), Member: event.ID})
+		return nil
+	})
+	return err
+}
+
+// PendingEvents implements zarinpalgo.Outbox
+func (s *Store) PendingEvents(ctx context.Context, limit int) (events []zarinpalgo.WebhookEvent, err error) {
+	ids, err := s.client.ZRange(ctx, s.outboxKey(), 0, int64(limit)-1).Result()
+	if err != nil || len(ids) == 0 {
+		return
+	}
+
+	values, err := s.client.HMGet(ctx, s.outboxDataKey(), ids...).Result()
+	if err != nil {
+		return
+	}
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var event zarinpalgo.WebhookEvent
+		if err = json.Unmarshal([]byte(data), &event); err != nil {
+			return
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+// MarkPublished implements zarinpalgo.Outbox, published events are deleted
+func (s *Store) MarkPublished(ctx context.Context, id string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.outboxKey(), id)
+		pipe.HDel(ctx, s.outboxDataKey(), id)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,238 @@
+// Package redisstore implements zarinpalgo.PaymentStore over Redis, for deployments running several instances.
+// The store also implements zarinpalgo.ReplayGuard, zarinpalgo.RetryQueue, zarinpalgo.Outbox, zarinpalgo.SellerStore and zarinpalgo.CardHistory and offers idempotency keys.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults of a Store
+const (
+	DefaultPrefix    = "zarinpal:"
+	DefaultRetention = 24 * time.Hour
+)
+
+// maxUpdateAttempts bounds the retries of a status update racing with other updates
+const maxUpdateAttempts = 3
+
+// Store is a zarinpalgo.PaymentStore backed by Redis. Sessions expire with their authority plus
+// the retention, payments reaching a final state are kept for the retention from then on.
+type Store struct {
+	client    redis.UniversalClient
+	prefix    string
+	retention time.Duration
+}
+
+var (
+	_ zarinpalgo.PaymentStore = (*Store)(nil)
+	_ zarinpalgo.ReplayGuard  = (*Store)(nil)
+)
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithPrefix sets the prefix of the keys written by the store
+func WithPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// WithRetention sets how long payments are kept after their authority expired or their outcome is known
+func WithRetention(retention time.Duration) Option {
+	return func(s *Store) {
+		s.retention = retention
+	}
+}
+
+// New creates a Store using the given client
+func New(client redis.UniversalClient, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		prefix:    DefaultPrefix,
+		retention: DefaultRetention,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) paymentKey(authority string) string { return s.prefix + "payment:" + authority }
+func (s *Store) orderKey(orderID string) string     { return s.prefix + "order:" + orderID }
+func (s *Store) orderPaymentsKey(orderID string) string {
+	return s.prefix + "order-payments:" + orderID
+}
+func (s *Store) pendingKey() string               { return s.prefix + "pending" }
+func (s *Store) createdKey() string               { return s.prefix + "created" }
+func (s *Store) verifiedKey() string              { return s.prefix + "verified" }
+func (s *Store) seenKey(authority string) string  { return s.prefix + "seen:" + authority }
+func (s *Store) idempotencyKey(key string) string { return s.prefix + "idempotency:" + key }
+
+// SaveSession implements zarinpalgo.PaymentStore
+func (s *Store) SaveSession(ctx context.Context, session zarinpalgo.PaymentSession) error {
+	payment := zarinpalgo.StoredPayment{
+		PaymentSession: session,
+		State:          zarinpalgo.PaymentStateCreated,
+		UpdatedAt:      time.Now(),
+	}
+	data, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(session.ExpiresAt) + s.retention
+	if ttl <= 0 {
+		ttl = s.retention
+	}
+
+	saved, err := s.client.SetNX(ctx, s.paymentKey(session.Authority), data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return zarinpalgo.ErrDuplicatePayment
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if session.OrderID != "" {
+			pipe.Set(ctx, s.orderKey(session.OrderID), session.Authority, ttl)
+			pipe.SAdd(ctx, s.orderPaymentsKey(session.OrderID), session.Authority)
+			pipe.Expire(ctx, s.orderPaymentsKey(session.OrderID), ttl+s.retention)
+		}
+		pipe.ZAdd(ctx, s.pendingKey(), redis.Z{Score: float64(session.CreatedAt.UnixNano()), Member: session.Authority})
+		pipe.ZAdd(ctx, s.createdKey(), redis.Z{Score: float64(session.CreatedAt.UnixNano()), Member: session.Authority})
+		return nil
+	})
+	return err
+}
+
+// GetByAuthority implements zarinpalgo.PaymentStore
+func (s *Store) GetByAuthority(ctx context.Context, authority string) (payment zarinpalgo.StoredPayment, err error) {
+	return s.get(ctx, s.client, authority)
+}
+
+// GetByOrderID implements zarinpalgo.PaymentStore
+func (s *Store) GetByOrderID(ctx context.Context, orderID string) (payment zarinpalgo.StoredPayment, err error) {
+	authority, err := s.client.Get(ctx, s.orderKey(orderID)).Result()
+	if errors.Is(err, redis.Nil) {
+		err = zarinpalgo.ErrPaymentNotFound
+	}
+	if err != nil {
+		return
+	}
+	return s.get(ctx, s.client, authority)
+}
+
+// UpdateStatus implements zarinpalgo.PaymentStore, the payment is watched so concurrent updates
+// can't make an invalid transition
+func (s *Store) UpdateStatus(ctx context.Context, authority string, state zarinpalgo.PaymentState, refID int) error {
+	key := s.paymentKey(authority)
+	update := func(tx *redis.Tx) error {
+		payment, err := s.get(ctx, tx, authority)
+		if err != nil {
+			return err
+		}
+		if err := zarinpalgo.CheckTransition(authority, payment.State, state); err != nil {
+			return err
+		}
+
+		payment.State = state
+		if refID != 0 {
+			payment.RefID = refID
+		}
+		payment.UpdatedAt = time.Now()
+		data, err := json.Marshal(payment)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if state.Pending() {
+				pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+				return nil
+			}
+			pipe.Set(ctx, key, data, s.retention)
+			pipe.ZRem(ctx, s.pendingKey(), authority)
+			if state == zarinpalgo.PaymentStateVerified {
+				pipe.ZAdd(ctx, s.verifiedKey(), redis.Z{Score: float64(payment.UpdatedAt.UnixNano()), Member: authority})
+			}
+			if payment.OrderID != "" {
+				pipe.Expire(ctx, s.orderKey(payment.OrderID), s.retention)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.New("redisstore: payment " + authority + " is updated concurrently")
+}
+
+// ListPending implements zarinpalgo.PaymentStore, payments are ordered by creation time.
+// Expired sessions are dropped from the pending set as they are found.
+func (s *Store) ListPending(ctx context.Context, createdBefore time.Time) (pending []zarinpalgo.StoredPayment, err error) {
+	max := "+inf"
+	if !createdBefore.IsZero() {
+		max = "(" + strconv.FormatInt(createdBefore.UnixNano(), 10)
+	}
+
+	authorities, err := s.client.ZRangeByScore(ctx, s.pendingKey(), &redis.ZRangeBy{Min: "-inf", Max: max}).Result()
+	if err != nil {
+		return
+	}
+
+	for _, authority := range authorities {
+		payment, getErr := s.get(ctx, s.client, authority)
+		if errors.Is(getErr, zarinpalgo.ErrPaymentNotFound) {
+			s.client.ZRem(ctx, s.pendingKey(), authority)
+			continue
+		}
+		if getErr != nil {
+			return nil, getErr
+		}
+		if payment.State.Pending() {
+			pending = append(pending, payment)
+		}
+	}
+	return
+}
+
+// MarkProcessed implements zarinpalgo.ReplayGuard, authorities are remembered for the retention
+func (s *Store) MarkProcessed(ctx context.Context, authority string) (bool, error) {
+	return s.client.SetNX(ctx, s.seenKey(authority), 1, s.retention).Result()
+}
+
+// Reserve claims an idempotency key for ttl and reports whether it was free
+func (s *Store) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.idempotencyKey(key), 1, ttl).Result()
+}
+
+// Release frees an idempotency key claimed by Reserve
+func (s *Store) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.idempotencyKey(key)).Err()
+}
+
+func (s *Store) get(ctx context.Context, client redis.Cmdable, authority string) (payment zarinpalgo.StoredPayment, err error) {
+	data, err := client.Get(ctx, s.paymentKey(authority)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		err = zarinpalgo.ErrPaymentNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &payment)
+	return
+}
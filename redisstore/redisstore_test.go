@@ -0,0 +1,106 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+func newStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return New(client, WithPrefix("test:"), WithRetention(time.Hour)), server
+}
+
+func TestStore(t *testing.T) {
+	store, server := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	sessions := []zarinpalgo.PaymentSession{
+		{Authority: "A1", Amount: 10000, OrderID: "42", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Minute)},
+		{Authority: "A2", Amount: 20000, OrderID: "42", CreatedAt: now, ExpiresAt: now.Add(15 * time.Minute)},
+	}
+	for _, session := range sessions {
+		if err := store.SaveSession(ctx, session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+	if err := store.SaveSession(ctx, sessions[0]); !errors.Is(err, zarinpalgo.ErrDuplicatePayment) {
+		t.Errorf("Expected ErrDuplicatePayment, got %v", err)
+	}
+
+	if ttl := server.TTL("test:payment:A2"); ttl < time.Hour || ttl > time.Hour+15*time.Minute {
+		t.Errorf("Expected session to expire with its authority plus the retention, got %s", ttl)
+	}
+
+	payment, err := store.GetByOrderID(ctx, "42")
+	if err != nil || payment.Authority != "A2" || payment.State != zarinpalgo.PaymentStateCreated {
+		t.Errorf("Expected latest payment of the order, got %+v %v", payment, err)
+	}
+
+	pending, err := store.ListPending(ctx, now.Add(-time.Minute))
+	if err != nil || len(pending) != 1 || pending[0].Authority != "A1" {
+		t.Errorf("Expected the old payment to be pending, got %+v %v", pending, err)
+	}
+
+	if err := store.UpdateStatus(ctx, "A1", zarinpalgo.PaymentStateVerified, 201); !errors.Is(err, zarinpalgo.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+	for _, state := range []zarinpalgo.PaymentState{zarinpalgo.PaymentStatePending, zarinpalgo.PaymentStateVerified} {
+		if err := store.UpdateStatus(ctx, "A1", state, 201); err != nil {
+			t.Fatalf("Failed to move payment to %s: %v", state, err)
+		}
+	}
+
+	payment, _ = store.GetByAuthority(ctx, "A1")
+	if payment.State != zarinpalgo.PaymentStateVerified || payment.RefID != 201 {
+		t.Errorf("Unexpected payment: %+v", payment)
+	}
+	if ttl := server.TTL("test:payment:A1"); ttl != time.Hour {
+		t.Errorf("Expected verified payment to be kept for the retention, got %s", ttl)
+	}
+	if pending, _ := store.ListPending(ctx, time.Time{}); len(pending) != 1 || pending[0].Authority != "A2" {
+		t.Errorf("Expected one pending payment, got %+v", pending)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if _, err := store.GetByAuthority(ctx, "A2"); !errors.Is(err, zarinpalgo.ErrPaymentNotFound) {
+		t.Errorf("Expected expired session to be gone, got %v", err)
+	}
+	if pending, _ := store.ListPending(ctx, time.Time{}); len(pending) != 0 {
+		t.Errorf("Expected no pending payments, got %+v", pending)
+	}
+}
+
+func TestStoreReplayGuardAndIdempotency(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+
+	if first, err := store.MarkProcessed(ctx, "A1"); !first || err != nil {
+		t.Errorf("Expected first callback to be accepted, got %v %v", first, err)
+	}
+	if first, _ := store.MarkProcessed(ctx, "A1"); first {
+		t.Error("Expected replayed callback to be detected")
+	}
+
+	if ok, _ := store.Reserve(ctx, "order-42", time.Minute); !ok {
+		t.Error("Expected key to be free")
+	}
+	if ok, _ := store.Reserve(ctx, "order-42", time.Minute); ok {
+		t.Error("Expected key to be taken")
+	}
+	store.Release(ctx, "order-42")
+	if ok, _ := store.Reserve(ctx, "order-42", time.Minute); !ok {
+		t.Error("Expected released key to be free")
+	}
+}
@@ -0,0 +1,41 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.RefundRecordStore = (*Store)(nil)
+
+func (s *Store) refundsKey() string { return s.prefix + "refunds" }
+
+// SaveRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) SaveRefundRecord(ctx context.Context, record zarinpalgo.RefundRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.refundsKey(), record.Key, data).Err()
+}
+
+// GetRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) GetRefundRecord(ctx context.Context, key string) (record zarinpalgo.RefundRecord, err error) {
+	data, err := s.client.HGet(ctx, s.refundsKey(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		err = zarinpalgo.ErrRefundNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &record)
+	return
+}
+
+// DeleteRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) DeleteRefundRecord(ctx context.Context, key string) error {
+	return s.client.HDel(ctx, s.refundsKey(), key).Err()
+}
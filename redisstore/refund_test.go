@@ -0,0 +1,38 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestRefundRecords(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+
+	if _, err := store.GetRefundRecord(ctx, "K1"); !errors.Is(err, zarinpalgo.ErrRefundNotFound) {
+		t.Errorf("Expected ErrRefundNotFound, got %v", err)
+	}
+
+	record := zarinpalgo.RefundRecord{Key: "K1", SessionID: "S1", Amount: 20000, CreatedAt: time.Now()}
+	if err := store.SaveRefundRecord(ctx, record); err != nil {
+		t.Fatalf("Failed to save record: %v", err)
+	}
+	record.Refund = &zarinpalgo.Refund{ID: "R1", SessionID: "S1", Amount: 20000}
+	if err := store.SaveRefundRecord(ctx, record); err != nil {
+		t.Fatalf("Failed to replace record: %v", err)
+	}
+
+	got, err := store.GetRefundRecord(ctx, "K1")
+	if err != nil || got.SessionID != "S1" || got.Refund == nil || got.Refund.ID != "R1" {
+		t.Errorf("Expected the confirmed record, got %+v %v", got, err)
+	}
+
+	store.DeleteRefundRecord(ctx, "K1")
+	if _, err := store.GetRefundRecord(ctx, "K1"); !errors.Is(err, zarinpalgo.ErrRefundNotFound) {
+		t.Errorf("Expected the record to be deleted, got %v", err)
+	}
+}
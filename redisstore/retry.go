@@ -0,0 +1,70 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.RetryQueue = (*Store)(nil)
+
+func (s *Store) retriesKey() string   { return s.prefix + "retries" }
+func (s *Store) retryDataKey() string { return s.prefix + "retries:data" }
+
+// Enqueue implements zarinpalgo.RetryQueue
+func (s *Store) Enqueue(ctx context.Context, retry zarinpalgo.VerifyRetry) error {
+	data, err := json.Marshal(retry)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.retryDataKey(), retry.Authority, data)
+		pipe.ZAdd(ctx, s.retriesKey(), redis.Z{Score: float64(retry.NextAttempt.UnixNano()), Member: retry.Authority})
+		return nil
+	})
+	return err
+}
+
+// Due implements zarinpalgo.RetryQueue
+func (s *Store) Due(ctx context.Context, now time.Time) (due []zarinpalgo.VerifyRetry, err error) {
+	authorities, err := s.client.ZRangeByScore(ctx, s.retriesKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixNano(), 10),
+	}).Result()
+	if err != nil || len(authorities) == 0 {
+		return
+	}
+
+	values, err := s.client.HMGet(ctx, s.retryDataKey(), authorities...).Result()
+	if err != nil {
+		return
+	}
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var retry zarinpalgo.VerifyRetry
+		if err = json.Unmarshal([]byte(data), &retry); err != nil {
+			return
+		}
+		due = append(due, retry)
+	}
+	return
+}
+
+// Remove implements zarinpalgo.RetryQueue
+func (s *Store) Remove(ctx context.Context, authority string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.retriesKey(), authority)
+		pipe.HDel(ctx, s.retryDataKey(), authority)
+		return nil
+	})
+	return err
+}
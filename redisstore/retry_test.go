@@ -0,0 +1,31 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestRetryQueue(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	store.Enqueue(ctx, zarinpalgo.VerifyRetry{Authority: "A1", Amount: 10000, Attempts: 1, NextAttempt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour)})
+	store.Enqueue(ctx, zarinpalgo.VerifyRetry{Authority: "A2", Amount: 20000, Attempts: 1, NextAttempt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)})
+	if err := store.Enqueue(ctx, zarinpalgo.VerifyRetry{Authority: "A1", Amount: 10000, Attempts: 2, NextAttempt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour), LastError: "timeout"}); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	due, err := store.Due(ctx, now)
+	if err != nil || len(due) != 1 || due[0].Authority != "A1" || due[0].Attempts != 2 || due[0].LastError != "timeout" {
+		t.Errorf("Expected the requeued retry to be due, got %+v %v", due, err)
+	}
+
+	store.Remove(ctx, "A1")
+	if due, _ := store.Due(ctx, now.Add(time.Hour)); len(due) != 1 || due[0].Authority != "A2" {
+		t.Errorf("Expected only A2 to be queued, got %+v", due)
+	}
+}
@@ -0,0 +1,54 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.SellerStore = (*Store)(nil)
+
+func (s *Store) sellersKey() string { return s.prefix + "sellers" }
+
+// SaveSeller implements zarinpalgo.SellerStore
+func (s *Store) SaveSeller(ctx context.Context, seller zarinpalgo.Seller) error {
+	data, err := json.Marshal(seller)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.sellersKey(), seller.ID, data).Err()
+}
+
+// GetSeller implements zarinpalgo.SellerStore
+func (s *Store) GetSeller(ctx context.Context, id string) (seller zarinpalgo.Seller, err error) {
+	data, err := s.client.HGet(ctx, s.sellersKey(), id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		err = zarinpalgo.ErrSellerNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &seller)
+	return
+}
+
+// ListSellers implements zarinpalgo.SellerStore
+func (s *Store) ListSellers(ctx context.Context) (sellers []zarinpalgo.Seller, err error) {
+	all, err := s.client.HGetAll(ctx, s.sellersKey()).Result()
+	if err != nil {
+		return
+	}
+	for _, data := range all {
+		var seller zarinpalgo.Seller
+		if err = json.Unmarshal([]byte(data), &seller); err != nil {
+			return
+		}
+		sellers = append(sellers, seller)
+	}
+	sort.Slice(sellers, func(i, j int) bool { return sellers[i].ID < sellers[j].ID })
+	return
+}
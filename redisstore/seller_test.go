@@ -0,0 +1,42 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestSellers(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+
+	if _, err := store.GetSeller(ctx, "lamps"); !errors.Is(err, zarinpalgo.ErrSellerNotFound) {
+		t.Errorf("Expected ErrSellerNotFound, got %v", err)
+	}
+
+	registry := zarinpalgo.NewSellerRegistry(store)
+	lamps := zarinpalgo.Seller{ID: "lamps", Name: "Lamp Co", Iban: "IR270120000000004139853880", Commission: zarinpalgo.CommissionRule{BasisPoints: 1000}, Active: true}
+	desks := zarinpalgo.Seller{ID: "desks", Name: "Desk Co", Iban: "IR270170000000100324200001", Active: true}
+	for _, seller := range []zarinpalgo.Seller{lamps, desks} {
+		if err := registry.Register(ctx, seller); err != nil {
+			t.Fatalf("Failed to register seller: %v", err)
+		}
+	}
+	if err := registry.SetActive(ctx, "lamps", false); err != nil {
+		t.Fatalf("Failed to deactivate seller: %v", err)
+	}
+
+	seller, err := store.GetSeller(ctx, "lamps")
+	if err != nil || seller.Active || seller.Commission.BasisPoints != 1000 || seller.CreatedAt.IsZero() {
+		t.Errorf("Expected the deactivated seller, got %+v %v", seller, err)
+	}
+	sellers, err := store.ListSellers(ctx)
+	if err != nil || len(sellers) != 2 || sellers[0].ID != "desks" || sellers[1].ID != "lamps" {
+		t.Errorf("Expected the sellers ordered by ID, got %+v %v", sellers, err)
+	}
+	if seller, err := registry.ByIban(ctx, desks.Iban); err != nil || seller.ID != "desks" {
+		t.Errorf("Expected the desks seller, got %+v %v", seller, err)
+	}
+}
@@ -0,0 +1,35 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ zarinpalgo.ShortLinkStore = (*Store)(nil)
+
+func (s *Store) shortLinkKey(code string) string { return s.prefix + "link:" + code }
+
+// CreateShortLink implements zarinpalgo.ShortLinkStore, links expire with their key
+func (s *Store) CreateShortLink(ctx context.Context, code, target string, ttl time.Duration) error {
+	created, err := s.client.SetNX(ctx, s.shortLinkKey(code), target, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return zarinpalgo.ErrShortLinkTaken
+	}
+	return nil
+}
+
+// GetShortLink implements zarinpalgo.ShortLinkStore
+func (s *Store) GetShortLink(ctx context.Context, code string) (string, error) {
+	target, err := s.client.Get(ctx, s.shortLinkKey(code)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", zarinpalgo.ErrShortLinkNotFound
+	}
+	return target, err
+}
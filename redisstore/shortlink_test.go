@@ -0,0 +1,32 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestShortLinks(t *testing.T) {
+	store, server := newStore(t)
+	ctx := context.Background()
+
+	if err := store.CreateShortLink(ctx, "ABC1234", "https://payment.zarinpal.com/pg/StartPay/A1", time.Hour); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	if err := store.CreateShortLink(ctx, "ABC1234", "https://example.com", time.Hour); !errors.Is(err, zarinpalgo.ErrShortLinkTaken) {
+		t.Errorf("Expected ErrShortLinkTaken, got %v", err)
+	}
+
+	target, err := store.GetShortLink(ctx, "ABC1234")
+	if err != nil || target != "https://payment.zarinpal.com/pg/StartPay/A1" {
+		t.Errorf("Expected the StartPay URL, got %s %v", target, err)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if _, err := store.GetShortLink(ctx, "ABC1234"); !errors.Is(err, zarinpalgo.ErrShortLinkNotFound) {
+		t.Errorf("Expected the link to expire, got %v", err)
+	}
+}
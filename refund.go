@@ -0,0 +1,414 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRefundNotFound is returned by GetRefund for unknown refunds
+var ErrRefundNotFound = errors.New("refund not found")
+
+// Refund methods
+const (
+	RefundMethodPaya = "PAYA" // settled with the next PAYA cycle
+	RefundMethodCard = "CARD" // instant card to card transfer, for a higher fee
+)
+
+// Refund reasons
+const (
+	RefundReasonCustomerRequest       = "CUSTOMER_REQUEST"
+	RefundReasonDuplicateTransaction  = "DUPLICATE_TRANSACTION"
+	RefundReasonSuspiciousTransaction = "SUSPICIOUS_TRANSACTION"
+	RefundReasonOther                 = "OTHER"
+)
+
+// RefundStatus is the progress of a refund
+type RefundStatus string
+
+// RefundStatus constants
+const (
+	RefundStatusPending    RefundStatus = "PENDING"     // registered, waiting for the next settlement
+	RefundStatusInProgress RefundStatus = "IN_PROGRESS" // sent to the bank
+	RefundStatusCompleted  RefundStatus = "COMPLETED"   // paid to the customer
+	RefundStatusFailed     RefundStatus = "FAILED"      // rejected by the bank, the amount returns to the terminal
+	RefundStatusCanceled   RefundStatus = "CANCELED"
+)
+
+// IsFinal reports whether the refund won't change anymore
+func (s RefundStatus) IsFinal() bool {
+	return s == RefundStatusCompleted || s == RefundStatusFailed || s == RefundStatusCanceled
+}
+
+// DefaultRefundPollInterval is the time between the inquiries of WaitRefund
+const DefaultRefundPollInterval = time.Minute
+
+// RefundRequest describes the refund of a verified transaction
+type RefundRequest struct {
+	SessionID   string // ID of the transaction, as listed by Transactions
+	Amount      int    // in Rials, at most the amount of the transaction
+	Description string
+	Method      string // one of the RefundMethod constants, PAYA by default
+	Reason      string // one of the RefundReason constants, CUSTOMER_REQUEST by default
+}
+
+// Refund is a refund registered on Zarinpal
+type Refund struct {
+	ID           string       `json:"id"`
+	TerminalID   string       `json:"terminal_id"`
+	SessionID    string       `json:"session_id,omitempty"` // transaction the refund is for
+	Amount       int          `json:"amount"`
+	RefundAmount int          `json:"refund_amount,omitempty"` // paid to the customer, Amount less the fee of the method
+	Description  string       `json:"description,omitempty"`
+	Reason       string       `json:"reason,omitempty"`
+	Method       string       `json:"method,omitempty"`
+	Status       RefundStatus `json:"status"`
+	CreatedAt    time.Time    `json:"created_at"` // when the refund was requested, listings only
+	RefundedAt   time.Time    `json:"refunded_at"`
+	Session      *Transaction `json:"session,omitempty"` // the refunded transaction, listings only
+}
+
+const addRefundMutation = `mutation AddRefund($session_id: ID!, $amount: BigInteger!, $description: String, $method: InstantPayoutActionTypeEnum, $reason: RefundReasonEnum) {
+  resource: AddRefund(session_id: $session_id, amount: $amount, description: $description, method: $method, reason: $reason) {
+    terminal_id
+    id
+    amount
+    timeline {
+      refund_amount
+      refund_time
+      refund_status
+    }
+  }
+}`
+
+// Refund refunds a verified transaction. Refunds are only available through the reporting API,
+// the access token needs the refund permission.
+func (r *Reporting) Refund(ctx context.Context, req RefundRequest) (refund Refund, err error) {
+	if req.SessionID == "" || req.Amount <= 0 {
+		err = errors.New("refund needs a session ID and a positive amount")
+		return
+	}
+	if req.Method == "" {
+		req.Method = RefundMethodPaya
+	}
+	if req.Reason == "" {
+		req.Reason = RefundReasonCustomerRequest
+	}
+
+	var data struct {
+		Resource struct {
+			ID         string `json:"id"`
+			TerminalID string `json:"terminal_id"`
+			Amount     int    `json:"amount"`
+			Timeline   struct {
+				RefundTime   time.Time `json:"refund_time"`
+				RefundStatus string    `json:"refund_status"`
+			} `json:"timeline"`
+		} `json:"resource"`
+	}
+	err = r.query(ctx, r.Endpoints.url(EndpointRefund, r.URL, ""), addRefundMutation, map[string]interface{}{
+		"session_id":  req.SessionID,
+		"amount":      req.Amount,
+		"description": req.Description,
+		"method":      req.Method,
+		"reason":      req.Reason,
+	}, &data)
+	if err != nil {
+		return
+	}
+
+	refund = Refund{
+		ID:         data.Resource.ID,
+		TerminalID: data.Resource.TerminalID,
+		Amount:     data.Resource.Amount,
+		SessionID:  req.SessionID,
+		Status:     RefundStatus(data.Resource.Timeline.RefundStatus),
+		RefundedAt: data.Resource.Timeline.RefundTime,
+	}
+	return
+}
+
+// RefundFilter selects refunds, zero fields don't filter
+type RefundFilter struct {
+	ID        string
+	SessionID string
+	From      time.Time // refunded at or after
+	To        time.Time // refunded before
+
+	Statuses  []RefundStatus
+	MinAmount int // in Rials, inclusive
+	MaxAmount int // in Rials, inclusive
+}
+
+// Matches reports whether the refund passes the filter
+func (f RefundFilter) Matches(refund Refund) bool {
+	if (f.ID != "" && refund.ID != f.ID) || (f.SessionID != "" && refund.SessionID != f.SessionID) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsRefundStatus(f.Statuses, refund.Status) {
+		return false
+	}
+	if (f.MinAmount > 0 && refund.Amount < f.MinAmount) || (f.MaxAmount > 0 && refund.Amount > f.MaxAmount) {
+		return false
+	}
+	if !f.From.IsZero() && refund.RefundedAt.Before(f.From) {
+		return false
+	}
+	return f.To.IsZero() || refund.RefundedAt.Before(f.To)
+}
+
+func containsRefundStatus(statuses []RefundStatus, status RefundStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+const refundsQuery = `query Refunds($terminal_id: ID!, $id: ID, $session_id: ID, $limit: Int, $offset: Int) {
+  resource: Refunds(terminal_id: $terminal_id, id: $id, session_id: $session_id, limit: $limit, offset: $offset) {
+    id
+    terminal_id
+    session_id
+    amount
+    description
+    reason
+    method
+    created_at
+    timeline {
+      refund_amount
+      refund_time
+      refund_status
+    }
+    session {
+      id
+      authority
+      status
+      amount
+      fee
+      reference_id
+      card_pan
+      description
+      created_at
+    }
+  }
+}`
+
+// refundRecord is a refund as the reporting API answers it
+type refundRecord struct {
+	ID          string    `json:"id"`
+	TerminalID  string    `json:"terminal_id"`
+	SessionID   string    `json:"session_id"`
+	Amount      int       `json:"amount"`
+	Description string    `json:"description"`
+	Reason      string    `json:"reason"`
+	Method      string    `json:"method"`
+	CreatedAt   time.Time `json:"created_at"`
+	Timeline    struct {
+		RefundAmount int       `json:"refund_amount"`
+		RefundTime   time.Time `json:"refund_time"`
+		RefundStatus string    `json:"refund_status"`
+	} `json:"timeline"`
+	Session *Transaction `json:"session"`
+}
+
+// Refunds returns the refunds of the terminal passing the filter, newest first. The ID and
+// session are sent to the API, the time range is matched on the fetched refunds.
+func (r *Reporting) Refunds(ctx context.Context, filter RefundFilter) (refunds []Refund, err error) {
+	err = r.eachRefund(ctx, filter, func(refund Refund) bool {
+		refunds = append(refunds, refund)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// eachRefund calls fn with the refunds passing the filter, fetching pages as they are needed
+// until fn returns false or the last page. Refunds aren't listed in the order of their refund
+// time, so every page is fetched and filtered.
+func (r *Reporting) eachRefund(ctx context.Context, filter RefundFilter, fn func(Refund) bool) error {
+	maxPages := r.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	variables := map[string]interface{}{
+		"terminal_id": r.TerminalID,
+		"limit":       reportingPageSize,
+	}
+	if filter.ID != "" {
+		variables["id"] = filter.ID
+	}
+	if filter.SessionID != "" {
+		variables["session_id"] = filter.SessionID
+	}
+
+	// first IDs of the pages fetched so far, an API ignoring the offset would repeat one
+	seen := make(map[string]bool)
+	for page := 0; ; page++ {
+		if page == maxPages {
+			return fmt.Errorf("%w: stopped after %d pages", ErrTooManyPages, maxPages)
+		}
+		variables["offset"] = page * reportingPageSize
+
+		var data struct {
+			Resource []refundRecord `json:"resource"`
+		}
+		if err := r.Query(ctx, refundsQuery, variables, &data); err != nil {
+			return err
+		}
+		if len(data.Resource) > 0 {
+			first := data.Resource[0].ID
+			if seen[first] {
+				return fmt.Errorf("%w: refund %s", ErrPaginationLoop, first)
+			}
+			seen[first] = true
+		}
+
+		for _, record := range data.Resource {
+			refund := Refund{
+				ID:           record.ID,
+				TerminalID:   record.TerminalID,
+				SessionID:    record.SessionID,
+				Amount:       record.Amount,
+				RefundAmount: record.Timeline.RefundAmount,
+				Description:  record.Description,
+				Reason:       record.Reason,
+				Method:       record.Method,
+				Status:       RefundStatus(record.Timeline.RefundStatus),
+				CreatedAt:    record.CreatedAt,
+				RefundedAt:   record.Timeline.RefundTime,
+				Session:      record.Session,
+			}
+			if filter.Matches(refund) && !fn(refund) {
+				return nil
+			}
+		}
+
+		if len(data.Resource) < reportingPageSize {
+			return nil
+		}
+	}
+}
+
+// GetRefund returns the refund of the ID, or ErrRefundNotFound
+func (r *Reporting) GetRefund(ctx context.Context, id string) (Refund, error) {
+	refunds, err := r.Refunds(ctx, RefundFilter{ID: id})
+	if err != nil {
+		return Refund{}, err
+	}
+	if len(refunds) == 0 {
+		return Refund{}, fmt.Errorf("%w: %s", ErrRefundNotFound, id)
+	}
+	return refunds[0], nil
+}
+
+// WaitRefund inquires the refund every interval, DefaultRefundPollInterval when zero, until it
+// reaches a final status or the context is done. onChange is optional and called with every
+// status the refund is seen in.
+func (r *Reporting) WaitRefund(ctx context.Context, id string, interval time.Duration, onChange func(Refund)) (refund Refund, err error) {
+	if interval <= 0 {
+		interval = DefaultRefundPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last RefundStatus
+	for {
+		refund, err = r.GetRefund(ctx, id)
+		if err != nil {
+			return
+		}
+		if refund.Status != last && onChange != nil {
+			if panicErr := RunHook("WaitRefund onChange", func() { onChange(refund) }); panicErr != nil && r.OnHookPanic != nil {
+				r.OnHookPanic(ctx, panicErr)
+			}
+		}
+		last = refund.Status
+		if refund.Status.IsFinal() {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return refund, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefundedTransaction is a transaction along with its refunds, for reconciling refunds
+// against the payments they return
+type RefundedTransaction struct {
+	Transaction Transaction `json:"transaction"`
+	Refunds     []Refund    `json:"refunds"`
+	Refunded    int         `json:"refunded"` // amount of the refunds that weren't canceled or failed
+}
+
+// Remaining returns the amount of the transaction that can still be refunded
+func (t RefundedTransaction) Remaining() int {
+	return t.Transaction.Amount - t.Refunded
+}
+
+// JoinRefunds groups the refunds under the transactions they are for, in the order of the
+// transactions. Transactions without refunds are left out, refunds of transactions that aren't
+// listed are returned as orphans, in their own order.
+func JoinRefunds(transactions []Transaction, refunds []Refund) (joined []RefundedTransaction, orphans []Refund) {
+	bySession := make(map[string][]Refund)
+	for _, refund := range refunds {
+		bySession[refund.SessionID] = append(bySession[refund.SessionID], refund)
+	}
+
+	listed := make(map[string]bool)
+	for _, transaction := range transactions {
+		listed[transaction.ID] = true
+		sessionRefunds := bySession[transaction.ID]
+		if len(sessionRefunds) == 0 {
+			continue
+		}
+		refunded := RefundedTransaction{Transaction: transaction, Refunds: sessionRefunds}
+		for _, refund := range sessionRefunds {
+			if refund.Status != RefundStatusCanceled && refund.Status != RefundStatusFailed {
+				refunded.Refunded += refund.Amount
+			}
+		}
+		joined = append(joined, refunded)
+	}
+
+	for _, refund := range refunds {
+		if !listed[refund.SessionID] {
+			orphans = append(orphans, refund)
+		}
+	}
+	return
+}
+
+// RefundedTransactions returns the refunds passing the filter grouped under the transactions
+// they are for, as the API answers them along with the refunds. Refunds outside the filter
+// aren't counted in Refunded.
+func (r *Reporting) RefundedTransactions(ctx context.Context, filter RefundFilter) ([]RefundedTransaction, error) {
+	refunds, err := r.Refunds(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var transactions []Transaction
+	index := make(map[string]int)
+	for _, refund := range refunds {
+		i, seen := index[refund.SessionID]
+		if !seen {
+			i = len(transactions)
+			index[refund.SessionID] = i
+			transactions = append(transactions, Transaction{ID: refund.SessionID})
+		}
+		if refund.Session != nil {
+			transactions[i] = *refund.Session
+			transactions[i].ID = refund.SessionID
+		}
+	}
+	joined, _ := JoinRefunds(transactions, refunds)
+	return joined, nil
+}
@@ -0,0 +1,176 @@
+package zarinpalgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRefundInProgress is returned when the same refund is being issued by another call
+var ErrRefundInProgress = errors.New("refund is being issued")
+
+// RefundKey returns the idempotency key of the refund, the same for every request refunding the
+// same amount of the same session for the same reason
+func RefundKey(req RefundRequest) string {
+	reason := req.Reason
+	if reason == "" {
+		reason = RefundReasonCustomerRequest
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", req.SessionID, req.Amount, reason)))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefundRecord is a refund attempt kept by IdempotentRefunds, its refund is set once the
+// reporting API confirmed it
+type RefundRecord struct {
+	Key       string    `json:"key"`
+	SessionID string    `json:"session_id"`
+	Amount    int       `json:"amount"`
+	Refund    *Refund   `json:"refund,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RefundRecordStore durably keeps refund attempts by key, lookups of unknown keys return
+// ErrRefundNotFound
+type RefundRecordStore interface {
+	// SaveRefundRecord creates or replaces the record
+	SaveRefundRecord(ctx context.Context, record RefundRecord) error
+	GetRefundRecord(ctx context.Context, key string) (RefundRecord, error)
+	DeleteRefundRecord(ctx context.Context, key string) error
+}
+
+// IdempotentRefunds issues every refund at most once. The attempt is recorded before the
+// refund is sent, so when a request times out a retry looks the refund up on the reporting API
+// instead of issuing it again. Refunds the API rejected can be retried.
+type IdempotentRefunds struct {
+	reporting *Reporting
+	store     RefundRecordStore
+
+	// Locker keeps concurrent calls for the same refund from issuing it twice,
+	// it defaults to a lock local to the process
+	Locker IdempotencyLocker
+}
+
+// NewIdempotentRefunds creates an IdempotentRefunds recording attempts in the store
+func NewIdempotentRefunds(reporting *Reporting, store RefundRecordStore) *IdempotentRefunds {
+	return &IdempotentRefunds{
+		reporting: reporting,
+		store:     store,
+		Locker:    &memoryLocker{keys: make(map[string]time.Time)},
+	}
+}
+
+// Refund issues the refund, or returns the one already issued for the same request
+func (r *IdempotentRefunds) Refund(ctx context.Context, req RefundRequest) (refund Refund, err error) {
+	if req.SessionID == "" || req.Amount <= 0 {
+		// invalid requests fail before anything is recorded
+		return r.reporting.Refund(ctx, req)
+	}
+	key := RefundKey(req)
+	reserved, err := r.Locker.Reserve(ctx, "refund:"+key, idempotencyLockTTL)
+	if err != nil {
+		return
+	}
+	if !reserved {
+		err = ErrRefundInProgress
+		return
+	}
+	defer r.Locker.Release(context.WithoutCancel(ctx), "refund:"+key)
+
+	record, err := r.store.GetRefundRecord(ctx, key)
+	switch {
+	case err == nil && record.Refund != nil:
+		return *record.Refund, nil
+	case err == nil:
+		// an earlier attempt didn't get an answer, the refund may have been issued
+		if found, ok, findErr := r.find(ctx, record); findErr != nil {
+			return refund, findErr
+		} else if ok {
+			return found, r.confirm(ctx, record, found)
+		}
+	case errors.Is(err, ErrRefundNotFound):
+		record = RefundRecord{Key: key, SessionID: req.SessionID, Amount: req.Amount, CreatedAt: time.Now()}
+		if err = r.store.SaveRefundRecord(ctx, record); err != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	refund, err = r.reporting.Refund(ctx, req)
+	var graphQLErr *GraphQLError
+	if errors.As(err, &graphQLErr) {
+		// rejected, nothing was refunded
+		r.store.DeleteRefundRecord(context.WithoutCancel(ctx), key)
+		return
+	}
+	if err != nil {
+		return
+	}
+	return refund, r.confirm(ctx, record, refund)
+}
+
+// find looks for the refund of an unanswered attempt among the refunds of its session
+func (r *IdempotentRefunds) find(ctx context.Context, record RefundRecord) (refund Refund, ok bool, err error) {
+	// refund times come from the API clock, allow for some skew
+	refunds, err := r.reporting.Refunds(ctx, RefundFilter{SessionID: record.SessionID, From: record.CreatedAt.Add(-time.Minute)})
+	if err != nil {
+		return
+	}
+	for _, refund := range refunds {
+		if refund.Amount == record.Amount {
+			return refund, true, nil
+		}
+	}
+	return
+}
+
+func (r *IdempotentRefunds) confirm(ctx context.Context, record RefundRecord, refund Refund) error {
+	record.Refund = &refund
+	return r.store.SaveRefundRecord(context.WithoutCancel(ctx), record)
+}
+
+// MemoryRefundRecordStore is a RefundRecordStore keeping records in memory, it is safe for concurrent use
+type MemoryRefundRecordStore struct {
+	mu      sync.Mutex
+	records map[string]RefundRecord
+}
+
+// NewMemoryRefundRecordStore creates an empty MemoryRefundRecordStore
+func NewMemoryRefundRecordStore() *MemoryRefundRecordStore {
+	return &MemoryRefundRecordStore{records: make(map[string]RefundRecord)}
+}
+
+// SaveRefundRecord implements RefundRecordStore
+func (s *MemoryRefundRecordStore) SaveRefundRecord(ctx context.Context, record RefundRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+	return nil
+}
+
+// GetRefundRecord implements RefundRecordStore
+func (s *MemoryRefundRecordStore) GetRefundRecord(ctx context.Context, key string) (RefundRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return RefundRecord{}, ErrRefundNotFound
+	}
+	return record, nil
+}
+
+// DeleteRefundRecord implements RefundRecordStore
+func (s *MemoryRefundRecordStore) DeleteRefundRecord(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,152 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// refundAPI is a reporting API issuing refunds, its answer to AddRefund is set by the test
+type refundAPI struct {
+	answer  string // "ok", "lost" once the refund was issued or "reject"
+	issued  []string
+	refunds []string
+}
+
+func newRefundAPI(t *testing.T) (*refundAPI, *Reporting) {
+	t.Helper()
+	api := &refundAPI{answer: "ok"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if strings.Contains(body.Query, "Refunds(") {
+			w.Write([]byte(`{"data":{"resource":[` + strings.Join(api.refunds, ",") + `]}}`))
+			return
+		}
+		if api.answer == "reject" {
+			w.Write([]byte(`{"errors":[{"message":"session is not refundable"}]}`))
+			return
+		}
+
+		id := fmt.Sprintf("R%d", len(api.issued)+1)
+		api.issued = append(api.issued, id)
+		refund := fmt.Sprintf(`{"id":%q,"terminal_id":"terminal-1","session_id":%q,"amount":%v,"timeline":{"refund_time":%q,"refund_status":"PENDING"}}`,
+			id, body.Variables["session_id"], body.Variables["amount"], time.Now().Format(time.RFC3339))
+		api.refunds = append(api.refunds, refund)
+		if api.answer == "lost" {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte("gateway timeout"))
+			return
+		}
+		w.Write([]byte(`{"data":{"resource":` + refund + `}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	return api, reporting
+}
+
+func TestRefundKey(t *testing.T) {
+	key := RefundKey(RefundRequest{SessionID: "S1", Amount: 10000})
+	if key != RefundKey(RefundRequest{SessionID: "S1", Amount: 10000, Reason: RefundReasonCustomerRequest, Description: "again"}) {
+		t.Error("Expected the default reason and the description not to change the key")
+	}
+	if key == RefundKey(RefundRequest{SessionID: "S1", Amount: 5000}) || key == RefundKey(RefundRequest{SessionID: "S2", Amount: 10000}) {
+		t.Error("Expected other sessions and amounts to have other keys")
+	}
+}
+
+func TestIdempotentRefundsRetry(t *testing.T) {
+	api, reporting := newRefundAPI(t)
+	refunds := NewIdempotentRefunds(reporting, NewMemoryRefundRecordStore())
+	ctx := context.Background()
+	req := RefundRequest{SessionID: "S1", Amount: 10000}
+
+	first, err := refunds.Refund(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	second, err := refunds.Refund(ctx, req)
+	if err != nil || second.ID != first.ID {
+		t.Errorf("Expected refund %s again, got %+v %v", first.ID, second, err)
+	}
+	if len(api.issued) != 1 {
+		t.Errorf("Expected 1 refund to be issued, got %v", api.issued)
+	}
+
+	if _, err := refunds.Refund(ctx, RefundRequest{SessionID: "S1", Amount: 5000}); err != nil || len(api.issued) != 2 {
+		t.Errorf("Expected another amount to be refunded, got %v %v", api.issued, err)
+	}
+}
+
+func TestIdempotentRefundsLostAnswer(t *testing.T) {
+	api, reporting := newRefundAPI(t)
+	store := NewMemoryRefundRecordStore()
+	refunds := NewIdempotentRefunds(reporting, store)
+	ctx := context.Background()
+	req := RefundRequest{SessionID: "S1", Amount: 10000}
+
+	api.answer = "lost"
+	if _, err := refunds.Refund(ctx, req); err == nil {
+		t.Fatal("Expected the lost answer to fail the refund")
+	}
+	if record, err := store.GetRefundRecord(ctx, RefundKey(req)); err != nil || record.Refund != nil {
+		t.Errorf("Expected a pending record, got %+v %v", record, err)
+	}
+
+	api.answer = "ok"
+	refund, err := refunds.Refund(ctx, req)
+	if err != nil || refund.ID != "R1" {
+		t.Errorf("Expected the issued refund R1, got %+v %v", refund, err)
+	}
+	if len(api.issued) != 1 {
+		t.Errorf("Expected the refund not to be issued again, got %v", api.issued)
+	}
+	if record, _ := store.GetRefundRecord(ctx, RefundKey(req)); record.Refund == nil || record.Refund.ID != "R1" {
+		t.Errorf("Expected the record to be confirmed, got %+v", record)
+	}
+}
+
+func TestIdempotentRefundsRejected(t *testing.T) {
+	api, reporting := newRefundAPI(t)
+	store := NewMemoryRefundRecordStore()
+	refunds := NewIdempotentRefunds(reporting, store)
+	ctx := context.Background()
+	req := RefundRequest{SessionID: "S1", Amount: 10000}
+
+	api.answer = "reject"
+	var graphQLErr *GraphQLError
+	if _, err := refunds.Refund(ctx, req); !errors.As(err, &graphQLErr) {
+		t.Fatalf("Expected a GraphQLError, got %v", err)
+	}
+	if _, err := store.GetRefundRecord(ctx, RefundKey(req)); !errors.Is(err, ErrRefundNotFound) {
+		t.Errorf("Expected the record to be deleted, got %v", err)
+	}
+
+	api.answer = "ok"
+	if refund, err := refunds.Refund(ctx, req); err != nil || refund.ID != "R1" {
+		t.Errorf("Expected the refund to be retried, got %+v %v", refund, err)
+	}
+}
+
+func TestIdempotentRefundsInProgress(t *testing.T) {
+	_, reporting := newRefundAPI(t)
+	refunds := NewIdempotentRefunds(reporting, NewMemoryRefundRecordStore())
+	req := RefundRequest{SessionID: "S1", Amount: 10000}
+
+	refunds.Locker.Reserve(context.Background(), "refund:"+RefundKey(req), time.Minute)
+	if _, err := refunds.Refund(context.Background(), req); !errors.Is(err, ErrRefundInProgress) {
+		t.Errorf("Expected ErrRefundInProgress, got %v", err)
+	}
+}
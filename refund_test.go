@@ -0,0 +1,214 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportingRefund(t *testing.T) {
+	var variables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.Contains(body.Query, "AddRefund") {
+			t.Errorf("Expected the AddRefund mutation, got %s", body.Query)
+		}
+		variables = body.Variables
+		w.Write([]byte(`{"data":{"resource":{"terminal_id":"terminal-1","id":"R1","amount":10000,"timeline":{"refund_amount":10000,"refund_time":"2024-05-12T10:30:00+03:30","refund_status":"PENDING"}}}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+
+	refund, err := reporting.Refund(context.Background(), RefundRequest{SessionID: "S1", Amount: 10000})
+	if err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	if refund.ID != "R1" || refund.Status != "PENDING" || refund.RefundedAt.IsZero() {
+		t.Errorf("Unexpected refund %+v", refund)
+	}
+	if variables["session_id"] != "S1" || variables["method"] != RefundMethodPaya || variables["reason"] != RefundReasonCustomerRequest {
+		t.Errorf("Unexpected variables %v", variables)
+	}
+
+	if _, err := reporting.Refund(context.Background(), RefundRequest{SessionID: "S1"}); err == nil {
+		t.Error("Expected an error for a refund without amount")
+	}
+}
+
+// newRefundsServer answers the Refunds query with the refunds of the session, or of the ID,
+// moving the status of each refund along statuses on every inquiry
+func newRefundsServer(t *testing.T, statuses ...string) *Reporting {
+	t.Helper()
+	inquiries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.Contains(body.Query, "Refunds(") {
+			t.Errorf("Expected the Refunds query, got %s", body.Query)
+		}
+
+		status := statuses[min(inquiries, len(statuses)-1)]
+		inquiries++
+		refunds := []string{
+			`{"id":"R2","terminal_id":"terminal-1","session_id":"S1","amount":5000,"timeline":{"refund_time":"2024-05-14T10:00:00Z","refund_status":"` + status + `"}}`,
+			`{"id":"R1","terminal_id":"terminal-1","session_id":"S1","amount":10000,"reason":"CUSTOMER_REQUEST","method":"PAYA","created_at":"2024-05-11T10:00:00Z",` +
+				`"timeline":{"refund_amount":9000,"refund_time":"2024-05-12T10:00:00Z","refund_status":"COMPLETED"},` +
+				`"session":{"id":"S1","authority":"A1","status":"VERIFIED","amount":50000,"reference_id":201}}`,
+		}
+		if body.Variables["id"] == "R2" {
+			refunds = refunds[:1]
+		}
+		w.Write([]byte(`{"data":{"resource":[` + strings.Join(refunds, ",") + `]}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	return reporting
+}
+
+func TestReportingRefunds(t *testing.T) {
+	reporting := newRefundsServer(t, "PENDING")
+
+	refunds, err := reporting.Refunds(context.Background(), RefundFilter{SessionID: "S1", From: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].ID != "R2" || refunds[0].Status != RefundStatusPending || refunds[0].SessionID != "S1" {
+		t.Errorf("Expected the pending refund R2, got %+v", refunds)
+	}
+}
+
+func TestReportingRefundsPagination(t *testing.T) {
+	ignoreOffset := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		offset := int(body.Variables["offset"].(float64))
+		if ignoreOffset {
+			offset = 0
+		}
+
+		// the first page holds older refunds than the second one
+		var refunds []string
+		count, refundTime := reportingPageSize, "2024-05-01T10:00:00Z"
+		if offset > 0 {
+			count, refundTime = 1, "2024-05-20T10:00:00Z"
+		}
+		for i := 0; i < count; i++ {
+			refunds = append(refunds, fmt.Sprintf(`{"id":"R%d","timeline":{"refund_time":%q}}`, offset+i, refundTime))
+		}
+		w.Write([]byte(`{"data":{"resource":[` + strings.Join(refunds, ",") + `]}}`))
+	}))
+	defer server.Close()
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+
+	refunds, err := reporting.Refunds(context.Background(), RefundFilter{From: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)})
+	if err != nil || len(refunds) != 1 || refunds[0].ID != "R100" {
+		t.Errorf("Expected the refund of the second page, got %+v %v", refunds, err)
+	}
+
+	ignoreOffset = true
+	if _, err := reporting.Refunds(context.Background(), RefundFilter{}); !errors.Is(err, ErrPaginationLoop) {
+		t.Errorf("Expected ErrPaginationLoop, got %v", err)
+	}
+}
+
+func TestReportingRefundsDetails(t *testing.T) {
+	reporting := newRefundsServer(t, "FAILED")
+
+	refunds, err := reporting.Refunds(context.Background(), RefundFilter{Statuses: []RefundStatus{RefundStatusCompleted}, MinAmount: 8000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].ID != "R1" {
+		t.Fatalf("Expected the completed refund R1, got %+v", refunds)
+	}
+	refund := refunds[0]
+	if refund.RefundAmount != 9000 || refund.Reason != RefundReasonCustomerRequest || refund.Method != RefundMethodPaya || refund.CreatedAt.IsZero() {
+		t.Errorf("Expected the details of the refund, got %+v", refund)
+	}
+	if refund.Session == nil || refund.Session.Authority != "A1" || refund.Session.Amount != 50000 {
+		t.Errorf("Expected the refunded session, got %+v", refund.Session)
+	}
+
+	joined, err := reporting.RefundedTransactions(context.Background(), RefundFilter{SessionID: "S1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(joined) != 1 || joined[0].Transaction.Authority != "A1" || len(joined[0].Refunds) != 2 {
+		t.Fatalf("Expected both refunds under S1, got %+v", joined)
+	}
+	if joined[0].Refunded != 10000 || joined[0].Remaining() != 40000 {
+		t.Errorf("Expected the failed refund left out of 10000 refunded, got %d", joined[0].Refunded)
+	}
+}
+
+func TestJoinRefunds(t *testing.T) {
+	transactions := []Transaction{{ID: "S1", Amount: 50000}, {ID: "S2", Amount: 20000}, {ID: "S3", Amount: 10000}}
+	refunds := []Refund{
+		{ID: "R3", SessionID: "S3", Amount: 10000, Status: RefundStatusPending},
+		{ID: "R1", SessionID: "S1", Amount: 5000, Status: RefundStatusCompleted},
+		{ID: "R2", SessionID: "S1", Amount: 5000, Status: RefundStatusCanceled},
+		{ID: "R4", SessionID: "S9", Amount: 1000, Status: RefundStatusCompleted},
+	}
+
+	joined, orphans := JoinRefunds(transactions, refunds)
+	if len(joined) != 2 || joined[0].Transaction.ID != "S1" || joined[1].Transaction.ID != "S3" {
+		t.Fatalf("Expected S1 and S3 in the order of the transactions, got %+v", joined)
+	}
+	if joined[0].Refunded != 5000 || joined[0].Remaining() != 45000 || joined[1].Remaining() != 0 {
+		t.Errorf("Expected 5000 and 10000 refunded, got %d and %d", joined[0].Refunded, joined[1].Refunded)
+	}
+	if len(orphans) != 1 || orphans[0].ID != "R4" {
+		t.Errorf("Expected R4 as an orphan, got %+v", orphans)
+	}
+}
+
+func TestWaitRefund(t *testing.T) {
+	reporting := newRefundsServer(t, "PENDING", "PENDING", "IN_PROGRESS", "COMPLETED")
+
+	var seen []RefundStatus
+	refund, err := reporting.WaitRefund(context.Background(), "R2", time.Millisecond, func(refund Refund) {
+		seen = append(seen, refund.Status)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != RefundStatusCompleted || len(seen) != 3 {
+		t.Errorf("Expected to see the refund pending, in progress and completed, got %v", seen)
+	}
+
+	panicking := newRefundsServer(t, "PENDING", "COMPLETED")
+	var panics []*PanicError
+	panicking.OnHookPanic = func(ctx context.Context, err *PanicError) { panics = append(panics, err) }
+	refund, err = panicking.WaitRefund(context.Background(), "R2", time.Millisecond, func(refund Refund) { panic("boom") })
+	if err != nil || refund.Status != RefundStatusCompleted || len(panics) != 2 {
+		t.Errorf("Expected the panics reported while waiting for the refund, got %+v %v %v", refund, err, panics)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	stuck := newRefundsServer(t, "PENDING")
+	if _, err := stuck.WaitRefund(ctx, "R2", time.Millisecond, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop polling, got %v", err)
+	}
+}
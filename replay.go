@@ -0,0 +1,52 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayGuard records the authorities whose callback has been processed, so a re-delivered
+// or replayed callback doesn't trigger the result callback twice
+type ReplayGuard interface {
+	// MarkProcessed records the authority and reports whether it was seen for the first time
+	MarkProcessed(ctx context.Context, authority string) (first bool, err error)
+}
+
+// MemoryReplayGuard is a ReplayGuard keeping authorities in memory for a limited time
+type MemoryReplayGuard struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryReplayGuard creates a new MemoryReplayGuard remembering authorities for ttl
+func NewMemoryReplayGuard(ttl time.Duration) *MemoryReplayGuard {
+	return &MemoryReplayGuard{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// MarkProcessed implements ReplayGuard
+func (g *MemoryReplayGuard) MarkProcessed(ctx context.Context, authority string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.After(g.nextSweep) {
+		for key, expiresAt := range g.seen {
+			if now.After(expiresAt) {
+				delete(g.seen, key)
+			}
+		}
+		g.nextSweep = now.Add(g.ttl)
+	}
+
+	if expiresAt, ok := g.seen[authority]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	g.seen[authority] = now.Add(g.ttl)
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryReplayGuard(t *testing.T) {
+	guard := NewMemoryReplayGuard(50 * time.Millisecond)
+	ctx := context.Background()
+
+	if first, _ := guard.MarkProcessed(ctx, "A1"); !first {
+		t.Error("Expected first callback to be accepted")
+	}
+	if first, _ := guard.MarkProcessed(ctx, "A1"); first {
+		t.Error("Expected replayed callback to be detected")
+	}
+	if first, _ := guard.MarkProcessed(ctx, "A2"); !first {
+		t.Error("Expected other authority to be accepted")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if first, _ := guard.MarkProcessed(ctx, "A1"); !first {
+		t.Error("Expected authority to be forgotten after the ttl")
+	}
+}
+
+func TestCallbackHandlerReplayGuard(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":101,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	calls := 0
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		calls++
+	}, WithReplayGuard(NewMemoryReplayGuard(time.Hour)))
+
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected result callback to run once, ran %d times", calls)
+	}
+
+	status, err := zp.ProcessCallback(context.Background(), httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil).URL.Query(), func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, WithReplayGuard(NewMemoryReplayGuard(time.Hour)))
+	if err != nil || status.Replayed {
+		t.Errorf("Expected fresh guard to accept the callback, got %+v %v", status, err)
+	}
+}
+
+func TestCallbackHandlerReplayGuardForgedNOK(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Paid","ref_id":201},"errors":[]}`,
+	})
+
+	var results []PaymentStatus
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		results = append(results, status)
+	}, WithReplayGuard(NewMemoryReplayGuard(time.Hour)))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A1&Status=NOK", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+
+	if len(results) != 2 || results[0].IsSuccessful {
+		t.Fatalf("Expected both callbacks passed on, got %+v", results)
+	}
+	if !results[1].IsSuccessful || results[1].Replayed {
+		t.Errorf("Expected the real callback processed after the forged one, got %+v", results[1])
+	}
+}
+
+// flakyCardHistory fails the first recording
+type flakyCardHistory struct {
+	*MemoryCardHistory
+	failed bool
+}
+
+func (h *flakyCardHistory) RecordCard(ctx context.Context, payment CardPayment) error {
+	if !h.failed {
+		h.failed = true
+		return errors.New("database unavailable")
+	}
+	return h.MemoryCardHistory.RecordCard(ctx, payment)
+}
+
+func TestCallbackHandlerReplayGuardRedelivery(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":101,"message":"Verified","ref_id":201,"card_hash":"H1"},"errors":[]}`,
+	})
+
+	cards := &flakyCardHistory{MemoryCardHistory: NewMemoryCardHistory()}
+	outbox := NewMemoryOutbox()
+	calls := 0
+	handler := zp.CallbackHandler(func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		calls++
+	}, WithReplayGuard(NewMemoryReplayGuard(time.Hour)), WithCardHistory(cards), WithOutbox(outbox))
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+		codes = append(codes, rec.Code)
+	}
+
+	if codes[0] != http.StatusInternalServerError || codes[1] != http.StatusOK || calls != 1 {
+		t.Errorf("Expected the redelivery of the failed callback processed once, got %v and %d calls", codes, calls)
+	}
+	if stats, _ := cards.CardStats(context.Background(), "H1"); stats.Payments != 1 {
+		t.Errorf("Expected the card recorded once, got %+v", stats)
+	}
+	if events, _ := outbox.PendingEvents(context.Background(), 10); len(events) != 1 {
+		t.Errorf("Expected a single event, got %+v", events)
+	}
+}
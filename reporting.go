@@ -0,0 +1,188 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultReportingURL is the endpoint of the Zarinpal GraphQL API
+const DefaultReportingURL = "https://next.zarinpal.com/api/v4/graphql/"
+
+// reportingPageSize is the number of transactions fetched per request
+const reportingPageSize = 100
+
+// Transaction is a payment session as listed by the Zarinpal reporting API
+type Transaction struct {
+	ID          string    `json:"id"`
+	Authority   string    `json:"authority"`
+	Status      string    `json:"status"` // one of the InquiryStatus constants
+	Amount      int       `json:"amount"`
+	Fee         int       `json:"fee"`
+	RefID       int       `json:"reference_id"`
+	CardPan     string    `json:"card_pan"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsPaid reports whether the customer was charged for the transaction
+func (t Transaction) IsPaid() bool {
+	return t.Status == InquiryStatusVerified || t.Status == InquiryStatusPaid
+}
+
+// TransactionSource lists the transactions created in a time range
+type TransactionSource interface {
+	Transactions(ctx context.Context, from, to time.Time) ([]Transaction, error)
+}
+
+// GraphQLError is returned when the reporting API answers with errors
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// Reporting is a client of the Zarinpal reporting API, authenticated with a panel access token
+type Reporting struct {
+	AccessToken string
+	TerminalID  string
+	URL         string
+	HTTPClient  *http.Client
+	// Endpoints routes refunds away from URL, only EndpointRefund applies
+	Endpoints EndpointOverrides
+	// MaxPages stops listings that don't end after this many pages, DefaultMaxPages when zero
+	MaxPages int
+	// OnHookPanic is called with the panics of the callbacks given to the client, like the
+	// onChange of WaitRefund, they are recovered so the call goes on
+	OnHookPanic func(ctx context.Context, err *PanicError)
+}
+
+var _ TransactionSource = (*Reporting)(nil)
+
+// NewReporting creates a new Reporting client for the terminal
+func NewReporting(accessToken, terminalID string) *Reporting {
+	return &Reporting{
+		AccessToken: accessToken,
+		TerminalID:  terminalID,
+		URL:         DefaultReportingURL,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Transactions implements TransactionSource, pages are fetched newest first until the range is covered
+func (r *Reporting) Transactions(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	return r.FilterTransactions(ctx, TransactionFilter{From: from, To: to})
+}
+
+// EachTransaction calls fn with the transactions passing the filter, newest first, fetching
+// pages as they are needed. Returning false from fn stops the listing.
+func (r *Reporting) EachTransaction(ctx context.Context, filter TransactionFilter, fn func(Transaction) bool) error {
+	return r.eachTransaction(ctx, filter, fn)
+}
+
+// eachTransaction calls fn with the transactions passing the filter, newest first, fetching
+// pages as they are needed until fn returns false or the pages are older than the filter
+func (r *Reporting) eachTransaction(ctx context.Context, filter TransactionFilter, fn func(Transaction) bool) error {
+	maxPages := r.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	// first IDs of the pages fetched so far, an API ignoring the offset would repeat one
+	seen := make(map[string]bool)
+	request := PageRequest{Limit: reportingPageSize, Filter: filter}
+	for pages := 1; ; pages++ {
+		if pages > maxPages {
+			return fmt.Errorf("%w: stopped after %d pages", ErrTooManyPages, maxPages)
+		}
+
+		page, err := r.TransactionsPage(ctx, request)
+		if err != nil {
+			return err
+		}
+		if len(page.Transactions) > 0 {
+			first := page.Transactions[0].ID
+			if seen[first] {
+				return fmt.Errorf("%w: transaction %s", ErrPaginationLoop, first)
+			}
+			seen[first] = true
+		}
+
+		for _, transaction := range page.Transactions {
+			if filter.Matches(transaction) && !fn(transaction) {
+				return nil
+			}
+		}
+
+		last := len(page.Transactions) - 1
+		if page.NextPageToken == "" || page.Transactions[last].CreatedAt.Before(filter.From) {
+			return nil
+		}
+		request.PageToken = page.NextPageToken
+	}
+}
+
+// Query runs a GraphQL query and decodes its data into out
+func (r *Reporting) Query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	return r.query(ctx, r.URL, query, variables, out)
+}
+
+func (r *Reporting) query(ctx context.Context, url, query string, variables map[string]interface{}, out interface{}) (err error) {
+	marshalled, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(marshalled))
+	if err != nil {
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+r.AccessToken)
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err = json.Unmarshal(bodyBytes, &response); err != nil {
+		return fmt.Errorf("reporting API answered %s: %w", resp.Status, err)
+	}
+	if len(response.Errors) > 0 {
+		graphQLErr := &GraphQLError{}
+		for _, e := range response.Errors {
+			graphQLErr.Messages = append(graphQLErr.Messages, e.Message)
+		}
+		return graphQLErr
+	}
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		return errors.New("reporting API answered without data")
+	}
+
+	return json.Unmarshal(response.Data, out)
+}
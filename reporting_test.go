@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportingTransactions(t *testing.T) {
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.Write([]byte(`{"data":null,"errors":[{"message":"Unauthenticated."}]}`))
+			return
+		}
+
+		var body struct {
+			Variables struct {
+				Offset int `json:"offset"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		// 150 sessions, one per hour, newest first
+		var sessions []string
+		for i := body.Variables.Offset; i < body.Variables.Offset+reportingPageSize && i < 150; i++ {
+			createdAt := start.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)
+			sessions = append(sessions, fmt.Sprintf(`{"id":"%d","authority":"A%d","status":"VERIFIED","amount":10000,"created_at":"%s"}`, i, i, createdAt))
+		}
+		w.Write([]byte(`{"data":{"Session":[` + strings.Join(sessions, ",") + `]}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+
+	transactions, err := reporting.Transactions(context.Background(), start.Add(-120*time.Hour), start)
+	if err != nil {
+		t.Fatalf("Failed to list transactions: %v", err)
+	}
+	if len(transactions) != 120 || transactions[0].Authority != "A1" || !transactions[0].IsPaid() {
+		t.Errorf("Expected 120 transactions starting with A1, got %d %+v", len(transactions), transactions[0])
+	}
+
+	reporting.AccessToken = "expired"
+	_, err = reporting.Transactions(context.Background(), start.Add(-time.Hour), start)
+	var graphQLErr *GraphQLError
+	if !errors.As(err, &graphQLErr) || graphQLErr.Messages[0] != "Unauthenticated." {
+		t.Errorf("Expected GraphQL error, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Verification retry defaults
+const (
+	DefaultVerifyRetryWindow     = time.Hour
+	DefaultVerifyRetryInterval   = 10 * time.Second
+	DefaultVerifyRetryBackoff    = 10 * time.Second
+	DefaultVerifyRetryMaxBackoff = 5 * time.Minute
+)
+
+var (
+	// ErrVerificationQueued is returned by the callback helpers when verification failed and was queued for a retry
+	ErrVerificationQueued = errors.New("verification failed and was queued for retry")
+	// ErrVerifyRetryExpired is passed to VerifyRetrier.OnError when a payment couldn't be verified in time
+	ErrVerifyRetryExpired = errors.New("verification retries expired")
+)
+
+// VerifyRetry is a verification waiting in a RetryQueue
+type VerifyRetry struct {
+	Authority   string    `json:"authority"`
+	Amount      int       `json:"amount"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	ExpiresAt   time.Time `json:"expires_at"` // retries stop after this time
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// RetryQueue durably keeps the verifications that failed because the gateway couldn't be
+// reached or answered with a transient error
+type RetryQueue interface {
+	// Enqueue adds the retry, replacing a queued retry of the same authority
+	Enqueue(ctx context.Context, retry VerifyRetry) error
+	// Due returns the retries whose next attempt is at or before now
+	Due(ctx context.Context, now time.Time) ([]VerifyRetry, error)
+	Remove(ctx context.Context, authority string) error
+}
+
+// WithRetryQueue queues the verifications that fail because the gateway couldn't be reached or
+// answered with a transient error, like too many attempts. The callback then fails with ErrVerificationQueued and status code 202. Process the queue with a VerifyRetrier.
+func WithRetryQueue(queue RetryQueue) CallbackOption {
+	return func(o *callbackOptions) {
+		o.retryQueue = queue
+	}
+}
+
+// enqueueVerification queues a failed verification for its first retry
+func enqueueVerification(ctx context.Context, queue RetryQueue, authority string, amount int, cause error) error {
+	now := time.Now()
+	return queue.Enqueue(ctx, VerifyRetry{
+		Authority:   authority,
+		Amount:      amount,
+		Attempts:    1,
+		NextAttempt: now.Add(DefaultVerifyRetryBackoff),
+		ExpiresAt:   now.Add(DefaultVerifyRetryWindow),
+		LastError:   cause.Error(),
+	})
+}
+
+// VerifyRetrier processes a RetryQueue, retrying verifications with exponential backoff
+// until they succeed, are rejected by the gateway or expire
+type VerifyRetrier struct {
+	Client Client
+	Queue  RetryQueue
+	Store  PaymentStore // optional, verified and rejected payments are recorded in it
+	// Outbox is optional, the events of the payments the gateway answered for are added to it.
+	// Retries stay queued until the event is added.
+	Outbox Outbox
+
+	Interval   time.Duration // time between queue polls, defaults to DefaultVerifyRetryInterval
+	Backoff    time.Duration // delay before the second retry, doubled on every attempt
+	MaxBackoff time.Duration
+
+	// OnResult is called with every payment the gateway answered for
+	OnResult func(ctx context.Context, status PaymentStatus)
+	// OnError is called with failed attempts and, wrapping ErrVerifyRetryExpired, with given up payments
+	OnError func(authority string, err error)
+}
+
+// NewVerifyRetrier creates a VerifyRetrier with the default interval and backoff
+func NewVerifyRetrier(client Client, queue RetryQueue, store PaymentStore) *VerifyRetrier {
+	return &VerifyRetrier{
+		Client:     client,
+		Queue:      queue,
+		Store:      store,
+		Interval:   DefaultVerifyRetryInterval,
+		Backoff:    DefaultVerifyRetryBackoff,
+		MaxBackoff: DefaultVerifyRetryMaxBackoff,
+	}
+}
+
+// Run processes the queue every interval until the context is done
+func (r *VerifyRetrier) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultVerifyRetryInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.ProcessDue(ctx); err != nil {
+			r.fail("", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue retries the due verifications, only failing to access the queue is returned as an error
+func (r *VerifyRetrier) ProcessDue(ctx context.Context) error {
+	now := time.Now()
+	due, err := r.Queue.Due(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, retry := range due {
+		if !now.Before(retry.ExpiresAt) {
+			r.fail(retry.Authority, ErrVerifyRetryExpired)
+			if err := r.Queue.Remove(ctx, retry.Authority); err != nil {
+				return err
+			}
+			continue
+		}
+
+		status, err := r.Client.CheckPaymentStatus(ctx, retry.Amount, retry.Authority)
+		rejected := IsPaymentRejected(err)
+		if err != nil && !rejected {
+			// the gateway couldn't be reached or answered with a transient error
+			r.fail(retry.Authority, err)
+			retry.LastError = err.Error()
+			retry.NextAttempt = now.Add(r.backoff(retry.Attempts))
+			retry.Attempts++
+			if err := r.Queue.Enqueue(ctx, retry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if r.Store != nil {
+			if err := recordCallback(ctx, r.Store, status, rejected); err != nil && !errors.Is(err, ErrPaymentNotFound) {
+				r.fail(retry.Authority, err)
+				continue
+			}
+		}
+		if r.Outbox != nil {
+			if err := r.Outbox.AddEvent(ctx, outboxEvent(status)); err != nil {
+				r.fail(retry.Authority, err)
+				continue
+			}
+		}
+		if err := r.Queue.Remove(ctx, retry.Authority); err != nil {
+			return err
+		}
+		if r.OnResult != nil {
+			if panicErr := RunHook("verify retrier OnResult", func() { r.OnResult(ctx, status) }); panicErr != nil {
+				r.fail(retry.Authority, panicErr)
+			}
+		}
+	}
+	return nil
+}
+
+// backoff returns the delay following the given number of attempts
+func (r *VerifyRetrier) backoff(attempts int) time.Duration {
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultVerifyRetryBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultVerifyRetryMaxBackoff
+	}
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (r *VerifyRetrier) fail(authority string, err error) {
+	if r.OnError != nil {
+		RunHook("verify retrier OnError", func() { r.OnError(authority, err) })
+	}
+}
+
+// MemoryRetryQueue is a RetryQueue kept in memory, it doesn't survive restarts
+type MemoryRetryQueue struct {
+	mu      sync.Mutex
+	retries map[string]VerifyRetry
+}
+
+// NewMemoryRetryQueue creates an empty MemoryRetryQueue
+func NewMemoryRetryQueue() *MemoryRetryQueue {
+	return &MemoryRetryQueue{retries: make(map[string]VerifyRetry)}
+}
+
+// Enqueue implements RetryQueue
+func (q *MemoryRetryQueue) Enqueue(ctx context.Context, retry VerifyRetry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.retries[retry.Authority] = retry
+	return nil
+}
+
+// Due implements RetryQueue, retries are ordered by their next attempt
+func (q *MemoryRetryQueue) Due(ctx context.Context, now time.Time) (due []VerifyRetry, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, retry := range q.retries {
+		if !retry.NextAttempt.After(now) {
+			due = append(due, retry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	return
+}
+
+// Remove implements RetryQueue
+func (q *MemoryRetryQueue) Remove(ctx context.Context, authority string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.retries, authority)
+	return nil
+}
@@ -0,0 +1,173 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestVerifyRetryQueue(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "<html>502 Bad Gateway</html>", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+	queue := NewMemoryRetryQueue()
+
+	handler := zp.CallbackHandler(StoreAmountLookup(store), func(ctx context.Context, status PaymentStatus) {
+		t.Error("Result callback must not be invoked before verification")
+	}, WithPaymentStore(store), WithRetryQueue(queue))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, rec.Code)
+	}
+
+	queued, _ := queue.Due(ctx, time.Now().Add(time.Hour))
+	if len(queued) != 1 || queued[0].Authority != "A1" || queued[0].Amount != 10000 {
+		t.Fatalf("Expected verification to be queued, got %+v", queued)
+	}
+	// make the retry due
+	queued[0].NextAttempt = time.Now()
+	queue.Enqueue(ctx, queued[0])
+
+	var results []PaymentStatus
+	var failures int
+	retrier := NewVerifyRetrier(zp, queue, store)
+	retrier.Backoff = time.Millisecond
+	retrier.Outbox = NewMemoryOutbox()
+	retrier.OnResult = func(ctx context.Context, status PaymentStatus) {
+		results = append(results, status)
+	}
+	retrier.OnError = func(authority string, err error) {
+		failures++
+	}
+
+	if err := retrier.ProcessDue(ctx); err != nil {
+		t.Fatalf("Failed to process queue: %v", err)
+	}
+	if failures != 1 || len(results) != 0 {
+		t.Errorf("Expected a failed attempt, got %d failures and %+v", failures, results)
+	}
+
+	down.Store(false)
+	time.Sleep(5 * time.Millisecond)
+	if err := retrier.ProcessDue(ctx); err != nil {
+		t.Fatalf("Failed to process queue: %v", err)
+	}
+	if len(results) != 1 || !results[0].IsSuccessful || results[0].RefID != 201 {
+		t.Errorf("Expected verified payment, got %+v", results)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateVerified {
+		t.Errorf("Expected payment to be verified, got %s", payment.State)
+	}
+	if events, _ := retrier.Outbox.PendingEvents(ctx, 10); len(events) != 1 || events[0].Type != EventPaymentVerified {
+		t.Errorf("Expected the verified payment in the outbox, got %+v", events)
+	}
+	if due, _ := queue.Due(ctx, time.Now().Add(time.Hour)); len(due) != 0 {
+		t.Errorf("Expected queue to be empty, got %+v", due)
+	}
+}
+
+func TestVerifyRetryExpired(t *testing.T) {
+	queue := NewMemoryRetryQueue()
+	ctx := context.Background()
+	queue.Enqueue(ctx, VerifyRetry{Authority: "A1", Amount: 10000, NextAttempt: time.Now(), ExpiresAt: time.Now()})
+
+	var expired error
+	retrier := NewVerifyRetrier(nil, queue, nil)
+	retrier.OnError = func(authority string, err error) {
+		expired = err
+	}
+
+	if err := retrier.ProcessDue(ctx); err != nil {
+		t.Fatalf("Failed to process queue: %v", err)
+	}
+	if !errors.Is(expired, ErrVerifyRetryExpired) {
+		t.Errorf("Expected ErrVerifyRetryExpired, got %v", expired)
+	}
+}
+
+func TestVerifyRetryBackoff(t *testing.T) {
+	retrier := &VerifyRetrier{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempts, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if backoff := retrier.backoff(attempts); backoff != expected {
+			t.Errorf("Expected backoff %s after %d attempts, got %s", expected, attempts, backoff)
+		}
+	}
+}
+
+func TestVerifyRetryTransientErrors(t *testing.T) {
+	var body atomic.Value
+	body.Store(fixtures.Error(-12))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+	queue := NewMemoryRetryQueue()
+
+	handler := zp.CallbackHandler(StoreAmountLookup(store), nil, WithPaymentStore(store), WithRetryQueue(queue))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected the transient error queued with status code %d, got %d", http.StatusAccepted, rec.Code)
+	}
+
+	queued, _ := queue.Due(ctx, time.Now().Add(time.Hour))
+	if len(queued) != 1 {
+		t.Fatalf("Expected the verification queued, got %+v", queued)
+	}
+	queued[0].NextAttempt = time.Now()
+	queue.Enqueue(ctx, queued[0])
+
+	var results []PaymentStatus
+	retrier := NewVerifyRetrier(zp, queue, store)
+	retrier.Backoff = time.Millisecond
+	retrier.OnResult = func(ctx context.Context, status PaymentStatus) {
+		results = append(results, status)
+	}
+	retrier.ProcessDue(ctx)
+
+	queued, _ = queue.Due(ctx, time.Now().Add(time.Hour))
+	if len(queued) != 1 || queued[0].Attempts != 2 || len(results) != 0 {
+		t.Fatalf("Expected the retry rescheduled, got %+v and %+v", queued, results)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); !payment.State.Pending() {
+		t.Errorf("Expected the payment left pending, got %s", payment.State)
+	}
+
+	body.Store(fixtures.VerifySuccess)
+	time.Sleep(5 * time.Millisecond)
+	retrier.ProcessDue(ctx)
+	if len(results) != 1 || !results[0].IsSuccessful {
+		t.Errorf("Expected the payment verified on the next attempt, got %+v", results)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateVerified {
+		t.Errorf("Expected payment to be verified, got %s", payment.State)
+	}
+}
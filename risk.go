@@ -0,0 +1,287 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPaymentDeclined is returned by CreatePayment for payments the risk hook declined
+var ErrPaymentDeclined = errors.New("payment declined by the risk check")
+
+// RiskStage is the step of the payment flow a risk check runs at
+type RiskStage string
+
+// RiskStage constants
+const (
+	RiskStageCreate RiskStage = "create" // before the payment is created
+	RiskStageVerify RiskStage = "verify" // after the payment was verified for the first time
+)
+
+// RiskDecision is the outcome of a risk check
+type RiskDecision string
+
+// RiskDecision constants
+const (
+	RiskAllow   RiskDecision = "allow"
+	RiskFlag    RiskDecision = "flag"    // go on, but mark the payment for review
+	RiskDecline RiskDecision = "decline" // refuse to create, or reverse a verified payment
+)
+
+// RiskCheck is what a risk hook knows about a payment
+type RiskCheck struct {
+	Stage     RiskStage
+	Amount    int           // in the currency of the payment
+	Mobile    string        // from the metadata, create stage only
+	IP        string        // of the user, when set with WithClientIP
+	CardHash  string        // verify stage only
+	Authority string        // verify stage only
+	Params    PaymentParams // create stage only
+}
+
+// RiskResult is the decision of a risk hook, the reason is shown in errors and statuses
+type RiskResult struct {
+	Decision RiskDecision
+	Reason   string
+}
+
+// RiskHook assesses payments before they are created and after they are verified. Payments
+// declined at creation fail with ErrPaymentDeclined, payments declined after verification are
+// reversed and reported unsuccessful. Flagged payments go on with PaymentStatus.Flagged set
+// after verification. Errors and panics of the hook fail creations and leave verifications
+// unassessed.
+type RiskHook func(ctx context.Context, check RiskCheck) (RiskResult, error)
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the IP of the user, for risk hooks. The start and
+// callback handlers set it from the remote address unless it is already set, the handlers of
+// the framework adapters from the client IP the framework resolves. Calls of the client
+// methods need it set by the caller.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP of the user carried by the context
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// withRemoteIP sets the client IP of the context to the remote address of the request, unless
+// one is already set
+func withRemoteIP(ctx context.Context, remoteAddr string) context.Context {
+	if ClientIP(ctx) != "" {
+		return ctx
+	}
+	if addr, ok := parseAddr(remoteAddr); ok {
+		return WithClientIP(ctx, addr.String())
+	}
+	return ctx
+}
+
+// assessCreation runs the risk hook on the parameters of a new payment
+func (z *Zarinpal) assessCreation(ctx context.Context, params PaymentParams) error {
+	check := RiskCheck{Stage: RiskStageCreate, Amount: params.Amount, IP: ClientIP(ctx), Params: params}
+	if params.Metadata != nil {
+		check.Mobile = params.Metadata.Mobile
+	}
+	var result RiskResult
+	var err error
+	if panicErr := z.runHook(ctx, "risk hook", func() { result, err = z.RiskHook(ctx, check) }); panicErr != nil {
+		return panicErr
+	}
+	if err != nil {
+		return err
+	}
+	if result.Decision == RiskDecline {
+		return fmt.Errorf("%w: %s", ErrPaymentDeclined, result.Reason)
+	}
+	return nil
+}
+
+// assessVerification runs the risk hook on a first successful verification, reversing the
+// payment when it is declined. A failed reversal leaves the payment successful and flagged.
+func (z *Zarinpal) assessVerification(ctx context.Context, status PaymentStatus) PaymentStatus {
+	if !status.IsSuccessful || status.IsRepeated {
+		return status
+	}
+	check := RiskCheck{
+		Stage:     RiskStageVerify,
+		Amount:    status.Amount,
+		IP:        ClientIP(ctx),
+		CardHash:  status.CardHash,
+		Authority: status.Authority,
+	}
+	var result RiskResult
+	var err error
+	if panicErr := z.runHook(ctx, "risk hook", func() { result, err = z.RiskHook(ctx, check) }); panicErr != nil || err != nil {
+		return status
+	}
+
+	switch result.Decision {
+	case RiskFlag:
+		status.Flagged = true
+		status.RiskReason = result.Reason
+	case RiskDecline:
+		if _, err := z.ReversePayment(ctx, status.Authority); err != nil {
+			status.Flagged = true
+			status.RiskReason = fmt.Sprintf("declined but not reversed (%v): %s", err, result.Reason)
+			return status
+		}
+		status.IsSuccessful = false
+		status.Reversed = true
+		status.RiskReason = result.Reason
+		status.Message = "payment was declined and reversed"
+	}
+	return status
+}
+
+// VelocityLimit limits the payments of a mobile number, card or IP within a window, zero
+// counts and amounts are unlimited
+type VelocityLimit struct {
+	Window    time.Duration
+	MaxCount  int
+	MaxAmount int          // in the currency of the payments
+	Action    RiskDecision // taken over the limit, RiskDecline by default
+}
+
+// VelocityCheck is a RiskHook limiting the payments of each mobile number and IP at creation
+// and of each card after verification, along with deny lists. Use its Assess method as the
+// risk hook of the client.
+type VelocityCheck struct {
+	PerMobile VelocityLimit
+	PerIP     VelocityLimit
+	PerCard   VelocityLimit
+
+	mu        sync.Mutex
+	denied    map[string]bool
+	history   map[string][]velocityEntry
+	lastSweep time.Time
+}
+
+// velocitySweepInterval is how often Assess drops the history of every key, the keys of mobile
+// numbers, cards and IPs not seen again would be kept forever otherwise
+const velocitySweepInterval = time.Minute
+
+type velocityEntry struct {
+	at     time.Time
+	amount int
+}
+
+// NewVelocityCheck creates a VelocityCheck without limits
+func NewVelocityCheck() *VelocityCheck {
+	return &VelocityCheck{
+		denied:  make(map[string]bool),
+		history: make(map[string][]velocityEntry),
+	}
+}
+
+// Deny declines the payments of the mobile numbers, card hashes or IPs
+func (v *VelocityCheck) Deny(values ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, value := range values {
+		v.denied[value] = true
+	}
+}
+
+// Assess implements RiskHook, payments within the limits are recorded
+func (v *VelocityCheck) Assess(ctx context.Context, check RiskCheck) (RiskResult, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	type key struct {
+		name  string
+		value string
+		limit VelocityLimit
+	}
+	var keys []key
+	if check.Stage == RiskStageCreate {
+		keys = append(keys, key{"mobile", check.Mobile, v.PerMobile}, key{"IP", check.IP, v.PerIP})
+	} else {
+		keys = append(keys, key{"card", check.CardHash, v.PerCard})
+	}
+
+	now := time.Now()
+	if now.Sub(v.lastSweep) >= velocitySweepInterval {
+		v.sweep(now)
+	}
+	result := RiskResult{Decision: RiskAllow}
+	for _, k := range keys {
+		if k.value == "" {
+			continue
+		}
+		if v.denied[k.value] {
+			return RiskResult{Decision: RiskDecline, Reason: k.name + " is denied"}, nil
+		}
+		if decision, reason := v.exceeds(k.name+":"+k.value, k.name, k.limit, check.Amount, now); decision != RiskAllow {
+			result = RiskResult{Decision: decision, Reason: reason}
+			if decision == RiskDecline {
+				return result, nil
+			}
+		}
+	}
+
+	for _, k := range keys {
+		if k.value != "" && k.limit.Window > 0 {
+			id := k.name + ":" + k.value
+			v.history[id] = append(v.history[id], velocityEntry{at: now, amount: check.Amount})
+		}
+	}
+	return result, nil
+}
+
+// sweep drops the expired entries of every key
+func (v *VelocityCheck) sweep(now time.Time) {
+	limits := map[string]VelocityLimit{"mobile": v.PerMobile, "IP": v.PerIP, "card": v.PerCard}
+	for id := range v.history {
+		name, _, _ := strings.Cut(id, ":")
+		v.expire(id, limits[name].Window, now)
+	}
+	v.lastSweep = now
+}
+
+// expire drops the entries of the key older than the window and the key once it has none
+func (v *VelocityCheck) expire(id string, window time.Duration, now time.Time) []velocityEntry {
+	entries := v.history[id]
+	for len(entries) > 0 && now.Sub(entries[0].at) >= window {
+		entries = entries[1:]
+	}
+	if len(entries) == 0 {
+		delete(v.history, id)
+	} else {
+		v.history[id] = entries
+	}
+	return entries
+}
+
+// exceeds drops the entries of the key older than the window and checks the payment against
+// the limit
+func (v *VelocityCheck) exceeds(id, name string, limit VelocityLimit, amount int, now time.Time) (RiskDecision, string) {
+	if limit.Window <= 0 {
+		return RiskAllow, ""
+	}
+
+	entries := v.expire(id, limit.Window, now)
+
+	total := amount
+	for _, entry := range entries {
+		total += entry.amount
+	}
+	action := limit.Action
+	if action == "" {
+		action = RiskDecline
+	}
+	switch {
+	case limit.MaxCount > 0 && len(entries)+1 > limit.MaxCount:
+		return action, fmt.Sprintf("more than %d payments of the %s within %s", limit.MaxCount, name, limit.Window)
+	case limit.MaxAmount > 0 && total > limit.MaxAmount:
+		return action, fmt.Sprintf("more than %d paid by the %s within %s", limit.MaxAmount, name, limit.Window)
+	}
+	return RiskAllow, ""
+}
@@ -0,0 +1,137 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVelocityCheck(t *testing.T) {
+	ctx := context.Background()
+	v := NewVelocityCheck()
+	v.PerMobile = VelocityLimit{Window: time.Hour, MaxCount: 2}
+	v.PerIP = VelocityLimit{Window: time.Hour, MaxAmount: 25000, Action: RiskFlag}
+	v.Deny("09120000000")
+
+	create := func(mobile, ip string, amount int) RiskResult {
+		result, err := v.Assess(ctx, RiskCheck{Stage: RiskStageCreate, Mobile: mobile, IP: ip, Amount: amount})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := create("09121111111", "10.0.0.1", 10000); result.Decision != RiskAllow {
+		t.Errorf("Expected the first payment to be allowed, got %+v", result)
+	}
+	if result := create("09121111111", "10.0.0.1", 10000); result.Decision != RiskAllow {
+		t.Errorf("Expected the second payment to be allowed, got %+v", result)
+	}
+	if result := create("09121111111", "10.0.0.2", 10000); result.Decision != RiskDecline {
+		t.Errorf("Expected a third payment of the mobile to be declined, got %+v", result)
+	}
+	if result := create("09122222222", "10.0.0.1", 10000); result.Decision != RiskFlag {
+		t.Errorf("Expected the IP going over its amount to be flagged, got %+v", result)
+	}
+	if result := create("09120000000", "", 1000); result.Decision != RiskDecline || result.Reason != "mobile is denied" {
+		t.Errorf("Expected a denied mobile to be declined, got %+v", result)
+	}
+
+	// the keys seen an hour ago are swept by the next assessment
+	v.mu.Lock()
+	for id, entries := range v.history {
+		for i := range entries {
+			entries[i].at = entries[i].at.Add(-time.Hour)
+		}
+		v.history[id] = entries
+	}
+	v.lastSweep = time.Time{}
+	v.mu.Unlock()
+	create("09123333333", "", 1000)
+	if len(v.history) != 1 {
+		t.Errorf("Expected only the history of the latest mobile kept, got %v", v.history)
+	}
+}
+
+func TestRiskHookCreate(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+	var checks []RiskCheck
+	zp.RiskHook = func(ctx context.Context, check RiskCheck) (RiskResult, error) {
+		checks = append(checks, check)
+		if check.Amount > 100000 {
+			return RiskResult{Decision: RiskDecline, Reason: "amount too high"}, nil
+		}
+		return RiskResult{Decision: RiskAllow}, nil
+	}
+
+	params := PaymentParams{Amount: 500000, Description: "Order", CallbackURL: "https://example.com", Metadata: &Metadata{Mobile: "09121111111"}}
+	if _, err := zp.CreatePayment(WithClientIP(context.Background(), "10.0.0.1"), params); !errors.Is(err, ErrPaymentDeclined) {
+		t.Errorf("Expected ErrPaymentDeclined, got %v", err)
+	}
+	if len(checks) != 1 || checks[0].Stage != RiskStageCreate || checks[0].Mobile != "09121111111" || checks[0].IP != "10.0.0.1" {
+		t.Errorf("Unexpected risk check: %+v", checks)
+	}
+
+	lookup := func(ctx context.Context, orderID string) (PaymentParams, error) { return params, nil }
+	rec := httptest.NewRecorder()
+	zp.StartHandler(lookup, nil).ServeHTTP(rec, httptest.NewRequest("GET", "/pay?order_id=1", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if ip := checks[len(checks)-1].IP; ip != "192.0.2.1" {
+		t.Errorf("Expected the remote address as IP, got %q", ip)
+	}
+}
+
+func TestRiskHookVerify(t *testing.T) {
+	reversed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify.json":
+			w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201,"card_hash":"H1"},"errors":[]}`))
+		case "/reverse.json":
+			reversed++
+			w.Write([]byte(`{"data":{"code":100,"message":"Reversed"},"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	decision := RiskFlag
+	zp.RiskHook = func(ctx context.Context, check RiskCheck) (RiskResult, error) {
+		if check.Stage != RiskStageVerify || check.CardHash != "H1" {
+			t.Errorf("Unexpected risk check: %+v", check)
+		}
+		return RiskResult{Decision: decision, Reason: "new card"}, nil
+	}
+
+	status, err := zp.CheckPaymentStatus(context.Background(), 10000, "A1")
+	if err != nil || !status.IsSuccessful || !status.Flagged || status.RiskReason != "new card" {
+		t.Errorf("Expected a flagged successful payment, got %+v and %v", status, err)
+	}
+
+	decision = RiskDecline
+	status, err = zp.CheckPaymentStatus(context.Background(), 10000, "A1")
+	if err != nil || status.IsSuccessful || !status.Reversed || reversed != 1 {
+		t.Errorf("Expected a declined payment to be reversed, got %+v and %v", status, err)
+	}
+
+	store := NewMemoryPaymentStore()
+	outbox := NewMemoryOutbox()
+	ctx := context.Background()
+	store.SaveSession(ctx, PaymentSession{Authority: "A2", Amount: 10000})
+	handler := zp.CallbackHandler(StoreAmountLookup(store), nil, WithPaymentStore(store), WithOutbox(outbox))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A2&Status=OK", nil))
+	if payment, _ := store.GetByAuthority(ctx, "A2"); payment.State != PaymentStateReversed {
+		t.Errorf("Expected the declined payment recorded as reversed, got %s", payment.State)
+	}
+	if events, _ := outbox.PendingEvents(ctx, 10); len(events) != 1 || events[0].Type != EventPaymentReversed {
+		t.Errorf("Expected a reversal event, got %+v", events)
+	}
+}
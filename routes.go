@@ -0,0 +1,52 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+)
+
+// Default paths used by Mount
+const (
+	DefaultStartPath    = "/zarinpal/start"
+	DefaultCallbackPath = "/zarinpal/callback"
+)
+
+// AuthorityParam is the query parameter RedirectHandler reads the authority from
+const AuthorityParam = "authority"
+
+// Router is implemented by *http.ServeMux and chi.Router
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Routes holds the paths the payment endpoints are mounted on, empty paths fall back to the defaults
+type Routes struct {
+	StartPath    string
+	CallbackPath string
+}
+
+// RedirectHandler returns an http.Handler that redirects the user to the payment page
+// of the authority given in the query string
+func (z *Zarinpal) RedirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authority := r.URL.Query().Get(AuthorityParam)
+		if authority == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, z.GetPaymentURL(authority), http.StatusFound)
+	})
+}
+
+// Mount registers the redirect and callback endpoints on the router
+func (z *Zarinpal) Mount(router Router, routes Routes, lookup AmountLookupFunc, onResult func(ctx context.Context, status PaymentStatus), opts ...CallbackOption) {
+	if routes.StartPath == "" {
+		routes.StartPath = DefaultStartPath
+	}
+	if routes.CallbackPath == "" {
+		routes.CallbackPath = DefaultCallbackPath
+	}
+
+	router.Handle(routes.StartPath, z.RedirectHandler())
+	router.Handle(routes.CallbackPath, z.CallbackHandler(lookup, onResult, opts...))
+}
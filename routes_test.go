@@ -0,0 +1,47 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	verified := false
+	mux := http.NewServeMux()
+	zp.Mount(mux, Routes{}, func(ctx context.Context, callback CallbackData) (int, error) {
+		return 10000, nil
+	}, func(ctx context.Context, status PaymentStatus) {
+		verified = status.IsSuccessful
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", DefaultStartPath+"?authority=A1", nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusFound, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != zp.GetPaymentURL("A1") {
+		t.Errorf("Expected redirect to %s, got %s", zp.GetPaymentURL("A1"), location)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", DefaultCallbackPath+"?Authority=A1&Status=OK", nil))
+	if !verified {
+		t.Error("Expected payment to be verified through the mounted callback")
+	}
+}
+
+func TestRedirectHandlerMissingAuthority(t *testing.T) {
+	zp := New("merchant-1")
+
+	rec := httptest.NewRecorder()
+	zp.RedirectHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/start", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,185 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidSearch is returned for transaction searches without criteria, or ones the sources
+// can't answer
+var ErrInvalidSearch = errors.New("invalid transaction search")
+
+// searchWindow is how far from the creation of a stored payment its transaction is looked for
+const searchWindow = time.Hour
+
+// TransactionQuery is what a customer quotes when disputing a charge, every set field must match
+type TransactionQuery struct {
+	RefID         int
+	Authority     string
+	OrderID       string
+	CardPanSuffix string // last digits of the card, like "5995"
+
+	// From and To bound the search on the reporting API, zero fetches every transaction unless
+	// the store knows the payment
+	From time.Time
+	To   time.Time
+}
+
+func (q TransactionQuery) empty() bool {
+	return q.RefID == 0 && q.Authority == "" && q.OrderID == "" && q.CardPanSuffix == ""
+}
+
+// matches reports whether the stored payment passes the fields the store knows, payments
+// without a reference ID yet aren't told apart by it
+func (q TransactionQuery) matches(payment StoredPayment) bool {
+	return (q.Authority == "" || payment.Authority == q.Authority) &&
+		(q.OrderID == "" || payment.OrderID == q.OrderID) &&
+		(q.RefID == 0 || payment.RefID == 0 || payment.RefID == q.RefID)
+}
+
+// SearchResult is a transaction found by a search along with its stored payment, either is nil
+// when only one source knows it
+type SearchResult struct {
+	Transaction *Transaction
+	Payment     *StoredPayment
+}
+
+// TransactionSearch locates transactions by the details customers give support, on the
+// reporting API and the payment store. Either source can be nil, order IDs are only known to
+// the store and cards only to the reporting API.
+type TransactionSearch struct {
+	Reporting *Reporting
+	Store     PaymentStore
+}
+
+// Search returns the transactions matching the query, the ones of the reporting API newest
+// first followed by the stored payments it didn't return
+func (s *TransactionSearch) Search(ctx context.Context, query TransactionQuery) (results []SearchResult, err error) {
+	switch {
+	case query.empty():
+		return nil, ErrInvalidSearch
+	case query.OrderID != "" && s.Store == nil:
+		return nil, fmt.Errorf("%w: order IDs need a payment store", ErrInvalidSearch)
+	case query.CardPanSuffix != "" && s.Reporting == nil:
+		return nil, fmt.Errorf("%w: cards need the reporting API", ErrInvalidSearch)
+	}
+
+	stored, err := s.storedPayments(ctx, query)
+	if err != nil {
+		return
+	}
+
+	found := make(map[string]bool)
+	if s.Reporting != nil {
+		filter := TransactionFilter{
+			From:          query.From,
+			To:            query.To,
+			CardPanSuffix: query.CardPanSuffix,
+			RefID:         query.RefID,
+			Authority:     query.Authority,
+		}
+		var filters []TransactionFilter
+		if query.OrderID != "" || (query.Authority != "" && len(stored) > 0) {
+			// look for the transactions of the stored payments around their creation
+			for _, payment := range stored {
+				f := filter
+				f.Authority = payment.Authority
+				if f.From.IsZero() {
+					f.From = payment.CreatedAt.Add(-searchWindow)
+					f.To = payment.CreatedAt.Add(searchWindow)
+				}
+				filters = append(filters, f)
+			}
+		} else {
+			filters = append(filters, filter)
+		}
+
+		for _, f := range filters {
+			var transactions []Transaction
+			if transactions, err = s.Reporting.FilterTransactions(ctx, f); err != nil {
+				return
+			}
+			for i := range transactions {
+				result := SearchResult{Transaction: &transactions[i]}
+				if result.Payment, err = s.payment(ctx, stored, transactions[i].Authority); err != nil {
+					return
+				}
+				found[transactions[i].Authority] = true
+				results = append(results, result)
+			}
+		}
+	}
+
+	if query.CardPanSuffix != "" {
+		// the store doesn't know cards
+		return
+	}
+	for i := range stored {
+		if !found[stored[i].Authority] {
+			results = append(results, SearchResult{Payment: &stored[i]})
+		}
+	}
+	return
+}
+
+// storedPayments returns the stored payments of the authority or order of the query, newest first
+func (s *TransactionSearch) storedPayments(ctx context.Context, query TransactionQuery) (payments []StoredPayment, err error) {
+	if s.Store == nil {
+		return
+	}
+
+	var candidates []StoredPayment
+	switch {
+	case query.Authority != "":
+		payment, getErr := s.Store.GetByAuthority(ctx, query.Authority)
+		if getErr != nil && !errors.Is(getErr, ErrPaymentNotFound) {
+			return nil, getErr
+		}
+		if getErr == nil {
+			candidates = append(candidates, payment)
+		}
+	case query.OrderID != "":
+		if lister, ok := s.Store.(PaymentLister); ok {
+			if candidates, err = lister.ListByOrderID(ctx, query.OrderID); err != nil {
+				return
+			}
+			break
+		}
+		payment, getErr := s.Store.GetByOrderID(ctx, query.OrderID)
+		if getErr != nil && !errors.Is(getErr, ErrPaymentNotFound) {
+			return nil, getErr
+		}
+		if getErr == nil {
+			candidates = append(candidates, payment)
+		}
+	}
+
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if query.matches(candidates[i]) {
+			payments = append(payments, candidates[i])
+		}
+	}
+	return
+}
+
+// payment returns the stored payment of the authority, nil when the store doesn't know it
+func (s *TransactionSearch) payment(ctx context.Context, stored []StoredPayment, authority string) (*StoredPayment, error) {
+	for i := range stored {
+		if stored[i].Authority == authority {
+			return &stored[i], nil
+		}
+	}
+	if s.Store == nil {
+		return nil, nil
+	}
+	payment, err := s.Store.GetByAuthority(ctx, authority)
+	if errors.Is(err, ErrPaymentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
@@ -0,0 +1,88 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransactionSearch(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the API ignores the arguments here, the client still applies them
+		fmt.Fprintf(w, `{"data":{"Session":[
+			{"id":"3","authority":"A3","status":"VERIFIED","amount":30000,"reference_id":203,"card_pan":"603799******1234","created_at":%q},
+			{"id":"2","authority":"A2","status":"VERIFIED","amount":20000,"reference_id":202,"card_pan":"502229******5995","created_at":%q},
+			{"id":"1","authority":"A1","status":"FAILED","amount":20000,"card_pan":"502229******5995","created_at":%q}
+		]}}`, now.Format(time.RFC3339), now.Add(-time.Minute).Format(time.RFC3339), now.Add(-2*time.Minute).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	for i, authority := range []string{"A1", "A2", "A4"} {
+		store.SaveSession(ctx, PaymentSession{Authority: authority, Amount: 20000, OrderID: "42", CreatedAt: now.Add(time.Duration(i-2) * time.Minute), ExpiresAt: now.Add(time.Hour)})
+	}
+	advancePayment(ctx, store, "A2", 202, PaymentStatePending, PaymentStateVerified)
+	search := &TransactionSearch{Reporting: reporting, Store: store}
+
+	tests := []struct {
+		name    string
+		query   TransactionQuery
+		want    []string // authorities of the results, "*" for results without a transaction
+		payment bool     // whether the first result has its stored payment
+	}{
+		{"ref ID", TransactionQuery{RefID: 202}, []string{"A2"}, true},
+		{"card", TransactionQuery{CardPanSuffix: "5995"}, []string{"A2", "A1"}, true},
+		{"authority", TransactionQuery{Authority: "A3"}, []string{"A3"}, false},
+		{"order", TransactionQuery{OrderID: "42"}, []string{"A2", "A1", "*A4"}, true},
+		{"order and card", TransactionQuery{OrderID: "42", CardPanSuffix: "5995"}, []string{"A2", "A1"}, true},
+		{"unknown ref ID", TransactionQuery{RefID: 999}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := search.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Failed to search: %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				if result.Transaction == nil {
+					got = append(got, "*"+result.Payment.Authority)
+				} else {
+					got = append(got, result.Transaction.Authority)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			if len(results) > 0 && (results[0].Payment != nil) != tt.payment {
+				t.Errorf("Expected stored payment %v, got %+v", tt.payment, results[0].Payment)
+			}
+		})
+	}
+}
+
+func TestTransactionSearchInvalid(t *testing.T) {
+	ctx := context.Background()
+	if _, err := (&TransactionSearch{Store: NewMemoryPaymentStore()}).Search(ctx, TransactionQuery{}); !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("Expected ErrInvalidSearch for an empty query, got %v", err)
+	}
+	if _, err := (&TransactionSearch{Store: NewMemoryPaymentStore()}).Search(ctx, TransactionQuery{CardPanSuffix: "5995"}); !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("Expected ErrInvalidSearch for a card without the reporting API, got %v", err)
+	}
+	if _, err := (&TransactionSearch{Reporting: NewReporting("token", "terminal-1")}).Search(ctx, TransactionQuery{OrderID: "42"}); !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("Expected ErrInvalidSearch for an order without a store, got %v", err)
+	}
+
+	results, err := (&TransactionSearch{Store: NewMemoryPaymentStore()}).Search(ctx, TransactionQuery{Authority: "A1"})
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no results, got %+v %v", results, err)
+	}
+}
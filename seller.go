@@ -0,0 +1,187 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Seller registry errors
+var (
+	ErrSellerNotFound = errors.New("seller not found")
+	ErrInvalidSeller  = errors.New("invalid seller")
+	ErrSellerInactive = errors.New("seller is inactive")
+)
+
+// Seller is a sub-merchant of a marketplace, paid through wages
+type Seller struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Iban       string         `json:"iban"`
+	Commission CommissionRule `json:"commission"`
+	Active     bool           `json:"active"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// Validate checks the seller can be paid
+func (s Seller) Validate() error {
+	if s.ID == "" || s.Name == "" {
+		return fmt.Errorf("%w: %q needs an ID and a name", ErrInvalidSeller, s.ID)
+	}
+	if err := ValidateIBAN(s.Iban); err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrInvalidSeller, s.ID, err)
+	}
+	c := s.Commission
+	if c.BasisPoints < 0 || c.BasisPoints > 10000 || c.Fixed < 0 || c.Min < 0 || c.Max < 0 || (c.Max > 0 && c.Min > c.Max) {
+		return fmt.Errorf("%w: %q has an invalid commission %+v", ErrInvalidSeller, s.ID, c)
+	}
+	return nil
+}
+
+// SellerStore keeps sellers, lookups of unknown ones return ErrSellerNotFound
+type SellerStore interface {
+	// SaveSeller creates or replaces the seller
+	SaveSeller(ctx context.Context, seller Seller) error
+	GetSeller(ctx context.Context, id string) (Seller, error)
+	// ListSellers returns every seller ordered by ID
+	ListSellers(ctx context.Context) ([]Seller, error)
+}
+
+// SellerRegistry validates and keeps the sellers of a marketplace, it fills the splits of
+// orders and names the sellers of settlement reports
+type SellerRegistry struct {
+	Store SellerStore
+}
+
+// NewSellerRegistry creates a registry keeping sellers in the store
+func NewSellerRegistry(store SellerStore) *SellerRegistry {
+	return &SellerRegistry{Store: store}
+}
+
+// Register validates and saves the seller, replacing the one of the same ID. An IBAN belongs to
+// a single seller.
+func (r *SellerRegistry) Register(ctx context.Context, seller Seller) error {
+	if err := seller.Validate(); err != nil {
+		return err
+	}
+	if owner, err := r.ByIban(ctx, seller.Iban); err == nil && owner.ID != seller.ID {
+		return fmt.Errorf("%w: IBAN of %q already belongs to %q", ErrInvalidSeller, seller.ID, owner.ID)
+	} else if err != nil && !errors.Is(err, ErrSellerNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	seller.CreatedAt = now
+	if existing, err := r.Store.GetSeller(ctx, seller.ID); err == nil {
+		seller.CreatedAt = existing.CreatedAt
+	}
+	seller.UpdatedAt = now
+	return r.Store.SaveSeller(ctx, seller)
+}
+
+// SetActive activates or deactivates the seller, inactive sellers can't be added to splits
+func (r *SellerRegistry) SetActive(ctx context.Context, id string, active bool) error {
+	seller, err := r.Store.GetSeller(ctx, id)
+	if err != nil {
+		return err
+	}
+	seller.Active = active
+	seller.UpdatedAt = time.Now()
+	return r.Store.SaveSeller(ctx, seller)
+}
+
+// Get returns the seller of the ID
+func (r *SellerRegistry) Get(ctx context.Context, id string) (Seller, error) {
+	return r.Store.GetSeller(ctx, id)
+}
+
+// ByIban returns the seller paid to the IBAN
+func (r *SellerRegistry) ByIban(ctx context.Context, iban string) (Seller, error) {
+	sellers, err := r.Store.ListSellers(ctx)
+	if err != nil {
+		return Seller{}, err
+	}
+	for _, seller := range sellers {
+		if seller.Iban == iban {
+			return seller, nil
+		}
+	}
+	return Seller{}, ErrSellerNotFound
+}
+
+// AddItem adds a sale of the seller to the split along with the commission of the seller
+func (r *SellerRegistry) AddItem(ctx context.Context, split *SplitBuilder, sellerID string, amount int, description string) error {
+	seller, err := r.Store.GetSeller(ctx, sellerID)
+	if err != nil {
+		return err
+	}
+	split.Seller(seller, amount, description)
+	return split.err
+}
+
+// NameSellers sets the ID and name of the registered sellers of the report
+func (r *SellerRegistry) NameSellers(ctx context.Context, report *SettlementReport) error {
+	sellers, err := r.Store.ListSellers(ctx)
+	if err != nil {
+		return err
+	}
+	byIban := make(map[string]Seller, len(sellers))
+	for _, seller := range sellers {
+		byIban[seller.Iban] = seller
+	}
+	for i := range report.Sellers {
+		if seller, ok := byIban[report.Sellers[i].Iban]; ok {
+			report.Sellers[i].SellerID = seller.ID
+			report.Sellers[i].Name = seller.Name
+		}
+	}
+	return nil
+}
+
+// MemorySellerStore is a SellerStore keeping sellers in memory, it is safe for concurrent use
+type MemorySellerStore struct {
+	mu      sync.Mutex
+	sellers map[string]Seller
+}
+
+// NewMemorySellerStore creates an empty MemorySellerStore
+func NewMemorySellerStore() *MemorySellerStore {
+	return &MemorySellerStore{sellers: make(map[string]Seller)}
+}
+
+// SaveSeller implements SellerStore
+func (s *MemorySellerStore) SaveSeller(ctx context.Context, seller Seller) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sellers[seller.ID] = seller
+	return nil
+}
+
+// GetSeller implements SellerStore
+func (s *MemorySellerStore) GetSeller(ctx context.Context, id string) (Seller, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seller, ok := s.sellers[id]
+	if !ok {
+		return Seller{}, ErrSellerNotFound
+	}
+	return seller, nil
+}
+
+// ListSellers implements SellerStore
+func (s *MemorySellerStore) ListSellers(ctx context.Context) (sellers []Seller, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, seller := range s.sellers {
+		sellers = append(sellers, seller)
+	}
+	sort.Slice(sellers, func(i, j int) bool { return sellers[i].ID < sellers[j].ID })
+	return
+}
@@ -0,0 +1,61 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSellerRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewSellerRegistry(NewMemorySellerStore())
+
+	desks := Seller{ID: "desks", Name: "Desk Co", Iban: sellerA, Commission: CommissionRule{BasisPoints: 500}, Active: true}
+	if err := registry.Register(ctx, desks); err != nil {
+		t.Fatal(err)
+	}
+	lamps := Seller{ID: "lamps", Name: "Lamp Co", Iban: sellerB, Commission: CommissionRule{BasisPoints: 1000}, Active: true}
+	registry.Register(ctx, lamps)
+
+	for _, invalid := range []Seller{
+		{ID: "x", Name: "X", Iban: "IR00"},
+		{ID: "x", Iban: sellerA},
+		{ID: "x", Name: "X", Iban: sellerA},
+		{ID: "x", Name: "X", Iban: fixChecksum("IR000560611828005012345678"), Commission: CommissionRule{BasisPoints: 12000}},
+	} {
+		if err := registry.Register(ctx, invalid); !errors.Is(err, ErrInvalidSeller) {
+			t.Errorf("Expected ErrInvalidSeller for %+v, got %v", invalid, err)
+		}
+	}
+
+	if seller, err := registry.ByIban(ctx, sellerB); err != nil || seller.ID != "lamps" {
+		t.Errorf("Expected the lamps seller, got %+v and %v", seller, err)
+	}
+	if _, err := registry.Get(ctx, "chairs"); !errors.Is(err, ErrSellerNotFound) {
+		t.Errorf("Expected ErrSellerNotFound, got %v", err)
+	}
+
+	split := NewSplit()
+	if err := registry.AddItem(ctx, split, "desks", 200000, "Desk"); err != nil {
+		t.Fatal(err)
+	}
+	registry.AddItem(ctx, split, "lamps", 100000, "Lamp")
+	wages, err := split.Wages()
+	if err != nil || len(wages) != 2 || wages[0].Amount != 190000 || wages[1].Amount != 90000 {
+		t.Errorf("Expected the wages minus the commissions of the sellers, got %+v and %v", wages, err)
+	}
+
+	registry.SetActive(ctx, "lamps", false)
+	if err := registry.AddItem(ctx, NewSplit(), "lamps", 100000, ""); !errors.Is(err, ErrSellerInactive) {
+		t.Errorf("Expected ErrSellerInactive, got %v", err)
+	}
+
+	report := Settle([]WagePayment{{Authority: "A1", Amount: 300000, VerifiedAt: time.Now(), Wages: wages}}, time.Time{}, time.Now().Add(time.Minute), FeeOnMerchant)
+	if err := registry.NameSellers(ctx, &report); err != nil {
+		t.Fatal(err)
+	}
+	if seller, _ := report.Seller(sellerA); seller.SellerID != "desks" || seller.Name != "Desk Co" {
+		t.Errorf("Expected the report to name Desk Co, got %+v", seller)
+	}
+}
@@ -0,0 +1,173 @@
+// Package server exposes a Zarinpal client as a small authenticated HTTP API, so a single
+// service can hold the merchant credentials for many applications.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 20
+
+// Server serves the payment API:
+//
+//	POST /payments              create a payment
+//	POST /payments/verify       verify a payment
+//	GET  /payments/unverified   list unverified payments
+//	GET  /payments/{authority}  inquire the status of a payment
+type Server struct {
+	z     zarinpalgo.Client
+	token string
+	mux   *http.ServeMux
+}
+
+// CreatePaymentRequest is the body of POST /payments
+type CreatePaymentRequest struct {
+	Amount      int                  `json:"amount"`
+	Description string               `json:"description"`
+	CallbackURL string               `json:"callback_url"`
+	Metadata    *zarinpalgo.Metadata `json:"metadata,omitempty"`
+	Wages       []zarinpalgo.Wage    `json:"wages,omitempty"`
+}
+
+// CreatePaymentResponse is the response of POST /payments
+type CreatePaymentResponse struct {
+	Authority  string `json:"authority"`
+	PaymentURL string `json:"payment_url"`
+	FeeType    string `json:"fee_type"`
+	Fee        int    `json:"fee"`
+}
+
+// VerifyPaymentRequest is the body of POST /payments/verify
+type VerifyPaymentRequest struct {
+	Amount    int    `json:"amount"`
+	Authority string `json:"authority"`
+}
+
+// ErrorResponse is the body of failed requests, Code is set when Zarinpal returned an error code
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  int    `json:"code,omitempty"`
+}
+
+// New creates a new Server authenticating requests with the bearer token
+func New(z zarinpalgo.Client, token string) *Server {
+	s := &Server{
+		z:     z,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("POST /payments", s.createPayment)
+	s.mux.HandleFunc("POST /payments/verify", s.verifyPayment)
+	s.mux.HandleFunc("GET /payments/unverified", s.unverifiedPayments)
+	s.mux.HandleFunc("GET /payments/{authority}", s.inquirePayment)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) createPayment(w http.ResponseWriter, r *http.Request) {
+	var body CreatePaymentRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	payment, err := s.z.CreatePayment(r.Context(), zarinpalgo.PaymentParams{
+		Amount:      body.Amount,
+		Description: body.Description,
+		CallbackURL: body.CallbackURL,
+		Metadata:    body.Metadata,
+		Wages:       body.Wages,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreatePaymentResponse{
+		Authority:  payment.Authority,
+		PaymentURL: s.z.GetPaymentURL(payment.Authority),
+		FeeType:    payment.FeeType,
+		Fee:        payment.Fee,
+	})
+}
+
+func (s *Server) verifyPayment(w http.ResponseWriter, r *http.Request) {
+	var body VerifyPaymentRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	status, err := s.z.CheckPaymentStatus(r.Context(), body.Amount, body.Authority)
+	var apiErr *zarinpalgo.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) inquirePayment(w http.ResponseWriter, r *http.Request) {
+	inquiry, err := s.z.InquirePayment(r.Context(), r.PathValue("authority"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, inquiry)
+}
+
+func (s *Server) unverifiedPayments(w http.ResponseWriter, r *http.Request) {
+	unverified, err := s.z.UnverifiedPayments(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, unverified)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return false
+	}
+	return true
+}
+
+// writeError answers gateway rejections with 422 and transport failures with 502
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *zarinpalgo.APIError
+	if errors.As(err, &apiErr) {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: apiErr.Message, Code: apiErr.Code})
+		return
+	}
+	writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "request.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`))
+		case strings.HasSuffix(r.URL.Path, "verify.json"):
+			w.Write([]byte(`{"data":[],"errors":{"code":-51,"message":"Session is not active, paid try","validations":[]}}`))
+		case strings.HasSuffix(r.URL.Path, "inquiry.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`))
+		default:
+			w.Write([]byte(`{"data":[],"errors":{"code":-10,"message":"Terminal is not valid","validations":[]}}`))
+		}
+	}))
+	t.Cleanup(gateway.Close)
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = gateway.URL + "/"
+	return New(zp, "token")
+}
+
+func do(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreatePayment(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := do(s, "POST", "/payments", `{"amount":10000,"description":"Order 1","callback_url":"https://example.com/callback"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var resp CreatePaymentResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Authority != "A1" || !strings.HasSuffix(resp.PaymentURL, "/StartPay/A1") {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
+
+func TestVerifyAndInquire(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := do(s, "POST", "/payments/verify", `{"amount":10000,"authority":"A1"}`)
+	var status zarinpalgo.PaymentStatus
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.IsSuccessful {
+		t.Errorf("Expected unsuccessful status, got %d %+v", rec.Code, status)
+	}
+
+	rec = do(s, "GET", "/payments/A1", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"PAID"`) {
+		t.Errorf("Unexpected inquiry response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(s, "GET", "/payments/unverified", "")
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"code":-10`) {
+		t.Errorf("Expected gateway error to be passed through, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/payments/unverified", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec = do(s, "POST", "/payments", `{"amount":"many"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
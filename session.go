@@ -0,0 +1,124 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPaymentNotRetryable is returned by RetryPayment for sessions that were paid, can still be
+// paid or don't carry their parameters
+var ErrPaymentNotRetryable = errors.New("payment can't be retried")
+
+// Currency is the currency a payment amount is expressed in
+type Currency string
+
+// Currency values
+const (
+	CurrencyRial  Currency = "IRR"
+	CurrencyToman Currency = "IRT"
+)
+
+// DefaultSessionTTL is how long an authority can be used to pay after it is created
+const DefaultSessionTTL = 15 * time.Minute
+
+// PaymentSession binds an authority to the amount it was created for, pass it to Verify
+// instead of pairing amounts and authorities by hand
+type PaymentSession struct {
+	Authority  string    `json:"authority"`
+	Amount     int       `json:"amount"`
+	Currency   Currency  `json:"currency,omitempty"`
+	OrderID    string    `json:"order_id,omitempty"`
+	PaymentURL string    `json:"payment_url"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Params are the parameters the payment was created with, RetryPayment needs them
+	Params *PaymentParams `json:"params,omitempty"`
+	// RetryOf is the authority of the session this one retries
+	RetryOf string `json:"retry_of,omitempty"`
+}
+
+// IsExpired reports whether the authority of the session can no longer be paid
+func (s PaymentSession) IsExpired() bool {
+	return !time.Now().Before(s.ExpiresAt)
+}
+
+// CreatedAtJalali returns the Jalali date the session was created on in Iran
+func (s PaymentSession) CreatedAtJalali() JalaliDate {
+	return ToJalali(s.CreatedAt.In(IranLocation))
+}
+
+// ExpiresAtJalali returns the Jalali date the session expires on in Iran
+func (s PaymentSession) ExpiresAtJalali() JalaliDate {
+	return ToJalali(s.ExpiresAt.In(IranLocation))
+}
+
+// NewSession creates a payment and returns its session, the order ID is taken from the metadata
+func (z *Zarinpal) NewSession(ctx context.Context, params PaymentParams) (session PaymentSession, err error) {
+	payment, err := z.CreatePayment(ctx, params)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	session = PaymentSession{
+		Authority:  payment.Authority,
+		Amount:     params.Amount,
+		Currency:   params.Currency,
+		PaymentURL: z.GetPaymentURL(payment.Authority),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(DefaultSessionTTL),
+		Params:     &params,
+	}
+	if params.Metadata != nil {
+		session.OrderID = params.Metadata.OrderID
+	}
+	return
+}
+
+// authorityGoneCodes are the inquiry errors meaning the authority is no longer known or active
+var authorityGoneCodes = map[int]bool{
+	-51: true, // session is not active
+	-54: true, // invalid authority
+	-55: true, // payment request not found
+}
+
+// RetryPayment creates a fresh payment with the parameters of a session whose authority expired
+// or was used by a failed payment, the new session links to the original one and its
+// PaymentURL is where the user pays. Paid sessions and ones the user can still pay fail with
+// ErrPaymentNotRetryable.
+func (z *Zarinpal) RetryPayment(ctx context.Context, session PaymentSession) (retry PaymentSession, err error) {
+	if session.Params == nil {
+		err = fmt.Errorf("%w: session %s has no parameters", ErrPaymentNotRetryable, session.Authority)
+		return
+	}
+
+	inquiry, err := z.InquirePayment(ctx, session.Authority)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && authorityGoneCodes[apiErr.Code]:
+		// the authority is no longer known or active
+	case err != nil:
+		return
+	case inquiry.Status == InquiryStatusPaid || inquiry.Status == InquiryStatusVerified:
+		err = fmt.Errorf("%w: session %s was paid", ErrPaymentNotRetryable, session.Authority)
+		return
+	case inquiry.Status != InquiryStatusFailed && inquiry.Status != InquiryStatusReversed && !session.IsExpired():
+		err = fmt.Errorf("%w: session %s can still be paid", ErrPaymentNotRetryable, session.Authority)
+		return
+	}
+
+	retry, err = z.NewSession(ctx, *session.Params)
+	if err != nil {
+		return
+	}
+	retry.RetryOf = session.Authority
+	return
+}
+
+// Verify verifies the payment of a session
+func (z *Zarinpal) Verify(ctx context.Context, session PaymentSession) (PaymentStatus, error) {
+	return z.CheckPaymentStatus(ctx, session.Amount, session.Authority)
+}
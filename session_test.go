@@ -0,0 +1,102 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPaymentSession(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`,
+		"verify.json":  `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	ctx := context.Background()
+	session, err := zp.NewSession(ctx, PaymentParams{
+		Amount:      1000,
+		Currency:    CurrencyToman,
+		Description: "Order 42",
+		CallbackURL: "https://example.com/callback",
+		Metadata:    &Metadata{OrderID: "42"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	if session.Authority != "A1" || session.Amount != 1000 || session.Currency != CurrencyToman || session.OrderID != "42" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+	if session.PaymentURL != zp.GetPaymentURL("A1") {
+		t.Errorf("Expected payment URL %s, got %s", zp.GetPaymentURL("A1"), session.PaymentURL)
+	}
+	if session.IsExpired() || session.ExpiresAt.Sub(session.CreatedAt) != DefaultSessionTTL {
+		t.Errorf("Expected session to expire in %s, got %+v", DefaultSessionTTL, session)
+	}
+
+	status, err := zp.Verify(ctx, session)
+	if err != nil || !status.IsSuccessful || status.Amount != 1000 || status.Authority != "A1" {
+		t.Errorf("Unexpected status %+v: %v", status, err)
+	}
+
+	session.ExpiresAt = time.Now().Add(-time.Second)
+	if !session.IsExpired() {
+		t.Error("Expected session to be expired")
+	}
+}
+
+func TestRetryPayment(t *testing.T) {
+	params := PaymentParams{Amount: 1000, Description: "Order 42", CallbackURL: "https://example.com/callback", Metadata: &Metadata{OrderID: "42"}}
+	original := PaymentSession{Authority: "A1", Amount: 1000, OrderID: "42", ExpiresAt: time.Now().Add(time.Minute), Params: &params}
+	created := `{"data":{"code":100,"message":"Success","authority":"A2","fee_type":"Merchant","fee":100},"errors":[]}`
+	inquiry := func(status string) string {
+		return `{"data":{"code":100,"message":"Success","status":"` + status + `"},"errors":[]}`
+	}
+
+	zp := newStubClient(t, map[string]string{"request.json": created, "inquiry.json": inquiry(InquiryStatusFailed)})
+	retry, err := zp.RetryPayment(context.Background(), original)
+	if err != nil {
+		t.Fatalf("Failed to retry: %v", err)
+	}
+	if retry.Authority != "A2" || retry.RetryOf != "A1" || retry.OrderID != "42" || retry.Amount != 1000 || retry.PaymentURL != zp.GetPaymentURL("A2") {
+		t.Errorf("Unexpected retry %+v", retry)
+	}
+	if retry.Params == nil || retry.Params.Description != "Order 42" || retry.Params.CallbackURL != params.CallbackURL {
+		t.Errorf("Expected the parameters of the original, got %+v", retry.Params)
+	}
+
+	expired := original
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	tests := []struct {
+		name    string
+		inquiry string
+		session PaymentSession
+		retried bool
+	}{
+		{"expired", inquiry(InquiryStatusInBank), expired, true},
+		{"unknown authority", `{"data":[],"errors":{"code":-54,"message":"Invalid authority.","validations":[]}}`, original, true},
+		{"payable", inquiry(InquiryStatusInBank), original, false},
+		{"paid", inquiry(InquiryStatusPaid), expired, false},
+		{"no parameters", inquiry(InquiryStatusFailed), PaymentSession{Authority: "A1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zp := newStubClient(t, map[string]string{"request.json": created, "inquiry.json": tt.inquiry})
+			retry, err := zp.RetryPayment(context.Background(), tt.session)
+			if tt.retried && (err != nil || retry.Authority != "A2") {
+				t.Errorf("Expected a retry, got %+v %v", retry, err)
+			}
+			if !tt.retried && !errors.Is(err, ErrPaymentNotRetryable) {
+				t.Errorf("Expected ErrPaymentNotRetryable, got %v", err)
+			}
+		})
+	}
+
+	// other inquiry errors say nothing about the authority
+	zp = newStubClient(t, map[string]string{"request.json": created, "inquiry.json": `{"data":[],"errors":{"code":-12,"message":"Too many attempts, please try again later.","validations":[]}}`})
+	var apiErr *APIError
+	if retry, err := zp.RetryPayment(context.Background(), original); !errors.As(err, &apiErr) || apiErr.Code != -12 {
+		t.Errorf("Expected the inquiry error, got %+v %v", retry, err)
+	}
+}
@@ -0,0 +1,146 @@
+package zarinpalgo
+
+import (
+	"sort"
+	"time"
+)
+
+// FeeAllocation is who bears the gateway fee of payments with wages
+type FeeAllocation int
+
+// FeeAllocation constants
+const (
+	FeeOnMerchant   FeeAllocation = iota // the marketplace pays the whole fee
+	FeeProportional                      // sellers pay the share of the fee of their wage, rounded down
+)
+
+// WagePayment is a verified payment along with the wages it was created with, the input of
+// settlement reports
+type WagePayment struct {
+	Authority  string
+	RefID      int
+	Amount     int // in Rials
+	Fee        int // charged by Zarinpal
+	VerifiedAt time.Time
+	Wages      []Wage
+}
+
+// WagePaymentOf returns the settlement input of a stored payment, with its amount and wages
+// converted to Rials and VerifiedAt taken from the last update of the payment. It returns false
+// for payments that aren't verified and for ones stored without their parameters, whose wages
+// are unknown; the memory, sqlstore and redisstore stores keep the parameters of the sessions
+// they save. Stores don't keep the fee, set Fee from the verification response or the
+// transaction reports.
+func WagePaymentOf(payment StoredPayment) (WagePayment, bool) {
+	if payment.State != PaymentStateVerified || payment.Params == nil {
+		return WagePayment{}, false
+	}
+	wages := make([]Wage, len(payment.Params.Wages))
+	for i, wage := range payment.Params.Wages {
+		wage.Amount = int(MoneyOf(wage.Amount, payment.Currency).Rials())
+		wages[i] = wage
+	}
+	return WagePayment{
+		Authority:  payment.Authority,
+		RefID:      payment.RefID,
+		Amount:     int(MoneyOf(payment.Amount, payment.Currency).Rials()),
+		VerifiedAt: payment.UpdatedAt,
+		Wages:      wages,
+	}, true
+}
+
+// SettlementLine is the wage of a seller in one payment
+type SettlementLine struct {
+	Authority     string    `json:"authority"`
+	RefID         int       `json:"ref_id"`
+	VerifiedAt    time.Time `json:"verified_at"`
+	PaymentAmount int       `json:"payment_amount"`
+	Wage          int       `json:"wage"`
+	FeeShare      int       `json:"fee_share"`
+	Description   string    `json:"description,omitempty"`
+}
+
+// SellerSettlement totals the wages paid to an IBAN
+type SellerSettlement struct {
+	Iban     string           `json:"iban"`
+	SellerID string           `json:"seller_id,omitempty"` // set by SellerRegistry.NameSellers
+	Name     string           `json:"name,omitempty"`
+	Payments int              `json:"payments"`
+	Gross    int              `json:"gross"` // sum of the wages
+	Fees     int              `json:"fees"`  // share of the fees allocated to the seller
+	Lines    []SettlementLine `json:"lines"` // ordered by verification time
+}
+
+// Net returns the amount the seller receives
+func (s SellerSettlement) Net() int {
+	return s.Gross - s.Fees
+}
+
+// SettlementReport is the settlement of the wages of the payments verified in [From, To)
+type SettlementReport struct {
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Payments int                `json:"payments"`
+	Sellers  []SellerSettlement `json:"sellers"` // ordered by IBAN
+}
+
+// Settle aggregates the wages of the payments verified in [from, to) per IBAN, payments
+// without wages are counted but not settled
+func Settle(payments []WagePayment, from, to time.Time, allocation FeeAllocation) SettlementReport {
+	report := SettlementReport{From: from, To: to}
+	sellers := make(map[string]*SellerSettlement)
+
+	for _, payment := range payments {
+		if payment.VerifiedAt.Before(from) || !payment.VerifiedAt.Before(to) {
+			continue
+		}
+		report.Payments++
+
+		for _, wage := range payment.Wages {
+			seller, ok := sellers[wage.Iban]
+			if !ok {
+				seller = &SellerSettlement{Iban: wage.Iban}
+				sellers[wage.Iban] = seller
+			}
+
+			line := SettlementLine{
+				Authority:     payment.Authority,
+				RefID:         payment.RefID,
+				VerifiedAt:    payment.VerifiedAt,
+				PaymentAmount: payment.Amount,
+				Wage:          wage.Amount,
+				Description:   wage.Description,
+			}
+			if allocation == FeeProportional && payment.Amount > 0 {
+				line.FeeShare = payment.Fee * wage.Amount / payment.Amount
+			}
+
+			seller.Payments++
+			seller.Gross += line.Wage
+			seller.Fees += line.FeeShare
+			seller.Lines = append(seller.Lines, line)
+		}
+	}
+
+	report.Sellers = make([]SellerSettlement, 0, len(sellers))
+	for _, seller := range sellers {
+		sort.SliceStable(seller.Lines, func(i, j int) bool {
+			return seller.Lines[i].VerifiedAt.Before(seller.Lines[j].VerifiedAt)
+		})
+		report.Sellers = append(report.Sellers, *seller)
+	}
+	sort.Slice(report.Sellers, func(i, j int) bool {
+		return report.Sellers[i].Iban < report.Sellers[j].Iban
+	})
+	return report
+}
+
+// Seller returns the settlement of the IBAN
+func (r SettlementReport) Seller(iban string) (SellerSettlement, bool) {
+	for _, seller := range r.Sellers {
+		if seller.Iban == iban {
+			return seller, true
+		}
+	}
+	return SellerSettlement{}, false
+}
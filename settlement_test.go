@@ -0,0 +1,72 @@
+package zarinpalgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettle(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	payments := []WagePayment{
+		{Authority: "A2", Amount: 300000, Fee: 3000, VerifiedAt: from.Add(48 * time.Hour), Wages: []Wage{
+			{Iban: "IR02", Amount: 100000},
+			{Iban: "IR01", Amount: 150000, Description: "Lamp"},
+		}},
+		{Authority: "A1", Amount: 100000, Fee: 999, VerifiedAt: from.Add(time.Hour), Wages: []Wage{{Iban: "IR01", Amount: 50000}}},
+		{Authority: "A3", Amount: 20000, VerifiedAt: from.Add(time.Hour)},
+		{Authority: "A4", Amount: 90000, Fee: 900, VerifiedAt: to, Wages: []Wage{{Iban: "IR01", Amount: 80000}}},
+	}
+
+	report := Settle(payments, from, to, FeeProportional)
+	if report.Payments != 3 || len(report.Sellers) != 2 || report.Sellers[0].Iban != "IR01" {
+		t.Fatalf("Expected 3 payments settled to IR01 and IR02, got %+v", report)
+	}
+
+	seller, _ := report.Seller("IR01")
+	// 999 * 50000 / 100000 is 499.5 and 3000 * 150000 / 300000 is 1500
+	if seller.Payments != 2 || seller.Gross != 200000 || seller.Fees != 1999 || seller.Net() != 198001 {
+		t.Errorf("Unexpected settlement of IR01: %+v", seller)
+	}
+	if seller.Lines[0].Authority != "A1" || seller.Lines[1].Description != "Lamp" {
+		t.Errorf("Expected the lines ordered by verification, got %+v", seller.Lines)
+	}
+
+	onMerchant := Settle(payments, from, to, FeeOnMerchant)
+	if seller, _ := onMerchant.Seller("IR02"); seller.Fees != 0 || seller.Net() != 100000 {
+		t.Errorf("Expected the marketplace to pay the fees, got %+v", seller)
+	}
+	if _, ok := onMerchant.Seller("IR03"); ok {
+		t.Error("Expected no settlement of IR03")
+	}
+}
+
+func TestWagePaymentOf(t *testing.T) {
+	verifiedAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	payment := StoredPayment{
+		PaymentSession: PaymentSession{Authority: "A1", Amount: 10000, Currency: CurrencyToman, Params: &PaymentParams{
+			Amount: 10000,
+			Wages:  []Wage{{Iban: "IR01", Amount: 4000, Description: "Lamp"}},
+		}},
+		State:     PaymentStateVerified,
+		RefID:     201,
+		UpdatedAt: verifiedAt,
+	}
+
+	wagePayment, ok := WagePaymentOf(payment)
+	if !ok || wagePayment.Amount != 100000 || wagePayment.RefID != 201 || !wagePayment.VerifiedAt.Equal(verifiedAt) {
+		t.Fatalf("Expected the payment in Rials, got %+v %v", wagePayment, ok)
+	}
+	if len(wagePayment.Wages) != 1 || wagePayment.Wages[0].Amount != 40000 || payment.Params.Wages[0].Amount != 4000 {
+		t.Errorf("Expected the wages converted to Rials without touching the session, got %+v", wagePayment.Wages)
+	}
+
+	payment.State = PaymentStatePending
+	if _, ok := WagePaymentOf(payment); ok {
+		t.Error("Expected no settlement of a pending payment")
+	}
+	payment.State, payment.Params = PaymentStateVerified, nil
+	if _, ok := WagePaymentOf(payment); ok {
+		t.Error("Expected no settlement of a payment without its parameters")
+	}
+}
@@ -0,0 +1,239 @@
+package zarinpalgo
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+var (
+	// ErrShortLinkNotFound is returned for short link codes that are unknown or expired
+	ErrShortLinkNotFound = errors.New("short link not found")
+	// ErrShortLinkTaken is returned by ShortLinkStore.CreateShortLink for codes already in use
+	ErrShortLinkTaken = errors.New("short link code is taken")
+)
+
+// Short link defaults, payment pages stop working well before links expire
+const (
+	DefaultShortLinkTTL        = 24 * time.Hour
+	DefaultShortLinkCodeLength = 7
+)
+
+// ShortLinkStore keeps the targets of short links until they expire, redisstore.Store
+// implements it
+type ShortLinkStore interface {
+	// CreateShortLink saves the target under the code, failing with ErrShortLinkTaken when the
+	// code is in use
+	CreateShortLink(ctx context.Context, code, target string, ttl time.Duration) error
+	GetShortLink(ctx context.Context, code string) (target string, err error)
+}
+
+// ShortLinks turns long payment URLs, like StartPay URLs, into short links that fit in an SMS.
+// Serve its Handler under BaseURL to redirect the links to their targets.
+type ShortLinks struct {
+	BaseURL    string        // the codes are appended to it, like "https://example.com/p/"
+	TTL        time.Duration // DefaultShortLinkTTL when zero
+	CodeLength int           // DefaultShortLinkCodeLength when zero
+
+	store ShortLinkStore
+}
+
+// NewShortLinks creates a ShortLinks saving links in the store
+func NewShortLinks(baseURL string, store ShortLinkStore) *ShortLinks {
+	return &ShortLinks{BaseURL: baseURL, store: store}
+}
+
+// Shorten returns a short link redirecting to target
+func (s *ShortLinks) Shorten(ctx context.Context, target string) (link string, err error) {
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = DefaultShortLinkTTL
+	}
+	length := s.CodeLength
+	if length <= 0 {
+		length = DefaultShortLinkCodeLength
+	}
+
+	// codes are random, a few attempts get past the rare collision
+	for attempt := 0; attempt < 3; attempt++ {
+		code := newShortLinkCode(length)
+		err = s.store.CreateShortLink(ctx, code, target, ttl)
+		if err == nil {
+			return s.BaseURL + code, nil
+		}
+		if !errors.Is(err, ErrShortLinkTaken) {
+			return
+		}
+	}
+	return
+}
+
+// ShortenPayment returns a short link to the payment page of the authority
+func (s *ShortLinks) ShortenPayment(ctx context.Context, z *Zarinpal, authority string) (string, error) {
+	return s.Shorten(ctx, z.GetPaymentURL(authority))
+}
+
+// Handler returns an http.Handler redirecting the short link in the last segment of the path
+// to its target, unknown and expired links are not found
+func (s *ShortLinks) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := strings.ToUpper(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		if code == "" {
+			http.NotFound(w, r)
+			return
+		}
+		target, err := s.store.GetShortLink(r.Context(), code)
+		if errors.Is(err, ErrShortLinkNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+}
+
+// newShortLinkCode returns a random code of the Crockford alphabet, readable over the phone
+// and matched without regard to case
+func newShortLinkCode(length int) string {
+	random := make([]byte, length)
+	if _, err := rand.Read(random); err != nil {
+		panic("zarinpalgo: reading random bytes failed: " + err.Error())
+	}
+	for i, b := range random {
+		random[i] = crockford[b&31]
+	}
+	return string(random)
+}
+
+// MemoryShortLinkStore is a ShortLinkStore keeping links in memory, it is safe for concurrent use
+type MemoryShortLinkStore struct {
+	mu    sync.Mutex
+	links map[string]memoryShortLink
+}
+
+type memoryShortLink struct {
+	target    string
+	expiresAt time.Time
+}
+
+// NewMemoryShortLinkStore creates an empty MemoryShortLinkStore
+func NewMemoryShortLinkStore() *MemoryShortLinkStore {
+	return &MemoryShortLinkStore{links: make(map[string]memoryShortLink)}
+}
+
+// CreateShortLink implements ShortLinkStore
+func (s *MemoryShortLinkStore) CreateShortLink(ctx context.Context, code, target string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if link, ok := s.links[code]; ok && time.Now().Before(link.expiresAt) {
+		return ErrShortLinkTaken
+	}
+	s.links[code] = memoryShortLink{target: target, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// GetShortLink implements ShortLinkStore
+func (s *MemoryShortLinkStore) GetShortLink(ctx context.Context, code string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[code]
+	if !ok || !time.Now().Before(link.expiresAt) {
+		return "", ErrShortLinkNotFound
+	}
+	return link.target, nil
+}
+
+// SMS segment sizes, texts with characters outside the GSM 7-bit alphabet, like Persian ones,
+// are sent as UCS-2 and fit fewer characters
+const (
+	SMSSegmentGSM         = 160
+	SMSSegmentGSMPart     = 153 // of each segment of a multipart message
+	SMSSegmentUnicode     = 70
+	SMSSegmentUnicodePart = 67
+)
+
+const (
+	gsmBasic    = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsmExtended = "^{}\\[~]|€\f"
+)
+
+// SMSSegments returns how many segments the text is sent in
+func SMSSegments(text string) int {
+	units, ucs2 := smsLength(text)
+	single, part := SMSSegmentGSM, SMSSegmentGSMPart
+	if ucs2 {
+		single, part = SMSSegmentUnicode, SMSSegmentUnicodePart
+	}
+	if units <= single {
+		return 1
+	}
+	return (units + part - 1) / part
+}
+
+// smsLength returns the length of the text in septets, or in UTF-16 code units when it needs
+// the Unicode encoding
+func smsLength(text string) (units int, ucs2 bool) {
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsmBasic, r):
+			units++
+		case strings.ContainsRune(gsmExtended, r):
+			units += 2
+		default:
+			return len(utf16.Encode([]rune(text))), true
+		}
+	}
+	return units, false
+}
+
+// ComposeSMS joins the message and the link on separate lines so that the text fits in
+// segments SMS segments, cutting the message with an ellipsis when needed. The link is never
+// cut, when it doesn't fit on its own the text is the link alone.
+func ComposeSMS(message, link string, segments int) string {
+	if segments <= 0 {
+		segments = 1
+	}
+	message = SanitizeDescription(message)
+	if message == "" {
+		return link
+	}
+	if text := message + "\n" + link; SMSSegments(text) <= segments {
+		return text
+	}
+
+	// a Unicode ellipsis would switch GSM texts to the shorter Unicode segments
+	ellipsis := "..."
+	if _, ucs2 := smsLength(message + link); ucs2 {
+		ellipsis = "…"
+	}
+	runes := []rune(message)
+	cut := func(n int) string {
+		return strings.TrimRightFunc(string(runes[:n]), unicode.IsSpace) + ellipsis + "\n" + link
+	}
+
+	// the longest cut that fits, found by bisection
+	lo, hi := 0, len(runes)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if SMSSegments(cut(mid)) <= segments {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 {
+		return link
+	}
+	return cut(lo)
+}
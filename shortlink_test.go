@@ -0,0 +1,95 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShortLinks(t *testing.T) {
+	zp := New("merchant-1")
+	links := NewShortLinks("https://example.com/p/", NewMemoryShortLinkStore())
+
+	link, err := links.ShortenPayment(context.Background(), zp, "A00000000000000000000000000000000001")
+	if err != nil {
+		t.Fatalf("Failed to shorten: %v", err)
+	}
+	if !strings.HasPrefix(link, "https://example.com/p/") || len(link) != len("https://example.com/p/")+DefaultShortLinkCodeLength {
+		t.Fatalf("Expected a short link under the base URL, got %s", link)
+	}
+
+	code := strings.ToLower(link[strings.LastIndex(link, "/")+1:])
+	rec := httptest.NewRecorder()
+	links.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/p/"+code, nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != zp.GetPaymentURL("A00000000000000000000000000000000001") {
+		t.Errorf("Expected a redirect to the payment page, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	links.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/p/UNKNOWN", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown links not found, got %d", rec.Code)
+	}
+}
+
+func TestMemoryShortLinkStoreExpiry(t *testing.T) {
+	store := NewMemoryShortLinkStore()
+	ctx := context.Background()
+
+	store.CreateShortLink(ctx, "A", "https://example.com", -time.Second)
+	if _, err := store.GetShortLink(ctx, "A"); !errors.Is(err, ErrShortLinkNotFound) {
+		t.Errorf("Expected expired links not found, got %v", err)
+	}
+	if err := store.CreateShortLink(ctx, "A", "https://example.com", time.Hour); err != nil {
+		t.Errorf("Expected expired codes to be reused, got %v", err)
+	}
+	if err := store.CreateShortLink(ctx, "A", "https://example.com", time.Hour); !errors.Is(err, ErrShortLinkTaken) {
+		t.Errorf("Expected ErrShortLinkTaken, got %v", err)
+	}
+}
+
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{strings.Repeat("a", 160), 1},
+		{strings.Repeat("a", 161), 2},
+		{strings.Repeat("a", 158) + "{", 1},
+		{strings.Repeat("a", 159) + "{", 2},
+		{strings.Repeat("پ", 70), 1},
+		{strings.Repeat("پ", 71), 2},
+		{strings.Repeat("a", 60) + "پ", 1},
+	}
+	for _, tt := range tests {
+		if got := SMSSegments(tt.text); got != tt.want {
+			t.Errorf("Expected %d segments for %d characters, got %d", tt.want, len([]rune(tt.text)), got)
+		}
+	}
+}
+
+func TestComposeSMS(t *testing.T) {
+	link := "https://example.com/p/ABC1234"
+
+	if got := ComposeSMS("Pay order 1024", link, 1); got != "Pay order 1024\n"+link {
+		t.Errorf("Expected the whole message, got %q", got)
+	}
+
+	long := ComposeSMS(strings.Repeat("Pay your order ", 20), link, 1)
+	if SMSSegments(long) != 1 || !strings.HasSuffix(long, "...\n"+link) || len(long) < SMSSegmentGSM-15 {
+		t.Errorf("Expected the message cut to fill one GSM segment, got %d characters %q", len(long), long)
+	}
+
+	persian := ComposeSMS(strings.Repeat("لطفا سفارش خود را پرداخت کنید ", 5), link, 2)
+	if SMSSegments(persian) != 2 || !strings.Contains(persian, "…\n"+link) {
+		t.Errorf("Expected the Persian message cut to two segments, got %q", persian)
+	}
+
+	if got := ComposeSMS("Pay", strings.Repeat("x", 200), 1); got != strings.Repeat("x", 200) {
+		t.Errorf("Expected the link alone when nothing else fits, got %q", got)
+	}
+}
@@ -0,0 +1,240 @@
+package zarinpalgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Wage limits of the gateway
+const (
+	MaxWages      = 5     // wages of a single payment
+	MinWageAmount = 10000 // in Rials
+)
+
+// ErrInvalidIBAN is returned for IBANs that aren't valid Iranian IBANs (Sheba numbers)
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+// ValidateIBAN checks the IBAN is IR followed by 24 digits with a valid checksum
+func ValidateIBAN(iban string) error {
+	if len(iban) != 26 || !strings.HasPrefix(iban, "IR") {
+		return fmt.Errorf("%w: %q must be IR followed by 24 digits", ErrInvalidIBAN, iban)
+	}
+	// move the country and check digits to the end, I is 18 and R is 27
+	remainder := 0
+	for _, c := range iban[4:] + "1827" + iban[2:4] {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("%w: %q must be IR followed by 24 digits", ErrInvalidIBAN, iban)
+		}
+		remainder = (remainder*10 + int(c-'0')) % 97
+	}
+	if remainder != 1 {
+		return fmt.Errorf("%w: %q has a wrong checksum", ErrInvalidIBAN, iban)
+	}
+	return nil
+}
+
+// Rounding is how commissions are rounded to whole units
+type Rounding int
+
+// Rounding constants
+const (
+	RoundNearest Rounding = iota // halves are rounded up
+	RoundDown                    // in favor of the sellers
+	RoundUp                      // in favor of the marketplace
+)
+
+// CommissionRule is the commission the marketplace keeps from the sales of a seller
+type CommissionRule struct {
+	BasisPoints int // hundredths of a percent of the sales, 750 is 7.5%
+	Fixed       int // in Rials, added to every line item
+	Min         int // in Rials, no minimum when zero
+	Max         int // in Rials, no cap when zero
+}
+
+// commission returns the commission of amount in sales over count line items
+func (r CommissionRule) commission(amount, count int, rounding Rounding, unit int) int {
+	scaled := amount * r.BasisPoints
+	commission := scaled / 10000
+	switch rem := scaled % 10000; {
+	case rem == 0:
+	case rounding == RoundUp, rounding == RoundNearest && rem*2 >= 10000:
+		commission++
+	}
+	commission += r.Fixed * count
+
+	if unit > 1 {
+		rem := commission % unit
+		commission -= rem
+		if rem > 0 && (rounding == RoundUp || rounding == RoundNearest && rem*2 >= unit) {
+			commission += unit
+		}
+	}
+	if commission < r.Min {
+		commission = r.Min
+	}
+	if r.Max > 0 && commission > r.Max {
+		commission = r.Max
+	}
+	if commission > amount {
+		commission = amount
+	}
+	return commission
+}
+
+// SellerItem is a sale of a seller, paid to the seller's IBAN minus the commission
+type SellerItem struct {
+	Iban        string
+	Amount      int // in Rials
+	Description string
+}
+
+// SplitBuilder computes the wages of a marketplace order from its line items, the sales of each
+// seller are summed, the commission of the seller is kept and the rest is paid to the seller as
+// a wage. The marketplace receives the commissions and the line items without an IBAN.
+type SplitBuilder struct {
+	items    []SellerItem
+	rule     CommissionRule
+	rules    map[string]CommissionRule
+	rounding Rounding
+	unit     int
+	err      error // of a step, returned by Wages
+}
+
+// NewSplit starts building the split of an order
+func NewSplit() *SplitBuilder {
+	return &SplitBuilder{rules: make(map[string]CommissionRule)}
+}
+
+// Commission sets the commission of the sellers without their own rule
+func (s *SplitBuilder) Commission(rule CommissionRule) *SplitBuilder {
+	s.rule = rule
+	return s
+}
+
+// SellerCommission sets the commission of the seller of the IBAN
+func (s *SplitBuilder) SellerCommission(iban string, rule CommissionRule) *SplitBuilder {
+	s.rules[iban] = rule
+	return s
+}
+
+// Rounding sets how commissions are rounded, to multiples of unit Rials when it is above one,
+// like 10 to round to Tomans
+func (s *SplitBuilder) Rounding(rounding Rounding, unit int) *SplitBuilder {
+	s.rounding = rounding
+	s.unit = unit
+	return s
+}
+
+// Item adds a sale of the seller of the IBAN, an empty IBAN is a sale of the marketplace
+func (s *SplitBuilder) Item(iban string, amount int, description string) *SplitBuilder {
+	s.items = append(s.items, SellerItem{Iban: iban, Amount: amount, Description: description})
+	return s
+}
+
+// Seller adds a sale of a registered seller along with the seller's commission, inactive
+// sellers fail the split with ErrSellerInactive
+func (s *SplitBuilder) Seller(seller Seller, amount int, description string) *SplitBuilder {
+	if !seller.Active {
+		s.err = fmt.Errorf("%w: %q", ErrSellerInactive, seller.ID)
+		return s
+	}
+	s.rules[seller.Iban] = seller.Commission
+	return s.Item(seller.Iban, amount, description)
+}
+
+// Total returns the amount of the order
+func (s *SplitBuilder) Total() (total int) {
+	for _, item := range s.items {
+		total += item.Amount
+	}
+	return
+}
+
+// Wages computes and validates the wages, ordered like the first items of their sellers
+func (s *SplitBuilder) Wages() (wages []Wage, err error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	type seller struct {
+		amount       int
+		count        int
+		descriptions []string
+	}
+	sellers := make(map[string]*seller)
+	var order []string
+	for i, item := range s.items {
+		if item.Amount <= 0 {
+			return nil, fmt.Errorf("%w: item %d needs a positive amount", ErrInvalidWage, i+1)
+		}
+		if item.Iban == "" {
+			continue
+		}
+		if err = ValidateIBAN(item.Iban); err != nil {
+			return nil, fmt.Errorf("%w: item %d: %w", ErrInvalidWage, i+1, err)
+		}
+		sel, ok := sellers[item.Iban]
+		if !ok {
+			sel = &seller{}
+			sellers[item.Iban] = sel
+			order = append(order, item.Iban)
+		}
+		sel.amount += item.Amount
+		sel.count++
+		if item.Description != "" {
+			sel.descriptions = append(sel.descriptions, item.Description)
+		}
+	}
+
+	if len(order) > MaxWages {
+		return nil, fmt.Errorf("%w: %d sellers, a payment pays at most %d", ErrInvalidWage, len(order), MaxWages)
+	}
+	for _, iban := range order {
+		sel := sellers[iban]
+		rule, ok := s.rules[iban]
+		if !ok {
+			rule = s.rule
+		}
+		amount := sel.amount - rule.commission(sel.amount, sel.count, s.rounding, s.unit)
+		if amount == 0 {
+			continue
+		}
+		if amount < MinWageAmount {
+			return nil, fmt.Errorf("%w: %d Rials to %s, wages are at least %d Rials", ErrInvalidWage, amount, iban, MinWageAmount)
+		}
+		wages = append(wages, Wage{Iban: iban, Amount: amount, Description: strings.Join(sel.descriptions, ", ")})
+	}
+	return
+}
+
+// Commissions returns the commission kept from each seller, by IBAN
+func (s *SplitBuilder) Commissions() (map[string]int, error) {
+	wages, err := s.Wages()
+	if err != nil {
+		return nil, err
+	}
+	commissions := make(map[string]int)
+	for _, item := range s.items {
+		if item.Iban != "" {
+			commissions[item.Iban] += item.Amount
+		}
+	}
+	for _, wage := range wages {
+		commissions[wage.Iban] -= wage.Amount
+	}
+	return commissions, nil
+}
+
+// Split sets the amount of the payment to the total of the order and its wages to the ones
+// computed by the split. Failures are returned when the payment is created.
+func (b *PaymentBuilder) Split(split *SplitBuilder) *PaymentBuilder {
+	wages, err := split.Wages()
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.params.Amount = split.Total()
+	b.params.Currency = CurrencyRial
+	b.params.Wages = wages
+	return b
+}
@@ -0,0 +1,120 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const (
+	sellerA = "IR270170000000100324200001"
+	sellerB = "IR270120000000004139853880"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	if err := ValidateIBAN(sellerA); err != nil {
+		t.Errorf("Expected %s to be valid, got %v", sellerA, err)
+	}
+	for _, iban := range []string{"", "IR28017000000010032420000", "IR280170000000100324200001", "DE270170000000100324200001", "IR27017000000010032420000X"} {
+		if err := ValidateIBAN(iban); !errors.Is(err, ErrInvalidIBAN) {
+			t.Errorf("Expected ErrInvalidIBAN for %q, got %v", iban, err)
+		}
+	}
+}
+
+func TestSplitBuilder(t *testing.T) {
+	split := NewSplit().
+		Commission(CommissionRule{BasisPoints: 750}).
+		SellerCommission(sellerB, CommissionRule{BasisPoints: 1000, Fixed: 1000, Max: 20000}).
+		Rounding(RoundUp, 10).
+		Item(sellerA, 123457, "Book").
+		Item(sellerB, 300000, "Lamp").
+		Item(sellerA, 100000, "").
+		Item("", 50000, "Shipping")
+
+	wages, err := split.Wages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A: 7.5% of 223457 is 16759.275, rounded up to 16760; B: 10% of 300000 plus 1000, capped to 20000
+	if len(wages) != 2 || wages[0] != (Wage{Iban: sellerA, Amount: 206697, Description: "Book"}) || wages[1].Amount != 280000 {
+		t.Errorf("Unexpected wages: %+v", wages)
+	}
+	commissions, _ := split.Commissions()
+	if commissions[sellerA] != 16760 || commissions[sellerB] != 20000 {
+		t.Errorf("Expected commissions of 16760 and 20000, got %v", commissions)
+	}
+
+	if got := split.Total(); got != 573457 {
+		t.Errorf("Expected a total of 573457, got %d", got)
+	}
+}
+
+func TestSplitRounding(t *testing.T) {
+	rule := CommissionRule{BasisPoints: 250}
+	for _, tc := range []struct {
+		rounding Rounding
+		unit     int
+		want     int
+	}{
+		{RoundNearest, 0, 1235},    // 1234.5
+		{RoundDown, 0, 1234},       // 1234.5
+		{RoundNearest, 10, 1240},   // 1235
+		{RoundDown, 10, 1230},      // 1234
+		{RoundUp, 1000, 2000},      // 1235
+		{RoundNearest, 1000, 1000}, // 1235
+	} {
+		if got := rule.commission(49380, 1, tc.rounding, tc.unit); got != tc.want {
+			t.Errorf("Expected %d rounding %v to %d, got %d", tc.want, tc.rounding, tc.unit, got)
+		}
+	}
+}
+
+func TestSplitConstraints(t *testing.T) {
+	if _, err := NewSplit().Item("IR00", 50000, "").Wages(); !errors.Is(err, ErrInvalidIBAN) || !errors.Is(err, ErrInvalidWage) {
+		t.Errorf("Expected an invalid IBAN, got %v", err)
+	}
+	if _, err := NewSplit().Item(sellerA, 10500, "").Commission(CommissionRule{BasisPoints: 1000}).Wages(); !errors.Is(err, ErrInvalidWage) {
+		t.Errorf("Expected a wage under the minimum to fail, got %v", err)
+	}
+
+	many := NewSplit()
+	for i := 0; i <= MaxWages; i++ {
+		iban := []byte(sellerA)
+		iban[len(iban)-1] = byte('0' + i)
+		many.Item(fixChecksum(string(iban)), 20000, "")
+	}
+	if _, err := many.Wages(); !errors.Is(err, ErrInvalidWage) {
+		t.Errorf("Expected more than %d sellers to fail, got %v", MaxWages, err)
+	}
+}
+
+// fixChecksum replaces the check digits of an Iranian IBAN with valid ones
+func fixChecksum(iban string) string {
+	remainder := 0
+	for _, c := range iban[4:] + "182700" {
+		remainder = (remainder*10 + int(c-'0')) % 97
+	}
+	check := 98 - remainder
+	return "IR" + string(rune('0'+check/10)) + string(rune('0'+check%10)) + iban[4:]
+}
+
+func TestPaymentBuilderSplit(t *testing.T) {
+	zp := New("merchant-1")
+	params, err := zp.Payment().
+		Description("Order 12").
+		Callback("https://example.com/callback").
+		Split(NewSplit().Commission(CommissionRule{BasisPoints: 500}).Item(sellerA, 200000, "Desk")).
+		Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Amount != 200000 || len(params.Wages) != 1 || params.Wages[0].Amount != 190000 {
+		t.Errorf("Unexpected parameters: %+v", params)
+	}
+
+	_, err = zp.Payment().Split(NewSplit().Item(sellerA, -1, "")).Create(context.Background())
+	if !errors.Is(err, ErrInvalidWage) {
+		t.Errorf("Expected the split error from Create, got %v", err)
+	}
+}
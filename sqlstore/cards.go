@@ -0,0 +1,49 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// RecordCard implements zarinpalgo.CardHistory
+func (s *Store) RecordCard(ctx context.Context, payment zarinpalgo.CardPayment) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+CardTable+` (authority, card_hash, ref_id, amount, paid_at) VALUES (?, ?, ?, ?, ?)`),
+		payment.Authority, payment.CardHash, payment.RefID, payment.Amount, dbTime(payment.PaidAt))
+	if err != nil {
+		// drivers report constraint violations differently, so look the authority up instead
+		var found string
+		if s.db.QueryRowContext(ctx, s.rebind(`SELECT authority FROM `+CardTable+` WHERE authority = ?`), payment.Authority).Scan(&found) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// CardStats implements zarinpalgo.CardHistory, the payments of the card are summed up here as
+// drivers scan the MIN and MAX of times differently
+func (s *Store) CardStats(ctx context.Context, cardHash string) (stats zarinpalgo.CardStats, err error) {
+	stats.CardHash = cardHash
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT amount, paid_at FROM `+CardTable+` WHERE card_hash = ? ORDER BY paid_at`), cardHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var amount int
+		var paidAt time.Time
+		if err = rows.Scan(&amount, &paidAt); err != nil {
+			return
+		}
+		if stats.Payments == 0 {
+			stats.FirstPaidAt = paidAt
+		}
+		stats.Payments++
+		stats.TotalAmount += amount
+		stats.LastPaidAt = paidAt
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,56 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IsFulfilled implements zarinpalgo.FulfillmentLog
+func (s *Store) IsFulfilled(ctx context.Context, authority string) (bool, error) {
+	var found string
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT authority FROM `+FulfillmentTable+` WHERE authority = ?`), authority).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MarkFulfilled implements zarinpalgo.FulfillmentLog
+func (s *Store) MarkFulfilled(ctx context.Context, authority string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+FulfillmentTable+` (authority, fulfilled_at) VALUES (?, ?)`),
+		authority, dbTime(time.Now()))
+	if err != nil {
+		if fulfilled, checkErr := s.IsFulfilled(ctx, authority); checkErr == nil && fulfilled {
+			return nil
+		}
+	}
+	return err
+}
+
+// Reserve implements zarinpalgo.IdempotencyLocker, expired locks are taken over
+func (s *Store) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+LockTable+` WHERE lock_key = ? AND expires_at <= ?`), key, dbTime(now))
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+LockTable+` (lock_key, expires_at) VALUES (?, ?)`), key, dbTime(now.Add(ttl)))
+	if err != nil {
+		// drivers report constraint violations differently, so look the lock up instead
+		var found string
+		if s.db.QueryRowContext(ctx, s.rebind(`SELECT lock_key FROM `+LockTable+` WHERE lock_key = ?`), key).Scan(&found) == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Release implements zarinpalgo.IdempotencyLocker
+func (s *Store) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+LockTable+` WHERE lock_key = ?`), key)
+	return err
+}
@@ -0,0 +1,44 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFulfillmentLog(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	if fulfilled, err := store.IsFulfilled(ctx, "A1"); fulfilled || err != nil {
+		t.Errorf("Expected payment not to be fulfilled, got %v %v", fulfilled, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.MarkFulfilled(ctx, "A1"); err != nil {
+			t.Errorf("Failed to mark payment fulfilled: %v", err)
+		}
+	}
+	if fulfilled, err := store.IsFulfilled(ctx, "A1"); !fulfilled || err != nil {
+		t.Errorf("Expected payment to be fulfilled, got %v %v", fulfilled, err)
+	}
+}
+
+func TestLocker(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	if ok, err := store.Reserve(ctx, "fulfill:A1", time.Minute); !ok || err != nil {
+		t.Errorf("Expected lock to be free, got %v %v", ok, err)
+	}
+	if ok, err := store.Reserve(ctx, "fulfill:A1", time.Minute); ok || err != nil {
+		t.Errorf("Expected lock to be held, got %v %v", ok, err)
+	}
+
+	store.Release(ctx, "fulfill:A1")
+	if ok, _ := store.Reserve(ctx, "fulfill:A1", -time.Second); !ok {
+		t.Error("Expected released lock to be free")
+	}
+	if ok, _ := store.Reserve(ctx, "fulfill:A1", time.Minute); !ok {
+		t.Error("Expected expired lock to be taken over")
+	}
+}
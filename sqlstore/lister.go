@@ -0,0 +1,45 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+var _ zarinpalgo.PaymentLister = (*Store)(nil)
+
+// ListByOrderID implements zarinpalgo.PaymentLister
+func (s *Store) ListByOrderID(ctx context.Context, orderID string) ([]zarinpalgo.StoredPayment, error) {
+	return s.list(ctx, `SELECT `+columns+` FROM `+Table+` WHERE order_id = ? ORDER BY created_at`, orderID)
+}
+
+// ListVerified implements zarinpalgo.PaymentLister
+func (s *Store) ListVerified(ctx context.Context, since time.Time) ([]zarinpalgo.StoredPayment, error) {
+	return s.list(ctx, `SELECT `+columns+` FROM `+Table+` WHERE state = ? AND updated_at >= ? ORDER BY created_at`,
+		string(zarinpalgo.PaymentStateVerified), dbTime(since))
+}
+
+// ListCreated implements zarinpalgo.PaymentLister
+func (s *Store) ListCreated(ctx context.Context, from, to time.Time) ([]zarinpalgo.StoredPayment, error) {
+	return s.list(ctx, `SELECT `+columns+` FROM `+Table+` WHERE created_at >= ? AND created_at < ? ORDER BY created_at`,
+		dbTime(from), dbTime(to))
+}
+
+func (s *Store) list(ctx context.Context, query string, args ...interface{}) (payments []zarinpalgo.StoredPayment, err error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment zarinpalgo.StoredPayment
+		if err = scan(rows, &payment); err != nil {
+			return
+		}
+		payments = append(payments, payment)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestLister(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, authority := range []string{"A1", "A2", "A3"} {
+		store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: authority, Amount: 10000, OrderID: "42", CreatedAt: now.Add(time.Duration(i) * time.Second), ExpiresAt: now.Add(time.Minute)})
+	}
+	for _, authority := range []string{"A1", "A3"} {
+		store.UpdateStatus(ctx, authority, zarinpalgo.PaymentStatePending, 0)
+		store.UpdateStatus(ctx, authority, zarinpalgo.PaymentStateVerified, 0)
+	}
+
+	payments, err := store.ListByOrderID(ctx, "42")
+	if err != nil || len(payments) != 3 || payments[0].Authority != "A1" || payments[2].Authority != "A3" {
+		t.Errorf("Expected the payments of the order, got %+v %v", payments, err)
+	}
+
+	verified, err := store.ListVerified(ctx, now.Add(-time.Minute))
+	if err != nil || len(verified) != 2 || verified[0].Authority != "A1" {
+		t.Errorf("Expected two verified payments, got %+v %v", verified, err)
+	}
+	if verified, _ := store.ListVerified(ctx, time.Now().Add(time.Minute)); len(verified) != 0 {
+		t.Errorf("Expected no payments verified in the future, got %+v", verified)
+	}
+
+	created, err := store.ListCreated(ctx, now.Add(time.Second), now.Add(2*time.Second))
+	if err != nil || len(created) != 1 || created[0].Authority != "A2" {
+		t.Errorf("Expected only A2 to be created in the range, got %+v %v", created, err)
+	}
+}
@@ -0,0 +1,55 @@
+package sqlstore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// AddEvent implements zarinpalgo.Outbox
+func (s *Store) AddEvent(ctx context.Context, event zarinpalgo.WebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+OutboxTable+` (id, created_at, payload) VALUES (?, ?, ?)`),
+		event.ID, dbTime(event.CreatedAt), string(payload))
+	if err != nil {
+		// drivers report constraint violations differently, so look the event up instead
+		var found string
+		if s.db.QueryRowContext(ctx, s.rebind(`SELECT id FROM `+OutboxTable+` WHERE id = ?`), event.ID).Scan(&found) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// PendingEvents implements zarinpalgo.Outbox
+func (s *Store) PendingEvents(ctx context.Context, limit int) (events []zarinpalgo.WebhookEvent, err error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT payload FROM `+OutboxTable+` ORDER BY created_at LIMIT ?`), limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return
+		}
+		var event zarinpalgo.WebhookEvent
+		if err = json.Unmarshal([]byte(payload), &event); err != nil {
+			return
+		}
+		events = append(events, event)
+	}
+	err = rows.Err()
+	return
+}
+
+// MarkPublished implements zarinpalgo.Outbox, published events are deleted
+func (s *Store) MarkPublished(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+OutboxTable+` WHERE id = ?`), id)
+	return err
+}
@@ -0,0 +1,33 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestOutbox(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	first := zarinpalgo.NewWebhookEvent(zarinpalgo.PaymentStatus{Authority: "A1", IsSuccessful: true, RefID: 201})
+	second := zarinpalgo.NewWebhookEvent(zarinpalgo.PaymentStatus{Authority: "A2"})
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	for _, event := range []zarinpalgo.WebhookEvent{second, first} {
+		if err := store.AddEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to add event: %v", err)
+		}
+	}
+
+	events, err := store.PendingEvents(ctx, 1)
+	if err != nil || len(events) != 1 || events[0].ID != first.ID || events[0].Payment.RefID != 201 {
+		t.Errorf("Expected the oldest event, got %+v %v", events, err)
+	}
+
+	store.MarkPublished(ctx, first.ID)
+	if events, _ := store.PendingEvents(ctx, 10); len(events) != 1 || events[0].ID != second.ID {
+		t.Errorf("Expected only the second event, got %+v", events)
+	}
+}
@@ -0,0 +1,55 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// SaveRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) SaveRefundRecord(ctx context.Context, record zarinpalgo.RefundRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// upserts differ between dialects, replacing the row works everywhere
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM `+RefundTable+` WHERE refund_key = ?`), record.Key); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO `+RefundTable+` (refund_key, created_at, payload) VALUES (?, ?, ?)`),
+		record.Key, dbTime(record.CreatedAt), string(payload))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) GetRefundRecord(ctx context.Context, key string) (record zarinpalgo.RefundRecord, err error) {
+	var payload string
+	err = s.db.QueryRowContext(ctx, s.rebind(`SELECT payload FROM `+RefundTable+` WHERE refund_key = ?`), key).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = zarinpalgo.ErrRefundNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(payload), &record)
+	return
+}
+
+// DeleteRefundRecord implements zarinpalgo.RefundRecordStore
+func (s *Store) DeleteRefundRecord(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+RefundTable+` WHERE refund_key = ?`), key)
+	return err
+}
@@ -0,0 +1,56 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+const retryColumns = "authority, amount, attempts, next_attempt, expires_at, last_error"
+
+// Enqueue implements zarinpalgo.RetryQueue
+func (s *Store) Enqueue(ctx context.Context, retry zarinpalgo.VerifyRetry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// upserts differ between dialects, replacing the row works everywhere
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM `+RetryTable+` WHERE authority = ?`), retry.Authority); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO `+RetryTable+` (`+retryColumns+`) VALUES (?, ?, ?, ?, ?, ?)`),
+		retry.Authority, retry.Amount, retry.Attempts, dbTime(retry.NextAttempt), dbTime(retry.ExpiresAt), retry.LastError)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Due implements zarinpalgo.RetryQueue
+func (s *Store) Due(ctx context.Context, now time.Time) (due []zarinpalgo.VerifyRetry, err error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT `+retryColumns+` FROM `+RetryTable+` WHERE next_attempt <= ? ORDER BY next_attempt`), dbTime(now))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var retry zarinpalgo.VerifyRetry
+		err = rows.Scan(&retry.Authority, &retry.Amount, &retry.Attempts, &retry.NextAttempt, &retry.ExpiresAt, &retry.LastError)
+		if err != nil {
+			return
+		}
+		due = append(due, retry)
+	}
+	err = rows.Err()
+	return
+}
+
+// Remove implements zarinpalgo.RetryQueue
+func (s *Store) Remove(ctx context.Context, authority string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+RetryTable+` WHERE authority = ?`), authority)
+	return err
+}
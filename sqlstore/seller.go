@@ -0,0 +1,69 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// SaveSeller implements zarinpalgo.SellerStore
+func (s *Store) SaveSeller(ctx context.Context, seller zarinpalgo.Seller) error {
+	payload, err := json.Marshal(seller)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM `+SellerTable+` WHERE id = ?`), seller.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO `+SellerTable+` (id, payload) VALUES (?, ?)`), seller.ID, string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSeller implements zarinpalgo.SellerStore
+func (s *Store) GetSeller(ctx context.Context, id string) (seller zarinpalgo.Seller, err error) {
+	var payload string
+	err = s.db.QueryRowContext(ctx, s.rebind(`SELECT payload FROM `+SellerTable+` WHERE id = ?`), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = zarinpalgo.ErrSellerNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(payload), &seller)
+	return
+}
+
+// ListSellers implements zarinpalgo.SellerStore
+func (s *Store) ListSellers(ctx context.Context) (sellers []zarinpalgo.Seller, err error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM `+SellerTable+` ORDER BY id`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return
+		}
+		var seller zarinpalgo.Seller
+		if err = json.Unmarshal([]byte(payload), &seller); err != nil {
+			return
+		}
+		sellers = append(sellers, seller)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,436 @@
+// Package sqlstore implements zarinpalgo.PaymentStore and the other persistence interfaces of zarinpalgo
+// over database/sql, for Postgres, MySQL and SQLite.
+// Create the table with Migrate or by running the dialect's Schema, Migrate also adds the columns of
+// later versions to tables created before. MySQL connections need parseTime=true.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Table names
+const (
+	Table            = "zarinpal_payments"
+	RetryTable       = "zarinpal_verify_retries"
+	OutboxTable      = "zarinpal_outbox"
+	FulfillmentTable = "zarinpal_fulfillments"
+	LockTable        = "zarinpal_locks"
+	RefundTable      = "zarinpal_refund_attempts"
+	SellerTable      = "zarinpal_sellers"
+	CardTable        = "zarinpal_card_payments"
+)
+
+// Dialect holds the database specific SQL
+type Dialect struct {
+	Name        string
+	schema      []string
+	columns     []addedColumn
+	placeholder func(n int) string
+}
+
+// addedColumn is a column added to a table after its first version
+type addedColumn struct {
+	table, name, definition string
+}
+
+// Supported dialects
+var (
+	Postgres = Dialect{
+		Name: "postgres",
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS ` + Table + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	amount BIGINT NOT NULL,
+	currency VARCHAR(8) NOT NULL DEFAULT '',
+	order_id VARCHAR(255) NOT NULL DEFAULT '',
+	payment_url VARCHAR(512) NOT NULL DEFAULT '',
+	state VARCHAR(16) NOT NULL,
+	ref_id BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	params TEXT,
+	retry_of VARCHAR(64) NOT NULL DEFAULT ''
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + Table + `_order_id ON ` + Table + ` (order_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS ` + Table + `_state ON ` + Table + ` (state, created_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + RetryTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	amount BIGINT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_error TEXT NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + RetryTable + `_next_attempt ON ` + RetryTable + ` (next_attempt)`,
+			`CREATE TABLE IF NOT EXISTS ` + OutboxTable + ` (
+	id VARCHAR(64) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	payload TEXT NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + OutboxTable + `_created_at ON ` + OutboxTable + ` (created_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + FulfillmentTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	fulfilled_at TIMESTAMPTZ NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + LockTable + ` (
+	lock_key VARCHAR(255) PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + RefundTable + ` (
+	refund_key VARCHAR(64) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + SellerTable + ` (
+	id VARCHAR(255) PRIMARY KEY,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + CardTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	card_hash VARCHAR(255) NOT NULL,
+	ref_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	paid_at TIMESTAMPTZ NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + CardTable + `_card_hash ON ` + CardTable + ` (card_hash)`,
+		},
+		columns: []addedColumn{
+			{Table, "params", "TEXT"},
+			{Table, "retry_of", "VARCHAR(64) NOT NULL DEFAULT ''"},
+		},
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+
+	MySQL = Dialect{
+		Name: "mysql",
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS ` + Table + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	amount BIGINT NOT NULL,
+	currency VARCHAR(8) NOT NULL DEFAULT '',
+	order_id VARCHAR(255) NOT NULL DEFAULT '',
+	payment_url VARCHAR(512) NOT NULL DEFAULT '',
+	state VARCHAR(16) NOT NULL,
+	ref_id BIGINT NOT NULL DEFAULT 0,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	params TEXT,
+	retry_of VARCHAR(64) NOT NULL DEFAULT '',
+	INDEX ` + Table + `_order_id (order_id, created_at),
+	INDEX ` + Table + `_state (state, created_at)
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + RetryTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	amount BIGINT NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	last_error TEXT NOT NULL,
+	INDEX ` + RetryTable + `_next_attempt (next_attempt)
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + OutboxTable + ` (
+	id VARCHAR(64) PRIMARY KEY,
+	created_at DATETIME(6) NOT NULL,
+	payload TEXT NOT NULL,
+	INDEX ` + OutboxTable + `_created_at (created_at)
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + FulfillmentTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	fulfilled_at DATETIME(6) NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + LockTable + ` (
+	lock_key VARCHAR(255) PRIMARY KEY,
+	expires_at DATETIME(6) NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + RefundTable + ` (
+	refund_key VARCHAR(64) PRIMARY KEY,
+	created_at DATETIME(6) NOT NULL,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + SellerTable + ` (
+	id VARCHAR(255) PRIMARY KEY,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + CardTable + ` (
+	authority VARCHAR(64) PRIMARY KEY,
+	card_hash VARCHAR(255) NOT NULL,
+	ref_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	paid_at DATETIME(6) NOT NULL,
+	INDEX ` + CardTable + `_card_hash (card_hash)
+)`,
+		},
+		columns: []addedColumn{
+			{Table, "params", "TEXT"},
+			{Table, "retry_of", "VARCHAR(64) NOT NULL DEFAULT ''"},
+		},
+		placeholder: func(n int) string { return "?" },
+	}
+
+	SQLite = Dialect{
+		Name: "sqlite",
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS ` + Table + ` (
+	authority TEXT PRIMARY KEY,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL DEFAULT '',
+	order_id TEXT NOT NULL DEFAULT '',
+	payment_url TEXT NOT NULL DEFAULT '',
+	state TEXT NOT NULL,
+	ref_id INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	params TEXT,
+	retry_of TEXT NOT NULL DEFAULT ''
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + Table + `_order_id ON ` + Table + ` (order_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS ` + Table + `_state ON ` + Table + ` (state, created_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + RetryTable + ` (
+	authority TEXT PRIMARY KEY,
+	amount INTEGER NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + RetryTable + `_next_attempt ON ` + RetryTable + ` (next_attempt)`,
+			`CREATE TABLE IF NOT EXISTS ` + OutboxTable + ` (
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	payload TEXT NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + OutboxTable + `_created_at ON ` + OutboxTable + ` (created_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + FulfillmentTable + ` (
+	authority TEXT PRIMARY KEY,
+	fulfilled_at TIMESTAMP NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + LockTable + ` (
+	lock_key TEXT PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + RefundTable + ` (
+	refund_key TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + SellerTable + ` (
+	id TEXT PRIMARY KEY,
+	payload TEXT NOT NULL
+)`,
+			`CREATE TABLE IF NOT EXISTS ` + CardTable + ` (
+	authority TEXT PRIMARY KEY,
+	card_hash TEXT NOT NULL,
+	ref_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	paid_at TIMESTAMP NOT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + CardTable + `_card_hash ON ` + CardTable + ` (card_hash)`,
+		},
+		columns: []addedColumn{
+			{Table, "params", "TEXT"},
+			{Table, "retry_of", "TEXT NOT NULL DEFAULT ''"},
+		},
+		placeholder: func(n int) string { return "?" },
+	}
+)
+
+// Schema returns the statements creating the tables
+func (d Dialect) Schema() string {
+	return strings.Join(d.schema, ";\n\n") + ";\n"
+}
+
+// Migrate creates the tables that don't exist and adds the missing columns to the ones that do
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	for _, statement := range dialect.schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	for _, column := range dialect.columns {
+		if err := addColumn(ctx, db, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumn adds the column unless the table has it, not every dialect supports ADD COLUMN IF
+// NOT EXISTS
+func addColumn(ctx context.Context, db *sql.DB, column addedColumn) error {
+	rows, err := db.QueryContext(ctx, `SELECT `+column.name+` FROM `+column.table+` WHERE 1 = 0`)
+	if err == nil {
+		return rows.Close()
+	}
+	_, err = db.ExecContext(ctx, `ALTER TABLE `+column.table+` ADD COLUMN `+column.name+` `+column.definition)
+	return err
+}
+
+// maxUpdateAttempts bounds the retries of a status update racing with other updates
+const maxUpdateAttempts = 3
+
+const columns = "authority, amount, currency, order_id, payment_url, state, ref_id, created_at, expires_at, updated_at, params, retry_of"
+
+// Store is a zarinpalgo.PaymentStore backed by a SQL database
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+var (
+	_ zarinpalgo.PaymentStore      = (*Store)(nil)
+	_ zarinpalgo.RetryQueue        = (*Store)(nil)
+	_ zarinpalgo.Outbox            = (*Store)(nil)
+	_ zarinpalgo.FulfillmentLog    = (*Store)(nil)
+	_ zarinpalgo.IdempotencyLocker = (*Store)(nil)
+	_ zarinpalgo.RefundRecordStore = (*Store)(nil)
+	_ zarinpalgo.SellerStore       = (*Store)(nil)
+	_ zarinpalgo.CardHistory       = (*Store)(nil)
+)
+
+// New creates a Store using the given database and dialect
+func New(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect}
+}
+
+// SaveSession implements zarinpalgo.PaymentStore
+func (s *Store) SaveSession(ctx context.Context, session zarinpalgo.PaymentSession) error {
+	var params sql.NullString
+	if session.Params != nil {
+		encoded, err := json.Marshal(session.Params)
+		if err != nil {
+			return err
+		}
+		params = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+Table+` (`+columns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		session.Authority, session.Amount, string(session.Currency), session.OrderID, session.PaymentURL,
+		string(zarinpalgo.PaymentStateCreated), 0, dbTime(session.CreatedAt), dbTime(session.ExpiresAt), dbTime(time.Now()),
+		params, session.RetryOf)
+	if err != nil {
+		// drivers report constraint violations differently, so look the authority up instead
+		if _, getErr := s.GetByAuthority(ctx, session.Authority); getErr == nil {
+			return zarinpalgo.ErrDuplicatePayment
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByAuthority implements zarinpalgo.PaymentStore
+func (s *Store) GetByAuthority(ctx context.Context, authority string) (zarinpalgo.StoredPayment, error) {
+	return s.get(ctx, `SELECT `+columns+` FROM `+Table+` WHERE authority = ?`, authority)
+}
+
+// GetByOrderID implements zarinpalgo.PaymentStore
+func (s *Store) GetByOrderID(ctx context.Context, orderID string) (zarinpalgo.StoredPayment, error) {
+	return s.get(ctx, `SELECT `+columns+` FROM `+Table+` WHERE order_id = ? ORDER BY created_at DESC LIMIT 1`, orderID)
+}
+
+// UpdateStatus implements zarinpalgo.PaymentStore. The update only applies if the state didn't change
+// since it was read, so concurrent updates can't make an invalid transition.
+func (s *Store) UpdateStatus(ctx context.Context, authority string, state zarinpalgo.PaymentState, refID int) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		payment, err := s.GetByAuthority(ctx, authority)
+		if err != nil {
+			return err
+		}
+		if err := zarinpalgo.CheckTransition(authority, payment.State, state); err != nil {
+			return err
+		}
+		// the ref ID of the row read in this attempt is kept, not of an earlier one
+		newRefID := refID
+		if newRefID == 0 {
+			newRefID = payment.RefID
+		}
+
+		result, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+Table+` SET state = ?, ref_id = ?, updated_at = ? WHERE authority = ? AND state = ?`),
+			string(state), newRefID, dbTime(time.Now()), authority, string(payment.State))
+		if err != nil {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+			return err
+		}
+	}
+	return errors.New("sqlstore: payment " + authority + " is updated concurrently")
+}
+
+// ListPending implements zarinpalgo.PaymentStore
+func (s *Store) ListPending(ctx context.Context, createdBefore time.Time) ([]zarinpalgo.StoredPayment, error) {
+	query := `SELECT ` + columns + ` FROM ` + Table + ` WHERE state IN (?, ?, ?)`
+	args := []interface{}{
+		string(zarinpalgo.PaymentStateCreated),
+		string(zarinpalgo.PaymentStateRedirected),
+		string(zarinpalgo.PaymentStatePending),
+	}
+	if !createdBefore.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, dbTime(createdBefore))
+	}
+
+	return s.list(ctx, query+` ORDER BY created_at`, args...)
+}
+
+func (s *Store) get(ctx context.Context, query string, args ...interface{}) (payment zarinpalgo.StoredPayment, err error) {
+	err = scan(s.db.QueryRowContext(ctx, s.rebind(query), args...), &payment)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = zarinpalgo.ErrPaymentNotFound
+	}
+	return
+}
+
+// rebind replaces the ? placeholders of a query with the ones of the dialect
+func (s *Store) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(s.dialect.placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner, payment *zarinpalgo.StoredPayment) error {
+	var currency, state string
+	var params sql.NullString
+	err := row.Scan(&payment.Authority, &payment.Amount, &currency, &payment.OrderID, &payment.PaymentURL,
+		&state, &payment.RefID, &payment.CreatedAt, &payment.ExpiresAt, &payment.UpdatedAt, &params, &payment.RetryOf)
+	if err != nil {
+		return err
+	}
+	if params.Valid && params.String != "" {
+		payment.Params = new(zarinpalgo.PaymentParams)
+		if err = json.Unmarshal([]byte(params.String), payment.Params); err != nil {
+			return err
+		}
+	}
+	payment.Currency = zarinpalgo.Currency(currency)
+	payment.State, err = zarinpalgo.ParsePaymentState(state)
+	return err
+}
+
+// dbTime normalizes times to the precision all dialects store
+func dbTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,193 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// every connection to :memory: opens a new database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := Migrate(context.Background(), db, SQLite); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return New(db, SQLite)
+}
+
+func TestStore(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	sessions := []zarinpalgo.PaymentSession{
+		{Authority: "A1", Amount: 10000, Currency: zarinpalgo.CurrencyRial, OrderID: "42", CreatedAt: now.Add(-time.Hour), ExpiresAt: now},
+		{Authority: "A2", Amount: 20000, OrderID: "42", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}
+	for _, session := range sessions {
+		if err := store.SaveSession(ctx, session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+	if err := store.SaveSession(ctx, sessions[0]); !errors.Is(err, zarinpalgo.ErrDuplicatePayment) {
+		t.Errorf("Expected ErrDuplicatePayment, got %v", err)
+	}
+
+	payment, err := store.GetByAuthority(ctx, "A1")
+	if err != nil || payment.Amount != 10000 || payment.Currency != zarinpalgo.CurrencyRial || payment.State != zarinpalgo.PaymentStateCreated {
+		t.Errorf("Unexpected payment %+v: %v", payment, err)
+	}
+	if !payment.CreatedAt.Equal(dbTime(sessions[0].CreatedAt)) {
+		t.Errorf("Expected created at %s, got %s", sessions[0].CreatedAt, payment.CreatedAt)
+	}
+
+	payment, err = store.GetByOrderID(ctx, "42")
+	if err != nil || payment.Authority != "A2" {
+		t.Errorf("Expected latest payment of the order, got %+v %v", payment, err)
+	}
+	if _, err := store.GetByOrderID(ctx, "43"); !errors.Is(err, zarinpalgo.ErrPaymentNotFound) {
+		t.Errorf("Expected ErrPaymentNotFound, got %v", err)
+	}
+
+	pending, err := store.ListPending(ctx, now.Add(-time.Minute))
+	if err != nil || len(pending) != 1 || pending[0].Authority != "A1" {
+		t.Errorf("Expected the old payment to be pending, got %+v %v", pending, err)
+	}
+
+	if err := store.UpdateStatus(ctx, "A1", zarinpalgo.PaymentStateVerified, 201); !errors.Is(err, zarinpalgo.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+	for _, state := range []zarinpalgo.PaymentState{zarinpalgo.PaymentStatePending, zarinpalgo.PaymentStateVerified} {
+		if err := store.UpdateStatus(ctx, "A1", state, 201); err != nil {
+			t.Fatalf("Failed to move payment to %s: %v", state, err)
+		}
+	}
+	if err := store.UpdateStatus(ctx, "A1", zarinpalgo.PaymentStateRefunded, 0); err != nil {
+		t.Fatalf("Failed to refund payment: %v", err)
+	}
+
+	payment, _ = store.GetByAuthority(ctx, "A1")
+	if payment.State != zarinpalgo.PaymentStateRefunded || payment.RefID != 201 {
+		t.Errorf("Unexpected payment: %+v", payment)
+	}
+	if pending, _ := store.ListPending(ctx, time.Time{}); len(pending) != 1 || pending[0].Authority != "A2" {
+		t.Errorf("Expected one pending payment, got %+v", pending)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	store := New(nil, Postgres)
+	if query := store.rebind("WHERE a = ? AND b = ?"); query != "WHERE a = $1 AND b = $2" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+	if !strings.Contains(MySQL.Schema(), "DATETIME(6)") {
+		t.Error("Expected MySQL schema to use DATETIME(6)")
+	}
+}
+
+func TestStoreRetryFields(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	params := &zarinpalgo.PaymentParams{
+		Amount:      10000,
+		Description: "Order 42",
+		CallbackURL: "https://example.com/callback",
+		Metadata:    &zarinpalgo.Metadata{OrderID: "42"},
+		Wages:       []zarinpalgo.Wage{{Iban: "IR1", Amount: 2000}},
+	}
+	sessions := []zarinpalgo.PaymentSession{
+		{Authority: "A1", Amount: 10000, OrderID: "42", Params: params, CreatedAt: time.Now(), ExpiresAt: time.Now()},
+		{Authority: "A2", Amount: 10000, OrderID: "42", Params: params, RetryOf: "A1", CreatedAt: time.Now(), ExpiresAt: time.Now()},
+		{Authority: "A3", Amount: 10000},
+	}
+	for _, session := range sessions {
+		if err := store.SaveSession(ctx, session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+
+	payment, err := store.GetByAuthority(ctx, "A2")
+	if err != nil || payment.RetryOf != "A1" || payment.Params == nil {
+		t.Fatalf("Expected the retry link and parameters, got %+v %v", payment, err)
+	}
+	if payment.Params.Description != "Order 42" || payment.Params.Metadata.OrderID != "42" || len(payment.Params.Wages) != 1 {
+		t.Errorf("Expected the parameters kept, got %+v", payment.Params)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A3"); payment.Params != nil || payment.RetryOf != "" {
+		t.Errorf("Expected no parameters nor retry link, got %+v", payment)
+	}
+
+	for _, state := range []zarinpalgo.PaymentState{zarinpalgo.PaymentStatePending, zarinpalgo.PaymentStateVerified} {
+		if err := store.UpdateStatus(ctx, "A2", state, 201); err != nil {
+			t.Fatalf("Failed to update status: %v", err)
+		}
+	}
+	payment, _ = store.GetByAuthority(ctx, "A2")
+	wagePayment, ok := zarinpalgo.WagePaymentOf(payment)
+	if !ok || len(wagePayment.Wages) != 1 || wagePayment.Wages[0].Iban != "IR1" || wagePayment.RefID != 201 {
+		t.Errorf("Expected the wages rebuilt from the stored payment, got %+v %v", wagePayment, ok)
+	}
+}
+
+func TestMigrateAddsColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	ctx := context.Background()
+	// the payments table as created before the parameters were kept
+	_, err = db.ExecContext(ctx, `CREATE TABLE `+Table+` (
+	authority TEXT PRIMARY KEY,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL DEFAULT '',
+	order_id TEXT NOT NULL DEFAULT '',
+	payment_url TEXT NOT NULL DEFAULT '',
+	state TEXT NOT NULL,
+	ref_id INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO `+Table+` (authority, amount, state, created_at, expires_at, updated_at) VALUES ('A0', 5000, 'created', ?, ?, ?)`,
+		time.Now().UTC(), time.Now().UTC(), time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Migrate(ctx, db, SQLite); err != nil {
+			t.Fatalf("Failed to migrate: %v", err)
+		}
+	}
+	store := New(db, SQLite)
+	if payment, err := store.GetByAuthority(ctx, "A0"); err != nil || payment.Params != nil {
+		t.Errorf("Expected the old payment readable, got %+v %v", payment, err)
+	}
+	if err := store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: "A1", Amount: 5000, RetryOf: "A0"}); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.RetryOf != "A0" {
+		t.Errorf("Expected the retry link kept, got %+v", payment)
+	}
+}
@@ -0,0 +1,81 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// OrderIDParam is the query parameter StartHandler reads the order ID from
+const OrderIDParam = "order_id"
+
+// PaymentParams describes a payment to be created
+type PaymentParams struct {
+	Amount      int       `json:"amount"`
+	Currency    Currency  `json:"currency,omitempty"` // defaults to Rials
+	Description string    `json:"description"`
+	CallbackURL string    `json:"callback_url"`
+	Metadata    *Metadata `json:"metadata,omitempty"`
+	Wages       []Wage    `json:"wages,omitempty"`
+}
+
+// OrderLookupFunc returns the payment parameters of an order, or ErrPaymentNotFound
+type OrderLookupFunc func(ctx context.Context, orderID string) (PaymentParams, error)
+
+// PaymentCreatedFunc is called with the created payment before the user is redirected,
+// returning an error aborts the redirect
+type PaymentCreatedFunc func(ctx context.Context, orderID string, params PaymentParams, payment PaymentCreationResponse) error
+
+// StartPayment creates the payment of an order and returns the payment page URL.
+// Failures are returned as a *HandlerError.
+func (z *Zarinpal) StartPayment(ctx context.Context, orderID string, lookup OrderLookupFunc, onCreated PaymentCreatedFunc) (paymentURL string, err error) {
+	if orderID == "" {
+		err = &HandlerError{StatusCode: http.StatusBadRequest, Err: errors.New("missing order id")}
+		return
+	}
+
+	params, err := lookup(ctx, orderID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrPaymentNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		err = &HandlerError{StatusCode: statusCode, Err: err}
+		return
+	}
+
+	payment, err := z.CreatePayment(ctx, params)
+	if err != nil {
+		statusCode := http.StatusBadGateway
+		if errors.Is(err, ErrPaymentDeclined) {
+			statusCode = http.StatusForbidden
+		}
+		err = &HandlerError{StatusCode: statusCode, Err: err}
+		return
+	}
+
+	if onCreated != nil {
+		if panicErr := z.runHook(ctx, "payment created callback", func() { err = onCreated(ctx, orderID, params, payment) }); panicErr != nil {
+			err = panicErr
+		}
+		if err != nil {
+			err = &HandlerError{StatusCode: http.StatusInternalServerError, Err: err}
+			return
+		}
+	}
+
+	return z.GetPaymentURL(payment.Authority), nil
+}
+
+// StartHandler returns an http.Handler that creates the payment of the order given in the
+// query string and redirects the user to the payment page
+func (z *Zarinpal) StartHandler(lookup OrderLookupFunc, onCreated PaymentCreatedFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paymentURL, err := z.StartPayment(withRemoteIP(r.Context(), r.RemoteAddr), r.URL.Query().Get(OrderIDParam), lookup, onCreated)
+		if err != nil {
+			writeHandlerError(w, err)
+			return
+		}
+		http.Redirect(w, r, paymentURL, http.StatusFound)
+	})
+}
@@ -0,0 +1,52 @@
+package zarinpalgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStartHandler(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`,
+	})
+
+	var createdFor, createdAuthority string
+	handler := zp.StartHandler(func(ctx context.Context, orderID string) (PaymentParams, error) {
+		if orderID != "42" {
+			return PaymentParams{}, ErrPaymentNotFound
+		}
+		return PaymentParams{Amount: 10000, Description: "Order 42", CallbackURL: "https://example.com/callback"}, nil
+	}, func(ctx context.Context, orderID string, params PaymentParams, payment PaymentCreationResponse) error {
+		createdFor, createdAuthority = orderID, payment.Authority
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/start?order_id=42", nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusFound, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != zp.GetPaymentURL("A1") {
+		t.Errorf("Expected redirect to %s, got %s", zp.GetPaymentURL("A1"), location)
+	}
+	if createdFor != "42" || createdAuthority != "A1" {
+		t.Errorf("Expected created callback for order 42 with authority A1, got %s %s", createdFor, createdAuthority)
+	}
+
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/start", http.StatusBadRequest},
+		{"/start?order_id=7", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.target, nil))
+		if rec.Code != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, rec.Code)
+		}
+	}
+}
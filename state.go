@@ -0,0 +1,93 @@
+package zarinpalgo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// StateParam is the callback URL query parameter the state value is stored in
+const StateParam = "zp_state"
+
+// StateCookieName is the cookie SetStateCookie stores the expected state in
+const StateCookieName = "zp_state"
+
+var ErrStateMismatch = errors.New("callback state does not match")
+
+// NewState returns a random value to bind a callback to the request that created the payment
+func NewState() (string, error) {
+	return NewStateFrom(rand.Reader)
+}
+
+// NewStateFrom returns a state value read from r, tests can pass a seeded source to get fixed values
+func NewStateFrom(r io.Reader) (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AppendState adds the state value to the callback URL
+func AppendState(callbackURL, state string) (string, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(StateParam, state)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// CheckState reports ErrStateMismatch unless the callback carries the expected state value
+func CheckState(callback CallbackData, expected string) error {
+	got := callback.Values.Get(StateParam)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+		return ErrStateMismatch
+	}
+	return nil
+}
+
+// SetStateCookie stores the expected state in a short-lived cookie scoped to the callback path
+func SetStateCookie(w http.ResponseWriter, state, callbackPath string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     StateCookieName,
+		Value:    state,
+		Path:     callbackPath,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// RequireState returns a middleware rejecting callbacks whose state doesn't match the state cookie.
+// The cookie is cleared once it has been checked, so a callback can't be replayed with it.
+func RequireState(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var expected string
+		if cookie, err := r.Cookie(StateCookieName); err == nil {
+			expected = cookie.Value
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:   StateCookieName,
+			Path:   r.URL.Path,
+			MaxAge: -1,
+		})
+
+		if err := CheckState(CallbackData{Values: r.URL.Query()}, expected); err != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
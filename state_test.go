@@ -0,0 +1,83 @@
+package zarinpalgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	other, _ := NewState()
+	if state == other {
+		t.Error("Expected different state values")
+	}
+
+	callbackURL, err := AppendState("https://example.com/callback", state)
+	if err != nil {
+		t.Fatalf("Failed to append state: %v", err)
+	}
+
+	u, _ := url.Parse(callbackURL)
+	callback := CallbackData{Authority: "A1", Status: CallbackStatusOK, Values: u.Query()}
+	if err := CheckState(callback, state); err != nil {
+		t.Errorf("Expected state to match, got %v", err)
+	}
+	if err := CheckState(callback, other); err != ErrStateMismatch {
+		t.Errorf("Expected %v, got %v", ErrStateMismatch, err)
+	}
+	if err := CheckState(CallbackData{Values: url.Values{}}, ""); err != ErrStateMismatch {
+		t.Errorf("Expected empty state to be rejected, got %v", err)
+	}
+}
+
+func TestNewStateFrom(t *testing.T) {
+	first, _ := NewStateFrom(strings.NewReader(strings.Repeat("x", 32)))
+	second, _ := NewStateFrom(strings.NewReader(strings.Repeat("x", 32)))
+	if first != second {
+		t.Errorf("Expected the same source to produce the same state, got %s and %s", first, second)
+	}
+
+	if _, err := NewStateFrom(strings.NewReader("short")); err == nil {
+		t.Error("Expected error for an exhausted source")
+	}
+}
+
+func TestRequireState(t *testing.T) {
+	handler := RequireState(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	SetStateCookie(rec, "abc", "/callback", time.Hour)
+	cookie := rec.Result().Cookies()[0]
+
+	tests := []struct {
+		target string
+		cookie *http.Cookie
+		code   int
+	}{
+		{"/callback?zp_state=abc", cookie, http.StatusNoContent},
+		{"/callback?zp_state=xyz", cookie, http.StatusForbidden},
+		{"/callback?zp_state=abc", nil, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.target, nil)
+		if test.cookie != nil {
+			req.AddCookie(test.cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDuplicatePayment is returned by stores saving an authority twice
+var ErrDuplicatePayment = errors.New("payment already stored")
+
+// StoredPayment is a payment session along with its current state
+type StoredPayment struct {
+	PaymentSession
+	State     PaymentState `json:"state"`
+	RefID     int          `json:"ref_id,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// PaymentStore keeps payment sessions and their state for the callback helpers and reconciliation.
+// Lookups of unknown payments return ErrPaymentNotFound.
+type PaymentStore interface {
+	// SaveSession stores a new session in the created state, or returns ErrDuplicatePayment
+	SaveSession(ctx context.Context, session PaymentSession) error
+	GetByAuthority(ctx context.Context, authority string) (StoredPayment, error)
+	// GetByOrderID returns the latest payment of the order
+	GetByOrderID(ctx context.Context, orderID string) (StoredPayment, error)
+	// UpdateStatus moves the payment to the given state, or returns ErrInvalidTransition.
+	// The reference ID is kept when refID is zero.
+	UpdateStatus(ctx context.Context, authority string, state PaymentState, refID int) error
+	// ListPending returns the pending payments created before the given time, all of them when it is zero
+	ListPending(ctx context.Context, createdBefore time.Time) ([]StoredPayment, error)
+}
+
+// CheckTransition returns ErrInvalidTransition when the payment can't move to the state,
+// for stores implementing UpdateStatus
+func CheckTransition(authority string, from, to PaymentState) error {
+	if !from.CanMoveTo(to) {
+		return fmt.Errorf("%w: payment %s from %s to %s", ErrInvalidTransition, authority, from, to)
+	}
+	return nil
+}
+
+// StoreAmountLookup returns an AmountLookupFunc reading the amount of the callback's session from the store
+func StoreAmountLookup(store PaymentStore) AmountLookupFunc {
+	return func(ctx context.Context, callback CallbackData) (int, error) {
+		payment, err := store.GetByAuthority(ctx, callback.Authority)
+		if err != nil {
+			return 0, err
+		}
+		return payment.Amount, nil
+	}
+}
+
+// MemoryPaymentStore is a PaymentStore keeping payments in memory, it is safe for concurrent use
+type MemoryPaymentStore struct {
+	mu       sync.Mutex
+	payments map[string]*StoredPayment
+	orders   map[string]string // latest authority of each order
+}
+
+// NewMemoryPaymentStore creates an empty MemoryPaymentStore
+func NewMemoryPaymentStore() *MemoryPaymentStore {
+	return &MemoryPaymentStore{
+		payments: make(map[string]*StoredPayment),
+		orders:   make(map[string]string),
+	}
+}
+
+// SaveSession implements PaymentStore
+func (s *MemoryPaymentStore) SaveSession(ctx context.Context, session PaymentSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.payments[session.Authority]; ok {
+		return ErrDuplicatePayment
+	}
+	s.payments[session.Authority] = &StoredPayment{
+		PaymentSession: session,
+		State:          PaymentStateCreated,
+		UpdatedAt:      time.Now(),
+	}
+	if session.OrderID != "" {
+		s.orders[session.OrderID] = session.Authority
+	}
+	return nil
+}
+
+// GetByAuthority implements PaymentStore
+func (s *MemoryPaymentStore) GetByAuthority(ctx context.Context, authority string) (StoredPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[authority]
+	if !ok {
+		return StoredPayment{}, ErrPaymentNotFound
+	}
+	return *payment, nil
+}
+
+// GetByOrderID implements PaymentStore
+func (s *MemoryPaymentStore) GetByOrderID(ctx context.Context, orderID string) (StoredPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authority, ok := s.orders[orderID]
+	if !ok {
+		return StoredPayment{}, ErrPaymentNotFound
+	}
+	return *s.payments[authority], nil
+}
+
+// UpdateStatus implements PaymentStore
+func (s *MemoryPaymentStore) UpdateStatus(ctx context.Context, authority string, state PaymentState, refID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[authority]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	if err := CheckTransition(authority, payment.State, state); err != nil {
+		return err
+	}
+
+	payment.State = state
+	if refID != 0 {
+		payment.RefID = refID
+	}
+	payment.UpdatedAt = time.Now()
+	return nil
+}
+
+// ListPending implements PaymentStore, payments are ordered by creation time
+func (s *MemoryPaymentStore) ListPending(ctx context.Context, createdBefore time.Time) (pending []StoredPayment, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, payment := range s.payments {
+		if payment.State.Pending() && (createdBefore.IsZero() || payment.CreatedAt.Before(createdBefore)) {
+			pending = append(pending, *payment)
+		}
+	}
+	sortByCreation(pending)
+	return
+}
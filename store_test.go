@@ -0,0 +1,121 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestMemoryPaymentStore(t *testing.T) {
+	store := NewMemoryPaymentStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	sessions := []PaymentSession{
+		{Authority: "A1", Amount: 10000, OrderID: "42", CreatedAt: now.Add(-time.Hour)},
+		{Authority: "A2", Amount: 20000, OrderID: "42", CreatedAt: now},
+	}
+	for _, session := range sessions {
+		if err := store.SaveSession(ctx, session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+	if err := store.SaveSession(ctx, sessions[0]); !errors.Is(err, ErrDuplicatePayment) {
+		t.Errorf("Expected ErrDuplicatePayment, got %v", err)
+	}
+
+	payment, err := store.GetByOrderID(ctx, "42")
+	if err != nil || payment.Authority != "A2" || payment.State != PaymentStateCreated {
+		t.Errorf("Expected latest payment of the order, got %+v %v", payment, err)
+	}
+	if _, err := store.GetByAuthority(ctx, "A3"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("Expected ErrPaymentNotFound, got %v", err)
+	}
+
+	pending, err := store.ListPending(ctx, now.Add(-time.Minute))
+	if err != nil || len(pending) != 1 || pending[0].Authority != "A1" {
+		t.Errorf("Expected the old payment to be pending, got %+v %v", pending, err)
+	}
+
+	if err := store.UpdateStatus(ctx, "A1", PaymentStateVerified, 201); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+	for _, state := range []PaymentState{PaymentStatePending, PaymentStateVerified} {
+		if err := store.UpdateStatus(ctx, "A1", state, 201); err != nil {
+			t.Fatalf("Failed to move payment to %s: %v", state, err)
+		}
+	}
+
+	payment, _ = store.GetByAuthority(ctx, "A1")
+	if payment.State != PaymentStateVerified || payment.RefID != 201 {
+		t.Errorf("Unexpected payment: %+v", payment)
+	}
+	if pending, _ := store.ListPending(ctx, time.Time{}); len(pending) != 1 {
+		t.Errorf("Expected one pending payment, got %+v", pending)
+	}
+}
+
+func TestCallbackHandlerPaymentStore(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"verify.json": `{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`,
+	})
+
+	store := NewMemoryPaymentStore()
+	ctx := context.Background()
+	store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+	store.SaveSession(ctx, PaymentSession{Authority: "A2", Amount: 10000})
+
+	handler := zp.CallbackHandler(StoreAmountLookup(store), nil, WithPaymentStore(store))
+	for _, target := range []string{
+		"/callback?Authority=A1&Status=OK",
+		"/callback?Authority=A1&Status=OK",
+		"/callback?Authority=A2&Status=NOK",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != 200 {
+			t.Errorf("Expected status code 200 for %s, got %d", target, rec.Code)
+		}
+	}
+
+	if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != PaymentStateVerified || payment.RefID != 201 {
+		t.Errorf("Expected verified payment, got %+v", payment)
+	}
+	if payment, _ := store.GetByAuthority(ctx, "A2"); payment.State != PaymentStatePending {
+		t.Errorf("Expected the canceled payment left pending, got %+v", payment)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A2&Status=OK", nil))
+	if payment, _ := store.GetByAuthority(ctx, "A2"); payment.State != PaymentStateVerified {
+		t.Errorf("Expected the payment verified by the real callback, got %+v", payment)
+	}
+}
+
+func TestCallbackHandlerPaymentStoreErrors(t *testing.T) {
+	tests := []struct {
+		code  int
+		state PaymentState
+	}{
+		{-51, PaymentStateFailed},
+		{-54, PaymentStateFailed},
+		{-12, PaymentStatePending},
+		{-52, PaymentStatePending},
+		{-11, PaymentStatePending},
+	}
+	for _, test := range tests {
+		zp := newStubClient(t, map[string]string{"verify.json": fixtures.Error(test.code)})
+		store := NewMemoryPaymentStore()
+		ctx := context.Background()
+		store.SaveSession(ctx, PaymentSession{Authority: "A1", Amount: 10000})
+
+		handler := zp.CallbackHandler(StoreAmountLookup(store), nil, WithPaymentStore(store))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+		if payment, _ := store.GetByAuthority(ctx, "A1"); payment.State != test.state {
+			t.Errorf("Expected %s payment after %d, got %s", test.state, test.code, payment.State)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscription errors
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrInvalidPlan          = errors.New("invalid plan")
+	// ErrSubscriptionConflict is returned by SaveSubscription when the subscription changed
+	// since it was read
+	ErrSubscriptionConflict = errors.New("subscription changed concurrently")
+	// ErrChargeUnresolved is reported for subscriptions whose last charge has an unknown
+	// outcome, they aren't charged again before Biller.ResolveCharge
+	ErrChargeUnresolved = errors.New("outcome of the charge is unknown")
+	// ErrChargeDeclined is wrapped by the errors of DirectDebit.Charge when the bank refused the
+	// charge, other errors leave its outcome unknown
+	ErrChargeDeclined = errors.New("charge declined")
+)
+
+// DefaultBillingInterval is the time between billing runs
+const DefaultBillingInterval = time.Hour
+
+// DefaultRetryDelays are the delays between the retries of a failed charge, the subscription is
+// suspended when the last retry fails
+var DefaultRetryDelays = []time.Duration{24 * time.Hour, 72 * time.Hour, 7 * 24 * time.Hour}
+
+// DirectDebit charges the direct debit contracts (Payman) signed by customers. Charges are made
+// without the customer, so a failed charge is retried later instead of asking them to pay.
+type DirectDebit interface {
+	// Charge debits amount Rials from the contract and returns the reference ID of the payment.
+	// The key is the same for every attempt to charge a cycle, send it to the bank as the
+	// idempotency key of successful debits. Errors of refused charges wrap ErrChargeDeclined,
+	// other errors, like timeouts, mean the contract may have been debited.
+	Charge(ctx context.Context, key, contractSignature string, amount int, description string) (refID int, err error)
+}
+
+// DirectDebitFunc adapts a function to DirectDebit
+type DirectDebitFunc func(ctx context.Context, key, contractSignature string, amount int, description string) (int, error)
+
+// Charge implements DirectDebit
+func (f DirectDebitFunc) Charge(ctx context.Context, key, contractSignature string, amount int, description string) (int, error) {
+	return f(ctx, key, contractSignature, amount, description)
+}
+
+// ChargeInquirer is implemented by the DirectDebits that can look a charge up by its key, the
+// Biller then resolves the charges with an unknown outcome on its own
+type ChargeInquirer interface {
+	// InquireCharge returns the reference ID of the charge of the key, zero when the contract
+	// wasn't debited
+	InquireCharge(ctx context.Context, key string) (refID int, err error)
+}
+
+// Plan is a billing plan, subscribers are charged its amount every cycle
+type Plan struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Amount int    `json:"amount"` // in Rials
+
+	// the cycle is IntervalMonths months and IntervalDays days, like 1 and 0 for monthly plans
+	IntervalMonths int `json:"interval_months"`
+	IntervalDays   int `json:"interval_days"`
+}
+
+// Validate checks the plan can be billed
+func (p Plan) Validate() error {
+	if p.ID == "" || p.Amount <= 0 {
+		return fmt.Errorf("%w: %q needs an ID and a positive amount", ErrInvalidPlan, p.ID)
+	}
+	if p.IntervalMonths < 0 || p.IntervalDays < 0 || p.IntervalMonths+p.IntervalDays == 0 {
+		return fmt.Errorf("%w: %q needs a positive cycle", ErrInvalidPlan, p.ID)
+	}
+	return nil
+}
+
+// next returns the end of the cycle starting at t
+func (p Plan) next(t time.Time) time.Time {
+	return t.AddDate(0, p.IntervalMonths, p.IntervalDays)
+}
+
+// SubscriptionStatus is the billing state of a subscription
+type SubscriptionStatus string
+
+// SubscriptionStatus constants
+const (
+	SubscriptionActive    SubscriptionStatus = "ACTIVE"    // charged every cycle
+	SubscriptionPastDue   SubscriptionStatus = "PAST_DUE"  // the last charge failed and will be retried
+	SubscriptionSuspended SubscriptionStatus = "SUSPENDED" // every retry failed, no more charges
+	SubscriptionCanceled  SubscriptionStatus = "CANCELED"  // canceled by the merchant or customer
+)
+
+// Subscription is a customer subscribed to a plan through a direct debit contract
+type Subscription struct {
+	ID                string             `json:"id"`
+	Plan              Plan               `json:"plan"`
+	Customer          string             `json:"customer"`
+	ContractSignature string             `json:"contract_signature"` // signature of the Payman contract
+	Status            SubscriptionStatus `json:"status"`
+
+	PeriodEnd time.Time `json:"period_end"`         // end of the paid cycle, the next charge is due then
+	RetryAt   time.Time `json:"retry_at,omitempty"` // next retry of a past due subscription
+	Failures  int       `json:"failures"`           // failed charges of the current cycle
+	LastRefID int       `json:"last_ref_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// ChargeKey names the charge of the cycle in progress, it is saved before the contract is
+	// debited and cleared along with the outcome. A subscription keeping one was maybe charged
+	// without the outcome being saved.
+	ChargeKey string `json:"charge_key,omitempty"`
+	// Version counts the saves of the subscription, to detect concurrent changes
+	Version int `json:"version"`
+}
+
+// chargeKey returns the key of the charge due for the subscription, the same for every attempt
+// to charge a cycle, retries included
+func (s Subscription) chargeKey() string {
+	return s.ID + "/" + s.PeriodEnd.UTC().Format(time.RFC3339)
+}
+
+// dueAt returns when the subscription is charged next, zero when it isn't
+func (s Subscription) dueAt() time.Time {
+	switch s.Status {
+	case SubscriptionActive:
+		return s.PeriodEnd
+	case SubscriptionPastDue:
+		return s.RetryAt
+	}
+	return time.Time{}
+}
+
+// SubscriptionStore keeps subscriptions, lookups of unknown ones return ErrSubscriptionNotFound
+type SubscriptionStore interface {
+	// SaveSubscription creates the subscription, or replaces it when the stored one has the
+	// same Version and returns ErrSubscriptionConflict otherwise. The stored Version is the
+	// given one plus one.
+	SaveSubscription(ctx context.Context, subscription Subscription) error
+	GetSubscription(ctx context.Context, id string) (Subscription, error)
+	// ListDue returns the subscriptions to charge at the given time
+	ListDue(ctx context.Context, now time.Time) ([]Subscription, error)
+}
+
+// BillingEventType is the outcome of a charge
+type BillingEventType string
+
+// BillingEventType constants
+const (
+	BillingRenewed   BillingEventType = "RENEWED"   // the subscription was charged for a new cycle
+	BillingFailed    BillingEventType = "FAILED"    // the charge failed and will be retried
+	BillingSuspended BillingEventType = "SUSPENDED" // the last retry failed
+)
+
+// BillingEvent reports the outcome of a charge
+type BillingEvent struct {
+	Type         BillingEventType
+	Subscription Subscription // after the charge
+	Err          error        // why the charge failed
+}
+
+// Biller charges the subscriptions of the store on their cycle. A failed charge moves the
+// subscription past due and is retried after each of the retry delays, the subscription keeps
+// its service during this grace period and is suspended when the last retry fails.
+//
+// The key of a charge is saved before the contract is debited. When the charge fails without
+// being declined or saving the outcome fails the key stays, and the subscription is reported
+// with ErrChargeUnresolved instead of being charged twice until the charge is resolved, by
+// ResolveCharge or by asking a DirectDebit implementing ChargeInquirer on the next run.
+type Biller struct {
+	Debit DirectDebit
+	Store SubscriptionStore
+
+	Interval    time.Duration   // time between runs, defaults to DefaultBillingInterval
+	RetryDelays []time.Duration // defaults to DefaultRetryDelays
+
+	// OnEvent is called with the outcome of every charge
+	OnEvent func(ctx context.Context, event BillingEvent)
+	// OnError is called with the failures to list or save subscriptions, they don't stop a run
+	OnError func(subscriptionID string, err error)
+}
+
+// NewBiller creates a Biller with the default interval and retry delays
+func NewBiller(debit DirectDebit, store SubscriptionStore) *Biller {
+	return &Biller{
+		Debit:       debit,
+		Store:       store,
+		Interval:    DefaultBillingInterval,
+		RetryDelays: DefaultRetryDelays,
+	}
+}
+
+// Subscribe subscribes the customer to the plan, the first charge is due at start. Pass the
+// current time to charge the first cycle on the next run.
+func (b *Biller) Subscribe(ctx context.Context, id string, plan Plan, customer, contractSignature string, start time.Time) (subscription Subscription, err error) {
+	if err = plan.Validate(); err != nil {
+		return
+	}
+	subscription = Subscription{
+		ID:                id,
+		Plan:              plan,
+		Customer:          customer,
+		ContractSignature: contractSignature,
+		Status:            SubscriptionActive,
+		PeriodEnd:         start,
+		CreatedAt:         time.Now(),
+	}
+	err = b.Store.SaveSubscription(ctx, subscription)
+	return
+}
+
+// Cancel stops charging the subscription, the paid cycle isn't refunded
+func (b *Biller) Cancel(ctx context.Context, id string) error {
+	return b.update(ctx, id, func(subscription *Subscription) error {
+		subscription.Status = SubscriptionCanceled
+		subscription.RetryAt = time.Time{}
+		return nil
+	})
+}
+
+// ResolveCharge settles a charge reported with ErrChargeUnresolved after checking it with the
+// bank: refID is the reference ID of the charge when the contract was debited, and zero when
+// it wasn't and the subscription should be charged again. Subscriptions without an unresolved
+// charge are left as they are.
+func (b *Biller) ResolveCharge(ctx context.Context, id string, refID int) error {
+	return b.update(ctx, id, func(subscription *Subscription) error {
+		if subscription.ChargeKey == "" {
+			return nil
+		}
+		if refID != 0 {
+			renew(subscription, refID)
+		}
+		subscription.ChargeKey = ""
+		return nil
+	})
+}
+
+// update applies fn to the stored subscription, reading it again when it changed concurrently
+func (b *Biller) update(ctx context.Context, id string, fn func(subscription *Subscription) error) error {
+	for {
+		subscription, err := b.Store.GetSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = fn(&subscription); err != nil {
+			return err
+		}
+		if err = b.Store.SaveSubscription(ctx, subscription); !errors.Is(err, ErrSubscriptionConflict) {
+			return err
+		}
+	}
+}
+
+// Run charges the due subscriptions every interval until the context is done
+func (b *Biller) Run(ctx context.Context) error {
+	interval := b.Interval
+	if interval <= 0 {
+		interval = DefaultBillingInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.BillOnce(ctx); err != nil {
+			b.fail("", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// BillOnce charges the subscriptions due now and returns the outcomes. Only failing to list
+// subscriptions is returned as an error.
+func (b *Biller) BillOnce(ctx context.Context) (events []BillingEvent, err error) {
+	due, err := b.Store.ListDue(ctx, time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, subscription := range due {
+		if ctx.Err() != nil {
+			return events, ctx.Err()
+		}
+		if subscription.ChargeKey != "" {
+			b.inquire(ctx, subscription)
+			continue
+		}
+
+		// claim the charge, which fails when the subscription was canceled or charged since
+		// it was listed
+		subscription.ChargeKey = subscription.chargeKey()
+		if err := b.Store.SaveSubscription(ctx, subscription); err != nil {
+			b.fail(subscription.ID, err)
+			continue
+		}
+		subscription.Version++
+
+		event, chargeErr := b.charge(ctx, subscription)
+		if chargeErr != nil {
+			// the contract may have been debited, the charge key stays until it is resolved
+			b.fail(subscription.ID, fmt.Errorf("%w: %s: %v", ErrChargeUnresolved, subscription.ChargeKey, chargeErr))
+			continue
+		}
+		saved, saveErr := b.saveOutcome(ctx, event.Subscription)
+		if saveErr != nil {
+			// the charge key stays, so the subscription isn't charged again before it is resolved
+			b.fail(subscription.ID, fmt.Errorf("%w: %v", ErrChargeUnresolved, saveErr))
+			continue
+		}
+		event.Subscription = saved
+		events = append(events, event)
+		if b.OnEvent != nil {
+			if panicErr := RunHook("biller OnEvent", func() { b.OnEvent(ctx, event) }); panicErr != nil {
+				b.fail(subscription.ID, panicErr)
+			}
+		}
+	}
+	return
+}
+
+// inquire resolves the unresolved charge of the subscription when the DirectDebit can look it
+// up, and reports it otherwise. A charge that didn't debit the contract is made on the next run.
+func (b *Biller) inquire(ctx context.Context, subscription Subscription) {
+	inquirer, ok := b.Debit.(ChargeInquirer)
+	if !ok {
+		b.fail(subscription.ID, fmt.Errorf("%w: %s", ErrChargeUnresolved, subscription.ChargeKey))
+		return
+	}
+	refID, err := inquirer.InquireCharge(ctx, subscription.ChargeKey)
+	if err != nil {
+		b.fail(subscription.ID, fmt.Errorf("%w: %s: %v", ErrChargeUnresolved, subscription.ChargeKey, err))
+		return
+	}
+	if err = b.ResolveCharge(ctx, subscription.ID, refID); err != nil {
+		b.fail(subscription.ID, err)
+	}
+}
+
+// charge charges a due subscription and moves it to its next state, err is set when the outcome
+// of the charge is unknown
+func (b *Biller) charge(ctx context.Context, subscription Subscription) (event BillingEvent, err error) {
+	plan := subscription.Plan
+	description := fmt.Sprintf("%s renewal until %s", plan.Name, plan.next(subscription.PeriodEnd).Format("2006-01-02"))
+	refID, err := b.Debit.Charge(ctx, subscription.ChargeKey, subscription.ContractSignature, plan.Amount, description)
+	if err == nil {
+		renew(&subscription, refID)
+		return BillingEvent{Type: BillingRenewed, Subscription: subscription}, nil
+	}
+	if !errors.Is(err, ErrChargeDeclined) {
+		return
+	}
+
+	delays := b.RetryDelays
+	if delays == nil {
+		delays = DefaultRetryDelays
+	}
+	subscription.Failures++
+	if subscription.Failures > len(delays) {
+		subscription.Status = SubscriptionSuspended
+		subscription.RetryAt = time.Time{}
+		return BillingEvent{Type: BillingSuspended, Subscription: subscription, Err: err}, nil
+	}
+	subscription.Status = SubscriptionPastDue
+	subscription.RetryAt = time.Now().Add(delays[subscription.Failures-1])
+	return BillingEvent{Type: BillingFailed, Subscription: subscription, Err: err}, nil
+}
+
+// saveOutcome saves the subscription after its charge, clearing the charge key. Changes made
+// during the charge, like a cancellation, are kept along with the paid cycle.
+func (b *Biller) saveOutcome(ctx context.Context, charged Subscription) (Subscription, error) {
+	key := charged.ChargeKey
+	charged.ChargeKey = ""
+	err := b.Store.SaveSubscription(ctx, charged)
+	if err == nil {
+		charged.Version++
+		return charged, nil
+	}
+	if !errors.Is(err, ErrSubscriptionConflict) {
+		return charged, err
+	}
+
+	current, err := b.Store.GetSubscription(ctx, charged.ID)
+	if err != nil {
+		return charged, err
+	}
+	if current.ChargeKey != key {
+		return charged, fmt.Errorf("%w: charge %s was resolved elsewhere", ErrSubscriptionConflict, key)
+	}
+	current.PeriodEnd = charged.PeriodEnd
+	current.RetryAt = charged.RetryAt
+	current.Failures = charged.Failures
+	current.LastRefID = charged.LastRefID
+	current.ChargeKey = ""
+	if current.Status != SubscriptionCanceled {
+		current.Status = charged.Status
+	} else {
+		current.RetryAt = time.Time{}
+	}
+	if err = b.Store.SaveSubscription(ctx, current); err != nil {
+		return charged, err
+	}
+	current.Version++
+	return current, nil
+}
+
+// renew moves the subscription to its next cycle after it was charged
+func renew(subscription *Subscription, refID int) {
+	subscription.Status = SubscriptionActive
+	subscription.PeriodEnd = subscription.Plan.next(subscription.PeriodEnd)
+	subscription.RetryAt = time.Time{}
+	subscription.Failures = 0
+	subscription.LastRefID = refID
+}
+
+func (b *Biller) fail(subscriptionID string, err error) {
+	if b.OnError != nil {
+		RunHook("biller OnError", func() { b.OnError(subscriptionID, err) })
+	}
+}
+
+// MemorySubscriptionStore is a SubscriptionStore keeping subscriptions in memory, it is safe
+// for concurrent use
+type MemorySubscriptionStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+}
+
+// NewMemorySubscriptionStore creates an empty MemorySubscriptionStore
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{subscriptions: make(map[string]Subscription)}
+}
+
+// SaveSubscription implements SubscriptionStore
+func (s *MemorySubscriptionStore) SaveSubscription(ctx context.Context, subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.subscriptions[subscription.ID]; ok && stored.Version != subscription.Version {
+		return fmt.Errorf("%w: %s", ErrSubscriptionConflict, subscription.ID)
+	}
+	subscription.Version++
+	s.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+// GetSubscription implements SubscriptionStore
+func (s *MemorySubscriptionStore) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// ListDue implements SubscriptionStore, subscriptions are ordered by due time and ID
+func (s *MemorySubscriptionStore) ListDue(ctx context.Context, now time.Time) (due []Subscription, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscription := range s.subscriptions {
+		if at := subscription.dueAt(); !at.IsZero() && !at.After(now) {
+			due = append(due, subscription)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if a, b := due[i].dueAt(), due[j].dueAt(); !a.Equal(b) {
+			return a.Before(b)
+		}
+		return due[i].ID < due[j].ID
+	})
+	return
+}
@@ -0,0 +1,260 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// rewind moves the due times of a stored subscription back, as if the time passed
+func rewind(t *testing.T, store *MemorySubscriptionStore, id string, d time.Duration) {
+	t.Helper()
+	subscription, err := store.GetSubscription(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscription.PeriodEnd = subscription.PeriodEnd.Add(-d)
+	if !subscription.RetryAt.IsZero() {
+		subscription.RetryAt = subscription.RetryAt.Add(-d)
+	}
+	store.SaveSubscription(context.Background(), subscription)
+}
+
+func TestBiller(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	declined := map[string]bool{"sig-2": true}
+	var charges []string
+	debit := DirectDebitFunc(func(ctx context.Context, key, signature string, amount int, description string) (int, error) {
+		charges = append(charges, signature)
+		if declined[signature] {
+			return 0, fmt.Errorf("%w: insufficient balance", ErrChargeDeclined)
+		}
+		return 1000 + len(charges), nil
+	})
+
+	store := NewMemorySubscriptionStore()
+	biller := NewBiller(debit, store)
+	biller.RetryDelays = []time.Duration{time.Hour}
+	var events []BillingEvent
+	biller.OnEvent = func(ctx context.Context, event BillingEvent) {
+		events = append(events, event)
+	}
+
+	monthly := Plan{ID: "pro", Name: "Pro", Amount: 100000, IntervalMonths: 1}
+	if _, err := biller.Subscribe(ctx, "S1", monthly, "c1", "sig-1", start); err != nil {
+		t.Fatal(err)
+	}
+	biller.Subscribe(ctx, "S2", monthly, "c2", "sig-2", start)
+	biller.Subscribe(ctx, "S3", monthly, "c3", "sig-3", time.Now().Add(time.Hour))
+	if _, err := biller.Subscribe(ctx, "S4", Plan{ID: "free"}, "c4", "sig-4", start); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("Expected ErrInvalidPlan, got %v", err)
+	}
+
+	if _, err := biller.BillOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != BillingRenewed || events[1].Type != BillingFailed {
+		t.Fatalf("Expected a renewal and a failure, got %+v", events)
+	}
+	s1, _ := store.GetSubscription(ctx, "S1")
+	if want := start.AddDate(0, 1, 0); !s1.PeriodEnd.Equal(want) || s1.LastRefID != 1001 {
+		t.Errorf("Expected S1 paid until %v with ref ID 1001, got %v and %d", want, s1.PeriodEnd, s1.LastRefID)
+	}
+	s2, _ := store.GetSubscription(ctx, "S2")
+	if s2.Status != SubscriptionPastDue || s2.Failures != 1 || time.Until(s2.RetryAt) < 59*time.Minute {
+		t.Errorf("Expected S2 past due for an hour, got %s until %v", s2.Status, s2.RetryAt)
+	}
+
+	// nothing is due until the retry of S2
+	events = nil
+	biller.BillOnce(ctx)
+	if len(events) != 0 {
+		t.Errorf("Expected no charges, got %+v", events)
+	}
+
+	rewind(t, store, "S2", time.Hour)
+	rewind(t, store, "S3", time.Hour)
+	events = nil
+	biller.BillOnce(ctx)
+	outcomes := make(map[string]BillingEventType)
+	for _, event := range events {
+		outcomes[event.Subscription.ID] = event.Type
+	}
+	if len(events) != 2 || outcomes["S2"] != BillingSuspended || outcomes["S3"] != BillingRenewed {
+		t.Fatalf("Expected S2 to be suspended and S3 to be renewed, got %+v", events)
+	}
+
+	if err := biller.Cancel(ctx, "S3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := biller.Cancel(ctx, "S9"); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Expected ErrSubscriptionNotFound, got %v", err)
+	}
+	rewind(t, store, "S3", 24*time.Hour*365)
+	events = nil
+	biller.BillOnce(ctx)
+	if len(events) != 0 {
+		t.Errorf("Expected no charges after the cancellation, got %+v", events)
+	}
+}
+
+func TestBillerRecoversWithinGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	fail := true
+	store := NewMemorySubscriptionStore()
+	biller := NewBiller(DirectDebitFunc(func(context.Context, string, string, int, string) (int, error) {
+		if fail {
+			return 0, fmt.Errorf("%w: contract expired", ErrChargeDeclined)
+		}
+		return 7, nil
+	}), store)
+
+	plan := Plan{ID: "weekly", Amount: 5000, IntervalDays: 7}
+	biller.Subscribe(ctx, "S1", plan, "c1", "sig", time.Now())
+	biller.BillOnce(ctx)
+
+	fail = false
+	rewind(t, store, "S1", DefaultRetryDelays[0])
+	events, _ := biller.BillOnce(ctx)
+	if len(events) != 1 || events[0].Type != BillingRenewed {
+		t.Fatalf("Expected the retry to renew the subscription, got %+v", events)
+	}
+	if s := events[0].Subscription; s.Status != SubscriptionActive || s.Failures != 0 || !s.RetryAt.IsZero() {
+		t.Errorf("Expected an active subscription without failures, got %+v", s)
+	}
+}
+
+// flakySubscriptionStore fails the saves made while failing is set
+type flakySubscriptionStore struct {
+	*MemorySubscriptionStore
+	failing bool
+}
+
+func (s *flakySubscriptionStore) SaveSubscription(ctx context.Context, subscription Subscription) error {
+	if s.failing {
+		return errors.New("connection lost")
+	}
+	return s.MemorySubscriptionStore.SaveSubscription(ctx, subscription)
+}
+
+func TestBillerUnsavedCharge(t *testing.T) {
+	ctx := context.Background()
+	store := &flakySubscriptionStore{MemorySubscriptionStore: NewMemorySubscriptionStore()}
+	charges := 0
+	biller := NewBiller(DirectDebitFunc(func(context.Context, string, string, int, string) (int, error) {
+		charges++
+		store.failing = true
+		return 42, nil
+	}), store)
+	var errs []error
+	biller.OnError = func(id string, err error) { errs = append(errs, err) }
+
+	start := time.Now()
+	plan := Plan{ID: "monthly", Amount: 5000, IntervalMonths: 1}
+	biller.Subscribe(ctx, "S1", plan, "c1", "sig", start)
+	biller.BillOnce(ctx)
+	store.failing = false
+	biller.BillOnce(ctx)
+
+	if charges != 1 {
+		t.Errorf("Expected the subscription charged once, got %d charges", charges)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], ErrChargeUnresolved) || !errors.Is(errs[1], ErrChargeUnresolved) {
+		t.Errorf("Expected the unresolved charge reported on both runs, got %v", errs)
+	}
+
+	if err := biller.ResolveCharge(ctx, "S1", 42); err != nil {
+		t.Fatal(err)
+	}
+	biller.ResolveCharge(ctx, "S1", 42)
+	s1, _ := store.GetSubscription(ctx, "S1")
+	if want := start.AddDate(0, 1, 0); !s1.PeriodEnd.Equal(want) || s1.LastRefID != 42 || s1.ChargeKey != "" {
+		t.Errorf("Expected S1 paid until %v with ref ID 42, got %+v", want, s1)
+	}
+	if events, _ := biller.BillOnce(ctx); len(events) != 0 || charges != 1 {
+		t.Errorf("Expected no charge before the next cycle, got %+v", events)
+	}
+}
+
+func TestBillerCancelDuringCharge(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	var biller *Biller
+	biller = NewBiller(DirectDebitFunc(func(context.Context, string, string, int, string) (int, error) {
+		if err := biller.Cancel(ctx, "S1"); err != nil {
+			t.Fatal(err)
+		}
+		return 42, nil
+	}), store)
+
+	start := time.Now()
+	biller.Subscribe(ctx, "S1", Plan{ID: "monthly", Amount: 5000, IntervalMonths: 1}, "c1", "sig", start)
+	events, _ := biller.BillOnce(ctx)
+	if len(events) != 1 || events[0].Type != BillingRenewed {
+		t.Fatalf("Expected the charge reported, got %+v", events)
+	}
+
+	s1, _ := store.GetSubscription(ctx, "S1")
+	if s1.Status != SubscriptionCanceled || s1.LastRefID != 42 || !s1.PeriodEnd.Equal(start.AddDate(0, 1, 0)) || s1.ChargeKey != "" {
+		t.Errorf("Expected the cancellation kept along with the paid cycle, got %+v", s1)
+	}
+
+	stale := s1
+	stale.Version--
+	if err := store.SaveSubscription(ctx, stale); !errors.Is(err, ErrSubscriptionConflict) {
+		t.Errorf("Expected ErrSubscriptionConflict, got %v", err)
+	}
+}
+
+// inquiringDebit times out on the first charge and answers inquiries from the charges it made
+type inquiringDebit struct {
+	charges map[string]int
+	calls   int
+}
+
+func (d *inquiringDebit) Charge(ctx context.Context, key, signature string, amount int, description string) (int, error) {
+	d.calls++
+	if _, ok := d.charges[key]; !ok {
+		d.charges[key] = 42
+		return 0, context.DeadlineExceeded
+	}
+	return d.charges[key], nil
+}
+
+func (d *inquiringDebit) InquireCharge(ctx context.Context, key string) (int, error) {
+	return d.charges[key], nil
+}
+
+func TestBillerInquiresTimedOutCharge(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	debit := &inquiringDebit{charges: map[string]int{}}
+	biller := NewBiller(debit, store)
+	var errs []error
+	biller.OnError = func(id string, err error) { errs = append(errs, err) }
+
+	start := time.Now()
+	biller.Subscribe(ctx, "S1", Plan{ID: "monthly", Amount: 5000, IntervalMonths: 1}, "c1", "sig", start)
+	if events, _ := biller.BillOnce(ctx); len(events) != 0 {
+		t.Errorf("Expected no event for the timed out charge, got %+v", events)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrChargeUnresolved) {
+		t.Errorf("Expected the timed out charge reported as unresolved, got %v", errs)
+	}
+	s1, _ := store.GetSubscription(ctx, "S1")
+	if s1.Failures != 0 || s1.Status != SubscriptionActive || s1.ChargeKey == "" {
+		t.Errorf("Expected the charge kept unresolved without a failure, got %+v", s1)
+	}
+
+	biller.BillOnce(ctx)
+	s1, _ = store.GetSubscription(ctx, "S1")
+	if want := start.AddDate(0, 1, 0); !s1.PeriodEnd.Equal(want) || s1.LastRefID != 42 || s1.ChargeKey != "" {
+		t.Errorf("Expected S1 paid until %v with ref ID 42, got %+v", want, s1)
+	}
+	if debit.calls != 1 {
+		t.Errorf("Expected the subscription charged once, got %d charges", debit.calls)
+	}
+}
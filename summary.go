@@ -0,0 +1,153 @@
+package zarinpalgo
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// summaryDateLayout is the layout of DailySummary.Date
+const summaryDateLayout = "2006-01-02"
+
+// DailySummary totals the payments created on one day
+type DailySummary struct {
+	Date           string  `json:"date"`        // YYYY-MM-DD in the location the summary was made in
+	JalaliDate     string  `json:"jalali_date"` // the same day as YYYY/MM/DD in the Jalali calendar
+	Count          int     `json:"count"`
+	Successful     int     `json:"successful"`
+	GrossAmount    int     `json:"gross_amount"` // sum of the successful payments, refunds included
+	Fees           int     `json:"fees"`
+	Refunds        int     `json:"refunds"`
+	RefundedAmount int     `json:"refunded_amount"`
+	SuccessRate    float64 `json:"success_rate"` // Successful / Count, between 0 and 1
+}
+
+// NetAmount returns the gross amount minus fees and refunds
+func (s DailySummary) NetAmount() int {
+	return s.GrossAmount - s.Fees - s.RefundedAmount
+}
+
+// summaryEntry is a payment reduced to what the summaries count
+type summaryEntry struct {
+	createdAt  time.Time
+	amount     int
+	fee        int
+	successful bool
+	refunded   bool
+}
+
+// SummarizePayments groups stored payments by their creation day in loc, nil meaning UTC.
+// Payments verified or refunded later count as successful, fees are unknown to the store and
+// left at zero.
+func SummarizePayments(payments []StoredPayment, loc *time.Location) []DailySummary {
+	entries := make([]summaryEntry, 0, len(payments))
+	for _, payment := range payments {
+		entries = append(entries, summaryEntry{
+			createdAt:  payment.CreatedAt,
+			amount:     payment.Amount,
+			successful: payment.State == PaymentStateVerified || payment.State == PaymentStateRefunded,
+			refunded:   payment.State == PaymentStateRefunded,
+		})
+	}
+	return summarize(entries, loc)
+}
+
+// SummarizeTransactions groups transactions of the reporting API by their creation day in loc,
+// nil meaning UTC. Reversed transactions count as refunded.
+func SummarizeTransactions(transactions []Transaction, loc *time.Location) []DailySummary {
+	entries := make([]summaryEntry, 0, len(transactions))
+	for _, transaction := range transactions {
+		reversed := transaction.Status == InquiryStatusReversed
+		entries = append(entries, summaryEntry{
+			createdAt:  transaction.CreatedAt,
+			amount:     transaction.Amount,
+			fee:        transaction.Fee,
+			successful: transaction.IsPaid() || reversed,
+			refunded:   reversed,
+		})
+	}
+	return summarize(entries, loc)
+}
+
+// DailySummaries summarizes the payments created in [from, to) in the store, which must
+// implement PaymentLister
+func DailySummaries(ctx context.Context, store PaymentStore, from, to time.Time, loc *time.Location) ([]DailySummary, error) {
+	lister, ok := store.(PaymentLister)
+	if !ok {
+		return nil, ErrListingUnsupported
+	}
+	payments, err := lister.ListCreated(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizePayments(payments, loc), nil
+}
+
+// DailyTransactionSummaries summarizes the transactions created in [from, to) on Zarinpal
+func DailyTransactionSummaries(ctx context.Context, source TransactionSource, from, to time.Time, loc *time.Location) ([]DailySummary, error) {
+	transactions, err := source.Transactions(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeTransactions(transactions, loc), nil
+}
+
+// TotalSummary adds up summaries, the date of the result is left empty
+func TotalSummary(summaries []DailySummary) (total DailySummary) {
+	for _, s := range summaries {
+		total.Count += s.Count
+		total.Successful += s.Successful
+		total.GrossAmount += s.GrossAmount
+		total.Fees += s.Fees
+		total.Refunds += s.Refunds
+		total.RefundedAmount += s.RefundedAmount
+	}
+	total.SuccessRate = successRate(total.Successful, total.Count)
+	return
+}
+
+// summarize groups the entries by day, days without payments are omitted
+func summarize(entries []summaryEntry, loc *time.Location) []DailySummary {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	days := make(map[string]*DailySummary)
+	for _, entry := range entries {
+		createdAt := entry.createdAt.In(loc)
+		date := createdAt.Format(summaryDateLayout)
+		day, ok := days[date]
+		if !ok {
+			day = &DailySummary{Date: date, JalaliDate: ToJalali(createdAt).String()}
+			days[date] = day
+		}
+
+		day.Count++
+		if entry.successful {
+			day.Successful++
+			day.GrossAmount += entry.amount
+			day.Fees += entry.fee
+		}
+		if entry.refunded {
+			day.Refunds++
+			day.RefundedAmount += entry.amount
+		}
+	}
+
+	summaries := make([]DailySummary, 0, len(days))
+	for _, day := range days {
+		day.SuccessRate = successRate(day.Successful, day.Count)
+		summaries = append(summaries, *day)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Date < summaries[j].Date
+	})
+	return summaries
+}
+
+func successRate(successful, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(successful) / float64(count)
+}
@@ -0,0 +1,81 @@
+package zarinpalgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDailySummaries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPaymentStore()
+	day := time.Date(2024, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	payments := []struct {
+		authority string
+		amount    int
+		createdAt time.Time
+		states    []PaymentState
+	}{
+		{"A1", 10000, day, []PaymentState{PaymentStatePending, PaymentStateVerified}},
+		{"A2", 20000, day.Add(time.Hour), []PaymentState{PaymentStatePending, PaymentStateFailed}},
+		{"A3", 30000, day.Add(2 * time.Hour), []PaymentState{PaymentStatePending, PaymentStateVerified, PaymentStateRefunded}},
+		{"A4", 40000, day.Add(24 * time.Hour), []PaymentState{PaymentStatePending, PaymentStateVerified}},
+		{"A5", 50000, day.Add(48 * time.Hour), nil},
+	}
+	for _, p := range payments {
+		store.SaveSession(ctx, PaymentSession{Authority: p.authority, Amount: p.amount, CreatedAt: p.createdAt})
+		for _, state := range p.states {
+			if err := store.UpdateStatus(ctx, p.authority, state, 0); err != nil {
+				t.Fatalf("Failed to update %s: %v", p.authority, err)
+			}
+		}
+	}
+
+	summaries, err := DailySummaries(ctx, store, day.Add(-time.Hour), day.Add(48*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Failed to summarize payments: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 days, got %+v", summaries)
+	}
+
+	first := summaries[0]
+	if first.Date != "2024-05-12" || first.Count != 3 || first.Successful != 2 {
+		t.Errorf("Unexpected first day %+v", first)
+	}
+	if first.GrossAmount != 40000 || first.Refunds != 1 || first.RefundedAmount != 30000 || first.NetAmount() != 10000 {
+		t.Errorf("Unexpected first day amounts %+v", first)
+	}
+	if first.SuccessRate < 0.66 || first.SuccessRate > 0.67 {
+		t.Errorf("Expected a success rate of 2/3, got %f", first.SuccessRate)
+	}
+	if summaries[1].Date != "2024-05-13" || summaries[1].SuccessRate != 1 {
+		t.Errorf("Unexpected second day %+v", summaries[1])
+	}
+
+	total := TotalSummary(summaries)
+	if total.Count != 4 || total.GrossAmount != 80000 || total.SuccessRate != 0.75 {
+		t.Errorf("Unexpected total %+v", total)
+	}
+}
+
+func TestSummarizeTransactionsLocation(t *testing.T) {
+	tehran := time.FixedZone("IRST", 3*3600+1800)
+	// 21:00 UTC is already the next day in Tehran
+	late := time.Date(2024, 5, 12, 21, 0, 0, 0, time.UTC)
+
+	summaries := SummarizeTransactions([]Transaction{
+		{Authority: "A1", Status: InquiryStatusVerified, Amount: 10000, Fee: 100, CreatedAt: late},
+		{Authority: "A2", Status: InquiryStatusReversed, Amount: 20000, Fee: 200, CreatedAt: late},
+		{Authority: "A3", Status: InquiryStatusFailed, Amount: 30000, CreatedAt: late},
+	}, tehran)
+
+	if len(summaries) != 1 || summaries[0].Date != "2024-05-13" || summaries[0].JalaliDate != "1403/02/24" {
+		t.Fatalf("Expected one day on 2024-05-13 (1403/02/24), got %+v", summaries)
+	}
+	day := summaries[0]
+	if day.Successful != 2 || day.Fees != 300 || day.Refunds != 1 || day.NetAmount() != 9700 {
+		t.Errorf("Unexpected summary %+v", day)
+	}
+}
@@ -0,0 +1,57 @@
+package zarinpalgo
+
+import (
+	"context"
+	"log/slog"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose gateway requests are labelled with the tenant, like the
+// shop a multi-tenant service serves, overriding Zarinpal.Tenant. The label reaches pprof
+// labels, traffic records, operation errors and the deadline and drift hooks so success rates
+// and latencies can be broken down per shop.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant carried by the context, hooks of the client see the one of the
+// request they are called for
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// withTenant returns the context labelled with the tenant of the client, unless it carries one
+func (z *Zarinpal) withTenant(ctx context.Context) context.Context {
+	if z.Tenant == "" || Tenant(ctx) != "" {
+		return ctx
+	}
+	return WithTenant(ctx, z.Tenant)
+}
+
+// TenantLogHandler wraps the handler to add a "tenant" attribute to the records logged with a
+// context carrying a tenant, like the ones of LogDeadlines and LogDrift
+func TenantLogHandler(handler slog.Handler) slog.Handler {
+	return tenantLogHandler{handler}
+}
+
+type tenantLogHandler struct {
+	slog.Handler
+}
+
+func (h tenantLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if tenant := Tenant(ctx); tenant != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String("tenant", tenant))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h tenantLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return tenantLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h tenantLogHandler) WithGroup(name string) slog.Handler {
+	return tenantLogHandler{h.Handler.WithGroup(name)}
+}
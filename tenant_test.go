@@ -0,0 +1,85 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest/fixtures"
+)
+
+func TestTenantLabels(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1","card_type":"debit"},"errors":[]}`,
+		"verify.json":  fixtures.Error(-51),
+	})
+	zp.Tenant = "shop-1"
+
+	var records []TrafficRecord
+	zp.Traffic = TrafficSinkFunc(func(ctx context.Context, record TrafficRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	var drift FieldDrift
+	var label string
+	zp.DriftHook = func(ctx context.Context, d FieldDrift) {
+		drift = d
+		label, _ = pprof.Label(ctx, "zarinpal.tenant")
+	}
+	var warning DeadlineWarning
+	zp.DeadlineHook = func(ctx context.Context, w DeadlineWarning) { warning = w }
+	var riskTenant string
+	zp.RiskHook = func(ctx context.Context, check RiskCheck) (RiskResult, error) {
+		riskTenant = Tenant(ctx)
+		return RiskResult{Decision: RiskAllow}, nil
+	}
+
+	params := PaymentParams{Amount: 10000, Description: "Order", CallbackURL: "https://example.com/callback"}
+	if _, err := zp.CreatePayment(context.Background(), params); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if riskTenant != "shop-1" || label != "shop-1" {
+		t.Errorf("Expected the hooks and pprof labels to carry the tenant, got %q %q", riskTenant, label)
+	}
+	if len(records) != 1 || records[0].Tenant != "shop-1" {
+		t.Errorf("Expected the traffic record labelled, got %+v", records)
+	}
+	if drift.Tenant != "shop-1" {
+		t.Errorf("Expected the drift labelled, got %+v", drift)
+	}
+
+	ctx, cancel := context.WithTimeout(WithTenant(context.Background(), "shop-2"), time.Second)
+	defer cancel()
+	_, err := zp.VerifyPayment(ctx, 10000, "A1")
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Tenant != "shop-2" {
+		t.Errorf("Expected the error labelled with the tenant of the context, got %#v", err)
+	}
+	if strings.Contains(err.Error(), "shop-2") {
+		t.Errorf("Expected the tenant left out of the message, got %s", err)
+	}
+	if warning.Tenant != "shop-2" || records[len(records)-1].Tenant != "shop-2" {
+		t.Errorf("Expected the deadline warning and traffic labelled, got %+v %+v", warning, records[len(records)-1])
+	}
+}
+
+func TestTenantLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(TenantLogHandler(slog.NewTextHandler(&buf, nil))).With("component", "payments")
+
+	logger.InfoContext(WithTenant(context.Background(), "shop-1"), "paid")
+	logger.InfoContext(context.Background(), "paid")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "component=payments tenant=shop-1") {
+		t.Errorf("Expected the tenant logged, got %q", lines)
+	}
+	if strings.Contains(lines[len(lines)-1], "tenant") {
+		t.Errorf("Expected no tenant without one in the context, got %s", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,222 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTerminalLimit is returned for payments the terminal is not configured to accept
+	ErrTerminalLimit = errors.New("payment exceeds the terminal limits")
+	// ErrTerminalNotFound is returned by TerminalLimits when the API doesn't know the terminal
+	ErrTerminalNotFound = errors.New("terminal not found")
+)
+
+// TerminalLimitsCache defaults
+const (
+	DefaultTerminalLimitsTTL      = time.Hour       // how long limits are kept, they rarely change
+	DefaultTerminalLimitsErrorTTL = time.Minute     // how long a failed fetch is kept before trying again
+	DefaultTerminalLimitsTimeout  = 5 * time.Second // of a fetch
+)
+
+// TerminalLimits is what the terminal is configured to accept, zero amounts are unlimited
+type TerminalLimits struct {
+	TerminalID string     `json:"terminal_id"`
+	MaxAmount  int        `json:"max_amount"`  // of a payment, in Rials
+	DailyLimit int        `json:"daily_limit"` // of the payments of a day, in Rials
+	Currencies []Currency `json:"currencies"`  // accepted currencies, any when empty
+	Wages      bool       `json:"wages"`       // whether payments can carry wages
+	FetchedAt  time.Time  `json:"fetched_at"`
+}
+
+// Check returns an ErrTerminalLimit when the terminal can't accept the payment
+func (l TerminalLimits) Check(params PaymentParams) error {
+	currency := params.Currency
+	if currency == "" {
+		currency = CurrencyRial
+	}
+	if len(l.Currencies) > 0 && !containsCurrency(l.Currencies, currency) {
+		return fmt.Errorf("%w: %s isn't accepted", ErrTerminalLimit, currency)
+	}
+	amount := MoneyOf(params.Amount, currency).Rials()
+	if l.MaxAmount > 0 && int(amount) > l.MaxAmount {
+		return fmt.Errorf("%w: %s is over the maximum of %s", ErrTerminalLimit, amount, Rial(l.MaxAmount))
+	}
+	if l.DailyLimit > 0 && int(amount) > l.DailyLimit {
+		return fmt.Errorf("%w: %s is over the daily limit of %s", ErrTerminalLimit, amount, Rial(l.DailyLimit))
+	}
+	if len(params.Wages) > 0 && !l.Wages {
+		return fmt.Errorf("%w: wages aren't enabled", ErrTerminalLimit)
+	}
+	return nil
+}
+
+// RemainingToday returns how much more the terminal accepts today after paidToday Rials, -1
+// without a daily limit
+func (l TerminalLimits) RemainingToday(paidToday int) int {
+	if l.DailyLimit <= 0 {
+		return -1
+	}
+	return max(l.DailyLimit-paidToday, 0)
+}
+
+func containsCurrency(currencies []Currency, currency Currency) bool {
+	for _, c := range currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+const terminalLimitsQuery = `query TerminalLimits($id: ID!) {
+  resource: Terminals(id: $id) {
+    id
+    max_amount
+    daily_limit
+    currencies
+    wages_enabled
+  }
+}`
+
+// TerminalLimits fetches the limits of the terminal
+func (r *Reporting) TerminalLimits(ctx context.Context) (limits TerminalLimits, err error) {
+	var data struct {
+		Resource []struct {
+			ID           string     `json:"id"`
+			MaxAmount    int        `json:"max_amount"`
+			DailyLimit   int        `json:"daily_limit"`
+			Currencies   []Currency `json:"currencies"`
+			WagesEnabled bool       `json:"wages_enabled"`
+		} `json:"resource"`
+	}
+	if err = r.Query(ctx, terminalLimitsQuery, map[string]interface{}{"id": r.TerminalID}, &data); err != nil {
+		return
+	}
+	if len(data.Resource) == 0 {
+		err = fmt.Errorf("%w: %s", ErrTerminalNotFound, r.TerminalID)
+		return
+	}
+	terminal := data.Resource[0]
+	limits = TerminalLimits{
+		TerminalID: terminal.ID,
+		MaxAmount:  terminal.MaxAmount,
+		DailyLimit: terminal.DailyLimit,
+		Currencies: terminal.Currencies,
+		Wages:      terminal.WagesEnabled,
+		FetchedAt:  time.Now(),
+	}
+	return
+}
+
+// TerminalLimitsCache keeps the limits of the terminal for TTL, set it as Zarinpal.Limits to
+// check payments before creating them. It is safe for concurrent use, concurrent Gets share a
+// single fetch and a failed fetch is kept for ErrorTTL so an unavailable API isn't queried by
+// every payment.
+type TerminalLimitsCache struct {
+	TTL      time.Duration // DefaultTerminalLimitsTTL when zero
+	ErrorTTL time.Duration // DefaultTerminalLimitsErrorTTL when zero
+	Timeout  time.Duration // DefaultTerminalLimitsTimeout when zero
+
+	reporting *Reporting
+	mu        sync.Mutex
+	limits    *TerminalLimits
+	err       error
+	failedAt  time.Time
+	fetch     *limitsFetch
+}
+
+// limitsFetch is a fetch of the limits shared by the Gets made while it runs
+type limitsFetch struct {
+	done   chan struct{}
+	limits TerminalLimits
+	err    error
+}
+
+// NewTerminalLimitsCache creates a TerminalLimitsCache fetching limits with the reporting client
+func NewTerminalLimitsCache(reporting *Reporting) *TerminalLimitsCache {
+	return &TerminalLimitsCache{reporting: reporting}
+}
+
+// Get returns the cached limits, fetching them when they are missing or older than TTL
+func (c *TerminalLimitsCache) Get(ctx context.Context) (TerminalLimits, error) {
+	c.mu.Lock()
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultTerminalLimitsTTL
+	}
+	errorTTL := c.ErrorTTL
+	if errorTTL <= 0 {
+		errorTTL = DefaultTerminalLimitsErrorTTL
+	}
+	if c.limits != nil && time.Since(c.limits.FetchedAt) < ttl {
+		limits := *c.limits
+		c.mu.Unlock()
+		return limits, nil
+	}
+	if c.err != nil && time.Since(c.failedAt) < errorTTL {
+		err := c.err
+		c.mu.Unlock()
+		return TerminalLimits{}, err
+	}
+	fetch := c.fetch
+	if fetch == nil {
+		fetch = &limitsFetch{done: make(chan struct{})}
+		c.fetch = fetch
+		// the fetch is shared, the context of the first caller must not cancel it for the others
+		go c.run(context.WithoutCancel(ctx), fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.limits, fetch.err
+	case <-ctx.Done():
+		return TerminalLimits{}, ctx.Err()
+	}
+}
+
+// run fetches the limits and caches the outcome
+func (c *TerminalLimitsCache) run(ctx context.Context, fetch *limitsFetch) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTerminalLimitsTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	fetch.limits, fetch.err = c.reporting.TerminalLimits(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetch == fetch {
+		c.fetch = nil
+		if fetch.err != nil {
+			c.err, c.failedAt = fetch.err, time.Now()
+		} else {
+			c.limits, c.err = &fetch.limits, nil
+		}
+	}
+	close(fetch.done)
+}
+
+// Invalidate drops the cached limits and failure, like after changing the terminal on the panel
+func (c *TerminalLimitsCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limits = nil
+	c.err = nil
+	c.fetch = nil
+}
+
+// checkLimits checks the payment against the limits of the terminal. Payments are let through
+// when the limits can't be fetched, the gateway has the final say.
+func (c *TerminalLimitsCache) checkLimits(ctx context.Context, params PaymentParams) error {
+	limits, err := c.Get(ctx)
+	if err != nil {
+		return nil
+	}
+	return limits.Check(params)
+}
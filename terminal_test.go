@@ -0,0 +1,173 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTerminalLimitsCheck(t *testing.T) {
+	limits := TerminalLimits{MaxAmount: 500_000_000, DailyLimit: 1_000_000_000, Currencies: []Currency{CurrencyRial}}
+	valid := PaymentParams{Amount: 100_000}
+
+	tests := []struct {
+		name   string
+		modify func(p *PaymentParams)
+		err    error
+	}{
+		{"valid", func(p *PaymentParams) {}, nil},
+		{"over the maximum", func(p *PaymentParams) { p.Amount = 500_000_010 }, ErrTerminalLimit},
+		{"Tomans over the maximum", func(p *PaymentParams) { p.Amount, p.Currency = 50_000_001, CurrencyToman }, ErrTerminalLimit},
+		{"wages", func(p *PaymentParams) { p.Wages = []Wage{{Iban: "IR1", Amount: 1000}} }, ErrTerminalLimit},
+	}
+	for _, test := range tests {
+		params := valid
+		test.modify(&params)
+		if err := limits.Check(params); !errors.Is(err, test.err) || (test.err == nil) != (err == nil) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	limits.Currencies = []Currency{CurrencyToman}
+	if err := limits.Check(valid); !errors.Is(err, ErrTerminalLimit) {
+		t.Errorf("Expected Rials refused by a Toman terminal, got %v", err)
+	}
+
+	if remaining := limits.RemainingToday(900_000_000); remaining != 100_000_000 {
+		t.Errorf("Expected 100000000 remaining, got %d", remaining)
+	}
+	if remaining := (TerminalLimits{}).RemainingToday(900_000_000); remaining != -1 {
+		t.Errorf("Expected -1 without a daily limit, got %d", remaining)
+	}
+}
+
+func TestTerminalLimitsCache(t *testing.T) {
+	queries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		w.Write([]byte(`{"data":{"resource":[{"id":"terminal-1","max_amount":1000000,"daily_limit":0,"currencies":["IRR","IRT"],"wages_enabled":false}]}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	cache := NewTerminalLimitsCache(reporting)
+
+	for i := 0; i < 3; i++ {
+		limits, err := cache.Get(context.Background())
+		if err != nil || limits.MaxAmount != 1000000 || limits.Wages || len(limits.Currencies) != 2 {
+			t.Fatalf("Expected the terminal limits, got %+v %v", limits, err)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("Expected the limits fetched once, got %d queries", queries)
+	}
+	cache.Invalidate()
+	cache.Get(context.Background())
+	if queries != 2 {
+		t.Errorf("Expected the limits fetched again after invalidating, got %d queries", queries)
+	}
+
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+	zp.Limits = cache
+	params := PaymentParams{Amount: 2000000, Description: "Order 1", CallbackURL: "https://example.com/callback"}
+	if _, err := zp.CreatePayment(context.Background(), params); !errors.Is(err, ErrTerminalLimit) || !strings.Contains(err.Error(), "maximum") {
+		t.Errorf("Expected ErrTerminalLimit, got %v", err)
+	}
+	params.Amount = 500000
+	if _, err := zp.CreatePayment(context.Background(), params); err != nil {
+		t.Errorf("Expected payments within the limits created, got %v", err)
+	}
+}
+
+func TestTerminalLimitsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"resource":[]}}`))
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	if _, err := reporting.TerminalLimits(context.Background()); !errors.Is(err, ErrTerminalNotFound) {
+		t.Errorf("Expected ErrTerminalNotFound, got %v", err)
+	}
+
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+	zp.Limits = NewTerminalLimitsCache(reporting)
+	params := PaymentParams{Amount: 2000000, Description: "Order 1", CallbackURL: "https://example.com/callback"}
+	if _, err := zp.CreatePayment(context.Background(), params); err != nil {
+		t.Errorf("Expected payments let through without limits, got %v", err)
+	}
+}
+
+func TestTerminalLimitsCacheSharedFetch(t *testing.T) {
+	var queries int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		<-release
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	cache := NewTerminalLimitsCache(reporting)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = cache.Get(context.Background())
+		}(i)
+	}
+	// a caller giving up doesn't wait for the fetch
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := cache.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			t.Errorf("Expected the failed fetch returned to every caller")
+		}
+	}
+	if _, err := cache.Get(context.Background()); err == nil {
+		t.Errorf("Expected the failure cached")
+	}
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Errorf("Expected the limits fetched once, got %d queries", n)
+	}
+}
+
+func TestTerminalLimitsCacheTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	cache := NewTerminalLimitsCache(reporting)
+	cache.Timeout = 10 * time.Millisecond
+	if _, err := cache.Get(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the fetch to time out, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package zarinpalgo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CallbackTokenParam is the callback URL query parameter the signed token is stored in
+const CallbackTokenParam = "zp_token"
+
+var (
+	ErrInvalidCallbackToken = errors.New("invalid callback token")
+	ErrCallbackTokenExpired = errors.New("callback token expired")
+)
+
+// CallbackToken carries the payment details needed on callback, so they don't have to be stored
+type CallbackToken struct {
+	Amount    int
+	OrderID   string
+	ExpiresAt time.Time
+}
+
+type callbackTokenPayload struct {
+	Amount    int    `json:"a"`
+	OrderID   string `json:"o,omitempty"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// TokenSigner signs and validates callback tokens with HMAC-SHA256
+type TokenSigner struct {
+	key []byte
+}
+
+// NewTokenSigner creates a new TokenSigner with the given secret key
+func NewTokenSigner(key []byte) *TokenSigner {
+	return &TokenSigner{key: key}
+}
+
+// Sign encodes and signs the token
+func (s *TokenSigner) Sign(token CallbackToken) (string, error) {
+	payload, err := json.Marshal(callbackTokenPayload{
+		Amount:    token.Amount,
+		OrderID:   token.OrderID,
+		ExpiresAt: token.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// Parse validates the signature and expiry of a signed token and decodes it
+func (s *TokenSigner) Parse(signed string) (token CallbackToken, err error) {
+	encoded, signature, found := strings.Cut(signed, ".")
+	if !found {
+		err = ErrInvalidCallbackToken
+		return
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(encoded)) {
+		err = ErrInvalidCallbackToken
+		return
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		err = ErrInvalidCallbackToken
+		return
+	}
+
+	var decoded callbackTokenPayload
+	if err = json.Unmarshal(payload, &decoded); err != nil {
+		err = ErrInvalidCallbackToken
+		return
+	}
+
+	token = CallbackToken{
+		Amount:    decoded.Amount,
+		OrderID:   decoded.OrderID,
+		ExpiresAt: time.Unix(decoded.ExpiresAt, 0),
+	}
+	if time.Now().After(token.ExpiresAt) {
+		err = ErrCallbackTokenExpired
+	}
+	return
+}
+
+// SignCallbackURL adds the signed token to the callback URL
+func (s *TokenSigner) SignCallbackURL(callbackURL string, token CallbackToken) (string, error) {
+	signed, err := s.Sign(token)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(CallbackTokenParam, signed)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// AmountLookup returns an AmountLookupFunc reading the amount from the signed token of the callback.
+// Invalid and expired tokens are reported as ErrPaymentNotFound.
+func (s *TokenSigner) AmountLookup() AmountLookupFunc {
+	return func(ctx context.Context, callback CallbackData) (int, error) {
+		token, err := s.Parse(callback.Values.Get(CallbackTokenParam))
+		if err != nil {
+			return 0, errors.Join(ErrPaymentNotFound, err)
+		}
+		return token.Amount, nil
+	}
+}
+
+func (s *TokenSigner) mac(message string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
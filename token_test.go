@@ -0,0 +1,64 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTokenSigner(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"))
+	token := CallbackToken{
+		Amount:    25000,
+		OrderID:   "ORDER-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	callbackURL, err := signer.SignCallbackURL("https://example.com/callback?lang=fa", token)
+	if err != nil {
+		t.Fatalf("Failed to sign callback URL: %v", err)
+	}
+
+	u, _ := url.Parse(callbackURL)
+	if u.Query().Get("lang") != "fa" {
+		t.Error("Expected existing query parameters to be kept")
+	}
+
+	parsed, err := signer.Parse(u.Query().Get(CallbackTokenParam))
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if parsed.Amount != token.Amount || parsed.OrderID != token.OrderID || parsed.ExpiresAt.Unix() != token.ExpiresAt.Unix() {
+		t.Errorf("Expected token %+v, got %+v", token, parsed)
+	}
+
+	values := u.Query()
+	values.Set(CallbackAuthorityParam, "A1")
+	values.Set(CallbackStatusParam, "OK")
+	callback, _ := ParseCallbackValues(values)
+	amount, err := signer.AmountLookup()(context.Background(), callback)
+	if err != nil || amount != token.Amount {
+		t.Errorf("Expected amount %d from lookup, got %d (%v)", token.Amount, amount, err)
+	}
+}
+
+func TestTokenSignerRejectsInvalidTokens(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"))
+
+	expired, _ := signer.Sign(CallbackToken{Amount: 1000, ExpiresAt: time.Now().Add(-time.Minute)})
+	if _, err := signer.Parse(expired); err != ErrCallbackTokenExpired {
+		t.Errorf("Expected %v, got %v", ErrCallbackTokenExpired, err)
+	}
+
+	forged, _ := NewTokenSigner([]byte("other")).Sign(CallbackToken{Amount: 1000, ExpiresAt: time.Now().Add(time.Hour)})
+	if _, err := signer.Parse(forged); err != ErrInvalidCallbackToken {
+		t.Errorf("Expected %v, got %v", ErrInvalidCallbackToken, err)
+	}
+
+	_, err := signer.AmountLookup()(context.Background(), CallbackData{Authority: "A1", Values: url.Values{}})
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("Expected %v, got %v", ErrPaymentNotFound, err)
+	}
+}
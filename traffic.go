@@ -0,0 +1,102 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// CorrelationHeader is the header gateway requests carry their correlation ID in
+const CorrelationHeader = "X-Correlation-ID"
+
+// TrafficRecord is a redacted copy of a gateway request and what the gateway answered
+type TrafficRecord struct {
+	CorrelationID string          `json:"correlation_id"`
+	Tenant        string          `json:"tenant,omitempty"`
+	Operation     string          `json:"operation"` // like "request" or "verify"
+	URL           string          `json:"url"`
+	RequestBody   json.RawMessage `json:"request_body"`
+	StatusCode    int             `json:"status_code,omitempty"` // zero when no response arrived
+	ResponseBody  json.RawMessage `json:"response_body,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	SentAt        time.Time       `json:"sent_at"`
+	Duration      time.Duration   `json:"duration"`
+}
+
+// TrafficSink persists traffic records. Failures of the sink don't fail the gateway request,
+// sinks that can't lose records should buffer and retry them.
+type TrafficSink interface {
+	RecordTraffic(ctx context.Context, record TrafficRecord) error
+}
+
+// TrafficSinkFunc is an adapter to use a function as a TrafficSink
+type TrafficSinkFunc func(ctx context.Context, record TrafficRecord) error
+
+// RecordTraffic implements TrafficSink
+func (f TrafficSinkFunc) RecordTraffic(ctx context.Context, record TrafficRecord) error {
+	return f(ctx, record)
+}
+
+// JSONLinesSink is a TrafficSink writing each record as a line of JSON, it is safe for
+// concurrent use
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesSink creates a JSONLinesSink writing to w, like an append-only file
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// RecordTraffic implements TrafficSink
+func (s *JSONLinesSink) RecordTraffic(ctx context.Context, record TrafficRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose gateway requests carry the ID, to tie traffic
+// records to a request of your own. Requests without one get a random ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by the context
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// recordTraffic sends the redacted exchange to the traffic sink
+func (z *Zarinpal) recordTraffic(ctx context.Context, record TrafficRecord, requestBody, responseBody []byte, err error) {
+	record.Duration = time.Since(record.SentAt)
+	policy := DefaultRedactionPolicy
+	if z.Redaction != nil {
+		policy = *z.Redaction
+	}
+	record.RequestBody = policy.RedactJSON(requestBody)
+	if responseBody != nil {
+		record.ResponseBody = policy.RedactJSON(responseBody)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	var recordErr error
+	z.runHook(ctx, "traffic sink", func() {
+		recordErr = z.Traffic.RecordTraffic(context.WithoutCancel(ctx), record)
+	})
+	if recordErr != nil && z.OnTrafficError != nil {
+		z.runHook(ctx, "OnTrafficError", func() { z.OnTrafficError(ctx, recordErr) })
+	}
+}
@@ -0,0 +1,91 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrafficRecording(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(CorrelationHeader)
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	zp := New("4d7e1c8a-0000-0000-0000-00000000abcd")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	zp.Traffic = NewJSONLinesSink(&buf)
+
+	ctx := WithCorrelationID(context.Background(), "order-42")
+	_, err := zp.CreatePayment(ctx, PaymentParams{
+		Amount:      10000,
+		Description: "Order 42",
+		CallbackURL: "https://example.com/callback",
+		Metadata:    &Metadata{Mobile: "09121234567", OrderID: "42"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if header != "order-42" {
+		t.Errorf("Expected the correlation ID to be sent, got %q", header)
+	}
+
+	var record TrafficRecord
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode record %q: %v", buf.String(), err)
+	}
+	if record.CorrelationID != "order-42" || record.Operation != "request" || record.StatusCode != 200 || record.SentAt.IsZero() || record.Error != "" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	request := string(record.RequestBody)
+	if strings.Contains(request, "4d7e1c8a") || !strings.Contains(request, `"merchant_id":"********************************abcd"`) || strings.Contains(request, "09121234567") {
+		t.Errorf("Expected the merchant ID and mobile to be masked, got %s", request)
+	}
+	if !strings.Contains(request, `"order_id":"42"`) || !strings.Contains(string(record.ResponseBody), `"authority":"A1"`) {
+		t.Errorf("Expected the rest to be kept, got %s %s", request, record.ResponseBody)
+	}
+}
+
+func TestTrafficRecordingFailure(t *testing.T) {
+	var records []TrafficRecord
+	zp := New("merchant-1")
+	zp.APIBaseURL = "http://127.0.0.1:1/"
+	zp.Traffic = TrafficSinkFunc(func(ctx context.Context, record TrafficRecord) error {
+		records = append(records, record)
+		return nil
+	})
+
+	if _, err := zp.InquirePayment(context.Background(), "A1"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if len(records) != 1 || records[0].Error == "" || records[0].StatusCode != 0 || records[0].CorrelationID == "" || records[0].ResponseBody != nil {
+		t.Errorf("Expected a record of the failure with a random correlation ID, got %+v", records)
+	}
+}
+
+func TestTrafficSinkError(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"request.json": `{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`,
+	})
+	sinkErr := errors.New("disk full")
+	zp.Traffic = TrafficSinkFunc(func(ctx context.Context, record TrafficRecord) error {
+		return sinkErr
+	})
+	var reported []error
+	zp.OnTrafficError = func(ctx context.Context, err error) { reported = append(reported, err) }
+
+	if _, err := zp.CreatePayment(context.Background(), PaymentParams{Amount: 10000, Description: "Order 42", CallbackURL: "https://example.com/callback"}); err != nil {
+		t.Fatalf("Expected the payment created despite the sink, got %v", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], sinkErr) {
+		t.Errorf("Expected the sink error reported, got %v", reported)
+	}
+}
@@ -0,0 +1,200 @@
+package zarinpalgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types
+const (
+	EventPaymentVerified = "payment.verified"
+	EventPaymentFailed   = "payment.failed"
+	EventPaymentExpired  = "payment.expired"
+	EventPaymentReversed = "payment.reversed"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Zarinpal-Signature"
+	WebhookTimestampHeader = "X-Zarinpal-Timestamp"
+	WebhookEventIDHeader   = "X-Zarinpal-Event-Id"
+)
+
+// WebhookEvent is the JSON body posted by WebhookRelay
+type WebhookEvent struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	CreatedAt time.Time     `json:"created_at"`
+	Payment   PaymentStatus `json:"payment"`
+}
+
+// WebhookRelay posts signed payment events to merchant-configured URLs, retrying failed
+// deliveries with exponential backoff
+type WebhookRelay struct {
+	URLs           []string
+	Secret         []byte
+	Client         *http.Client
+	MaxAttempts    int                         // defaults to 5
+	InitialBackoff time.Duration               // defaults to one second, doubled after every failed attempt
+	OnError        func(url string, err error) // called when a delivery is given up
+	// Redaction masks the fields of the events before they are posted, NewWebhookRelay sets it
+	// to DefaultRedactionPolicy. Set it to nil to post the events unmasked.
+	Redaction *RedactionPolicy
+}
+
+// NewWebhookRelay creates a new WebhookRelay signing events with the secret and masking them
+// with DefaultRedactionPolicy
+func NewWebhookRelay(secret []byte, urls ...string) *WebhookRelay {
+	policy := DefaultRedactionPolicy
+	return &WebhookRelay{
+		URLs:   urls,
+		Secret: secret,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Redaction: &policy,
+	}
+}
+
+// NewWebhookEvent creates an event for the payment status
+func NewWebhookEvent(status PaymentStatus) WebhookEvent {
+	eventType := EventPaymentFailed
+	switch {
+	case status.IsSuccessful:
+		eventType = EventPaymentVerified
+	case status.Reversed:
+		eventType = EventPaymentReversed
+	}
+	return WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Payment:   status,
+	}
+}
+
+// Notify sends an event for the payment status to every URL
+func (r *WebhookRelay) Notify(ctx context.Context, status PaymentStatus) error {
+	return r.Send(ctx, NewWebhookEvent(status))
+}
+
+// Send delivers the event to every URL and returns the errors of the deliveries that were given up
+func (r *WebhookRelay) Send(ctx context.Context, event WebhookEvent) error {
+	var body []byte
+	var err error
+	if r.Redaction != nil {
+		body, err = r.Redaction.Redact(event)
+	} else {
+		body, err = json.Marshal(event)
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, url := range r.URLs {
+		if err := r.deliver(ctx, url, event.ID, body); err != nil {
+			if r.OnError != nil {
+				RunHook("webhook relay OnError", func() { r.OnError(url, err) })
+			}
+			errs = append(errs, fmt.Errorf("webhook %s: %w", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *WebhookRelay) deliver(ctx context.Context, url, eventID string, body []byte) (err error) {
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	backoff := r.InitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err = r.post(ctx, url, eventID, body); err == nil || attempt == maxAttempts {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *WebhookRelay) post(ctx context.Context, url, eventID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventIDHeader, eventID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(r.Secret, timestamp, body))
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook returns the signature header value for a webhook body sent at timestamp
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// DefaultWebhookTolerance is how far the timestamp of a webhook accepted by VerifyWebhook may be
+// from the time of the receiver
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// VerifyWebhook checks the signature of a received webhook body and that it was sent within
+// DefaultWebhookTolerance
+func VerifyWebhook(secret []byte, timestamp string, body []byte, signature string) bool {
+	return VerifyWebhookWithin(secret, timestamp, body, signature, DefaultWebhookTolerance)
+}
+
+// VerifyWebhookWithin checks the signature of a received webhook body and that its timestamp is
+// within tolerance of now, so a captured webhook can't be replayed later. Relays sign every
+// attempt anew, retries don't age.
+func VerifyWebhookWithin(secret []byte, timestamp string, body []byte, signature string, tolerance time.Duration) bool {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,124 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookRelay(t *testing.T) {
+	secret := []byte("secret")
+	var attempts int32
+	var received WebhookEvent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if !VerifyWebhook(secret, r.Header.Get(WebhookTimestampHeader), body, r.Header.Get(WebhookSignatureHeader)) {
+			t.Error("Expected a valid webhook signature")
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	relay := NewWebhookRelay(secret, server.URL)
+	relay.InitialBackoff = time.Millisecond
+
+	err := relay.Notify(context.Background(), PaymentStatus{Authority: "A1", IsSuccessful: true, RefID: 201})
+	if err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if received.Type != EventPaymentVerified || received.Payment.RefID != 201 {
+		t.Errorf("Unexpected event %+v", received)
+	}
+}
+
+func TestVerifyWebhookTolerance(t *testing.T) {
+	secret, body := []byte("secret"), []byte(`{"id":"E1"}`)
+	sign := func(sentAt time.Time) (string, string) {
+		timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+		return timestamp, SignWebhook(secret, timestamp, body)
+	}
+
+	if timestamp, signature := sign(time.Now().Add(-time.Minute)); !VerifyWebhook(secret, timestamp, body, signature) {
+		t.Error("Expected a webhook sent a minute ago to be valid")
+	}
+	if timestamp, signature := sign(time.Now().Add(-time.Hour)); VerifyWebhook(secret, timestamp, body, signature) {
+		t.Error("Expected a replayed webhook to be rejected")
+	}
+	if timestamp, signature := sign(time.Now().Add(time.Hour)); VerifyWebhook(secret, timestamp, body, signature) {
+		t.Error("Expected a webhook from the future to be rejected")
+	}
+	if timestamp, signature := sign(time.Now().Add(-time.Hour)); !VerifyWebhookWithin(secret, timestamp, body, signature, 2*time.Hour) {
+		t.Error("Expected a webhook within the tolerance to be valid")
+	}
+	if VerifyWebhook(secret, "yesterday", body, SignWebhook(secret, "yesterday", body)) {
+		t.Error("Expected a malformed timestamp to be rejected")
+	}
+}
+
+func TestWebhookRelayRedaction(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	relay := NewWebhookRelay([]byte("secret"), server.URL)
+	status := PaymentStatus{Authority: "A1", IsSuccessful: true, RefID: 201, Amount: 10000, CardHash: "0123456789abcdef"}
+	if err := relay.Notify(context.Background(), status); err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+	var received WebhookEvent
+	json.Unmarshal(body, &received)
+	if received.Payment.Amount != 10000 || received.Payment.CardHash != "************cdef" {
+		t.Errorf("Expected the card hash masked by default, got %+v", received.Payment)
+	}
+
+	policy := StrictRedactionPolicy()
+	relay.Redaction = &policy
+	if err := relay.Notify(context.Background(), status); err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+	received = WebhookEvent{}
+	json.Unmarshal(body, &received)
+	if received.Payment.Authority != "A1" || received.Payment.RefID != 201 || received.Payment.Amount != 0 || received.Payment.CardHash != "************cdef" {
+		t.Errorf("Expected the amount and card hash to be masked, got %+v", received.Payment)
+	}
+}
+
+func TestWebhookRelayGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var failedURL string
+	relay := NewWebhookRelay([]byte("secret"), server.URL)
+	relay.MaxAttempts = 2
+	relay.InitialBackoff = time.Millisecond
+	relay.OnError = func(url string, err error) {
+		failedURL = url
+	}
+
+	if err := relay.Notify(context.Background(), PaymentStatus{Authority: "A1"}); err == nil {
+		t.Error("Expected delivery error")
+	}
+	if failedURL != server.URL {
+		t.Errorf("Expected error callback for %s, got %q", server.URL, failedURL)
+	}
+}
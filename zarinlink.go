@@ -0,0 +1,195 @@
+package zarinpalgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrZarinLinkNotFound is returned by GetZarinLink for unknown links
+	ErrZarinLinkNotFound = errors.New("zarinlink not found")
+	// ErrInvalidZarinLink is returned for links without a title or with a negative amount
+	ErrInvalidZarinLink = errors.New("invalid zarinlink")
+)
+
+// ZarinLinkStatus is whether a link accepts payments
+type ZarinLinkStatus string
+
+// ZarinLinkStatus constants
+const (
+	ZarinLinkActive   ZarinLinkStatus = "ACTIVE"
+	ZarinLinkInactive ZarinLinkStatus = "INACTIVE"
+)
+
+// ZarinLink is a reusable payment link of the terminal, shareable without creating a payment
+// for every payer
+type ZarinLink struct {
+	ID          string          `json:"id"`
+	TerminalID  string          `json:"terminal_id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Amount      int             `json:"amount"` // in Rials, 0 lets the payer enter the amount
+	URL         string          `json:"url"`
+	Status      ZarinLinkStatus `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// ZarinLinkParams describes a new link
+type ZarinLinkParams struct {
+	Title       string
+	Description string
+	Amount      int // in Rials, 0 lets the payer enter the amount
+}
+
+// ZarinLinkUpdate changes a link, zero fields are left as they are
+type ZarinLinkUpdate struct {
+	Title  string
+	Amount int
+}
+
+const zarinLinkFields = `
+    id
+    terminal_id
+    title
+    description
+    amount
+    url
+    status
+    created_at`
+
+const addZarinLinkMutation = `mutation ZarinLinkAdd($terminal_id: ID!, $title: String!, $description: String, $amount: BigInteger) {
+  resource: ZarinLinkAdd(terminal_id: $terminal_id, title: $title, description: $description, amount: $amount) {` + zarinLinkFields + `
+  }
+}`
+
+const editZarinLinkMutation = `mutation ZarinLinkEdit($id: ID!, $title: String, $amount: BigInteger, $status: String) {
+  resource: ZarinLinkEdit(id: $id, title: $title, amount: $amount, status: $status) {` + zarinLinkFields + `
+  }
+}`
+
+const zarinLinksQuery = `query ZarinLinks($terminal_id: ID!, $id: ID, $limit: Int, $offset: Int) {
+  resource: ZarinLinks(terminal_id: $terminal_id, id: $id, limit: $limit, offset: $offset) {` + zarinLinkFields + `
+  }
+}`
+
+// CreateZarinLink creates a link on the terminal
+func (r *Reporting) CreateZarinLink(ctx context.Context, params ZarinLinkParams) (link ZarinLink, err error) {
+	if params.Title == "" {
+		err = fmt.Errorf("%w: it needs a title", ErrInvalidZarinLink)
+		return
+	}
+	if err = checkZarinLinkAmount(params.Amount); err != nil {
+		return
+	}
+
+	var data struct {
+		Resource ZarinLink `json:"resource"`
+	}
+	err = r.Query(ctx, addZarinLinkMutation, map[string]interface{}{
+		"terminal_id": r.TerminalID,
+		"title":       params.Title,
+		"description": params.Description,
+		"amount":      params.Amount,
+	}, &data)
+	return data.Resource, err
+}
+
+// ZarinLinks returns the links of the terminal, newest first
+func (r *Reporting) ZarinLinks(ctx context.Context) ([]ZarinLink, error) {
+	return r.zarinLinks(ctx, "")
+}
+
+// GetZarinLink returns the link of the ID, or ErrZarinLinkNotFound
+func (r *Reporting) GetZarinLink(ctx context.Context, id string) (ZarinLink, error) {
+	links, err := r.zarinLinks(ctx, id)
+	if err != nil {
+		return ZarinLink{}, err
+	}
+	if len(links) == 0 {
+		return ZarinLink{}, fmt.Errorf("%w: %s", ErrZarinLinkNotFound, id)
+	}
+	return links[0], nil
+}
+
+func (r *Reporting) zarinLinks(ctx context.Context, id string) (links []ZarinLink, err error) {
+	variables := map[string]interface{}{
+		"terminal_id": r.TerminalID,
+		"limit":       reportingPageSize,
+	}
+	if id != "" {
+		variables["id"] = id
+	}
+
+	maxPages := r.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+	for page := 0; ; page++ {
+		if page == maxPages {
+			return nil, fmt.Errorf("%w: stopped after %d pages", ErrTooManyPages, maxPages)
+		}
+		variables["offset"] = page * reportingPageSize
+
+		var data struct {
+			Resource []ZarinLink `json:"resource"`
+		}
+		if err = r.Query(ctx, zarinLinksQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		links = append(links, data.Resource...)
+		if len(data.Resource) < reportingPageSize {
+			return
+		}
+	}
+}
+
+// UpdateZarinLink changes the title or amount of the link
+func (r *Reporting) UpdateZarinLink(ctx context.Context, id string, update ZarinLinkUpdate) (link ZarinLink, err error) {
+	if err = checkZarinLinkAmount(update.Amount); err != nil {
+		return
+	}
+	variables := map[string]interface{}{"id": id}
+	if update.Title != "" {
+		variables["title"] = update.Title
+	}
+	if update.Amount > 0 {
+		variables["amount"] = update.Amount
+	}
+	return r.editZarinLink(ctx, variables)
+}
+
+// DisableZarinLink stops the link from accepting payments, its history is kept
+func (r *Reporting) DisableZarinLink(ctx context.Context, id string) (ZarinLink, error) {
+	return r.editZarinLink(ctx, map[string]interface{}{"id": id, "status": ZarinLinkInactive})
+}
+
+// EnableZarinLink lets a disabled link accept payments again
+func (r *Reporting) EnableZarinLink(ctx context.Context, id string) (ZarinLink, error) {
+	return r.editZarinLink(ctx, map[string]interface{}{"id": id, "status": ZarinLinkActive})
+}
+
+func (r *Reporting) editZarinLink(ctx context.Context, variables map[string]interface{}) (link ZarinLink, err error) {
+	var data struct {
+		Resource ZarinLink `json:"resource"`
+	}
+	err = r.Query(ctx, editZarinLinkMutation, variables, &data)
+	return data.Resource, err
+}
+
+// ZarinLinkPayments returns the transactions paid through the link in [from, to), newest first
+func (r *Reporting) ZarinLinkPayments(ctx context.Context, id string, from, to time.Time) ([]Transaction, error) {
+	return r.FilterTransactions(ctx, TransactionFilter{From: from, To: to, ZarinLinkID: id})
+}
+
+// checkZarinLinkAmount checks fixed amounts against the minimum in Rials, 0 is left to the payer
+func checkZarinLinkAmount(amount int) error {
+	if amount < 0 {
+		return fmt.Errorf("%w: negative amount %d", ErrInvalidZarinLink, amount)
+	}
+	if amount == 0 {
+		return nil
+	}
+	return checkMinAmount(amount, CurrencyRial, nil)
+}
@@ -0,0 +1,121 @@
+package zarinpalgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newZarinLinkServer serves the ZarinLink queries from an in-memory set of links
+func newZarinLinkServer(t *testing.T) (*Reporting, map[string]*ZarinLink) {
+	t.Helper()
+
+	links := map[string]*ZarinLink{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		v := body.Variables
+
+		var resource interface{}
+		switch {
+		case strings.Contains(body.Query, "mutation ZarinLinkAdd"):
+			link := &ZarinLink{ID: "L1", TerminalID: v["terminal_id"].(string), Title: v["title"].(string),
+				Amount: int(v["amount"].(float64)), URL: "https://zarinp.al/L1", Status: ZarinLinkActive}
+			links[link.ID] = link
+			resource = link
+		case strings.Contains(body.Query, "mutation ZarinLinkEdit"):
+			link, ok := links[v["id"].(string)]
+			if !ok {
+				w.Write([]byte(`{"errors":[{"message":"not found"}]}`))
+				return
+			}
+			if title, ok := v["title"].(string); ok {
+				link.Title = title
+			}
+			if amount, ok := v["amount"].(float64); ok {
+				link.Amount = int(amount)
+			}
+			if status, ok := v["status"].(string); ok {
+				link.Status = ZarinLinkStatus(status)
+			}
+			resource = link
+		case strings.Contains(body.Query, "query ZarinLinks"):
+			list := []*ZarinLink{}
+			for id, link := range links {
+				if v["id"] == nil || v["id"] == id {
+					list = append(list, link)
+				}
+			}
+			resource = list
+		case strings.Contains(body.Query, "zarin_link_id: $zarin_link_id"):
+			if v["zarin_link_id"] != "L1" {
+				t.Errorf("Expected the link ID sent, got %v", v["zarin_link_id"])
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"Session": []Transaction{{ID: "S1", Amount: 50000, Status: InquiryStatusVerified, CreatedAt: time.Now()}},
+			}})
+			return
+		default:
+			t.Errorf("Unexpected query %s", body.Query)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"resource": resource}})
+	}))
+	t.Cleanup(server.Close)
+
+	reporting := NewReporting("token", "terminal-1")
+	reporting.URL = server.URL
+	return reporting, links
+}
+
+func TestZarinLinks(t *testing.T) {
+	reporting, links := newZarinLinkServer(t)
+	ctx := context.Background()
+
+	if _, err := reporting.CreateZarinLink(ctx, ZarinLinkParams{Amount: 50000}); !errors.Is(err, ErrInvalidZarinLink) {
+		t.Errorf("Expected ErrInvalidZarinLink without a title, got %v", err)
+	}
+	if _, err := reporting.CreateZarinLink(ctx, ZarinLinkParams{Title: "Course", Amount: 10}); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount below the minimum, got %v", err)
+	}
+
+	link, err := reporting.CreateZarinLink(ctx, ZarinLinkParams{Title: "Course", Amount: 50000})
+	if err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	if link.ID != "L1" || link.TerminalID != "terminal-1" || link.URL == "" {
+		t.Errorf("Unexpected link %+v", link)
+	}
+
+	link, err = reporting.UpdateZarinLink(ctx, "L1", ZarinLinkUpdate{Amount: 60000})
+	if err != nil || link.Title != "Course" || link.Amount != 60000 {
+		t.Errorf("Expected the amount changed and the title kept, got %+v %v", link, err)
+	}
+
+	if link, err = reporting.DisableZarinLink(ctx, "L1"); err != nil || link.Status != ZarinLinkInactive {
+		t.Errorf("Expected the link disabled, got %+v %v", link, err)
+	}
+	if links["L1"].Status != ZarinLinkInactive {
+		t.Errorf("Expected the status sent, got %s", links["L1"].Status)
+	}
+
+	all, err := reporting.ZarinLinks(ctx)
+	if err != nil || len(all) != 1 {
+		t.Errorf("Expected one link, got %+v %v", all, err)
+	}
+	if _, err := reporting.GetZarinLink(ctx, "L2"); !errors.Is(err, ErrZarinLinkNotFound) {
+		t.Errorf("Expected ErrZarinLinkNotFound, got %v", err)
+	}
+
+	payments, err := reporting.ZarinLinkPayments(ctx, "L1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil || len(payments) != 1 || payments[0].ID != "S1" {
+		t.Errorf("Expected the payment of the link, got %+v %v", payments, err)
+	}
+}
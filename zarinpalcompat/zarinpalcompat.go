@@ -0,0 +1,134 @@
+// Package zarinpalcompat mimics the API of the older go-zarinpal-checkout client on top of
+// zarinpalgo, so code written against it can move over one call site at a time. Methods return
+// the gateway status code next to the error like the old client did; new code should use
+// zarinpalgo directly.
+package zarinpalcompat
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// ErrInvalidMerchantID is returned by NewZarinpal for merchant IDs that are not 36 characters
+var ErrInvalidMerchantID = errors.New("MerchantID must be 36 characters")
+
+// Zarinpal is a client with the method signatures of go-zarinpal-checkout
+type Zarinpal struct {
+	MerchantID string
+	Sandbox    bool
+	// APIEndpoint and PaymentEndpoint start as the base URLs of Client, setting them points
+	// Client at other base URLs from the next call on
+	APIEndpoint     string
+	PaymentEndpoint string
+
+	// Client makes the requests, configure its transport or endpoints here
+	Client *zarinpalgo.Zarinpal
+
+	// the endpoints NewZarinpal started with, so replacing Client keeps its own ones
+	defaultAPIEndpoint, defaultPaymentEndpoint string
+}
+
+// UnverifiedAuthority is a paid payment that was not verified
+type UnverifiedAuthority struct {
+	Authority   string
+	Amount      int
+	Channel     string
+	CallbackURL string
+	Referer     string
+	Email       string
+	CellPhone   string
+	Date        string
+}
+
+// NewZarinpal creates a new client, the merchant ID must be 36 characters
+func NewZarinpal(merchantID string, sandbox bool) (*Zarinpal, error) {
+	if len(merchantID) != 36 {
+		return nil, ErrInvalidMerchantID
+	}
+
+	client := zarinpalgo.NewWithMode(merchantID, sandbox)
+	return &Zarinpal{
+		MerchantID:             merchantID,
+		Sandbox:                sandbox,
+		APIEndpoint:            client.APIBaseURL,
+		PaymentEndpoint:        client.PaymentBaseURL,
+		Client:                 client,
+		defaultAPIEndpoint:     client.APIBaseURL,
+		defaultPaymentEndpoint: client.PaymentBaseURL,
+	}, nil
+}
+
+// client returns Client with the endpoints set on the Zarinpal applied
+func (z *Zarinpal) client() *zarinpalgo.Zarinpal {
+	if z.APIEndpoint != "" && z.APIEndpoint != z.defaultAPIEndpoint {
+		z.Client.APIBaseURL = z.APIEndpoint
+	}
+	if z.PaymentEndpoint != "" && z.PaymentEndpoint != z.defaultPaymentEndpoint {
+		z.Client.PaymentBaseURL = z.PaymentEndpoint
+	}
+	return z.Client
+}
+
+// NewPaymentRequest creates a payment and returns the URL to redirect the user to
+func (z *Zarinpal) NewPaymentRequest(amount int, callbackURL, description, email, mobile string) (paymentURL, authority string, statusCode int, err error) {
+	var metadata *zarinpalgo.Metadata
+	if email != "" || mobile != "" {
+		metadata = &zarinpalgo.Metadata{Email: email, Mobile: mobile}
+	}
+
+	client := z.client()
+	payment, err := client.NewPayment(context.Background(), amount, description, metadata, callbackURL, nil)
+	if err != nil {
+		statusCode = codeOf(err)
+		return
+	}
+	return client.GetPaymentURL(payment.Authority), payment.Authority, payment.Code, nil
+}
+
+// PaymentVerification verifies a payment, verified is also set for payments verified before
+// which report status code 101
+func (z *Zarinpal) PaymentVerification(amount int, authority string) (verified bool, refID string, statusCode int, err error) {
+	verification, err := z.client().VerifyPayment(context.Background(), amount, authority)
+	if err != nil {
+		statusCode = codeOf(err)
+		return
+	}
+
+	statusCode = verification.Code
+	verified = statusCode == zarinpalgo.PaymentCodeSuccess || statusCode == zarinpalgo.PaymentCodeAlreadyVerified
+	refID = strconv.Itoa(verification.RefID)
+	return
+}
+
+// UnverifiedTransactions lists the paid payments that were not verified
+func (z *Zarinpal) UnverifiedTransactions() (authorities []UnverifiedAuthority, statusCode int, err error) {
+	unverified, err := z.client().UnverifiedPayments(context.Background())
+	if err != nil {
+		statusCode = codeOf(err)
+		return
+	}
+
+	statusCode = unverified.Code
+	for _, payment := range unverified.Authorities {
+		authorities = append(authorities, UnverifiedAuthority{
+			Authority:   payment.Authority,
+			Amount:      payment.Amount,
+			CallbackURL: payment.CallbackURL,
+			Referer:     payment.Referer,
+			Date:        payment.Date,
+		})
+	}
+	return
+}
+
+// codeOf returns the gateway status code of err, 0 for transport errors
+func codeOf(err error) int {
+	var apiErr *zarinpalgo.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package zarinpalcompat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+)
+
+const merchantID = "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+
+func TestNewZarinpal(t *testing.T) {
+	if _, err := NewZarinpal("merchant-1", false); !errors.Is(err, ErrInvalidMerchantID) {
+		t.Errorf("Expected ErrInvalidMerchantID, got %v", err)
+	}
+
+	zp, err := NewZarinpal(merchantID, true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if zp.APIEndpoint != "https://sandbox.zarinpal.com/pg/v4/payment/" {
+		t.Errorf("Expected the sandbox endpoint, got %s", zp.APIEndpoint)
+	}
+}
+
+func TestPaymentFlow(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	zp, _ := NewZarinpal(merchantID, false)
+	zp.Client = sim.Client(merchantID)
+
+	paymentURL, authority, statusCode, err := zp.NewPaymentRequest(10000, "https://example.com/callback", "Test", "user@example.com", "09120000000")
+	if err != nil || statusCode != 100 || paymentURL != sim.URL+"/pg/StartPay/"+authority {
+		t.Fatalf("Expected payment request to succeed, got %s %d %v", paymentURL, statusCode, err)
+	}
+
+	verified, _, statusCode, err := zp.PaymentVerification(10000, authority)
+	if verified || statusCode != zarinpalgotest.CodeNotPaid || err == nil {
+		t.Errorf("Expected unpaid payment to fail with %d, got %v %d %v", zarinpalgotest.CodeNotPaid, verified, statusCode, err)
+	}
+
+	sim.Pay(authority)
+	authorities, statusCode, err := zp.UnverifiedTransactions()
+	if err != nil || statusCode != 100 || len(authorities) != 1 || authorities[0].Authority != authority {
+		t.Errorf("Expected the payment to be unverified, got %+v %d %v", authorities, statusCode, err)
+	}
+
+	verified, refID, statusCode, err := zp.PaymentVerification(10000, authority)
+	if !verified || refID == "" || statusCode != 100 || err != nil {
+		t.Errorf("Expected verification to succeed, got %v %s %d %v", verified, refID, statusCode, err)
+	}
+
+	verified, _, statusCode, _ = zp.PaymentVerification(10000, authority)
+	if !verified || statusCode != 101 {
+		t.Errorf("Expected a repeated verification with 101, got %v %d", verified, statusCode)
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	zp, _ := NewZarinpal(merchantID, false)
+	zp.APIEndpoint = sim.URL + "/pg/v4/payment/"
+	zp.PaymentEndpoint = "https://pay.example.com/StartPay/"
+
+	paymentURL, authority, statusCode, err := zp.NewPaymentRequest(10000, "https://example.com/callback", "Test", "", "")
+	if err != nil || statusCode != 100 || paymentURL != "https://pay.example.com/StartPay/"+authority {
+		t.Errorf("Expected the payment created through the endpoints, got %s %d %v", paymentURL, statusCode, err)
+	}
+}
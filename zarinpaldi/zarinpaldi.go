@@ -0,0 +1,156 @@
+// Package zarinpaldi builds the payment subsystem from a Config for the google/wire and uber/fx
+// dependency injection frameworks. The constructors are plain functions, so they also work when
+// wiring by hand.
+//
+// With wire, add ProviderSet and a PaymentStore to the injector:
+//
+//	wire.Build(zarinpaldi.ProviderSet, zarinpaldi.MemoryStoreSet, newServer)
+//
+// With fx, add Module, a PaymentStore and the Config:
+//
+//	fx.New(zarinpaldi.Module, zarinpaldi.MemoryStoreModule, zarinpaldi.RunReconciler, fx.Supply(cfg), ...)
+package zarinpaldi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/google/wire"
+	"go.uber.org/fx"
+)
+
+// ErrMissingMerchantID is returned by NewClient when the config has no merchant ID
+var ErrMissingMerchantID = errors.New("zarinpaldi: missing merchant ID")
+
+// Config configures the payment subsystem
+type Config struct {
+	MerchantID string
+	Sandbox    bool
+	// Tenant labels the requests of the client, see zarinpalgo.WithTenant
+	Tenant string
+
+	// APIBaseURL and PaymentBaseURL override the gateway endpoints, like for a simulator
+	APIBaseURL     string
+	PaymentBaseURL string
+	// Endpoints routes operations elsewhere, see zarinpalgo.EndpointOverrides
+	Endpoints zarinpalgo.EndpointOverrides
+	// Timeout limits gateway requests, no limit when zero
+	Timeout time.Duration
+
+	// ReconcileInterval and ReconcileMinAge default to the ones of zarinpalgo.NewReconciler
+	ReconcileInterval time.Duration
+	ReconcileMinAge   time.Duration
+}
+
+// CallbackHandler serves the callback URL, verifying payments and recording their outcome in
+// the store
+type CallbackHandler http.Handler
+
+// RedirectHandler redirects users to the payment page of the authority in their path
+type RedirectHandler http.Handler
+
+// NewClient creates the client described by the config
+func NewClient(cfg Config) (*zarinpalgo.Zarinpal, error) {
+	if cfg.MerchantID == "" {
+		return nil, ErrMissingMerchantID
+	}
+
+	z := zarinpalgo.NewWithMode(cfg.MerchantID, cfg.Sandbox)
+	if cfg.APIBaseURL != "" {
+		z.APIBaseURL = cfg.APIBaseURL
+	}
+	if cfg.PaymentBaseURL != "" {
+		z.PaymentBaseURL = cfg.PaymentBaseURL
+	}
+	z.Endpoints = cfg.Endpoints
+	z.Tenant = cfg.Tenant
+	if cfg.Timeout > 0 {
+		z.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	}
+	return z, nil
+}
+
+// NewReconciler creates a reconciler of the payments in the store
+func NewReconciler(cfg Config, client zarinpalgo.Client, store zarinpalgo.PaymentStore) *zarinpalgo.Reconciler {
+	r := zarinpalgo.NewReconciler(client, store)
+	if cfg.ReconcileInterval > 0 {
+		r.Interval = cfg.ReconcileInterval
+	}
+	if cfg.ReconcileMinAge > 0 {
+		r.MinAge = cfg.ReconcileMinAge
+	}
+	return r
+}
+
+// NewCallbackHandler creates the callback handler, reading expected amounts from the store
+func NewCallbackHandler(z *zarinpalgo.Zarinpal, store zarinpalgo.PaymentStore) CallbackHandler {
+	return z.CallbackHandler(zarinpalgo.StoreAmountLookup(store), nil, zarinpalgo.WithPaymentStore(store))
+}
+
+// NewRedirectHandler creates the redirect handler
+func NewRedirectHandler(z *zarinpalgo.Zarinpal) RedirectHandler {
+	return z.RedirectHandler()
+}
+
+// ProviderSet provides the client, as *zarinpalgo.Zarinpal and zarinpalgo.Client, the
+// reconciler and the handlers. The injector provides the Config and a zarinpalgo.PaymentStore.
+var ProviderSet = wire.NewSet(
+	NewClient,
+	wire.Bind(new(zarinpalgo.Client), new(*zarinpalgo.Zarinpal)),
+	NewReconciler,
+	NewCallbackHandler,
+	NewRedirectHandler,
+)
+
+// MemoryStoreSet provides a zarinpalgo.MemoryPaymentStore as zarinpalgo.PaymentStore
+var MemoryStoreSet = wire.NewSet(
+	zarinpalgo.NewMemoryPaymentStore,
+	wire.Bind(new(zarinpalgo.PaymentStore), new(*zarinpalgo.MemoryPaymentStore)),
+)
+
+// Module is the fx counterpart of ProviderSet
+var Module = fx.Module("zarinpal",
+	fx.Provide(
+		NewClient,
+		func(z *zarinpalgo.Zarinpal) zarinpalgo.Client { return z },
+		NewReconciler,
+		NewCallbackHandler,
+		NewRedirectHandler,
+	),
+)
+
+// MemoryStoreModule is the fx counterpart of MemoryStoreSet
+var MemoryStoreModule = fx.Provide(
+	fx.Annotate(zarinpalgo.NewMemoryPaymentStore, fx.As(new(zarinpalgo.PaymentStore))),
+)
+
+// RunReconciler runs the reconciler in the background from the start of the fx application
+// to its stop
+var RunReconciler = fx.Invoke(runReconciler)
+
+func runReconciler(lc fx.Lifecycle, r *zarinpalgo.Reconciler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				r.Run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
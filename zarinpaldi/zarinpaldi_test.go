@@ -0,0 +1,97 @@
+package zarinpaldi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+	"go.uber.org/fx"
+)
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(Config{}); !errors.Is(err, ErrMissingMerchantID) {
+		t.Errorf("Expected ErrMissingMerchantID, got %v", err)
+	}
+
+	z, err := NewClient(Config{
+		MerchantID: "merchant-1",
+		Sandbox:    true,
+		Tenant:     "shop-1",
+		Timeout:    5 * time.Second,
+		Endpoints:  zarinpalgo.EndpointOverrides{zarinpalgo.EndpointVerify: "/psp/verify"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if z.APIBaseURL != "https://sandbox.zarinpal.com/pg/v4/payment/" || z.HTTPClient.Timeout != 5*time.Second {
+		t.Errorf("Expected a sandbox client with a timeout, got %s %v", z.APIBaseURL, z.HTTPClient)
+	}
+	if z.Endpoints[zarinpalgo.EndpointVerify] != "/psp/verify" {
+		t.Errorf("Expected the verify override, got %v", z.Endpoints)
+	}
+	if z.Tenant != "shop-1" {
+		t.Errorf("Expected tenant shop-1, got %q", z.Tenant)
+	}
+}
+
+func TestModule(t *testing.T) {
+	sim := zarinpalgotest.NewSimulator()
+	defer sim.Close()
+
+	var (
+		client     zarinpalgo.Client
+		store      zarinpalgo.PaymentStore
+		reconciler *zarinpalgo.Reconciler
+		callback   CallbackHandler
+	)
+	app := fx.New(
+		Module,
+		MemoryStoreModule,
+		RunReconciler,
+		fx.Supply(Config{
+			MerchantID:        "merchant-1",
+			APIBaseURL:        sim.URL + "/pg/v4/payment/",
+			PaymentBaseURL:    sim.URL + "/pg/StartPay/",
+			ReconcileInterval: time.Hour,
+		}),
+		fx.Populate(&client, &store, &reconciler, &callback),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("Failed to build the application: %v", err)
+	}
+	if reconciler.Interval != time.Hour || reconciler.Store != store {
+		t.Errorf("Expected the configured reconciler of the store, got %+v", reconciler)
+	}
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer app.Stop(ctx)
+
+	payment, err := client.NewPayment(ctx, 10000, "Test", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	store.SaveSession(ctx, zarinpalgo.PaymentSession{Authority: payment.Authority, Amount: 10000, CreatedAt: time.Now()})
+	sim.Pay(payment.Authority)
+
+	rec := httptest.NewRecorder()
+	callback.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority="+payment.Authority+"&Status=OK", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the callback to succeed, got %d %s", rec.Code, rec.Body)
+	}
+	if stored, _ := store.GetByAuthority(ctx, payment.Authority); stored.State != zarinpalgo.PaymentStateVerified {
+		t.Errorf("Expected the payment to be verified in the store, got %s", stored.State)
+	}
+
+	if err := app.Stop(ctx); err != nil {
+		t.Errorf("Expected the reconciler to stop, got %v", err)
+	}
+}
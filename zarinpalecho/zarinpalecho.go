@@ -0,0 +1,68 @@
+// Package zarinpalecho integrates the Zarinpal payment flow with the Echo framework.
+package zarinpalecho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/labstack/echo/v4"
+)
+
+// StatusKey is the context key the verified payment status is stored under
+const StatusKey = "zarinpalgo.status"
+
+// Callback returns an Echo middleware that parses the callback, verifies the payment and stores
+// the resulting status in the context for the next handler
+func Callback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, opts ...zarinpalgo.CallbackOption) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			status, err := z.ProcessCallback(clientContext(c), c.QueryParams(), lookup, opts...)
+			if err != nil {
+				return HTTPError(err)
+			}
+
+			c.Set(StatusKey, status)
+			return next(c)
+		}
+	}
+}
+
+// Start returns an Echo handler that creates the payment of the order given in the
+// zarinpalgo.OrderIDParam query parameter and redirects the user to the payment page
+func Start(z *zarinpalgo.Zarinpal, lookup zarinpalgo.OrderLookupFunc, onCreated zarinpalgo.PaymentCreatedFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paymentURL, err := z.StartPayment(clientContext(c), c.QueryParam(zarinpalgo.OrderIDParam), lookup, onCreated)
+		if err != nil {
+			return HTTPError(err)
+		}
+		return c.Redirect(http.StatusFound, paymentURL)
+	}
+}
+
+// clientContext returns the context of the request carrying the client IP Echo resolved, for
+// the risk hooks, unless a middleware set one already
+func clientContext(c echo.Context) context.Context {
+	ctx := c.Request().Context()
+	if zarinpalgo.ClientIP(ctx) != "" {
+		return ctx
+	}
+	return zarinpalgo.WithClientIP(ctx, c.RealIP())
+}
+
+// Status returns the payment status stored by Callback
+func Status(c echo.Context) (status zarinpalgo.PaymentStatus, ok bool) {
+	status, ok = c.Get(StatusKey).(zarinpalgo.PaymentStatus)
+	return
+}
+
+// HTTPError maps an error returned by the zarinpalgo server-side helpers to an Echo HTTP error
+func HTTPError(err error) *echo.HTTPError {
+	statusCode := http.StatusInternalServerError
+	var handlerErr *zarinpalgo.HandlerError
+	if errors.As(err, &handlerErr) {
+		statusCode = handlerErr.StatusCode
+	}
+	return echo.NewHTTPError(statusCode, http.StatusText(statusCode)).SetInternal(err)
+}
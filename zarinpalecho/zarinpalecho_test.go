@@ -0,0 +1,55 @@
+package zarinpalecho
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	lookup := func(ctx context.Context, callback zarinpalgo.CallbackData) (int, error) {
+		if callback.Authority != "A1" {
+			return 0, zarinpalgo.ErrPaymentNotFound
+		}
+		return 10000, nil
+	}
+
+	e := echo.New()
+	e.GET("/callback", func(c echo.Context) error {
+		status, ok := Status(c)
+		if !ok {
+			t.Error("Expected payment status in context")
+		}
+		return c.String(http.StatusOK, strconv.Itoa(status.RefID))
+	}, Callback(zp, lookup))
+
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/callback?Authority=A1&Status=OK", http.StatusOK},
+		{"/callback?Authority=A2&Status=OK", http.StatusNotFound},
+		{"/callback?Authority=A1", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest("GET", test.target, nil))
+		if rec.Code != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, rec.Code)
+		}
+	}
+}
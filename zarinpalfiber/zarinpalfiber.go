@@ -0,0 +1,77 @@
+// Package zarinpalfiber integrates the Zarinpal payment flow with the Fiber framework.
+package zarinpalfiber
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatusKey is the locals key the verified payment status is stored under
+const StatusKey = "zarinpalgo.status"
+
+// Callback returns a Fiber handler that parses the callback, verifies the payment and stores
+// the resulting status in the locals for the next handler
+func Callback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, opts ...zarinpalgo.CallbackOption) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		values, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, http.StatusText(fiber.StatusBadRequest))
+		}
+
+		status, err := z.ProcessCallback(clientContext(c), values, lookup, opts...)
+		if err != nil {
+			return Error(err)
+		}
+
+		c.Locals(StatusKey, status)
+		return c.Next()
+	}
+}
+
+// Start returns a Fiber handler that creates the payment of the order given in the
+// zarinpalgo.OrderIDParam query parameter and redirects the user to the payment page
+func Start(z *zarinpalgo.Zarinpal, lookup zarinpalgo.OrderLookupFunc, onCreated zarinpalgo.PaymentCreatedFunc) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		paymentURL, err := z.StartPayment(clientContext(c), c.Query(zarinpalgo.OrderIDParam), lookup, onCreated)
+		if err != nil {
+			return Error(err)
+		}
+		return c.Redirect(paymentURL, fiber.StatusFound)
+	}
+}
+
+// clientContext returns the user context of the request carrying the client IP Fiber resolved,
+// for the risk hooks, unless a middleware set one already
+func clientContext(c *fiber.Ctx) context.Context {
+	ctx := c.UserContext()
+	if zarinpalgo.ClientIP(ctx) != "" {
+		return ctx
+	}
+	return zarinpalgo.WithClientIP(ctx, c.IP())
+}
+
+// Status returns the payment status stored by Callback
+func Status(c *fiber.Ctx) (status zarinpalgo.PaymentStatus, ok bool) {
+	status, ok = c.Locals(StatusKey).(zarinpalgo.PaymentStatus)
+	return
+}
+
+// Redirect sends the user to the Zarinpal payment page of the given authority
+func Redirect(c *fiber.Ctx, z *zarinpalgo.Zarinpal, authority string) error {
+	return c.Redirect(z.GetPaymentURL(authority), fiber.StatusFound)
+}
+
+// Error maps an error returned by the zarinpalgo server-side helpers to a Fiber error
+func Error(err error) *fiber.Error {
+	statusCode := fiber.StatusInternalServerError
+	var handlerErr *zarinpalgo.HandlerError
+	if errors.As(err, &handlerErr) {
+		statusCode = handlerErr.StatusCode
+	}
+	return fiber.NewError(statusCode, http.StatusText(statusCode))
+}
@@ -0,0 +1,85 @@
+package zarinpalfiber
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	lookup := func(ctx context.Context, callback zarinpalgo.CallbackData) (int, error) {
+		return 10000, nil
+	}
+
+	app := fiber.New()
+	app.Get("/callback", Callback(zp, lookup), func(c *fiber.Ctx) error {
+		status, ok := Status(c)
+		if !ok {
+			t.Error("Expected payment status in locals")
+		}
+		return c.SendString(strconv.Itoa(status.RefID))
+	})
+	app.Get("/pay/:authority", func(c *fiber.Ctx) error {
+		return Redirect(c, zp, c.Params("authority"))
+	})
+
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/callback?Authority=A1&Status=OK", fiber.StatusOK},
+		{"/callback?Status=OK", fiber.StatusBadRequest},
+		{"/pay/A1", fiber.StatusFound},
+	}
+
+	for _, test := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", test.target, nil))
+		if err != nil {
+			t.Fatalf("Request to %s failed: %v", test.target, err)
+		}
+		if resp.StatusCode != test.code {
+			t.Errorf("Expected status code %d for %s, got %d", test.code, test.target, resp.StatusCode)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1"},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	lookup := func(ctx context.Context, orderID string) (zarinpalgo.PaymentParams, error) {
+		return zarinpalgo.PaymentParams{Amount: 10000, Description: "Order " + orderID}, nil
+	}
+
+	app := fiber.New()
+	app.Get("/start", Start(zp, lookup, nil))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/start?order_id=42", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusFound, resp.StatusCode)
+	}
+	if location := resp.Header.Get("Location"); location != zp.GetPaymentURL("A1") {
+		t.Errorf("Expected redirect to %s, got %s", zp.GetPaymentURL("A1"), location)
+	}
+}
@@ -0,0 +1,71 @@
+// Package zarinpalgin integrates the Zarinpal payment flow with the Gin framework.
+package zarinpalgin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/gin-gonic/gin"
+)
+
+// StatusKey is the context key the verified payment status is stored under
+const StatusKey = "zarinpalgo.status"
+
+// Callback returns a Gin handler that parses the callback, verifies the payment and stores
+// the resulting status in the context for the next handlers. Requests that can't be processed
+// are aborted with the matching HTTP status code.
+func Callback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, opts ...zarinpalgo.CallbackOption) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := z.ProcessCallback(clientContext(c), c.Request.URL.Query(), lookup, opts...)
+		if err != nil {
+			c.AbortWithError(statusCode(err), err)
+			return
+		}
+
+		c.Set(StatusKey, status)
+		c.Next()
+	}
+}
+
+// Start returns a Gin handler that creates the payment of the order given in the
+// zarinpalgo.OrderIDParam query parameter and redirects the user to the payment page
+func Start(z *zarinpalgo.Zarinpal, lookup zarinpalgo.OrderLookupFunc, onCreated zarinpalgo.PaymentCreatedFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paymentURL, err := z.StartPayment(clientContext(c), c.Query(zarinpalgo.OrderIDParam), lookup, onCreated)
+		if err != nil {
+			c.AbortWithError(statusCode(err), err)
+			return
+		}
+		c.Redirect(http.StatusFound, paymentURL)
+	}
+}
+
+// Status returns the payment status stored by Callback
+func Status(c *gin.Context) (status zarinpalgo.PaymentStatus, ok bool) {
+	value, exists := c.Get(StatusKey)
+	if !exists {
+		return
+	}
+	status, ok = value.(zarinpalgo.PaymentStatus)
+	return
+}
+
+// clientContext returns the context of the request carrying the client IP Gin resolved, for
+// the risk hooks, unless a middleware set one already
+func clientContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if zarinpalgo.ClientIP(ctx) != "" {
+		return ctx
+	}
+	return zarinpalgo.WithClientIP(ctx, c.ClientIP())
+}
+
+func statusCode(err error) int {
+	var handlerErr *zarinpalgo.HandlerError
+	if errors.As(err, &handlerErr) {
+		return handlerErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,51 @@
+package zarinpalgin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	lookup := func(ctx context.Context, callback zarinpalgo.CallbackData) (int, error) {
+		if ip := zarinpalgo.ClientIP(ctx); ip != "192.0.2.1" {
+			t.Errorf("Expected the client IP resolved by Gin, got %q", ip)
+		}
+		return 10000, nil
+	}
+
+	router := gin.New()
+	router.GET("/callback", Callback(zp, lookup), func(c *gin.Context) {
+		status, ok := Status(c)
+		if !ok {
+			t.Error("Expected payment status in context")
+		}
+		c.String(http.StatusOK, "%d", status.RefID)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Authority=A1&Status=OK", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "201" {
+		t.Errorf("Unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?Status=OK", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -4,30 +4,84 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"runtime/pprof"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Zarinpal struct {
 	MerchantID     string
 	APIBaseURL     string
 	PaymentBaseURL string
-	client         *http.Client
+	HTTPClient     *http.Client     // client used for gateway requests, replace it to customize the transport
+	RiskHook       RiskHook         // optional, assesses payments before creation and after verification
+	Traffic        TrafficSink      // optional, receives a redacted copy of every gateway request and response
+	Redaction      *RedactionPolicy // masks traffic records, DefaultRedactionPolicy when nil
+	DeadlineHook   DeadlineHook     // optional, warns about context deadlines likely to cut requests short
+	DriftHook      DriftHook        // optional, reports response fields the client doesn't decode
+
+	// OnTrafficError is called when Traffic fails to record an exchange, the request goes on
+	OnTrafficError func(ctx context.Context, err error)
+
+	// Tenant labels the requests of the client in observability, like the shop it serves in a
+	// multi-tenant service, see WithTenant. Unlike MerchantID it isn't a secret.
+	Tenant string
+
+	// TruncateDescriptions cuts descriptions longer than MaxDescriptionLength with an ellipsis
+	// instead of failing with ErrDescriptionTooLong
+	TruncateDescriptions bool
+
+	// RateLimiter optionally limits gateway requests, waiting for a token of the bucket of
+	// MerchantID. Share one between the clients of the merchants a service hosts so a burst of
+	// one merchant, like a reconciliation run, can't starve the checkouts of the others.
+	RateLimiter *RateLimiter
+
+	// Limits checks payments against the limits of the terminal before they are created,
+	// failing them with ErrTerminalLimit
+	Limits *TerminalLimitsCache
+
+	// MinAmounts replaces the smallest amounts per currency checked before payments are
+	// created, like when Zarinpal changes its limits before the library catches up. Currencies
+	// without an entry aren't checked, the minimums of MinAmount are checked when nil.
+	MinAmounts map[Currency]int
+
+	// Endpoints routes operations away from their endpoint under APIBaseURL, like for an API
+	// gateway in front of Zarinpal
+	Endpoints EndpointOverrides
+
+	// OnHookPanic is called with the panics of the hooks and callbacks of the client, they are
+	// recovered so a buggy hook can't abort a payment midway. Panic again in it to crash instead.
+	OnHookPanic func(ctx context.Context, err *PanicError)
+
+	latencies latencies
+	drift     driftSeen
 }
 
 // PaymentStatus represents the result of a payment verification
 type PaymentStatus struct {
-	IsSuccessful bool
-	IsRepeated   bool
-	RefID        int
-	Message      string
+	Authority    string `json:"authority"`
+	IsSuccessful bool   `json:"is_successful"`
+	IsRepeated   bool   `json:"is_repeated"`
+	RefID        int    `json:"ref_id"`
+	Amount       int    `json:"amount"`
+	CardPan      string `json:"card_pan"`              // masked card number reported by Zarinpal
+	CardHash     string `json:"card_hash,omitempty"`   // hash of the card number, the same for every payment of the card
+	Flagged      bool   `json:"flagged,omitempty"`     // set by the risk hook for review
+	RiskReason   string `json:"risk_reason,omitempty"` // why the risk hook flagged or declined the payment
+	Reversed     bool   `json:"reversed,omitempty"`    // declined by the risk hook and reversed to the card
+	Message      string `json:"message"`
+	Replayed     bool   `json:"replayed"` // callback was already processed, set by the callback helpers
 }
 
 type PaymentRequest struct {
 	MerchantID  string    `json:"merchant_id"`
 	Amount      int       `json:"amount"`
+	Currency    Currency  `json:"currency,omitempty"`
 	Description string    `json:"description"`
 	Metadata    *Metadata `json:"metadata,omitempty"`
 	CallbackURL string    `json:"callback_url"`
@@ -40,6 +94,20 @@ type PaymentVerificationRequest struct {
 	Authority  string `json:"authority"`
 }
 
+type PaymentInquiryRequest struct {
+	MerchantID string `json:"merchant_id"`
+	Authority  string `json:"authority"`
+}
+
+type UnverifiedPaymentsRequest struct {
+	MerchantID string `json:"merchant_id"`
+}
+
+type PaymentReverseRequest struct {
+	MerchantID string `json:"merchant_id"`
+	Authority  string `json:"authority"`
+}
+
 type Metadata struct {
 	Email   string `json:"email"`
 	Mobile  string `json:"mobile"`
@@ -70,6 +138,31 @@ type PaymentVerificationResponse struct {
 	Fee      int    `json:"fee"`
 }
 
+type PaymentInquiryResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"` // one of the InquiryStatus constants
+}
+
+type UnverifiedPayment struct {
+	Authority   string `json:"authority"`
+	Amount      int    `json:"amount"`
+	CallbackURL string `json:"callback_url"`
+	Referer     string `json:"referer"`
+	Date        string `json:"date"`
+}
+
+type UnverifiedPaymentsResponse struct {
+	Code        int                 `json:"code"`
+	Message     string              `json:"message"`
+	Authorities []UnverifiedPayment `json:"authorities"`
+}
+
+type PaymentReverseResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
 type BaseResponse struct {
 	Data   json.RawMessage `json:"data"`
 	Errors json.RawMessage `json:"errors"`
@@ -81,12 +174,49 @@ type ErrorResponse struct {
 	Validations []interface{} `json:"validations"`
 }
 
+// APIError is returned when Zarinpal answers a request with an error code
+type APIError struct {
+	Code        int
+	Message     string
+	Validations []interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// rejectionCodes are the verification errors meaning the payment won't verify
+var rejectionCodes = map[int]bool{
+	-50: true, // amount doesn't match the session
+	-51: true, // session is not active, the payment failed or was canceled
+	-53: true, // session belongs to another merchant
+	-54: true, // invalid authority
+	-55: true, // manual payment request not found
+}
+
+// IsPaymentRejected reports whether the error is a gateway answer that the payment won't
+// verify. Other errors, like too many attempts or a terminal problem, may pass on a retry and
+// say nothing about the payment.
+func IsPaymentRejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && rejectionCodes[apiErr.Code]
+}
+
 // PaymentResult constants
 const (
 	PaymentCodeSuccess         = 100 // Payment was successful
 	PaymentCodeAlreadyVerified = 101 // Payment was successful and verified before
 )
 
+// InquiryStatus constants
+const (
+	InquiryStatusVerified = "VERIFIED" // Payment was paid and verified
+	InquiryStatusPaid     = "PAID"     // Payment was paid but is not verified yet
+	InquiryStatusInBank   = "IN_BANK"  // User is on the bank page
+	InquiryStatusFailed   = "FAILED"   // Payment failed or was canceled
+	InquiryStatusReversed = "REVERSED" // Payment was reversed
+)
+
 // New creates a new Zarinpal client with the given merchant ID
 func New(merchantID string) *Zarinpal {
 	return NewWithMode(merchantID, false)
@@ -103,56 +233,68 @@ func NewWithMode(merchantID string, sandbox bool) *Zarinpal {
 		MerchantID:     merchantID,
 		APIBaseURL:     baseURL + "/pg/v4/payment/",
 		PaymentBaseURL: baseURL + "/pg/StartPay/",
-		client: &http.Client{
+		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
-// NewPayment initiates a new payment request
-func (z *Zarinpal) NewPayment(ctx context.Context, amount int, description string, metadata *Metadata, callbackURL string, wages []Wage) (paymentCreationResponse PaymentCreationResponse, err error) {
-	paymentRequestBody := PaymentRequest{
-		MerchantID:  z.MerchantID,
-		Amount:      amount,
-		Description: description,
-		Metadata:    metadata,
-		CallbackURL: callbackURL,
-		Wages:       wages,
-	}
-
-	marshalled, err := json.Marshal(paymentRequestBody)
-	if err != nil {
+// CreatePayment initiates a new payment request from its parameters. Prefer it over NewPayment,
+// whose positional arguments can't grow with the parameters the gateway accepts. The
+// description is sent through SanitizeDescription.
+func (z *Zarinpal) CreatePayment(ctx context.Context, params PaymentParams) (paymentCreationResponse PaymentCreationResponse, err error) {
+	ctx = z.withTenant(ctx)
+	if params, err = z.checkCreation(ctx, params); err != nil {
+		err = wrapOperation(ctx, err, "request", "request.json", "")
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", z.APIBaseURL+"request.json", bytes.NewBuffer(marshalled))
-	if err != nil {
-		return
+	paymentRequestBody := PaymentRequest{
+		MerchantID:  z.MerchantID,
+		Amount:      params.Amount,
+		Currency:    params.Currency,
+		Description: params.Description,
+		Metadata:    params.Metadata,
+		CallbackURL: params.CallbackURL,
+		Wages:       params.Wages,
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := z.client.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
+	err = z.post(ctx, "request", "request.json", "", paymentRequestBody, &paymentCreationResponse)
+	return
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return
+// checkCreation runs the checks made before a payment is created, returning the parameters
+// with the description sanitized
+func (z *Zarinpal) checkCreation(ctx context.Context, params PaymentParams) (PaymentParams, error) {
+	if err := checkMinAmount(params.Amount, params.Currency, z.MinAmounts); err != nil {
+		return params, err
 	}
-
-	rawMessage, err := checkResponse(bodyBytes)
-	if err != nil {
-		return
+	var err error
+	if params.Description, err = checkDescription(params.Description, z.TruncateDescriptions); err != nil {
+		return params, err
 	}
-
-	err = json.Unmarshal(rawMessage, &paymentCreationResponse)
-	if err != nil {
-		return
+	if z.Limits != nil {
+		if err := z.Limits.checkLimits(ctx, params); err != nil {
+			return params, err
+		}
+	}
+	if z.RiskHook != nil {
+		if err := z.assessCreation(ctx, params); err != nil {
+			return params, err
+		}
 	}
+	return params, nil
+}
 
-	return
+// NewPayment initiates a new payment request, it is CreatePayment with positional arguments
+func (z *Zarinpal) NewPayment(ctx context.Context, amount int, description string, metadata *Metadata, callbackURL string, wages []Wage) (PaymentCreationResponse, error) {
+	return z.CreatePayment(ctx, PaymentParams{
+		Amount:      amount,
+		Description: description,
+		Metadata:    metadata,
+		CallbackURL: callbackURL,
+		Wages:       wages,
+	})
 }
 
 // VerifyPayment verifies a payment using authority and amount
@@ -163,38 +305,40 @@ func (z *Zarinpal) VerifyPayment(ctx context.Context, amount int, authority stri
 		Authority:  authority,
 	}
 
-	marshalled, err := json.Marshal(paymentVerificationRequestBody)
-	if err != nil {
-		return
-	}
+	err = z.post(ctx, "verify", "verify.json", authority, paymentVerificationRequestBody, &paymentVerificationResponse)
+	return
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", z.APIBaseURL+"verify.json", bytes.NewBuffer(marshalled))
-	if err != nil {
-		return
+// InquirePayment returns the current status of a payment without verifying it
+func (z *Zarinpal) InquirePayment(ctx context.Context, authority string) (paymentInquiryResponse PaymentInquiryResponse, err error) {
+	paymentInquiryRequestBody := PaymentInquiryRequest{
+		MerchantID: z.MerchantID,
+		Authority:  authority,
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := z.client.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
+	err = z.post(ctx, "inquiry", "inquiry.json", authority, paymentInquiryRequestBody, &paymentInquiryResponse)
+	return
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return
+// UnverifiedPayments returns the successful payments that haven't been verified yet
+func (z *Zarinpal) UnverifiedPayments(ctx context.Context) (unverifiedPaymentsResponse UnverifiedPaymentsResponse, err error) {
+	unverifiedPaymentsRequestBody := UnverifiedPaymentsRequest{
+		MerchantID: z.MerchantID,
 	}
 
-	rawMessage, err := checkResponse(bodyBytes)
-	if err != nil {
-		return
-	}
+	err = z.post(ctx, "unverified", "unVerified.json", "", unverifiedPaymentsRequestBody, &unverifiedPaymentsResponse)
+	return
+}
 
-	err = json.Unmarshal(rawMessage, &paymentVerificationResponse)
-	if err != nil {
-		return
+// ReversePayment returns the money of a successful payment to the user's card. Zarinpal only
+// reverses payments within 30 minutes of the payment and from the IPs allowed for the terminal.
+func (z *Zarinpal) ReversePayment(ctx context.Context, authority string) (paymentReverseResponse PaymentReverseResponse, err error) {
+	paymentReverseRequestBody := PaymentReverseRequest{
+		MerchantID: z.MerchantID,
+		Authority:  authority,
 	}
 
+	err = z.post(ctx, "reverse", "reverse.json", authority, paymentReverseRequestBody, &paymentReverseResponse)
 	return
 }
 
@@ -203,14 +347,28 @@ func (z *Zarinpal) CheckPaymentStatus(ctx context.Context, amount int, authority
 	verification, err := z.VerifyPayment(ctx, amount, authority)
 	if err != nil {
 		return PaymentStatus{
+			Authority:    authority,
 			IsSuccessful: false,
-			Message:      err.Error(),
+			Message:      operationCause(err).Error(),
 		}, err
 	}
 
+	status := NewPaymentStatus(authority, amount, verification)
+	if z.RiskHook != nil {
+		status = z.assessVerification(ctx, status)
+	}
+	return status, nil
+}
+
+// NewPaymentStatus builds the user-friendly status of a verification response
+func NewPaymentStatus(authority string, amount int, verification PaymentVerificationResponse) PaymentStatus {
 	status := PaymentStatus{
-		Message: verification.Message,
-		RefID:   verification.RefID,
+		Authority: authority,
+		Message:   verification.Message,
+		RefID:     verification.RefID,
+		Amount:    amount,
+		CardPan:   verification.CardPan,
+		CardHash:  verification.CardHash,
 	}
 
 	// Check if payment was successful
@@ -226,7 +384,7 @@ func (z *Zarinpal) CheckPaymentStatus(ctx context.Context, amount int, authority
 		status.IsRepeated = false
 	}
 
-	return status, nil
+	return status
 }
 
 // GetPaymentURL generates the payment URL from an authority token
@@ -234,6 +392,90 @@ func (z *Zarinpal) GetPaymentURL(authority string) string {
 	return z.PaymentBaseURL + authority
 }
 
+// post sends body to the given endpoint and decodes the response data into out.
+// The request runs under pprof labels naming the operation, merchant and tenant
+// so profiles of busy services can attribute gateway time to specific calls.
+// Failures are returned as an *OperationError.
+func (z *Zarinpal) post(ctx context.Context, operation, endpoint, authority string, body interface{}, out interface{}) (err error) {
+	ctx = z.withTenant(ctx)
+	if CorrelationID(ctx) == "" && z.Traffic != nil {
+		ctx = WithCorrelationID(ctx, uuid.NewString())
+	}
+	defer func() {
+		err = wrapOperation(ctx, err, operation, endpoint, authority)
+	}()
+
+	if z.RateLimiter != nil {
+		if err = z.RateLimiter.Wait(ctx, z.MerchantID); err != nil {
+			return
+		}
+	}
+	if z.DeadlineHook != nil {
+		z.checkDeadline(ctx, operation)
+	}
+
+	labels := []string{"zarinpal.operation", operation, "zarinpal.merchant", z.MerchantID}
+	if tenant := Tenant(ctx); tenant != "" {
+		labels = append(labels, "zarinpal.tenant", tenant)
+	}
+	start := time.Now()
+	pprof.Do(ctx, pprof.Labels(labels...), func(ctx context.Context) {
+		err = z.doPost(ctx, operation, endpoint, body, out)
+	})
+	if err == nil {
+		z.latencies.observe(operation, time.Since(start))
+	}
+	return
+}
+
+func (z *Zarinpal) doPost(ctx context.Context, operation, endpoint string, body interface{}, out interface{}) (err error) {
+	marshalled, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", z.Endpoints.url(operation, z.APIBaseURL, endpoint), bytes.NewBuffer(marshalled))
+	if err != nil {
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	correlationID := CorrelationID(ctx)
+	if correlationID != "" {
+		req.Header.Set(CorrelationHeader, correlationID)
+	}
+
+	var bodyBytes []byte
+	record := TrafficRecord{CorrelationID: correlationID, Tenant: Tenant(ctx), Operation: operation, URL: req.URL.String(), SentAt: time.Now()}
+	if z.Traffic != nil {
+		defer func() {
+			z.recordTraffic(ctx, record, marshalled, bodyBytes, err)
+		}()
+	}
+
+	resp, err := z.HTTPClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	record.StatusCode = resp.StatusCode
+
+	bodyBytes, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	rawMessage, err := checkResponse(bodyBytes)
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(rawMessage, out); err == nil && z.DriftHook != nil {
+		z.checkDrift(ctx, operation, rawMessage, out)
+	}
+	return
+}
+
 func checkResponse(body []byte) (rawMessage json.RawMessage, err error) {
 	var baseResponse BaseResponse
 	err = json.Unmarshal(body, &baseResponse)
@@ -245,11 +487,28 @@ func checkResponse(body []byte) (rawMessage json.RawMessage, err error) {
 
 	if !isEmptyErrors {
 		var errorResponse ErrorResponse
-		err = json.Unmarshal(baseResponse.Errors, &errorResponse)
-		if err != nil {
-			return
+		if baseResponse.Errors[0] == '[' {
+			// some endpoints send the errors as a list, the first one is reported
+			var errorResponses []ErrorResponse
+			err = json.Unmarshal(baseResponse.Errors, &errorResponses)
+			if err != nil {
+				return
+			}
+			if len(errorResponses) == 0 {
+				return baseResponse.Data, nil
+			}
+			errorResponse = errorResponses[0]
+		} else {
+			err = json.Unmarshal(baseResponse.Errors, &errorResponse)
+			if err != nil {
+				return
+			}
+		}
+		err = &APIError{
+			Code:        errorResponse.Code,
+			Message:     errorResponse.Message,
+			Validations: errorResponse.Validations,
 		}
-		err = fmt.Errorf("error code: %d, error: %s", errorResponse.Code, errorResponse.Message)
 		return
 	}
 
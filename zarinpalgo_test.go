@@ -2,7 +2,12 @@ package zarinpalgo
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
 	"testing"
+
 	"github.com/google/uuid"
 )
 
@@ -27,8 +32,8 @@ func TestSandboxPayment(t *testing.T) {
 	description := "Test payment"
 	callbackURL := "http://localhost:8080/callback"
 	metadata := &Metadata{
-		Email: "test@example.com",
-		Mobile: "09123456789",
+		Email:   "test@example.com",
+		Mobile:  "09123456789",
 		OrderID: "TEST-ORDER-1",
 	}
 
@@ -103,4 +108,106 @@ func TestSandboxPaymentWithWages(t *testing.T) {
 	if payment.Authority == "" {
 		t.Error("Expected non-empty authority token for payment with wages")
 	}
-}
\ No newline at end of file
+}
+
+type labelRecorder struct {
+	operation, merchant string
+	next                http.RoundTripper
+}
+
+func (l *labelRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.operation, _ = pprof.Label(req.Context(), "zarinpal.operation")
+	l.merchant, _ = pprof.Label(req.Context(), "zarinpal.merchant")
+	return l.next.RoundTrip(req)
+}
+
+func TestPprofLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A0000000000000000000000000000wwOGYpd"},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+	recorder := &labelRecorder{next: http.DefaultTransport}
+	zp.HTTPClient.Transport = recorder
+
+	_, err := zp.NewPayment(context.Background(), 10000, "Test payment", nil, "http://localhost/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	if recorder.operation != "request" {
+		t.Errorf("Expected operation label %q, got %q", "request", recorder.operation)
+	}
+	if recorder.merchant != "merchant-1" {
+		t.Errorf("Expected merchant label %q, got %q", "merchant-1", recorder.merchant)
+	}
+}
+
+func TestInquiryAndUnverifiedPayments(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"inquiry.json":    `{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`,
+		"unVerified.json": `{"data":{"code":100,"message":"Success","authorities":[{"authority":"A1","amount":50500,"callback_url":"https://example.com/callback","referer":"https://example.com","date":"2024-05-12 12:24:11"}]},"errors":[]}`,
+	})
+
+	inquiry, err := zp.InquirePayment(context.Background(), "A1")
+	if err != nil {
+		t.Fatalf("Failed to inquire payment: %v", err)
+	}
+	if inquiry.Status != InquiryStatusPaid {
+		t.Errorf("Expected status %s, got %s", InquiryStatusPaid, inquiry.Status)
+	}
+
+	unverified, err := zp.UnverifiedPayments(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list unverified payments: %v", err)
+	}
+	if len(unverified.Authorities) != 1 || unverified.Authorities[0].Amount != 50500 {
+		t.Errorf("Unexpected unverified payments %+v", unverified.Authorities)
+	}
+}
+
+func TestReversePayment(t *testing.T) {
+	zp := newStubClient(t, map[string]string{
+		"reverse.json": `{"data":{"code":100,"message":"Reversed"},"errors":[]}`,
+	})
+
+	response, err := zp.ReversePayment(context.Background(), "A1")
+	if err != nil {
+		t.Fatalf("Failed to reverse payment: %v", err)
+	}
+	if response.Code != PaymentCodeSuccess {
+		t.Errorf("Expected code %d, got %d", PaymentCodeSuccess, response.Code)
+	}
+}
+
+func TestCreatePayment(t *testing.T) {
+	var body PaymentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`))
+	}))
+	defer server.Close()
+
+	zp := New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+
+	payment, err := zp.CreatePayment(context.Background(), PaymentParams{
+		Amount:      5000,
+		Currency:    CurrencyToman,
+		Description: "Order 1",
+		CallbackURL: "https://example.com/callback",
+		Metadata:    &Metadata{OrderID: "1"},
+		Wages:       []Wage{{Iban: "IR000000000000000000000001", Amount: 1000, Description: "Seller"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if payment.Authority != "A1" {
+		t.Errorf("Expected authority A1, got %s", payment.Authority)
+	}
+	if body.MerchantID != "merchant-1" || body.Amount != 5000 || body.Currency != CurrencyToman || body.Metadata.OrderID != "1" || len(body.Wages) != 1 {
+		t.Errorf("Expected the parameters to be sent, got %+v", body)
+	}
+}
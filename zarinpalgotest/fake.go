@@ -0,0 +1,255 @@
+// Package zarinpalgotest provides test doubles for code using zarinpalgo.
+package zarinpalgotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Call is a call recorded by FakeClient
+type Call struct {
+	Method      string
+	Amount      int
+	Currency    zarinpalgo.Currency
+	Authority   string
+	Description string
+	CallbackURL string
+	Metadata    *zarinpalgo.Metadata
+	Wages       []zarinpalgo.Wage
+}
+
+type createResult struct {
+	response zarinpalgo.PaymentCreationResponse
+	err      error
+}
+
+type verifyResult struct {
+	response zarinpalgo.PaymentVerificationResponse
+	err      error
+}
+
+// FakeClient is a zarinpalgo.Client returning scripted responses. Payments are created
+// with sequential authorities unless scripted otherwise, verifications fail with -51
+// (payment not completed) unless scripted otherwise.
+type FakeClient struct {
+	PaymentBaseURL string
+
+	mu           sync.Mutex
+	calls        []Call
+	creates      []createResult
+	verifies     []verifyResult
+	inquiries    map[string]zarinpalgo.PaymentInquiryResponse
+	unverified   []zarinpalgo.UnverifiedPayment
+	authorityNum int
+}
+
+var _ zarinpalgo.Client = (*FakeClient)(nil)
+
+// NewFakeClient creates a new FakeClient
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		PaymentBaseURL: "https://sandbox.zarinpal.com/pg/StartPay/",
+		inquiries:      make(map[string]zarinpalgo.PaymentInquiryResponse),
+	}
+}
+
+// NextAuthority makes the next CreatePayment or NewPayment call succeed with the given authority
+func (f *FakeClient) NextAuthority(authority string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.creates = append(f.creates, createResult{response: zarinpalgo.PaymentCreationResponse{
+		Code:      zarinpalgo.PaymentCodeSuccess,
+		Message:   "Success",
+		Authority: authority,
+	}})
+}
+
+// FailNextCreate makes the next CreatePayment or NewPayment call return err
+func (f *FakeClient) FailNextCreate(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.creates = append(f.creates, createResult{err: err})
+}
+
+// SucceedNextVerify makes the next verification succeed with code 100
+func (f *FakeClient) SucceedNextVerify(refID int) {
+	f.scriptVerify(verifyResult{response: zarinpalgo.PaymentVerificationResponse{
+		Code:    zarinpalgo.PaymentCodeSuccess,
+		Message: "Verified",
+		RefID:   refID,
+		CardPan: "502229******5995",
+	}})
+}
+
+// RepeatNextVerify makes the next verification report an already verified payment (code 101)
+func (f *FakeClient) RepeatNextVerify(refID int) {
+	f.scriptVerify(verifyResult{response: zarinpalgo.PaymentVerificationResponse{
+		Code:    zarinpalgo.PaymentCodeAlreadyVerified,
+		Message: "Verified",
+		RefID:   refID,
+		CardPan: "502229******5995",
+	}})
+}
+
+// FailNextVerify makes the next verification return a gateway error with the code
+func (f *FakeClient) FailNextVerify(code int, message string) {
+	f.scriptVerify(verifyResult{err: &zarinpalgo.APIError{Code: code, Message: message}})
+}
+
+// ErrNextVerify makes the next verification return err, e.g. to simulate network failures
+func (f *FakeClient) ErrNextVerify(err error) {
+	f.scriptVerify(verifyResult{err: err})
+}
+
+func (f *FakeClient) scriptVerify(result verifyResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.verifies = append(f.verifies, result)
+}
+
+// SetInquiry sets the inquiry status returned for the authority
+func (f *FakeClient) SetInquiry(authority, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inquiries[authority] = zarinpalgo.PaymentInquiryResponse{
+		Code:    zarinpalgo.PaymentCodeSuccess,
+		Message: "Success",
+		Status:  status,
+	}
+}
+
+// SetUnverified sets the payments returned by UnverifiedPayments
+func (f *FakeClient) SetUnverified(payments ...zarinpalgo.UnverifiedPayment) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unverified = payments
+}
+
+// Calls returns the calls recorded so far
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the recorded calls of a method
+func (f *FakeClient) CallsTo(method string) (calls []Call) {
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return
+}
+
+func (f *FakeClient) record(call Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+// CreatePayment implements zarinpalgo.Client
+func (f *FakeClient) CreatePayment(ctx context.Context, params zarinpalgo.PaymentParams) (zarinpalgo.PaymentCreationResponse, error) {
+	f.record(Call{
+		Method:      "CreatePayment",
+		Amount:      params.Amount,
+		Currency:    params.Currency,
+		Description: params.Description,
+		CallbackURL: params.CallbackURL,
+		Metadata:    params.Metadata,
+		Wages:       params.Wages,
+	})
+	return f.nextCreate()
+}
+
+// NewPayment implements zarinpalgo.Client
+func (f *FakeClient) NewPayment(ctx context.Context, amount int, description string, metadata *zarinpalgo.Metadata, callbackURL string, wages []zarinpalgo.Wage) (zarinpalgo.PaymentCreationResponse, error) {
+	f.record(Call{
+		Method:      "NewPayment",
+		Amount:      amount,
+		Description: description,
+		CallbackURL: callbackURL,
+		Metadata:    metadata,
+		Wages:       wages,
+	})
+	return f.nextCreate()
+}
+
+func (f *FakeClient) nextCreate() (zarinpalgo.PaymentCreationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.creates) > 0 {
+		result := f.creates[0]
+		f.creates = f.creates[1:]
+		return result.response, result.err
+	}
+
+	f.authorityNum++
+	return zarinpalgo.PaymentCreationResponse{
+		Code:      zarinpalgo.PaymentCodeSuccess,
+		Message:   "Success",
+		Authority: fmt.Sprintf("A%035d", f.authorityNum),
+		FeeType:   "Merchant",
+	}, nil
+}
+
+// VerifyPayment implements zarinpalgo.Client
+func (f *FakeClient) VerifyPayment(ctx context.Context, amount int, authority string) (zarinpalgo.PaymentVerificationResponse, error) {
+	f.record(Call{Method: "VerifyPayment", Amount: amount, Authority: authority})
+	return f.nextVerify()
+}
+
+func (f *FakeClient) nextVerify() (zarinpalgo.PaymentVerificationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.verifies) > 0 {
+		result := f.verifies[0]
+		f.verifies = f.verifies[1:]
+		return result.response, result.err
+	}
+	return zarinpalgo.PaymentVerificationResponse{}, &zarinpalgo.APIError{Code: -51, Message: "Session is not active, paid try"}
+}
+
+// CheckPaymentStatus implements zarinpalgo.Client
+func (f *FakeClient) CheckPaymentStatus(ctx context.Context, amount int, authority string) (zarinpalgo.PaymentStatus, error) {
+	f.record(Call{Method: "CheckPaymentStatus", Amount: amount, Authority: authority})
+
+	verification, err := f.nextVerify()
+	if err != nil {
+		return zarinpalgo.PaymentStatus{Authority: authority, Message: err.Error()}, err
+	}
+	return zarinpalgo.NewPaymentStatus(authority, amount, verification), nil
+}
+
+// InquirePayment implements zarinpalgo.Client
+func (f *FakeClient) InquirePayment(ctx context.Context, authority string) (zarinpalgo.PaymentInquiryResponse, error) {
+	f.record(Call{Method: "InquirePayment", Authority: authority})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if inquiry, ok := f.inquiries[authority]; ok {
+		return inquiry, nil
+	}
+	return zarinpalgo.PaymentInquiryResponse{}, &zarinpalgo.APIError{Code: -54, Message: "Invalid authority"}
+}
+
+// UnverifiedPayments implements zarinpalgo.Client
+func (f *FakeClient) UnverifiedPayments(ctx context.Context) (zarinpalgo.UnverifiedPaymentsResponse, error) {
+	f.record(Call{Method: "UnverifiedPayments"})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return zarinpalgo.UnverifiedPaymentsResponse{
+		Code:        zarinpalgo.PaymentCodeSuccess,
+		Message:     "Success",
+		Authorities: append([]zarinpalgo.UnverifiedPayment(nil), f.unverified...),
+	}, nil
+}
+
+// GetPaymentURL implements zarinpalgo.Client
+func (f *FakeClient) GetPaymentURL(authority string) string {
+	return f.PaymentBaseURL + authority
+}
@@ -0,0 +1,71 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestFakeClient(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeClient()
+
+	payment, err := fake.NewPayment(ctx, 10000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if len(payment.Authority) != 36 {
+		t.Errorf("Expected a 36 character authority, got %s", payment.Authority)
+	}
+
+	status, err := fake.CheckPaymentStatus(ctx, 10000, payment.Authority)
+	var apiErr *zarinpalgo.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != -51 || status.IsSuccessful {
+		t.Errorf("Expected unscripted verification to fail with -51, got %+v %v", status, err)
+	}
+
+	fake.SucceedNextVerify(201)
+	fake.RepeatNextVerify(201)
+
+	status, _ = fake.CheckPaymentStatus(ctx, 10000, payment.Authority)
+	if !status.IsSuccessful || status.IsRepeated || status.RefID != 201 {
+		t.Errorf("Expected successful verification, got %+v", status)
+	}
+	status, _ = fake.CheckPaymentStatus(ctx, 10000, payment.Authority)
+	if !status.IsSuccessful || !status.IsRepeated {
+		t.Errorf("Expected repeated verification, got %+v", status)
+	}
+
+	if calls := fake.CallsTo("CheckPaymentStatus"); len(calls) != 3 || calls[0].Authority != payment.Authority {
+		t.Errorf("Unexpected recorded calls %+v", calls)
+	}
+}
+
+func TestFakeClientScriptedCreate(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeClient()
+
+	failure := errors.New("network down")
+	fake.FailNextCreate(failure)
+	fake.NextAuthority("A1")
+
+	if _, err := fake.NewPayment(ctx, 10000, "", nil, "", nil); err != failure {
+		t.Errorf("Expected scripted error, got %v", err)
+	}
+	if payment, _ := fake.NewPayment(ctx, 10000, "", nil, "", nil); payment.Authority != "A1" {
+		t.Errorf("Expected scripted authority, got %s", payment.Authority)
+	}
+	fake.NextAuthority("A2")
+	payment, err := fake.CreatePayment(ctx, zarinpalgo.PaymentParams{Amount: 100, Currency: zarinpalgo.CurrencyToman})
+	if err != nil || payment.Authority != "A2" {
+		t.Errorf("Expected scripted authority from CreatePayment, got %+v %v", payment, err)
+	}
+	if calls := fake.CallsTo("CreatePayment"); len(calls) != 1 || calls[0].Currency != zarinpalgo.CurrencyToman {
+		t.Errorf("Expected the CreatePayment call recorded, got %+v", calls)
+	}
+	if fake.GetPaymentURL("A1") != "https://sandbox.zarinpal.com/pg/StartPay/A1" {
+		t.Errorf("Unexpected payment URL %s", fake.GetPaymentURL("A1"))
+	}
+}
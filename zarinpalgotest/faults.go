@@ -0,0 +1,137 @@
+package zarinpalgotest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultTransport is an http.RoundTripper injecting gateway failures at configurable rates.
+// Timeouts, resets and malformed responses are injected after the request reached the gateway,
+// which is the case where the gateway may have acted on a request the client saw fail.
+type FaultTransport struct {
+	Base http.RoundTripper // defaults to http.DefaultTransport
+
+	TimeoutRate     float64       // probability of a timeout
+	TimeoutAfter    time.Duration // how long a timeout blocks, unless the request context ends first
+	ResetRate       float64       // probability of a connection reset
+	MalformedRate   float64       // probability of a truncated JSON response
+	ServerErrorRate float64       // probability of a 5xx response, the request isn't forwarded
+
+	// Rand decides which faults are injected, set it for reproducible runs
+	Rand *rand.Rand
+
+	mu     sync.Mutex
+	counts FaultCounts
+}
+
+// FaultCounts counts the faults a FaultTransport injected
+type FaultCounts struct {
+	Timeouts     int
+	Resets       int
+	Malformed    int
+	ServerErrors int
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "zarinpalgotest: injected timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+// Counts returns the faults injected so far
+func (f *FaultTransport) Counts() FaultCounts {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counts
+}
+
+// RoundTrip implements http.RoundTripper
+func (f *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault := f.pick()
+
+	if fault == &f.counts.ServerErrors {
+		return &http.Response{
+			Status:     "502 Bad Gateway",
+			StatusCode: http.StatusBadGateway,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       io.NopCloser(strings.NewReader("<html><body>502 Bad Gateway</body></html>")),
+			Request:    req,
+		}, nil
+	}
+
+	base := f.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || fault == nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	switch fault {
+	case &f.counts.Timeouts:
+		return nil, f.timeout(req.Context())
+	case &f.counts.Resets:
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	default:
+		resp.Body = io.NopCloser(bytes.NewReader(body[:len(body)/2]))
+		resp.ContentLength = int64(len(body) / 2)
+		return resp, nil
+	}
+}
+
+// pick decides the fault of a request and counts it, it returns nil when no fault is injected
+func (f *FaultTransport) pick() *int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Rand == nil {
+		f.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	roll := f.Rand.Float64()
+	for _, fault := range []struct {
+		rate  float64
+		count *int
+	}{
+		{f.TimeoutRate, &f.counts.Timeouts},
+		{f.ResetRate, &f.counts.Resets},
+		{f.MalformedRate, &f.counts.Malformed},
+		{f.ServerErrorRate, &f.counts.ServerErrors},
+	} {
+		if roll < fault.rate {
+			*fault.count++
+			return fault.count
+		}
+		roll -= fault.rate
+	}
+	return nil
+}
+
+func (f *FaultTransport) timeout(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(f.TimeoutAfter):
+		return timeoutError{}
+	}
+}
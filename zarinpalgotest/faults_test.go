@@ -0,0 +1,59 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestFaultTransport(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	faults := &FaultTransport{
+		TimeoutRate:     0.25,
+		ResetRate:       0.25,
+		MalformedRate:   0.25,
+		ServerErrorRate: 0.25,
+		Rand:            rand.New(rand.NewSource(1)),
+	}
+
+	zp := sim.Client("merchant-1")
+	zp.HTTPClient = &http.Client{Transport: faults}
+
+	for i := 0; i < 40; i++ {
+		_, err := zp.NewPayment(ctx, 10000, "Order", nil, "https://example.com/callback", nil)
+		if err == nil {
+			t.Fatal("Expected every request to fail")
+		}
+		var apiErr *zarinpalgo.APIError
+		if errors.As(err, &apiErr) {
+			t.Fatalf("Expected injected faults not to look like gateway errors, got %v", err)
+		}
+	}
+
+	counts := faults.Counts()
+	if counts.Timeouts == 0 || counts.Resets == 0 || counts.Malformed == 0 || counts.ServerErrors == 0 {
+		t.Errorf("Expected every kind of fault to be injected, got %+v", counts)
+	}
+}
+
+func TestFaultTransportTimeout(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	zp := sim.Client("merchant-1")
+	zp.HTTPClient = &http.Client{Transport: &FaultTransport{TimeoutRate: 1}}
+
+	payment, err := zp.NewPayment(context.Background(), 10000, "Order", nil, "https://example.com/callback", nil)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected a timeout error, got %+v %v", payment, err)
+	}
+}
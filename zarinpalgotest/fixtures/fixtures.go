@@ -0,0 +1,67 @@
+// Package fixtures holds sanitized Zarinpal response bodies for tests that exercise response parsing.
+// Merchant data, card numbers and reference IDs have been replaced with placeholders.
+package fixtures
+
+import "fmt"
+
+// Authority used by the fixtures
+const Authority = "A00000000000000000000000000217885159"
+
+// Successful responses
+const (
+	RequestSuccess = `{"data":{"code":100,"message":"Success","authority":"A00000000000000000000000000217885159","fee_type":"Merchant","fee":100},"errors":[]}`
+
+	VerifySuccess = `{"data":{"code":100,"message":"Paid","card_hash":"1EBE3EBEBE35C7EC0F8D6EE4F2F859107A87822CA179BC9528767EA7B5489B69","card_pan":"502229******5995","ref_id":201,"fee_type":"Merchant","fee":100},"errors":[]}`
+
+	VerifyAlreadyVerified = `{"data":{"code":101,"message":"Verified","card_hash":"1EBE3EBEBE35C7EC0F8D6EE4F2F859107A87822CA179BC9528767EA7B5489B69","card_pan":"502229******5995","ref_id":201,"fee_type":"Merchant","fee":100},"errors":[]}`
+
+	InquiryPaid = `{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`
+
+	InquiryVerified = `{"data":{"code":100,"message":"Success","status":"VERIFIED"},"errors":[]}`
+
+	UnverifiedPayments = `{"data":{"code":100,"message":"Success","authorities":[{"authority":"A00000000000000000000000000217885159","amount":10000,"callback_url":"https://example.com/callback","referer":"https://example.com/checkout","date":"2024-01-01 12:00:00"}]},"errors":[]}`
+
+	UnverifiedPaymentsEmpty = `{"data":{"code":100,"message":"Success","authorities":[]},"errors":[]}`
+)
+
+// Failed responses with a single error object, as sent by most endpoints
+const (
+	ValidationError = `{"data":[],"errors":{"code":-9,"message":"The input params invalid, validation error.","validations":[{"amount":"The amount must be at least 1000."},{"callback_url":"The callback url field is required."}]}}`
+
+	// ArrayErrors is the form where errors is a list of error objects
+	ArrayErrors = `{"data":[],"errors":[{"code":-9,"message":"The input params invalid, validation error.","validations":[{"merchant_id":"The merchant id field is required."}]}]}`
+)
+
+// ErrorMessages maps the documented gateway error codes to the messages Zarinpal sends with them
+var ErrorMessages = map[int]string{
+	-9:  "The input params invalid, validation error.",
+	-10: "Terminal is not valid, please check merchant_id or ip address.",
+	-11: "Terminal is not active, please contact our support team.",
+	-12: "To many attempts, please try again later.",
+	-15: "Terminal user is suspend : (please contact our support team).",
+	-16: "Terminal user level is not valid : ( please contact our support team).",
+	-17: "Terminal user level is not valid : ( please contact our support team).",
+	-30: "Terminal do not allow to accept floating wages.",
+	-31: "Terminal do not allow to accept wages, please add default bank account in panel.",
+	-32: "Wages is not valid, Total wages(floating) has been overload max amount.",
+	-33: "Wages floating is not valid.",
+	-34: "Wages is not valid, Total wages(fixed) has been overload max amount.",
+	-35: "Wages is not valid, Total wages(floating) has been reached the limit in max parts.",
+	-36: "The minimum amount for wages(floating) should be 10,000 Rials",
+	-37: "One or more iban entered for wages(floating) from the bank side are inactive.",
+	-38: "Wages need to set Iban in shaparak.",
+	-39: "Wages have a error!",
+	-40: "Invalid extra params, expire_in is not valid.",
+	-41: "Maximum amount is 100,000,000 tomans.",
+	-50: "Session is not valid, amounts values is not the same.",
+	-51: "Session is not active, paid try.",
+	-52: "Oops!!, please contact our support team",
+	-53: "Session is not this merchant_id session",
+	-54: "Invalid authority.",
+	-55: "Manual payment request not found.",
+}
+
+// Error returns the failed response Zarinpal sends for the given error code
+func Error(code int) string {
+	return fmt.Sprintf(`{"data":[],"errors":{"code":%d,"message":%q,"validations":[]}}`, code, ErrorMessages[code])
+}
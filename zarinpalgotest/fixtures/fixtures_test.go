@@ -0,0 +1,84 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func newClient(t *testing.T, body string) *zarinpalgo.Zarinpal {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/pg/v4/payment/"
+	return zp
+}
+
+func TestSuccessFixtures(t *testing.T) {
+	ctx := context.Background()
+
+	payment, err := newClient(t, RequestSuccess).NewPayment(ctx, 10000, "Order", nil, "https://example.com/callback", nil)
+	if err != nil || payment.Authority != Authority {
+		t.Errorf("Unexpected payment %+v: %v", payment, err)
+	}
+
+	status, err := newClient(t, VerifySuccess).CheckPaymentStatus(ctx, 10000, Authority)
+	if err != nil || !status.IsSuccessful || status.IsRepeated {
+		t.Errorf("Unexpected status %+v: %v", status, err)
+	}
+
+	status, err = newClient(t, VerifyAlreadyVerified).CheckPaymentStatus(ctx, 10000, Authority)
+	if err != nil || !status.IsRepeated {
+		t.Errorf("Unexpected status %+v: %v", status, err)
+	}
+
+	inquiry, err := newClient(t, InquiryPaid).InquirePayment(ctx, Authority)
+	if err != nil || inquiry.Status != zarinpalgo.InquiryStatusPaid {
+		t.Errorf("Unexpected inquiry %+v: %v", inquiry, err)
+	}
+
+	unverified, err := newClient(t, UnverifiedPayments).UnverifiedPayments(ctx)
+	if err != nil || len(unverified.Authorities) != 1 {
+		t.Errorf("Unexpected unverified payments %+v: %v", unverified, err)
+	}
+}
+
+func TestErrorFixtures(t *testing.T) {
+	tests := []struct {
+		body        string
+		code        int
+		validations int
+	}{
+		{ValidationError, -9, 2},
+		{ArrayErrors, -9, 1},
+	}
+	for code := range ErrorMessages {
+		tests = append(tests, struct {
+			body        string
+			code        int
+			validations int
+		}{Error(code), code, 0})
+	}
+
+	for _, test := range tests {
+		_, err := newClient(t, test.body).VerifyPayment(context.Background(), 10000, Authority)
+
+		var apiErr *zarinpalgo.APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("Expected an APIError for %s, got %v", test.body, err)
+			continue
+		}
+		if apiErr.Code != test.code || len(apiErr.Validations) != test.validations {
+			t.Errorf("Expected code %d with %d validations, got %+v", test.code, test.validations, apiErr)
+		}
+	}
+}
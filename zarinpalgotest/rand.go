@@ -0,0 +1,25 @@
+package zarinpalgotest
+
+import (
+	"io"
+	"math/rand"
+)
+
+const authorityAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// SeededRand returns a deterministic source of bytes for the given seed
+func SeededRand(seed int64) io.Reader {
+	return rand.New(rand.NewSource(seed))
+}
+
+// NewAuthority generates a 36 character authority in the gateway's format from r
+func NewAuthority(r io.Reader) (string, error) {
+	b := make([]byte, 35)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = authorityAlphabet[int(b[i])%len(authorityAlphabet)]
+	}
+	return "A" + string(b), nil
+}
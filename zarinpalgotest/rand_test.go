@@ -0,0 +1,36 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeededAuthorities(t *testing.T) {
+	ctx := context.Background()
+
+	create := func(sim *Simulator) string {
+		payment, err := sim.Client("merchant-1").NewPayment(ctx, 10000, "Order 1", nil, "https://example.com/callback", nil)
+		if err != nil {
+			t.Fatalf("Failed to create payment: %v", err)
+		}
+		return payment.Authority
+	}
+
+	first := NewSimulator(WithRand(SeededRand(42)))
+	defer first.Close()
+	second := NewSimulator(WithRand(SeededRand(42)))
+	defer second.Close()
+	random := NewSimulator()
+	defer random.Close()
+
+	authority := create(first)
+	if len(authority) != 36 || authority[0] != 'A' {
+		t.Errorf("Unexpected authority format %s", authority)
+	}
+	if other := create(second); other != authority {
+		t.Errorf("Expected the same seed to produce %s, got %s", authority, other)
+	}
+	if other := create(random); other == authority {
+		t.Error("Expected random simulators to produce different authorities")
+	}
+}
@@ -0,0 +1,148 @@
+package zarinpalgotest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Operations that can be scripted
+const (
+	OpRequest    = "request"
+	OpVerify     = "verify"
+	OpInquiry    = "inquiry"
+	OpUnverified = "unverified"
+	OpReverse    = "reverse"
+)
+
+type stepKind int
+
+const (
+	stepRegular stepKind = iota
+	stepError
+	stepSucceed
+	stepHTTPError
+	stepTimeout
+)
+
+// Step is the scripted answer to a single request
+type Step struct {
+	kind    stepKind
+	code    int
+	refID   int
+	latency time.Duration
+}
+
+// Regular answers the request with the simulator's regular behavior
+func Regular() Step {
+	return Step{kind: stepRegular}
+}
+
+// Fail answers the request with the gateway error code
+func Fail(code int) Step {
+	return Step{kind: stepError, code: code}
+}
+
+// Succeed answers a verification with code 100 and the reference ID, whatever the payment state is.
+// Other operations behave regularly.
+func Succeed(refID int) Step {
+	return Step{kind: stepSucceed, refID: refID}
+}
+
+// HTTPError answers the request with a bare HTTP status code, e.g. a 502 from a proxy
+func HTTPError(statusCode int) Step {
+	return Step{kind: stepHTTPError, code: statusCode}
+}
+
+// Timeout never answers the request, the client has to give up on it
+func Timeout() Step {
+	return Step{kind: stepTimeout}
+}
+
+// After delays the answer of the step
+func (st Step) After(latency time.Duration) Step {
+	st.latency = latency
+	return st
+}
+
+// Script queues steps answering the next requests of an operation in order, once they are
+// used up the operation behaves regularly again. For example
+//
+//	sim.Script(zarinpalgotest.OpVerify,
+//		zarinpalgotest.Fail(zarinpalgotest.CodeNotPaid),
+//		zarinpalgotest.Succeed(201).After(300*time.Millisecond),
+//		zarinpalgotest.Timeout(),
+//	)
+func (s *Simulator) Script(operation string, steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[operation] = append(s.scripts[operation], steps...)
+}
+
+func (s *Simulator) nextStep(operation string) Step {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steps := s.scripts[operation]
+	if len(steps) == 0 {
+		return Regular()
+	}
+	s.scripts[operation] = steps[1:]
+	return steps[0]
+}
+
+// scripted wraps the regular handler of an operation with its script
+func (s *Simulator) scripted(operation string, regular http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		step := s.nextStep(operation)
+
+		if step.latency > 0 {
+			select {
+			case <-time.After(step.latency):
+			case <-r.Context().Done():
+				return
+			case <-s.closed:
+				return
+			}
+		}
+
+		switch step.kind {
+		case stepError:
+			writeError(w, step.code, nil)
+		case stepSucceed:
+			if operation != OpVerify {
+				regular(w, r)
+				return
+			}
+			s.succeedVerify(w, r, step.refID)
+		case stepHTTPError:
+			http.Error(w, http.StatusText(step.code), step.code)
+		case stepTimeout:
+			select {
+			case <-r.Context().Done():
+			case <-s.closed:
+			}
+		default:
+			regular(w, r)
+		}
+	}
+}
+
+func (s *Simulator) succeedVerify(w http.ResponseWriter, r *http.Request, refID int) {
+	var body zarinpalgo.PaymentVerificationRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[body.Authority]
+	if !ok {
+		payment = &SimulatedPayment{Authority: body.Authority, Amount: body.Amount}
+	}
+	payment.RefID = refID
+	payment.Status = zarinpalgo.InquiryStatusVerified
+	writeData(w, verification(payment, zarinpalgo.PaymentCodeSuccess, "Verified"))
+}
@@ -0,0 +1,60 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestSimulatorScript(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	zp := sim.Client("merchant-1")
+
+	payment, err := zp.NewPayment(ctx, 25000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	sim.Script(OpVerify,
+		Fail(CodeNotPaid),
+		Succeed(201).After(50*time.Millisecond),
+		Timeout(),
+		HTTPError(http.StatusBadGateway),
+	)
+
+	var apiErr *zarinpalgo.APIError
+	if _, err := zp.VerifyPayment(ctx, 25000, payment.Authority); !errors.As(err, &apiErr) || apiErr.Code != CodeNotPaid {
+		t.Errorf("Expected scripted %d, got %v", CodeNotPaid, err)
+	}
+
+	start := time.Now()
+	verification, err := zp.VerifyPayment(ctx, 25000, payment.Authority)
+	if err != nil || verification.RefID != 201 {
+		t.Errorf("Expected scripted success, got %+v %v", verification, err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("Expected scripted latency")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := zp.VerifyPayment(timeoutCtx, 25000, payment.Authority); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected scripted timeout, got %v", err)
+	}
+
+	if _, err := zp.VerifyPayment(ctx, 25000, payment.Authority); err == nil || errors.As(err, &apiErr) {
+		t.Errorf("Expected non-gateway error for bare HTTP error, got %v", err)
+	}
+
+	verification, err = zp.VerifyPayment(ctx, 25000, payment.Authority)
+	if err != nil || verification.Code != zarinpalgo.PaymentCodeAlreadyVerified {
+		t.Errorf("Expected regular behavior after the script, got %+v %v", verification, err)
+	}
+}
@@ -0,0 +1,403 @@
+package zarinpalgotest
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Gateway error codes returned by the simulator
+const (
+	CodeValidation       = -9  // The input params invalid, validation error
+	CodeInvalidMerchant  = -10 // Terminal is not valid
+	CodeAmountMismatch   = -50 // Session is not valid, amounts values is not the same
+	CodeNotPaid          = -51 // Session is not active, paid try
+	CodeInvalidAuthority = -54 // Invalid authority
+	CodeNotReversible    = -61 // Session is not in success status
+)
+
+var errorMessages = map[int]string{
+	CodeValidation:       "The input params invalid, validation error.",
+	CodeInvalidMerchant:  "Terminal is not valid, please check merchant_id or ip address.",
+	CodeAmountMismatch:   "Session is not valid, amounts values is not the same.",
+	CodeNotPaid:          "Session is not active, paid try.",
+	CodeInvalidAuthority: "Invalid authority.",
+	CodeNotReversible:    "Session is not in success status.",
+}
+
+// SimulatedPayment is a payment known to the simulator
+type SimulatedPayment struct {
+	Authority   string
+	Amount      int
+	Description string
+	CallbackURL string
+	Metadata    *zarinpalgo.Metadata
+	Wages       []zarinpalgo.Wage
+	Status      string // one of the zarinpalgo.InquiryStatus constants
+	RefID       int
+	CreatedAt   time.Time
+}
+
+// Simulator is a local Zarinpal gateway implementing the payment API endpoints, for offline
+// integration tests. Created payments wait on the bank page until Pay or Fail is called, or until
+// the user submits the StartPay page served at the payment URL.
+type Simulator struct {
+	*httptest.Server
+
+	// MerchantID rejects requests of other merchants with -10 when set
+	MerchantID string
+
+	mu         sync.Mutex
+	minAmounts map[zarinpalgo.Currency]int
+	payments   map[string]*SimulatedPayment
+	scripts    map[string][]Step
+	closed     chan struct{}
+	rand       io.Reader
+	nextRefID  int
+	apiMux     *http.ServeMux
+	listener   net.Listener
+}
+
+// SimulatorOption configures a Simulator
+type SimulatorOption func(*Simulator)
+
+// WithRand sets the source authorities are generated from, use SeededRand for reproducible authorities
+func WithRand(r io.Reader) SimulatorOption {
+	return func(s *Simulator) {
+		s.rand = r
+	}
+}
+
+// WithListener serves the simulator on l instead of a random local port, like to run it on a fixed
+// port for manual testing
+func WithListener(l net.Listener) SimulatorOption {
+	return func(s *Simulator) {
+		s.listener = l
+	}
+}
+
+// WithMinAmounts sets the smallest amounts per currency the simulator accepts instead of the
+// minimums of zarinpalgo.MinAmount, currencies without an entry aren't checked
+func WithMinAmounts(amounts map[zarinpalgo.Currency]int) SimulatorOption {
+	return func(s *Simulator) {
+		s.minAmounts = amounts
+	}
+}
+
+// NewSimulator starts a new Simulator, close it when done. Authorities are random unless
+// WithRand is given, so parallel simulators don't hand out the same ones.
+func NewSimulator(opts ...SimulatorOption) *Simulator {
+	s := &Simulator{
+		rand:      crand.Reader,
+		payments:  make(map[string]*SimulatedPayment),
+		scripts:   make(map[string][]Step),
+		closed:    make(chan struct{}),
+		nextRefID: 100000,
+		apiMux:    http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.apiMux.HandleFunc("POST /pg/v4/payment/request.json", s.scripted(OpRequest, s.handleRequest))
+	s.apiMux.HandleFunc("POST /pg/v4/payment/verify.json", s.scripted(OpVerify, s.handleVerify))
+	s.apiMux.HandleFunc("POST /pg/v4/payment/inquiry.json", s.scripted(OpInquiry, s.handleInquiry))
+	s.apiMux.HandleFunc("POST /pg/v4/payment/unVerified.json", s.scripted(OpUnverified, s.handleUnverified))
+	s.apiMux.HandleFunc("POST /pg/v4/payment/reverse.json", s.scripted(OpReverse, s.handleReverse))
+	s.apiMux.HandleFunc("GET /pg/StartPay/{authority}", s.handleStartPayPage)
+	s.apiMux.HandleFunc("POST /pg/StartPay/{authority}", s.handleStartPay)
+
+	s.Server = httptest.NewUnstartedServer(s.apiMux)
+	if s.listener != nil {
+		s.Server.Listener.Close()
+		s.Server.Listener = s.listener
+	}
+	s.Server.Start()
+	return s
+}
+
+// Close releases requests held by scripted steps and shuts the simulator down
+func (s *Simulator) Close() {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.Server.Close()
+}
+
+// Client returns a client talking to the simulator
+func (s *Simulator) Client(merchantID string) *zarinpalgo.Zarinpal {
+	z := zarinpalgo.New(merchantID)
+	z.APIBaseURL = s.URL + "/pg/v4/payment/"
+	z.PaymentBaseURL = s.URL + "/pg/StartPay/"
+	return z
+}
+
+// Pay completes the payment as if the user paid on the bank page
+func (s *Simulator) Pay(authority string) error {
+	return s.setStatus(authority, zarinpalgo.InquiryStatusPaid)
+}
+
+// Fail fails the payment as if the user canceled on the bank page
+func (s *Simulator) Fail(authority string) error {
+	return s.setStatus(authority, zarinpalgo.InquiryStatusFailed)
+}
+
+func (s *Simulator) setStatus(authority, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[authority]
+	if !ok {
+		return fmt.Errorf("unknown authority %s", authority)
+	}
+	if payment.Status != zarinpalgo.InquiryStatusInBank {
+		return fmt.Errorf("payment %s is %s", authority, payment.Status)
+	}
+	payment.Status = status
+	return nil
+}
+
+// Payment returns a copy of a payment known to the simulator
+func (s *Simulator) Payment(authority string) (SimulatedPayment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[authority]
+	if !ok {
+		return SimulatedPayment{}, false
+	}
+	return *payment, true
+}
+
+// minAmount returns the smallest amount accepted in the currency, payments without one are in Rials
+func (s *Simulator) minAmount(currency zarinpalgo.Currency) (int, bool) {
+	if s.minAmounts == nil {
+		return zarinpalgo.MinAmount(currency)
+	}
+	if currency == "" {
+		currency = zarinpalgo.CurrencyRial
+	}
+	min, ok := s.minAmounts[currency]
+	return min, ok
+}
+
+func (s *Simulator) handleRequest(w http.ResponseWriter, r *http.Request) {
+	var body zarinpalgo.PaymentRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	var validations []interface{}
+	if min, ok := s.minAmount(body.Currency); ok && body.Amount < min {
+		validations = append(validations, map[string]string{"amount": fmt.Sprintf("The amount must be at least %d.", min)})
+	}
+	if strings.TrimSpace(body.Description) == "" {
+		validations = append(validations, map[string]string{"description": "The description field is required."})
+	}
+	if !strings.HasPrefix(body.CallbackURL, "http://") && !strings.HasPrefix(body.CallbackURL, "https://") {
+		validations = append(validations, map[string]string{"callback_url": "The callback url format is invalid."})
+	}
+	if len(validations) > 0 {
+		writeError(w, CodeValidation, validations)
+		return
+	}
+
+	s.mu.Lock()
+	authority, err := NewAuthority(s.rand)
+	if err != nil {
+		s.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	payment := &SimulatedPayment{
+		Authority:   authority,
+		Amount:      body.Amount,
+		Description: body.Description,
+		CallbackURL: body.CallbackURL,
+		Metadata:    body.Metadata,
+		Wages:       body.Wages,
+		Status:      zarinpalgo.InquiryStatusInBank,
+		CreatedAt:   time.Now(),
+	}
+	s.payments[payment.Authority] = payment
+	s.mu.Unlock()
+
+	writeData(w, zarinpalgo.PaymentCreationResponse{
+		Code:      zarinpalgo.PaymentCodeSuccess,
+		Message:   "Success",
+		Authority: payment.Authority,
+		FeeType:   "Merchant",
+		Fee:       fee(payment.Amount),
+	})
+}
+
+func (s *Simulator) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var body zarinpalgo.PaymentVerificationRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[body.Authority]
+	switch {
+	case !ok:
+		writeError(w, CodeInvalidAuthority, nil)
+	case payment.Amount != body.Amount:
+		writeError(w, CodeAmountMismatch, nil)
+	case payment.Status == zarinpalgo.InquiryStatusPaid:
+		s.nextRefID++
+		payment.RefID = s.nextRefID
+		payment.Status = zarinpalgo.InquiryStatusVerified
+		writeData(w, verification(payment, zarinpalgo.PaymentCodeSuccess, "Verified"))
+	case payment.Status == zarinpalgo.InquiryStatusVerified:
+		writeData(w, verification(payment, zarinpalgo.PaymentCodeAlreadyVerified, "Verified"))
+	default:
+		writeError(w, CodeNotPaid, nil)
+	}
+}
+
+func (s *Simulator) handleInquiry(w http.ResponseWriter, r *http.Request) {
+	var body zarinpalgo.PaymentInquiryRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[body.Authority]
+	if !ok {
+		writeError(w, CodeInvalidAuthority, nil)
+		return
+	}
+	writeData(w, zarinpalgo.PaymentInquiryResponse{
+		Code:    zarinpalgo.PaymentCodeSuccess,
+		Message: "Success",
+		Status:  payment.Status,
+	})
+}
+
+func (s *Simulator) handleUnverified(w http.ResponseWriter, r *http.Request) {
+	var body zarinpalgo.UnverifiedPaymentsRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := zarinpalgo.UnverifiedPaymentsResponse{
+		Code:        zarinpalgo.PaymentCodeSuccess,
+		Message:     "Success",
+		Authorities: []zarinpalgo.UnverifiedPayment{},
+	}
+	for _, payment := range s.payments {
+		if payment.Status == zarinpalgo.InquiryStatusPaid {
+			response.Authorities = append(response.Authorities, zarinpalgo.UnverifiedPayment{
+				Authority:   payment.Authority,
+				Amount:      payment.Amount,
+				CallbackURL: payment.CallbackURL,
+				Date:        payment.CreatedAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+	}
+	writeData(w, response)
+}
+
+func (s *Simulator) handleReverse(w http.ResponseWriter, r *http.Request) {
+	var body zarinpalgo.PaymentReverseRequest
+	if !s.decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.payments[body.Authority]
+	switch {
+	case !ok:
+		writeError(w, CodeInvalidAuthority, nil)
+	case payment.Status == zarinpalgo.InquiryStatusPaid || payment.Status == zarinpalgo.InquiryStatusVerified:
+		payment.Status = zarinpalgo.InquiryStatusReversed
+		writeData(w, zarinpalgo.PaymentReverseResponse{Code: zarinpalgo.PaymentCodeSuccess, Message: "Reversed"})
+	default:
+		writeError(w, CodeNotReversible, nil)
+	}
+}
+
+// decode reads the request body into v and checks its merchant ID
+func (s *Simulator) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	var merchant struct {
+		MerchantID string `json:"merchant_id"`
+	}
+
+	raw, err := io.ReadAll(r.Body)
+	if err != nil || json.Unmarshal(raw, v) != nil || json.Unmarshal(raw, &merchant) != nil {
+		writeError(w, CodeValidation, nil)
+		return false
+	}
+
+	if merchant.MerchantID == "" || (s.MerchantID != "" && merchant.MerchantID != s.MerchantID) {
+		writeError(w, CodeInvalidMerchant, nil)
+		return false
+	}
+	return true
+}
+
+func verification(payment *SimulatedPayment, code int, message string) zarinpalgo.PaymentVerificationResponse {
+	hash := sha256.Sum256([]byte(payment.Authority))
+	return zarinpalgo.PaymentVerificationResponse{
+		Code:     code,
+		Message:  message,
+		CardHash: strings.ToUpper(hex.EncodeToString(hash[:])),
+		CardPan:  "502229******5995",
+		RefID:    payment.RefID,
+		FeeType:  "Merchant",
+		Fee:      fee(payment.Amount),
+	}
+}
+
+// fee mimics the gateway fee of one percent
+func fee(amount int) int {
+	return amount / 100
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":   data,
+		"errors": []interface{}{},
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, validations []interface{}) {
+	if validations == nil {
+		validations = []interface{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": []interface{}{},
+		"errors": zarinpalgo.ErrorResponse{
+			Code:        code,
+			Message:     errorMessages[code],
+			Validations: validations,
+		},
+	})
+}
@@ -0,0 +1,142 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestSimulatorPaymentFlow(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	zp := sim.Client("merchant-1")
+
+	payment, err := zp.NewPayment(ctx, 25000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	status, err := zp.CheckPaymentStatus(ctx, 25000, payment.Authority)
+	var apiErr *zarinpalgo.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeNotPaid || status.IsSuccessful {
+		t.Errorf("Expected unpaid payment to fail verification with %d, got %v", CodeNotPaid, err)
+	}
+
+	if err := sim.Pay(payment.Authority); err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+
+	unverified, err := zp.UnverifiedPayments(ctx)
+	if err != nil || len(unverified.Authorities) != 1 || unverified.Authorities[0].Authority != payment.Authority {
+		t.Errorf("Expected paid payment to be listed as unverified, got %+v %v", unverified, err)
+	}
+
+	if _, err := zp.CheckPaymentStatus(ctx, 20000, payment.Authority); !errors.As(err, &apiErr) || apiErr.Code != CodeAmountMismatch {
+		t.Errorf("Expected amount mismatch, got %v", err)
+	}
+
+	status, err = zp.CheckPaymentStatus(ctx, 25000, payment.Authority)
+	if err != nil || !status.IsSuccessful || status.IsRepeated || status.RefID == 0 {
+		t.Errorf("Expected successful verification, got %+v %v", status, err)
+	}
+
+	status, err = zp.CheckPaymentStatus(ctx, 25000, payment.Authority)
+	if err != nil || !status.IsRepeated {
+		t.Errorf("Expected repeated verification, got %+v %v", status, err)
+	}
+
+	inquiry, err := zp.InquirePayment(ctx, payment.Authority)
+	if err != nil || inquiry.Status != zarinpalgo.InquiryStatusVerified {
+		t.Errorf("Expected verified inquiry status, got %+v %v", inquiry, err)
+	}
+}
+
+func TestSimulatorValidation(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+	sim.MerchantID = "merchant-1"
+
+	ctx := context.Background()
+	var apiErr *zarinpalgo.APIError
+
+	_, err := sim.Client("merchant-1").NewPayment(ctx, 999, "Order 1", nil, "https://example.com/callback", nil)
+	if !errors.Is(err, zarinpalgo.ErrInvalidAmount) || errors.As(err, &apiErr) {
+		t.Errorf("Expected the client to refuse the amount, got %v", err)
+	}
+
+	// without the client side minimum the simulator refuses it
+	unchecked := sim.Client("merchant-1")
+	unchecked.MinAmounts = map[zarinpalgo.Currency]int{}
+	_, err = unchecked.NewPayment(ctx, 999, "Order 1", nil, "https://example.com/callback", nil)
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeValidation || len(apiErr.Validations) != 1 {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+
+	// Tomans are checked against the Toman minimum
+	params := zarinpalgo.PaymentParams{Amount: 500, Currency: zarinpalgo.CurrencyToman, Description: "Order 1", CallbackURL: "https://example.com/callback"}
+	if _, err = unchecked.CreatePayment(ctx, params); err != nil {
+		t.Errorf("Expected 500 Tomans accepted, got %v", err)
+	}
+	params.Amount = zarinpalgo.MinAmountToman - 1
+	if _, err = unchecked.CreatePayment(ctx, params); !errors.As(err, &apiErr) || apiErr.Code != CodeValidation {
+		t.Errorf("Expected validation error below the Toman minimum, got %v", err)
+	}
+
+	_, err = sim.Client("merchant-2").NewPayment(ctx, 10000, "Order 1", nil, "https://example.com/callback", nil)
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeInvalidMerchant {
+		t.Errorf("Expected invalid merchant error, got %v", err)
+	}
+
+	_, err = sim.Client("merchant-1").VerifyPayment(ctx, 10000, "unknown")
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeInvalidAuthority {
+		t.Errorf("Expected invalid authority error, got %v", err)
+	}
+}
+
+func TestSimulatorReverse(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	zp := sim.Client("merchant-1")
+
+	payment, err := zp.NewPayment(ctx, 25000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	var apiErr *zarinpalgo.APIError
+	if _, err := zp.ReversePayment(ctx, payment.Authority); !errors.As(err, &apiErr) || apiErr.Code != CodeNotReversible {
+		t.Errorf("Expected unpaid payment not to be reversible, got %v", err)
+	}
+
+	sim.Pay(payment.Authority)
+	if _, err := zp.ReversePayment(ctx, payment.Authority); err != nil {
+		t.Fatalf("Failed to reverse payment: %v", err)
+	}
+	if p, _ := sim.Payment(payment.Authority); p.Status != zarinpalgo.InquiryStatusReversed {
+		t.Errorf("Expected reversed payment, got %s", p.Status)
+	}
+}
+
+func TestSimulatorWithListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim := NewSimulator(WithListener(l))
+	defer sim.Close()
+
+	if want := "http://" + l.Addr().String(); sim.URL != want {
+		t.Errorf("Expected URL %s, got %s", want, sim.URL)
+	}
+	if _, err := sim.Client("merchant-1").NewPayment(context.Background(), 10000, "Order 1", nil, "https://example.com/callback", nil); err != nil {
+		t.Errorf("Failed to create payment: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package zarinpalgotest
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Form values of the StartPay page
+const (
+	StartPayActionParam  = "action"
+	StartPayActionPay    = "pay"
+	StartPayActionCancel = "cancel"
+)
+
+var startPayTemplate = template.Must(template.New("startpay").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Zarinpal Simulator</title>
+</head>
+<body>
+<h1>Zarinpal Simulator</h1>
+<p>{{.Description}}</p>
+<p>Amount: {{.Amount}} Rials</p>
+<form method="post">
+<button type="submit" name="action" value="pay">Pay</button>
+<button type="submit" name="action" value="cancel">Cancel</button>
+</form>
+</body>
+</html>
+`))
+
+// handleStartPayPage renders the bank page of a payment waiting for the user
+func (s *Simulator) handleStartPayPage(w http.ResponseWriter, r *http.Request) {
+	payment, ok := s.Payment(r.PathValue("authority"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if payment.Status != zarinpalgo.InquiryStatusInBank {
+		http.Error(w, "payment is "+payment.Status, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	startPayTemplate.Execute(w, payment)
+}
+
+// handleStartPay pays or cancels the payment and redirects the user to the merchant's callback URL
+func (s *Simulator) handleStartPay(w http.ResponseWriter, r *http.Request) {
+	authority := r.PathValue("authority")
+
+	var err error
+	status := zarinpalgo.CallbackStatusOK
+	switch r.FormValue(StartPayActionParam) {
+	case StartPayActionPay:
+		err = s.Pay(authority)
+	case StartPayActionCancel:
+		err = s.Fail(authority)
+		status = zarinpalgo.CallbackStatusNOK
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	payment, _ := s.Payment(authority)
+	callbackURL, err := url.Parse(payment.CallbackURL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	query := callbackURL.Query()
+	query.Set(zarinpalgo.CallbackAuthorityParam, authority)
+	query.Set(zarinpalgo.CallbackStatusParam, string(status))
+	callbackURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, callbackURL.String(), http.StatusFound)
+}
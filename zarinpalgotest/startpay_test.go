@@ -0,0 +1,68 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestSimulatorStartPay(t *testing.T) {
+	sim := NewSimulator()
+	defer sim.Close()
+
+	ctx := context.Background()
+	zp := sim.Client("merchant-1")
+
+	results := make(chan zarinpalgo.PaymentStatus, 1)
+	merchant := httptest.NewServer(zp.CallbackHandler(func(ctx context.Context, callback zarinpalgo.CallbackData) (int, error) {
+		return 25000, nil
+	}, func(ctx context.Context, status zarinpalgo.PaymentStatus) {
+		results <- status
+	}))
+	defer merchant.Close()
+
+	tests := []struct {
+		action     string
+		successful bool
+	}{
+		{StartPayActionPay, true},
+		{StartPayActionCancel, false},
+	}
+
+	for _, test := range tests {
+		payment, err := zp.NewPayment(ctx, 25000, "Order 1", nil, merchant.URL+"/callback?order=1", nil)
+		if err != nil {
+			t.Fatalf("Failed to create payment: %v", err)
+		}
+
+		page, err := http.Get(zp.GetPaymentURL(payment.Authority))
+		if err != nil {
+			t.Fatalf("Failed to load StartPay page: %v", err)
+		}
+		body, _ := io.ReadAll(page.Body)
+		page.Body.Close()
+		if page.StatusCode != http.StatusOK || !strings.Contains(string(body), "Order 1") {
+			t.Errorf("Unexpected StartPay page %d: %s", page.StatusCode, body)
+		}
+
+		resp, err := http.PostForm(zp.GetPaymentURL(payment.Authority), url.Values{StartPayActionParam: {test.action}})
+		if err != nil {
+			t.Fatalf("Failed to submit StartPay page: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Request.URL.Query().Get("order") != "1" {
+			t.Errorf("Expected to land on the callback URL, got %d %s", resp.StatusCode, resp.Request.URL)
+		}
+
+		status := <-results
+		if status.Authority != payment.Authority || status.IsSuccessful != test.successful {
+			t.Errorf("Unexpected status after %s: %+v", test.action, status)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package zarinpalgotest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	sim := NewSimulator()
+	recorder, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	recorder.BeforeSave = func(interaction *Interaction) {
+		interaction.RequestBody = []byte(strings.ReplaceAll(string(interaction.RequestBody), "merchant-1", "merchant-x"))
+	}
+
+	zp := sim.Client("merchant-1")
+	zp.HTTPClient = recorder.Client()
+	payment, err := zp.NewPayment(ctx, 25000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	sim.Pay(payment.Authority)
+	recorded, err := zp.CheckPaymentStatus(ctx, 25000, payment.Authority)
+	if err != nil {
+		t.Fatalf("Failed to verify payment: %v", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+	sim.Close()
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+
+	zp = sim.Client("merchant-x")
+	zp.HTTPClient = replayer.Client()
+	replayed, err := zp.NewPayment(ctx, 25000, "Order 1", nil, "https://example.com/callback", nil)
+	if err != nil || replayed.Authority != payment.Authority {
+		t.Errorf("Expected replayed authority %s, got %+v %v", payment.Authority, replayed, err)
+	}
+	status, err := zp.CheckPaymentStatus(ctx, 25000, payment.Authority)
+	if err != nil || status.RefID != recorded.RefID {
+		t.Errorf("Expected replayed verification %+v, got %+v %v", recorded, status, err)
+	}
+
+	if _, err := zp.CheckPaymentStatus(ctx, 25000, payment.Authority); err == nil {
+		t.Error("Expected error once the recorded interactions are used up")
+	}
+	if _, err := zp.NewPayment(ctx, 30000, "Order 1", nil, "https://example.com/callback", nil); err == nil {
+		t.Error("Expected error for a request that wasn't recorded")
+	}
+}
+
+func TestRedactWith(t *testing.T) {
+	interaction := Interaction{
+		RequestBody:  []byte(`{"merchant_id":"4d7e1c8a-0000-0000-0000-00000000abcd","authority":"A1"}`),
+		ResponseBody: []byte(`{"data":{"card_hash":"0123456789abcdef","ref_id":201}}`),
+	}
+	RedactWith(zarinpalgo.DefaultRedactionPolicy)(&interaction)
+
+	if request := string(interaction.RequestBody); strings.Contains(request, "4d7e1c8a") || !strings.Contains(request, `"authority":"A1"`) {
+		t.Errorf("Expected the merchant ID to be masked, got %s", request)
+	}
+	if response := string(interaction.ResponseBody); strings.Contains(response, "0123") || !strings.Contains(response, `"ref_id":201`) {
+		t.Errorf("Expected the card hash to be masked, got %s", response)
+	}
+}
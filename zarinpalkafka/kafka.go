@@ -0,0 +1,53 @@
+// Package zarinpalkafka publishes zarinpalgo payment events to Kafka
+package zarinpalkafka
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/segmentio/kafka-go"
+)
+
+// Event headers set on every message
+const (
+	EventIDHeader   = "zarinpal-event-id"
+	EventTypeHeader = "zarinpal-event-type"
+)
+
+// Writer writes messages to Kafka, *kafka.Writer implements it
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Publisher is a zarinpalgo.Publisher writing events as JSON messages keyed by authority,
+// so the events of a payment stay ordered within their partition
+type Publisher struct {
+	writer Writer
+}
+
+var _ zarinpalgo.Publisher = (*Publisher)(nil)
+
+// New creates a Publisher using the writer, configure the topic and acks on the writer.
+// Use kafka.RequireAll acks for at-least-once delivery.
+func New(writer Writer) *Publisher {
+	return &Publisher{writer: writer}
+}
+
+// Publish implements zarinpalgo.Publisher
+func (p *Publisher) Publish(ctx context.Context, event zarinpalgo.WebhookEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.Payment.Authority),
+		Value: value,
+		Time:  event.CreatedAt,
+		Headers: []kafka.Header{
+			{Key: EventIDHeader, Value: []byte(event.ID)},
+			{Key: EventTypeHeader, Value: []byte(event.Type)},
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package zarinpalkafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/segmentio/kafka-go"
+)
+
+type recordingWriter struct {
+	messages []kafka.Message
+}
+
+func (w *recordingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	writer := &recordingWriter{}
+	event := zarinpalgo.NewWebhookEvent(zarinpalgo.PaymentStatus{Authority: "A1", IsSuccessful: true, RefID: 201})
+
+	if err := New(writer).Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if len(writer.messages) != 1 {
+		t.Fatalf("Expected one message, got %d", len(writer.messages))
+	}
+
+	message := writer.messages[0]
+	if string(message.Key) != "A1" || string(message.Headers[0].Value) != event.ID || string(message.Headers[1].Value) != zarinpalgo.EventPaymentVerified {
+		t.Errorf("Unexpected message: %+v", message)
+	}
+
+	var decoded zarinpalgo.WebhookEvent
+	if err := json.Unmarshal(message.Value, &decoded); err != nil || decoded.Payment.RefID != 201 {
+		t.Errorf("Unexpected message value %s: %v", message.Value, err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package zarinpalkit exposes a Zarinpal client as go-kit endpoints with an HTTP transport, for
+// services built on go-kit. The request and response bodies are the ones of the server package.
+package zarinpalkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/server"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 20
+
+// Endpoints are the endpoints of the payment service, wrap them with go-kit middlewares
+// before passing them to NewHTTPHandler
+type Endpoints struct {
+	Create endpoint.Endpoint // server.CreatePaymentRequest to server.CreatePaymentResponse
+	Verify endpoint.Endpoint // server.VerifyPaymentRequest to zarinpalgo.PaymentStatus
+	Status endpoint.Endpoint // StatusRequest to zarinpalgo.PaymentInquiryResponse
+}
+
+// StatusRequest asks for the status of a payment
+type StatusRequest struct {
+	Authority string `json:"authority"`
+}
+
+// MakeEndpoints creates the endpoints of the client
+func MakeEndpoints(z zarinpalgo.Client) Endpoints {
+	return Endpoints{
+		Create: MakeCreateEndpoint(z),
+		Verify: MakeVerifyEndpoint(z),
+		Status: MakeStatusEndpoint(z),
+	}
+}
+
+// Wrap returns the endpoints wrapped with the middleware, like logging or rate limiting
+func (e Endpoints) Wrap(mw endpoint.Middleware) Endpoints {
+	return Endpoints{
+		Create: mw(e.Create),
+		Verify: mw(e.Verify),
+		Status: mw(e.Status),
+	}
+}
+
+// MakeCreateEndpoint creates a payment
+func MakeCreateEndpoint(z zarinpalgo.Client) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(server.CreatePaymentRequest)
+		payment, err := z.CreatePayment(ctx, zarinpalgo.PaymentParams{
+			Amount:      req.Amount,
+			Description: req.Description,
+			CallbackURL: req.CallbackURL,
+			Metadata:    req.Metadata,
+			Wages:       req.Wages,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return server.CreatePaymentResponse{
+			Authority:  payment.Authority,
+			PaymentURL: z.GetPaymentURL(payment.Authority),
+			FeeType:    payment.FeeType,
+			Fee:        payment.Fee,
+		}, nil
+	}
+}
+
+// MakeVerifyEndpoint verifies a payment. Payments the gateway refuses to verify are answered with
+// an unsuccessful status instead of an error.
+func MakeVerifyEndpoint(z zarinpalgo.Client) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(server.VerifyPaymentRequest)
+		status, err := z.CheckPaymentStatus(ctx, req.Amount, req.Authority)
+		var apiErr *zarinpalgo.APIError
+		if err != nil && !errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return status, nil
+	}
+}
+
+// MakeStatusEndpoint inquires the status of a payment
+func MakeStatusEndpoint(z zarinpalgo.Client) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(StatusRequest)
+		return z.InquirePayment(ctx, req.Authority)
+	}
+}
+
+// NewHTTPHandler serves the endpoints over HTTP:
+//
+//	POST /payments              create a payment
+//	POST /payments/verify       verify a payment
+//	GET  /payments/{authority}  inquire the status of a payment
+//
+// Errors are answered with server.ErrorResponse bodies unless opts set another error encoder.
+func NewHTTPHandler(e Endpoints, opts ...httptransport.ServerOption) http.Handler {
+	opts = append([]httptransport.ServerOption{httptransport.ServerErrorEncoder(EncodeError)}, opts...)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /payments", httptransport.NewServer(e.Create, DecodeCreateRequest, encodeResponse(http.StatusCreated), opts...))
+	mux.Handle("POST /payments/verify", httptransport.NewServer(e.Verify, DecodeVerifyRequest, encodeResponse(http.StatusOK), opts...))
+	mux.Handle("GET /payments/{authority}", httptransport.NewServer(e.Status, DecodeStatusRequest, encodeResponse(http.StatusOK), opts...))
+	return mux
+}
+
+// decodeError is a request the transport could not decode, answered with 400
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string { return e.err.Error() }
+
+func (e decodeError) Unwrap() error { return e.err }
+
+// DecodeCreateRequest decodes the body of POST /payments
+func DecodeCreateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req server.CreatePaymentRequest
+	return req, decodeJSON(r, &req)
+}
+
+// DecodeVerifyRequest decodes the body of POST /payments/verify
+func DecodeVerifyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req server.VerifyPaymentRequest
+	return req, decodeJSON(r, &req)
+}
+
+// DecodeStatusRequest reads the authority from the path of GET /payments/{authority}
+func DecodeStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	authority := r.PathValue("authority")
+	if authority == "" {
+		return nil, decodeError{err: errors.New("missing authority")}
+	}
+	return StatusRequest{Authority: authority}, nil
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return decodeError{err: err}
+	}
+	return nil
+}
+
+func encodeResponse(statusCode int) httptransport.EncodeResponseFunc {
+	return func(_ context.Context, w http.ResponseWriter, response interface{}) error {
+		return writeJSON(w, statusCode, response)
+	}
+}
+
+// EncodeError answers undecodable requests with 400, gateway rejections with 422 and transport
+// failures with 502, like the server package
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
+	var (
+		apiErr    *zarinpalgo.APIError
+		decodeErr decodeError
+	)
+	switch {
+	case errors.As(err, &decodeErr):
+		writeJSON(w, http.StatusBadRequest, server.ErrorResponse{Error: err.Error()})
+	case errors.As(err, &apiErr):
+		writeJSON(w, http.StatusUnprocessableEntity, server.ErrorResponse{Error: apiErr.Message, Code: apiErr.Code})
+	default:
+		writeJSON(w, http.StatusBadGateway, server.ErrorResponse{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(v)
+}
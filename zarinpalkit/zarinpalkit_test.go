@@ -0,0 +1,117 @@
+package zarinpalkit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/blackestwhite/zarinpalgo/server"
+	"github.com/blackestwhite/zarinpalgo/zarinpalgotest"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func newTestHandler(t *testing.T, mw endpoint.Middleware) http.Handler {
+	t.Helper()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "request.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","authority":"A1","fee_type":"Merchant","fee":100},"errors":[]}`))
+		case strings.HasSuffix(r.URL.Path, "verify.json"):
+			w.Write([]byte(`{"data":[],"errors":{"code":-51,"message":"Session is not active, paid try","validations":[]}}`))
+		case strings.HasSuffix(r.URL.Path, "inquiry.json"):
+			w.Write([]byte(`{"data":{"code":100,"message":"Success","status":"PAID"},"errors":[]}`))
+		default:
+			w.Write([]byte(`{"data":[],"errors":{"code":-10,"message":"Terminal is not valid","validations":[]}}`))
+		}
+	}))
+	t.Cleanup(gateway.Close)
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = gateway.URL + "/"
+
+	endpoints := MakeEndpoints(zp)
+	if mw != nil {
+		endpoints = endpoints.Wrap(mw)
+	}
+	return NewHTTPHandler(endpoints)
+}
+
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateEndpoint(t *testing.T) {
+	var calls int
+	h := newTestHandler(t, func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			return next(ctx, request)
+		}
+	})
+
+	rec := do(h, "POST", "/payments", `{"amount":10000,"description":"Order 1","callback_url":"https://example.com/callback"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var resp server.CreatePaymentResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Authority != "A1" || !strings.HasSuffix(resp.PaymentURL, "/StartPay/A1") {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the middleware to be called once, got %d", calls)
+	}
+
+	if rec := do(h, "POST", "/payments", `{"amount":"ten"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid body, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestVerifyAndStatusEndpoints(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	rec := do(h, "POST", "/payments/verify", `{"amount":10000,"authority":"A1"}`)
+	var status zarinpalgo.PaymentStatus
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.IsSuccessful {
+		t.Errorf("Expected an unsuccessful status, got %d %+v", rec.Code, status)
+	}
+
+	rec = do(h, "GET", "/payments/A1", "")
+	var inquiry zarinpalgo.PaymentInquiryResponse
+	json.NewDecoder(rec.Body).Decode(&inquiry)
+	if rec.Code != http.StatusOK || inquiry.Status != zarinpalgo.InquiryStatusPaid {
+		t.Errorf("Expected status PAID, got %d %+v", rec.Code, inquiry)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	EncodeError(context.Background(), &zarinpalgo.APIError{Code: -10, Message: "Terminal is not valid"}, rec)
+
+	var resp server.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != -10 {
+		t.Errorf("Expected the gateway code with status %d, got %d %+v", http.StatusUnprocessableEntity, rec.Code, resp)
+	}
+}
+
+func TestCreateEndpointParams(t *testing.T) {
+	fake := zarinpalgotest.NewFakeClient()
+	create := MakeCreateEndpoint(fake)
+
+	_, err := create(context.Background(), server.CreatePaymentRequest{Amount: 10000, Description: "Order 1", CallbackURL: "https://example.com/callback"})
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if calls := fake.CallsTo("CreatePayment"); len(calls) != 1 || calls[0].Amount != 10000 || calls[0].Description != "Order 1" {
+		t.Errorf("Expected the payment created with CreatePayment, got %+v", fake.Calls())
+	}
+}
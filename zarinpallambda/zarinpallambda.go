@@ -0,0 +1,184 @@
+// Package zarinpallambda processes Zarinpal callbacks in AWS Lambda behind API Gateway or an
+// Application Load Balancer. Google Cloud Functions and other runtimes serving net/http use
+// Zarinpal.CallbackHandler with a client from Client.
+package zarinpallambda
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+// Environment variables read by ClientFromEnv
+const (
+	MerchantIDEnv = "ZARINPAL_MERCHANT_ID"
+	SandboxEnv    = "ZARINPAL_SANDBOX"
+)
+
+// Timeouts of the clients, short so a slow gateway fails the invocation long before the
+// function times out
+const (
+	DefaultTimeout     = 8 * time.Second
+	DefaultDialTimeout = 2 * time.Second
+)
+
+// ErrMissingMerchantID is returned by ClientFromEnv when the merchant ID is not set
+var ErrMissingMerchantID = errors.New("missing merchant ID, set $" + MerchantIDEnv)
+
+type clientKey struct {
+	merchantID string
+	sandbox    bool
+}
+
+var clients sync.Map // clientKey to *zarinpalgo.Zarinpal
+
+// Client returns a client for the merchant, shared by the invocations of a warm function so
+// connections to the gateway are reused. Call it from the handler, not init, to keep cold
+// starts short.
+func Client(merchantID string, sandbox bool) *zarinpalgo.Zarinpal {
+	key := clientKey{merchantID: merchantID, sandbox: sandbox}
+	if z, ok := clients.Load(key); ok {
+		return z.(*zarinpalgo.Zarinpal)
+	}
+
+	z := zarinpalgo.NewWithMode(merchantID, sandbox)
+	z.HTTPClient = &http.Client{
+		Timeout: DefaultTimeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: DefaultDialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout: DefaultDialTimeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	actual, _ := clients.LoadOrStore(key, z)
+	return actual.(*zarinpalgo.Zarinpal)
+}
+
+// ClientFromEnv returns the shared client of the merchant in $ZARINPAL_MERCHANT_ID, in sandbox
+// mode when $ZARINPAL_SANDBOX is true
+func ClientFromEnv() (*zarinpalgo.Zarinpal, error) {
+	merchantID := os.Getenv(MerchantIDEnv)
+	if merchantID == "" {
+		return nil, ErrMissingMerchantID
+	}
+	sandbox, _ := strconv.ParseBool(os.Getenv(SandboxEnv))
+	return Client(merchantID, sandbox), nil
+}
+
+// response is the answer to a callback, shared by the event types
+type response struct {
+	statusCode int
+	body       string
+}
+
+func (r response) headers() map[string]string {
+	return map[string]string{"Content-Type": "text/plain; charset=utf-8"}
+}
+
+// handle verifies the payment like Zarinpal.CallbackHandler and returns the answer to the user,
+// clientIP is the source IP reported by the event for the risk hooks
+func handle(ctx context.Context, z *zarinpalgo.Zarinpal, clientIP string, values url.Values, lookup zarinpalgo.AmountLookupFunc, onResult func(ctx context.Context, status zarinpalgo.PaymentStatus), opts []zarinpalgo.CallbackOption) response {
+	if clientIP != "" && zarinpalgo.ClientIP(ctx) == "" {
+		ctx = zarinpalgo.WithClientIP(ctx, clientIP)
+	}
+	status, err := z.ProcessCallback(ctx, values, lookup, opts...)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		var handlerErr *zarinpalgo.HandlerError
+		if errors.As(err, &handlerErr) {
+			statusCode = handlerErr.StatusCode
+		}
+		return response{statusCode: statusCode, body: http.StatusText(statusCode)}
+	}
+
+	if onResult != nil && !status.Replayed {
+		// a panicking callback must not turn a verified payment into an error page
+		if panicErr := zarinpalgo.RunHook("callback result", func() { onResult(ctx, status) }); panicErr != nil && z.OnHookPanic != nil {
+			z.OnHookPanic(ctx, panicErr)
+		}
+	}
+	return response{statusCode: http.StatusOK, body: status.Message}
+}
+
+// queryValues merges the single and multi value query parameters of an event
+func queryValues(single map[string]string, multi map[string][]string) url.Values {
+	values := url.Values{}
+	for key, value := range single {
+		values.Set(key, value)
+	}
+	for key, list := range multi {
+		values[key] = list
+	}
+	return values
+}
+
+// forwardedIP returns the address the load balancer appended to X-Forwarded-For, earlier
+// addresses are sent by the client and can't be trusted
+func forwardedIP(single map[string]string, multi map[string][]string) string {
+	forwarded := single["x-forwarded-for"]
+	if list := multi["x-forwarded-for"]; len(list) > 0 {
+		forwarded = list[len(list)-1]
+	}
+	addresses := strings.Split(forwarded, ",")
+	return strings.TrimSpace(addresses[len(addresses)-1])
+}
+
+// APIGatewayCallback returns a Lambda handler serving the callback URL behind an API Gateway
+// REST API with proxy integration. It verifies the payment and passes the result to onResult
+// before answering the user with the status message.
+func APIGatewayCallback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, onResult func(ctx context.Context, status zarinpalgo.PaymentStatus), opts ...zarinpalgo.CallbackOption) func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		values := queryValues(req.QueryStringParameters, req.MultiValueQueryStringParameters)
+		r := handle(ctx, z, req.RequestContext.Identity.SourceIP, values, lookup, onResult, opts)
+		return events.APIGatewayProxyResponse{StatusCode: r.statusCode, Headers: r.headers(), Body: r.body}, nil
+	}
+}
+
+// HTTPAPICallback returns a Lambda handler serving the callback URL behind an API Gateway HTTP
+// API or a function URL, both sending version 2.0 payloads
+func HTTPAPICallback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, onResult func(ctx context.Context, status zarinpalgo.PaymentStatus), opts ...zarinpalgo.CallbackOption) func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		values, err := url.ParseQuery(req.RawQueryString)
+		if err != nil {
+			values = queryValues(req.QueryStringParameters, nil)
+		}
+		r := handle(ctx, z, req.RequestContext.HTTP.SourceIP, values, lookup, onResult, opts)
+		return events.APIGatewayV2HTTPResponse{StatusCode: r.statusCode, Headers: r.headers(), Body: r.body}, nil
+	}
+}
+
+// ALBCallback returns a Lambda handler serving the callback URL behind an Application Load
+// Balancer, which passes the query parameters without decoding them
+func ALBCallback(z *zarinpalgo.Zarinpal, lookup zarinpalgo.AmountLookupFunc, onResult func(ctx context.Context, status zarinpalgo.PaymentStatus), opts ...zarinpalgo.CallbackOption) func(ctx context.Context, req events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	return func(ctx context.Context, req events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		values := url.Values{}
+		for key, list := range queryValues(req.QueryStringParameters, req.MultiValueQueryStringParameters) {
+			for _, value := range list {
+				if unescaped, err := url.QueryUnescape(value); err == nil {
+					value = unescaped
+				}
+				values.Add(key, value)
+			}
+		}
+
+		r := handle(ctx, z, forwardedIP(req.Headers, req.MultiValueHeaders), values, lookup, onResult, opts)
+		return events.ALBTargetGroupResponse{
+			StatusCode:        r.statusCode,
+			StatusDescription: strconv.Itoa(r.statusCode) + " " + http.StatusText(r.statusCode),
+			Headers:           r.headers(),
+			Body:              r.body,
+		}, nil
+	}
+}
@@ -0,0 +1,114 @@
+package zarinpallambda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/blackestwhite/zarinpalgo"
+)
+
+func newStubClient(t *testing.T) *zarinpalgo.Zarinpal {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":100,"message":"Verified","ref_id":201},"errors":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	zp := zarinpalgo.New("merchant-1")
+	zp.APIBaseURL = server.URL + "/"
+	return zp
+}
+
+func lookup(ctx context.Context, callback zarinpalgo.CallbackData) (int, error) {
+	if callback.Authority != "A1" {
+		return 0, zarinpalgo.ErrPaymentNotFound
+	}
+	return 10000, nil
+}
+
+func TestAPIGatewayCallback(t *testing.T) {
+	var refID int
+	handler := APIGatewayCallback(newStubClient(t), lookup, func(ctx context.Context, status zarinpalgo.PaymentStatus) {
+		refID = status.RefID
+	})
+
+	tests := []struct {
+		query map[string]string
+		code  int
+	}{
+		{map[string]string{"Authority": "A1", "Status": "OK"}, http.StatusOK},
+		{map[string]string{"Authority": "A2", "Status": "OK"}, http.StatusNotFound},
+		{map[string]string{"Authority": "A1"}, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		resp, err := handler(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: test.query})
+		if err != nil || resp.StatusCode != test.code {
+			t.Errorf("Expected status code %d for %v, got %d %v", test.code, test.query, resp.StatusCode, err)
+		}
+	}
+	if refID != 201 {
+		t.Errorf("Expected onResult with ref ID 201, got %d", refID)
+	}
+}
+
+func TestHTTPAPICallback(t *testing.T) {
+	handler := HTTPAPICallback(newStubClient(t), lookup, nil)
+
+	resp, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{RawQueryString: "Authority=A1&Status=OK"})
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Body != "Verified" {
+		t.Errorf("Expected the payment to be verified, got %+v %v", resp, err)
+	}
+}
+
+func TestALBCallback(t *testing.T) {
+	handler := ALBCallback(newStubClient(t), lookup, func(ctx context.Context, status zarinpalgo.PaymentStatus) {
+		if ip := zarinpalgo.ClientIP(ctx); ip != "198.51.100.7" {
+			t.Errorf("Expected the client IP appended by the load balancer, got %q", ip)
+		}
+	})
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		QueryStringParameters: map[string]string{"Authority": "%41%31", "Status": "OK"},
+		Headers:               map[string]string{"x-forwarded-for": "10.0.0.1, 198.51.100.7"},
+	})
+	if err != nil || resp.StatusCode != http.StatusOK || resp.StatusDescription != "200 OK" || resp.Body != "Verified" {
+		t.Errorf("Expected the decoded authority to be verified, got %+v %v", resp, err)
+	}
+
+	resp, _ = handler(context.Background(), events.ALBTargetGroupRequest{
+		QueryStringParameters: map[string]string{"Authority": "A1", "Status": "NOK"},
+		Headers:               map[string]string{"x-forwarded-for": "198.51.100.7"},
+	})
+	if resp.StatusCode != http.StatusOK || resp.Body != "payment was canceled or failed" {
+		t.Errorf("Expected a canceled payment, got %+v", resp)
+	}
+}
+
+func TestClient(t *testing.T) {
+	a := Client("merchant-1", true)
+	if b := Client("merchant-1", true); a != b {
+		t.Error("Expected the client to be shared")
+	}
+	if c := Client("merchant-1", false); a == c {
+		t.Error("Expected a client per mode")
+	}
+	if a.HTTPClient.Timeout != DefaultTimeout {
+		t.Errorf("Expected timeout %s, got %s", DefaultTimeout, a.HTTPClient.Timeout)
+	}
+
+	t.Setenv(MerchantIDEnv, "")
+	if _, err := ClientFromEnv(); !errors.Is(err, ErrMissingMerchantID) {
+		t.Errorf("Expected ErrMissingMerchantID, got %v", err)
+	}
+	t.Setenv(MerchantIDEnv, "merchant-1")
+	t.Setenv(SandboxEnv, "true")
+	if z, err := ClientFromEnv(); err != nil || z != a {
+		t.Errorf("Expected the shared sandbox client, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Package zarinpalnats publishes zarinpalgo payment events to NATS JetStream
+package zarinpalnats
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// EventTypeHeader is set on every message
+const EventTypeHeader = "Zarinpal-Event-Type"
+
+// JetStream publishes acknowledged messages, jetstream.JetStream implements it
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Publisher is a zarinpalgo.Publisher publishing events as JSON messages to a JetStream subject.
+// Event IDs are used as message IDs, so JetStream drops events the outbox publishes twice
+// within the stream's duplicate window.
+type Publisher struct {
+	js      JetStream
+	subject string
+}
+
+var _ zarinpalgo.Publisher = (*Publisher)(nil)
+
+// New creates a Publisher publishing to the subject
+func New(js JetStream, subject string) *Publisher {
+	return &Publisher{js: js, subject: subject}
+}
+
+// Publish implements zarinpalgo.Publisher, it returns once the stream acknowledged the event
+func (p *Publisher) Publish(ctx context.Context, event zarinpalgo.WebhookEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.subject)
+	msg.Data = data
+	msg.Header.Set(EventTypeHeader, event.Type)
+
+	_, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID))
+	return err
+}
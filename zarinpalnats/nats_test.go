@@ -0,0 +1,44 @@
+package zarinpalnats
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/blackestwhite/zarinpalgo"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type recordingJetStream struct {
+	msgs []*nats.Msg
+	opts int
+}
+
+func (js *recordingJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.msgs = append(js.msgs, msg)
+	js.opts += len(opts)
+	return &jetstream.PubAck{Stream: "PAYMENTS", Sequence: uint64(len(js.msgs))}, nil
+}
+
+func TestPublisher(t *testing.T) {
+	js := &recordingJetStream{}
+	event := zarinpalgo.NewWebhookEvent(zarinpalgo.PaymentStatus{Authority: "A1"})
+
+	if err := New(js, "payments.events").Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if len(js.msgs) != 1 || js.opts != 1 {
+		t.Fatalf("Expected one message with its ID, got %d messages and %d options", len(js.msgs), js.opts)
+	}
+
+	msg := js.msgs[0]
+	if msg.Subject != "payments.events" || msg.Header.Get(EventTypeHeader) != zarinpalgo.EventPaymentFailed {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	var decoded zarinpalgo.WebhookEvent
+	if err := json.Unmarshal(msg.Data, &decoded); err != nil || decoded.ID != event.ID {
+		t.Errorf("Unexpected message data %s: %v", msg.Data, err)
+	}
+}